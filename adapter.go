@@ -0,0 +1,57 @@
+package buffer
+
+// AsIoBuffer moves the contents of b into an IoBuffer without copying them
+// and leaves b empty. The IoBuffer reads and writes b's memory until it
+// grows, the memory is never put back to a pool.
+func AsIoBuffer(b *Buffer) IoBuffer {
+	var buf *ioBuffer
+	if b.B == nil {
+		buf = New().(*ioBuffer)
+	} else {
+		buf = New(withBytes(b.B)).(*ioBuffer)
+		buf.off = b.off
+	}
+	b.B = nil
+	b.off = 0
+	b.lastRead = opInvalid
+	return buf
+}
+
+// AsBuffer moves the unread bytes of b into a Buffer and leaves b empty.
+// The memory of plain IoBuffers and MultiIoBuffers is handed over without
+// copying, it belongs to the Buffer from then on and b takes new memory on
+// the next write. The bytes of other implementations, like views and file
+// mappings, are copied.
+func AsBuffer(b IoBuffer) *Buffer {
+	switch ib := b.(type) {
+	case *ioBuffer:
+		if ib.closed || ib.fixed {
+			break
+		}
+		buf := &Buffer{B: ib.buf, off: ib.off}
+		if ib.b != nil {
+			ib.onFree(cap(*ib.b))
+		}
+		// the slice now belongs to the Buffer instead of the pool
+		ib.dropped += int64(len(ib.buf))
+		ib.b = nil
+		ib.buf = nullByte
+		ib.charge(0)
+		ib.off = 0
+		ib.offMark = ResetOffMark
+		ib.lastRune = 0
+		return buf
+	case *multiIoBuffer:
+		ib.coalesce()
+		if len(ib.bufs) == 0 {
+			return &Buffer{}
+		}
+		buf := AsBuffer(ib.bufs[0])
+		ib.releaseExhausted()
+		ib.lastRuneLen = 0
+		return buf
+	}
+	buf := &Buffer{B: b.CopyBytes()}
+	b.DiscardAll()
+	return buf
+}
@@ -0,0 +1,74 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestAsIoBuffer(t *testing.T) {
+	b := &Buffer{}
+	b.WriteString("hello world")
+	p := make([]byte, 6)
+	b.Read(p)
+	base := &b.B[6]
+
+	buf := AsIoBuffer(b)
+	if buf.String() != "world" {
+		t.Fatalf("got %q, want %q", buf.String(), "world")
+	}
+	if &buf.Bytes()[0] != base {
+		t.Fatal("contents were copied")
+	}
+	if b.Len() != 0 || b.B != nil {
+		t.Fatalf("source not emptied, len %d", b.Len())
+	}
+	buf.WriteString("!")
+	if buf.String() != "world!" {
+		t.Fatalf("got %q", buf.String())
+	}
+	// wrapped memory is not put back to the pool
+	PutIoBuffer(buf)
+
+	if empty := AsIoBuffer(&Buffer{}); empty.Len() != 0 {
+		t.Fatalf("len %d, want 0", empty.Len())
+	}
+}
+
+func TestAsBuffer(t *testing.T) {
+	buf := GetIoBuffer(64)
+	buf.WriteString("hello world")
+	buf.Drain(6)
+	base := &buf.Bytes()[0]
+
+	b := AsBuffer(buf)
+	if b.String() != "world" {
+		t.Fatalf("got %q, want %q", b.String(), "world")
+	}
+	if &b.Bytes()[0] != base {
+		t.Fatal("contents were copied")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("source not emptied, len %d", buf.Len())
+	}
+	// the source takes new memory and no longer aliases b
+	buf.WriteString("again")
+	if b.String() != "world" || buf.String() != "again" {
+		t.Fatalf("got %q and %q", b.String(), buf.String())
+	}
+	PutIoBuffer(buf)
+
+	m := newMulti()
+	b = AsBuffer(m)
+	if b.String() != "header|body|trailer" {
+		t.Fatalf("got %q", b.String())
+	}
+	if m.Len() != 0 {
+		t.Fatalf("multi not emptied, len %d", m.Len())
+	}
+
+	src := NewIoBufferString("hello world")
+	v := src.Limit(5)
+	b = AsBuffer(v)
+	if b.String() != "hello" || v.Len() != 0 || src.String() != " world" {
+		t.Fatalf("got %q, view len %d, source %q", b.String(), v.Len(), src.String())
+	}
+}
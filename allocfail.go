@@ -0,0 +1,72 @@
+package buffer
+
+import "sync/atomic"
+
+// RecoveryAction tells an IoBuffer how to go on after a failed allocation,
+// see SetAllocFailureHandler.
+type RecoveryAction int
+
+const (
+	// RecoveryFail fails the operation with the error, as without handler.
+	RecoveryFail RecoveryAction = iota
+	// RecoveryRetry checks again, once, e.g. after the handler shed load
+	// or flushed caches to free budget. Reads fail regardless, they have
+	// consumed the byte that didn't fit.
+	RecoveryRetry
+	// RecoveryPanic panics with the error, e.g. to crash early in tests.
+	RecoveryPanic
+)
+
+type allocFailureHandler struct {
+	fn func(req int, err error) RecoveryAction
+}
+
+var allocFailure atomic.Value // allocFailureHandler
+
+// SetAllocFailureHandler sets a function called whenever an IoBuffer can't
+// grow, because of its max size, its Budget or because the size doesn't
+// fit in an int. req is the number of bytes asked for and err an *Error
+// wrapping ErrTooLarge or ErrBudgetExceeded, the returned action decides
+// how the buffer goes on. nil removes the handler.
+//
+// The Go runtime doesn't report running out of memory, so only these
+// checks reach the handler.
+func SetAllocFailureHandler(h func(req int, err error) RecoveryAction) {
+	allocFailure.Store(allocFailureHandler{fn: h})
+}
+
+// checkGrow returns an error if n more bytes can't be written, after
+// consulting the alloc failure handler
+func (b *ioBuffer) checkGrow(op string, n int) error {
+	retried := false
+	for {
+		var err error
+		if n > maxInt-2*cap(b.buf) {
+			err = ErrTooLarge
+		} else {
+			err = b.overflow(n)
+		}
+		if err == nil {
+			return nil
+		}
+		e := opError(op, n, b, err)
+		if b.allocFailed(n, e) != RecoveryRetry || retried {
+			return e
+		}
+		retried = true
+	}
+}
+
+// allocFailed reports the failure to grow by req bytes to the alloc
+// failure handler, panicking if it asks to
+func (b *ioBuffer) allocFailed(req int, err error) RecoveryAction {
+	h, _ := allocFailure.Load().(allocFailureHandler)
+	if h.fn == nil {
+		return RecoveryFail
+	}
+	action := h.fn(req, err)
+	if action == RecoveryPanic {
+		panic(err)
+	}
+	return action
+}
@@ -0,0 +1,52 @@
+package buffer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAllocFailureHandler(t *testing.T) {
+	defer SetAllocFailureHandler(nil)
+
+	g := NewBudget(256)
+	hog := New(WithBudget(g), WithCapacity(200))
+	b := New(WithBudget(g), WithCapacity(16))
+
+	var reqs []int
+	SetAllocFailureHandler(func(req int, err error) RecoveryAction {
+		reqs = append(reqs, req)
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("got %v, want ErrBudgetExceeded", err)
+		}
+		// shed load and try again
+		hog.Free()
+		return RecoveryRetry
+	})
+	if _, err := b.Write(make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 || reqs[0] != 100 {
+		t.Fatalf("handler got %v, want [100]", reqs)
+	}
+
+	SetAllocFailureHandler(func(req int, err error) RecoveryAction {
+		return RecoveryRetry
+	})
+	// only one retry
+	if _, err := b.Write(make([]byte, 1000)); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded", err)
+	}
+
+	SetAllocFailureHandler(func(req int, err error) RecoveryAction {
+		return RecoveryPanic
+	})
+	expectPanic(t, "write", func() { b.WriteString(strings.Repeat("x", 1000)) })
+	expectPanic(t, "read", func() { b.ReadFrom(strings.NewReader(strings.Repeat("x", 1000))) })
+
+	SetAllocFailureHandler(nil)
+	m := New(WithMaxSize(4))
+	if err := m.ReplaceRange(0, 0, []byte("12345")); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+}
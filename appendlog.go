@@ -0,0 +1,124 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+// appendLogReadSize is how much ReplayAppendLog reads at a time
+const appendLogReadSize = 32 << 10
+
+// AppendLog is a write-ahead style log of records framed by
+// WriteCRCFrame. Records are appended to a DoubleBuffer, so appends don't
+// wait for a Flush in progress. It is safe for concurrent use.
+type AppendLog struct {
+	d *DoubleBuffer
+}
+
+// NewAppendLog returns an empty AppendLog backed by pooled buffers, Release
+// returns them.
+func NewAppendLog() *AppendLog {
+	return &AppendLog{d: NewDoubleBuffer()}
+}
+
+// Append appends record to the log. Records of 4GB or more fail with
+// ErrTooLarge.
+func (l *AppendLog) Append(record []byte) error {
+	if uint64(len(record)) > uint64(^uint32(0)) {
+		return &Error{Op: "append", Size: len(record), Err: ErrTooLarge}
+	}
+	l.d.Append(func(b *Buffer) {
+		b.WriteCRCFrame(record)
+	})
+	return nil
+}
+
+// Buffered returns the number of framed bytes appended since the last
+// Flush.
+func (l *AppendLog) Buffered() int {
+	return l.d.Len()
+}
+
+// Flush writes the appended records to w. Records left over by a failed
+// flush are written first on the next call.
+func (l *AppendLog) Flush(w io.Writer) (int64, error) {
+	return l.d.FlushTo(w)
+}
+
+// Release returns the buffers to the pool, dropping unflushed records. The
+// log mustn't be used afterwards.
+func (l *AppendLog) Release() {
+	l.d.Release()
+}
+
+// LogReplay is the outcome of ReplayAppendLog.
+type LogReplay struct {
+	// Records is the number of intact records replayed.
+	Records int
+	// Valid is the length of the intact prefix of the log, a damaged log
+	// should be truncated to it before appending again.
+	Valid int64
+	// Discarded is the number of bytes after the intact prefix.
+	Discarded int64
+	// Tail is nil for an intact log, io.ErrUnexpectedEOF for a torn last
+	// record and ErrCRCMismatch for a corrupted record, which ends the
+	// replay even if intact records follow.
+	Tail error
+}
+
+// ReplayAppendLog reads the log written by AppendLog from r and calls fn
+// with every intact record in order, stopping at the first torn or
+// corrupted record. record is only valid during the call.
+//
+// A damaged tail is reported in LogReplay.Tail, the error is non-nil only
+// if reading r or fn failed.
+func ReplayAppendLog(r io.Reader, fn func(record []byte) error) (LogReplay, error) {
+	var res LogReplay
+	b := Get()
+	defer Put(b)
+
+	eof := false
+	for {
+		for {
+			p, err := b.ReadCRCFrame()
+			if err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					res.Tail = err
+				}
+				break
+			}
+			res.Records++
+			res.Valid += int64(len(p) + CRCFrameOverhead)
+			if err := fn(p); err != nil {
+				return res, err
+			}
+		}
+		if res.Tail != nil || eof {
+			break
+		}
+
+		// keep the incomplete record and read more
+		b.Set(b.Bytes())
+		b.Grow(appendLogReadSize)
+		n, err := r.Read(b.B[len(b.B):cap(b.B)])
+		b.B = b.B[:len(b.B)+n]
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return res, err
+		}
+	}
+
+	res.Discarded = int64(b.Len())
+	if res.Tail == nil && res.Discarded > 0 {
+		res.Tail = io.ErrUnexpectedEOF
+	}
+	if res.Tail != nil {
+		n, err := io.Copy(ioutil.Discard, r)
+		res.Discarded += n
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
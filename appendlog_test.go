@@ -0,0 +1,68 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func replayAll(t *testing.T, log []byte) ([]string, LogReplay) {
+	t.Helper()
+	var records []string
+	res, err := ReplayAppendLog(iotest.HalfReader(bytes.NewReader(log)), func(p []byte) error {
+		records = append(records, string(p))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records, res
+}
+
+func TestAppendLog(t *testing.T) {
+	l := NewAppendLog()
+	defer l.Release()
+
+	var file bytes.Buffer
+	for i := 0; i < 100; i++ {
+		l.Append([]byte(fmt.Sprintf("record %d", i)))
+		if i%30 == 0 {
+			if _, err := l.Flush(&file); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	l.Append(bytes.Repeat([]byte("x"), 100<<10))
+	if l.Buffered() == 0 {
+		t.Error("Expect buffered records")
+	}
+	l.Flush(&file)
+
+	records, res := replayAll(t, file.Bytes())
+	if len(records) != 101 || records[42] != "record 42" || res.Tail != nil || res.Valid != int64(file.Len()) {
+		t.Fatalf("unexpected replay: %d records, %+v", len(records), res)
+	}
+
+	// a torn write leaves a partial record
+	torn := file.Bytes()[:file.Len()-10]
+	records, res = replayAll(t, torn)
+	if len(records) != 100 || res.Tail != io.ErrUnexpectedEOF || res.Valid+res.Discarded != int64(len(torn)) {
+		t.Errorf("unexpected torn replay: %d records, %+v", len(records), res)
+	}
+
+	// a flipped bit stops the replay at the damaged record
+	corrupt := append([]byte(nil), file.Bytes()...)
+	corrupt[res.Valid/2] ^= 0x80
+	records, res = replayAll(t, corrupt)
+	if res.Tail != ErrCRCMismatch || len(records) == 0 || len(records) >= 100 || res.Valid+res.Discarded != int64(len(corrupt)) {
+		t.Errorf("unexpected corrupt replay: %d records, %+v", len(records), res)
+	}
+
+	stop := errors.New("stop")
+	if _, err := ReplayAppendLog(bytes.NewReader(file.Bytes()), func([]byte) error { return stop }); err != stop {
+		t.Errorf("Expect the callback error, but got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package buffer
+
+// DefaultArenaBlockSize is the block size of arenas created with a
+// non-positive block size.
+const DefaultArenaBlockSize = 8 << 10
+
+// Arena carves many small slices out of large pooled blocks and releases
+// them all at once with Free, for request scoped allocations such as the
+// header names and values of a parsed request.
+//
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	blockSize int
+	blocks    []*[]byte
+	cur       []byte // free tail of the current block
+	size      int
+}
+
+// NewArena returns an Arena allocating blocks of blockSize bytes from the
+// byte pool.
+func NewArena(blockSize int) *Arena {
+	if blockSize <= 0 {
+		blockSize = DefaultArenaBlockSize
+	}
+	return &Arena{blockSize: blockSize}
+}
+
+// Alloc returns a zeroed slice of n bytes. Its capacity is n, so appending
+// to it never overwrites neighboring allocations.
+//
+// The slice mustn't be used after calling Free.
+func (a *Arena) Alloc(n int) []byte {
+	p := a.alloc(n)
+	for i := range p {
+		p[i] = 0
+	}
+	return p
+}
+
+// Copy returns a copy of p allocated in the arena.
+func (a *Arena) Copy(p []byte) []byte {
+	b := a.alloc(len(p))
+	copy(b, p)
+	return b
+}
+
+// CopyString returns the bytes of s allocated in the arena.
+func (a *Arena) CopyString(s string) []byte {
+	b := a.alloc(len(s))
+	copy(b, s)
+	return b
+}
+
+func (a *Arena) alloc(n int) []byte {
+	if n < 0 {
+		panic("buffer: negative arena allocation")
+	}
+	a.size += n
+	if n > len(a.cur) {
+		if n > a.blockSize/4 {
+			// large allocations get a block of their own, keeping the
+			// free tail of the current block for small ones
+			b := GetBytes(n)
+			a.blocks = append(a.blocks, b)
+			return (*b)[:n:n]
+		}
+		b := GetBytes(a.blockSize)
+		a.blocks = append(a.blocks, b)
+		a.cur = *b
+	}
+	p := a.cur[:n:n]
+	a.cur = a.cur[n:]
+	return p
+}
+
+// Size returns the number of bytes allocated since the arena was created
+// or last freed.
+func (a *Arena) Size() int {
+	return a.size
+}
+
+// Free returns all blocks to the pool, invalidating every slice allocated
+// from the arena. The arena may be reused afterwards.
+func (a *Arena) Free() {
+	for i, b := range a.blocks {
+		PutBytes(b)
+		a.blocks[i] = nil
+	}
+	a.blocks = a.blocks[:0]
+	a.cur = nil
+	a.size = 0
+}
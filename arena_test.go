@@ -0,0 +1,48 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	a := NewArena(256)
+
+	var parts [][]byte
+	for i := 0; i < 100; i++ {
+		parts = append(parts, a.CopyString("header"))
+	}
+	big := a.Alloc(1000)
+	if len(big) != 1000 || cap(big) != 1000 {
+		t.Errorf("unexpected large allocation: len %d, cap %d", len(big), cap(big))
+	}
+	for i, c := range big {
+		if c != 0 {
+			t.Fatalf("Expect zeroed allocation, but got %x at %d", c, i)
+		}
+	}
+
+	parts[0] = append(parts[0], 'X')
+	for i, p := range parts[1:] {
+		if string(p) != "header" {
+			t.Fatalf("allocation %d was overwritten: %q", i+1, p)
+		}
+	}
+	if a.Size() != 100*6+1000 {
+		t.Errorf("unexpected arena size: %d", a.Size())
+	}
+
+	before := GetPoolStats()
+	a.Free()
+	after := GetPoolStats()
+	if after.Puts-before.Puts != uint64(600/256+1+1) {
+		t.Errorf("unexpected number of released blocks: %d", after.Puts-before.Puts)
+	}
+	if a.Size() != 0 {
+		t.Errorf("Expect empty arena after Free, but got %d", a.Size())
+	}
+
+	if p := a.Copy([]byte("again")); string(p) != "again" {
+		t.Errorf("unexpected copy after Free: %q", p)
+	}
+	a.Free()
+}
@@ -0,0 +1,90 @@
+package buffer
+
+// toUpperASCII upper-cases the ASCII letters of p in place
+func toUpperASCII(p []byte) {
+	for i, c := range p {
+		if 'a' <= c && c <= 'z' {
+			p[i] = c - ('a' - 'A')
+		}
+	}
+}
+
+// toLowerASCII lower-cases the ASCII letters of p in place
+func toLowerASCII(p []byte) {
+	for i, c := range p {
+		if 'A' <= c && c <= 'Z' {
+			p[i] = c + ('a' - 'A')
+		}
+	}
+}
+
+func replaceByte(p []byte, old, new byte) {
+	for i, c := range p {
+		if c == old {
+			p[i] = new
+		}
+	}
+}
+
+// ToUpperASCII upper-cases the ASCII letters of the unread portion of
+// Buffer.B in place, other bytes are left untouched.
+func (b *Buffer) ToUpperASCII() {
+	toUpperASCII(b.B[b.off:])
+}
+
+// ToLowerASCII lower-cases the ASCII letters of the unread portion of
+// Buffer.B in place, other bytes are left untouched.
+func (b *Buffer) ToLowerASCII() {
+	toLowerASCII(b.B[b.off:])
+}
+
+// ReplaceByte replaces every old byte of the unread portion of Buffer.B
+// with new in place.
+func (b *Buffer) ReplaceByte(old, new byte) {
+	replaceByte(b.B[b.off:], old, new)
+}
+
+func (b *ioBuffer) ToUpperASCII() {
+	toUpperASCII(b.buf[b.off:])
+}
+
+func (b *ioBuffer) ToLowerASCII() {
+	toLowerASCII(b.buf[b.off:])
+}
+
+func (b *ioBuffer) ReplaceByte(old, new byte) {
+	replaceByte(b.buf[b.off:], old, new)
+}
+
+func (m *multiIoBuffer) ToUpperASCII() {
+	for _, b := range m.bufs {
+		b.ToUpperASCII()
+	}
+}
+
+func (m *multiIoBuffer) ToLowerASCII() {
+	for _, b := range m.bufs {
+		b.ToLowerASCII()
+	}
+}
+
+func (m *multiIoBuffer) ReplaceByte(old, new byte) {
+	for _, b := range m.bufs {
+		b.ReplaceByte(old, new)
+	}
+}
+
+// The in-place transforms keep the length, so the view allows them on the
+// bytes it covers.
+
+func (v *limitedIoBuffer) ToUpperASCII() {
+	toUpperASCII(v.Bytes())
+}
+
+func (v *limitedIoBuffer) ToLowerASCII() {
+	toLowerASCII(v.Bytes())
+}
+
+func (v *limitedIoBuffer) ReplaceByte(old, new byte) {
+	replaceByte(v.Bytes(), old, new)
+}
@@ -0,0 +1,50 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestASCIITransforms(t *testing.T) {
+	var bb Buffer
+	bb.SetString("skip:Content-TYPE: Ünïcode")
+	bb.Next(5)
+	bb.ToLowerASCII()
+	if bb.String() != "content-type: Ünïcode" || string(bb.B[:5]) != "skip:" {
+		t.Errorf("unexpected ToLowerASCII result: %q", bb.B)
+	}
+	bb.ToUpperASCII()
+	if bb.String() != "CONTENT-TYPE: ÜNïCODE" {
+		t.Errorf("unexpected ToUpperASCII result: %q", bb.String())
+	}
+	bb.ReplaceByte('-', '_')
+	if bb.String() != "CONTENT_TYPE: ÜNïCODE" {
+		t.Errorf("unexpected ReplaceByte result: %q", bb.String())
+	}
+
+	b := NewIoBufferString("x-forwarded-for: a\r\nrest")
+	v := b.Limit(15)
+	v.ToUpperASCII()
+	v.ReplaceByte('-', '_')
+	if b.String() != "X_FORWARDED_FOR: a\r\nrest" {
+		t.Errorf("Expect only the view to change, but got %q", b.String())
+	}
+	b.ToLowerASCII()
+	if b.String() != "x_forwarded_for: a\r\nrest" {
+		t.Errorf("unexpected ToLowerASCII result: %q", b.String())
+	}
+
+	m := MultiIoBuffer(NewIoBufferString("Ab"), NewIoBufferString("cD"))
+	m.ToUpperASCII()
+	m.ReplaceByte('B', 'b')
+	if m.String() != "AbCD" {
+		t.Errorf("unexpected multi buffer result: %q", m.String())
+	}
+}
+
+func BenchmarkToLowerASCII(b *testing.B) {
+	buf := NewIoBufferString("Content-Type: Application/JSON; Charset=UTF-8")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.ToLowerASCII()
+	}
+}
@@ -0,0 +1,42 @@
+package buffer
+
+import "io"
+
+// ReadResult reports the progress of ReadFromAsync.
+type ReadResult struct {
+	// N is the total number of bytes read so far.
+	N int64
+	// Err is the error that ended the read, io.EOF is not reported.
+	Err error
+	// Done is set on the final result, after which the channel is closed
+	// and the buffer may be used again.
+	Done bool
+}
+
+func (b *ioBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, progress func(int64)) (int64, error) {
+		return b.readFrom(r, -1, progress)
+	}, r)
+}
+
+// readFromAsync runs fill in a new goroutine. Progress results are dropped
+// while the receiver lags behind, the final result is always delivered.
+func readFromAsync(fill func(io.Reader, func(int64)) (int64, error), r io.Reader) <-chan ReadResult {
+	ch := make(chan ReadResult, 1)
+	go func() {
+		n, err := fill(r, func(n int64) {
+			select {
+			case ch <- ReadResult{N: n}:
+			default:
+			}
+		})
+		// make room for the final result, only this goroutine sends
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- ReadResult{N: n, Err: err, Done: true}
+		close(ch)
+	}()
+	return ch
+}
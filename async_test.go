@@ -0,0 +1,81 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadFromAsync(t *testing.T) {
+	data := bytes.Repeat([]byte("async"), 10000)
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < len(data); i += 1000 {
+			pw.Write(data[i : i+1000])
+		}
+		pw.Close()
+	}()
+
+	b := NewIoBuffer(0)
+	var last ReadResult
+	progress := 0
+	for res := range b.ReadFromAsync(pr) {
+		if res.N < last.N {
+			t.Errorf("progress went backwards: %d after %d", res.N, last.N)
+		}
+		if !res.Done {
+			progress++
+		}
+		last = res
+	}
+	if !last.Done || last.Err != nil || last.N != int64(len(data)) {
+		t.Fatalf("unexpected final result %+v", last)
+	}
+	if progress == 0 {
+		t.Error("no progress reported")
+	}
+	if !bytes.Equal(b.Bytes(), data) {
+		t.Error("buffer content mismatch")
+	}
+}
+
+// brokenReader returns its data together with err
+type brokenReader struct {
+	data string
+	err  error
+}
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, r.err
+}
+
+func TestReadFromAsyncError(t *testing.T) {
+	errBroken := errors.New("broken")
+
+	b := NewIoBuffer(0)
+	var last ReadResult
+	for res := range b.ReadFromAsync(&brokenReader{data: "partial", err: errBroken}) {
+		last = res
+	}
+	if !last.Done || !errors.Is(last.Err, errBroken) || last.N != 7 {
+		t.Fatalf("unexpected final result %+v", last)
+	}
+	if b.String() != "partial" {
+		t.Errorf("Expect partial, but got %q", b.String())
+	}
+}
+
+func TestRecordingBufferReadFromAsync(t *testing.T) {
+	rb := NewRecordingBuffer(NewIoBuffer(0))
+	res := <-rb.ReadFromAsync(bytes.NewReader([]byte("recorded")))
+	if !res.Done || res.N != 8 {
+		t.Fatalf("unexpected final result %+v", res)
+	}
+	ops := rb.Ops()
+	if len(ops) != 1 || ops[0].Kind != OpWrite || string(ops[0].Data) != "recorded" {
+		t.Errorf("unexpected ops %+v", ops)
+	}
+}
@@ -0,0 +1,159 @@
+package buffer
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what AsyncWriter.Write does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// BlockOnFull makes Write wait for room in the queue.
+	BlockOnFull OverflowPolicy = iota
+	// DropOnFull makes Write drop the data, counting it in AsyncStats.
+	DropOnFull
+)
+
+// AsyncStats are the counters of an AsyncWriter.
+type AsyncStats struct {
+	// Writes is the number of writes passed to the underlying writer.
+	Writes uint64
+	// Dropped is the number of writes dropped because the queue was full.
+	Dropped uint64
+	// DroppedBytes is the number of bytes of the dropped writes.
+	DroppedBytes uint64
+	// Errors is the number of writes the underlying writer failed.
+	Errors uint64
+}
+
+// asyncItem is a queued write, or a sync marker when done is set
+type asyncItem struct {
+	b    *Buffer
+	done chan error
+}
+
+// AsyncWriter copies writes into pooled Buffers, queues them on a bounded
+// channel and writes them to the underlying writer from a dedicated
+// goroutine, the core of an asynchronous logger. It is safe for concurrent
+// use.
+type AsyncWriter struct {
+	// counters are kept first for 64-bit alignment
+	writes       uint64
+	dropped      uint64
+	droppedBytes uint64
+	errors       uint64
+
+	w      io.Writer
+	policy OverflowPolicy
+	queue  chan asyncItem
+	done   chan struct{}
+
+	// mu guards closed against sends on the closed queue
+	mu     sync.RWMutex
+	closed bool
+
+	// err is the last write error, owned by the flushing goroutine
+	err error
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to w with room for
+// queueSize pending writes, queueSize <= 0 means 1024.
+func NewAsyncWriter(w io.Writer, queueSize int, policy OverflowPolicy) *AsyncWriter {
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	a := &AsyncWriter{
+		w:      w,
+		policy: policy,
+		queue:  make(chan asyncItem, queueSize),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	for item := range a.queue {
+		if item.done != nil {
+			item.done <- a.err
+			a.err = nil
+			continue
+		}
+		if _, err := item.b.WriteTo(a.w); err != nil {
+			atomic.AddUint64(&a.errors, 1)
+			a.err = err
+		}
+		atomic.AddUint64(&a.writes, 1)
+		Put(item.b)
+	}
+}
+
+// Write queues a copy of p. With DropOnFull it never blocks, dropping p
+// when the queue is full. Errors of the underlying writer are reported by
+// Sync and Close.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	b := Get()
+	b.Write(p)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		Put(b)
+		return 0, ErrClosedWriter
+	}
+	if a.policy == DropOnFull {
+		select {
+		case a.queue <- asyncItem{b: b}:
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+			atomic.AddUint64(&a.droppedBytes, uint64(len(p)))
+			Put(b)
+		}
+		return len(p), nil
+	}
+	a.queue <- asyncItem{b: b}
+	return len(p), nil
+}
+
+// Sync waits until the writes queued before it have been written and
+// returns the last error of the underlying writer since the previous Sync.
+func (a *AsyncWriter) Sync() error {
+	done := make(chan error, 1)
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return ErrClosedWriter
+	}
+	a.queue <- asyncItem{done: done}
+	a.mu.RUnlock()
+	return <-done
+}
+
+// Stats returns the counters of the writer.
+func (a *AsyncWriter) Stats() AsyncStats {
+	return AsyncStats{
+		Writes:       atomic.LoadUint64(&a.writes),
+		Dropped:      atomic.LoadUint64(&a.dropped),
+		DroppedBytes: atomic.LoadUint64(&a.droppedBytes),
+		Errors:       atomic.LoadUint64(&a.errors),
+	}
+}
+
+// Close writes the queued writes and stops the flushing goroutine. It
+// returns the last error of the underlying writer since the previous Sync
+// and doesn't close the underlying writer.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrClosedWriter
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+	<-a.done
+	return a.err
+}
@@ -0,0 +1,84 @@
+package buffer
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockingWriter blocks writes until release is closed
+type blockingWriter struct {
+	syncWriter
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.syncWriter.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &syncWriter{}
+	a := NewAsyncWriter(out, 16, BlockOnFull)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				a.Write([]byte("line\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "line\n"); n != 400 {
+		t.Errorf("Expect 400 lines, but got %d", n)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := a.Stats(); s.Writes != 400 || s.Dropped != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if _, err := a.Write([]byte("x")); err != ErrClosedWriter {
+		t.Errorf("Expect ErrClosedWriter, but got %v", err)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	a := NewAsyncWriter(out, 2, DropOnFull)
+
+	// one write is held by the flusher, two fill the queue
+	for i := 0; i < 10; i++ {
+		if _, err := a.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(out.release)
+	a.Close()
+
+	s := a.Stats()
+	if s.Writes+s.Dropped != 10 || s.Dropped < 7 || s.DroppedBytes != 4*s.Dropped {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if uint64(len(out.String())) != 4*s.Writes {
+		t.Errorf("Expect %d bytes written, but got %d", 4*s.Writes, len(out.String()))
+	}
+}
+
+func TestAsyncWriterError(t *testing.T) {
+	w := &failingWriter{limit: 4}
+	a := NewAsyncWriter(w, 4, BlockOnFull)
+	a.Write([]byte("abcdefgh"))
+	if err := a.Sync(); err != errWriterFull {
+		t.Errorf("Expect errWriterFull, but got %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("Expect the error to be reported once, but got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package buffer
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+)
+
+// ErrInvalidEncoding is returned by UnmarshalBinary for malformed data.
+var ErrInvalidEncoding = errors.New("io buffer: invalid binary encoding")
+
+// The binary encoding is a version byte, a flags byte, the read offset as
+// uvarint and the contents starting at offset 0.
+const (
+	binaryVersion = 1
+
+	binaryFlagEOF = 1 << 0
+)
+
+// MarshalBinaryKeepOffset is like the MarshalBinary method of b, but also
+// includes the bytes already read together with the read offset, so that a
+// checkpoint restores the buffer exactly. Buffers that can't keep their read
+// bytes encode only the unread ones.
+func MarshalBinaryKeepOffset(b IoBuffer) ([]byte, error) {
+	if ib, ok := b.(*ioBuffer); ok {
+		return marshalBinary(ib.buf, ib.off, ib.eof), nil
+	}
+	if m, ok := b.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return marshalBinary(b.CopyBytes(), 0, b.EOF()), nil
+}
+
+// The buffers implement encoding.BinaryMarshaler and BinaryUnmarshaler,
+// encoding the unread bytes and the EOF flag. The methods are not part of
+// IoBuffer, gob would otherwise treat IoBuffer fields as opaque and fail to
+// decode into nil fields.
+func init() {
+	// buffers held in IoBuffer fields can be sent over gob
+	gob.Register(&ioBuffer{})
+	gob.Register(&multiIoBuffer{})
+}
+
+func marshalBinary(p []byte, off int, eof bool) []byte {
+	var flags byte
+	if eof {
+		flags |= binaryFlagEOF
+	}
+	data := make([]byte, 2, 2+binary.MaxVarintLen64+len(p))
+	data[0], data[1] = binaryVersion, flags
+	data = appendUvarint(data, uint64(off))
+	return append(data, p...)
+}
+
+func appendUvarint(p []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(p, tmp[:n]...)
+}
+
+// unmarshalBinary returns the contents, read offset and EOF flag of data,
+// the contents alias data
+func unmarshalBinary(data []byte) (p []byte, off int, eof bool, err error) {
+	if len(data) < 2 || data[0] != binaryVersion {
+		return nil, 0, false, ErrInvalidEncoding
+	}
+	eof = data[1]&binaryFlagEOF != 0
+	v, n := binary.Uvarint(data[2:])
+	p = data[2+n:]
+	if n <= 0 || v > uint64(len(p)) {
+		return nil, 0, false, ErrInvalidEncoding
+	}
+	return p, int(v), eof, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding Buffer.B
+// together with the read offset.
+func (b *Buffer) MarshalBinary() ([]byte, error) {
+	return marshalBinary(b.B, b.off, false), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the
+// contents and the read offset.
+func (b *Buffer) UnmarshalBinary(data []byte) error {
+	p, off, _, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	b.Set(p)
+	b.off = off
+	return nil
+}
+
+func (b *ioBuffer) MarshalBinary() ([]byte, error) {
+	return marshalBinary(b.buf[b.off:], 0, b.eof), nil
+}
+
+func (b *ioBuffer) UnmarshalBinary(data []byte) error {
+	p, off, eof, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	if b.count == nil {
+		// zero value allocated by a decoder such as gob
+		*b = *New().(*ioBuffer)
+	}
+	b.reset()
+	if _, err := b.Write(p); err != nil {
+		return err
+	}
+	b.off = off
+	b.eof = eof
+	return nil
+}
+
+func (m *multiIoBuffer) MarshalBinary() ([]byte, error) {
+	return marshalBinary(m.CopyBytes(), 0, m.EOF()), nil
+}
+
+func (m *multiIoBuffer) UnmarshalBinary(data []byte) error {
+	p, off, eof, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	if m.count == nil {
+		*m = *MultiIoBuffer().(*multiIoBuffer)
+	}
+	m.Reset()
+	if _, err := m.Write(p[off:]); err != nil {
+		return err
+	}
+	m.SetEOF(eof)
+	return nil
+}
+
+func (v *limitedIoBuffer) MarshalBinary() ([]byte, error) {
+	return marshalBinary(v.Bytes(), 0, true), nil
+}
+
+func (v *limitedIoBuffer) UnmarshalBinary(data []byte) error {
+	return v.readOnly("unmarshal", len(data))
+}
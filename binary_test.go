@@ -0,0 +1,90 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+)
+
+func TestBufferBinary(t *testing.T) {
+	var bb Buffer
+	bb.WriteString("foobar")
+	bb.Next(3)
+	data, err := bb.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored Buffer
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.String() != "bar" || string(restored.B) != "foobar" {
+		t.Errorf("unexpected contents: %q, %q", restored.String(), restored.B)
+	}
+
+	for _, bad := range [][]byte{nil, {2, 0, 0}, {binaryVersion, 0}, {binaryVersion, 0, 4, 'a'}} {
+		if err := restored.UnmarshalBinary(bad); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("Expect ErrInvalidEncoding for %v, but got %v", bad, err)
+		}
+	}
+}
+
+func TestIoBufferBinary(t *testing.T) {
+	b := NewIoBufferString("hello world").(*ioBuffer)
+	b.Drain(6)
+	b.SetEOF(true)
+	data, _ := b.MarshalBinary()
+
+	r := NewIoBuffer(0).(*ioBuffer)
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "world" || !r.EOF() {
+		t.Errorf("unexpected contents: %q, eof %v", r.String(), r.EOF())
+	}
+
+	data, _ = MarshalBinaryKeepOffset(b)
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "world" || r.off != 6 || string(r.buf) != "hello world" {
+		t.Errorf("Expect the read offset to be restored, but got %q at %d", r.buf, r.off)
+	}
+	if data, _ = b.MarshalBinary(); data[2] != 0 {
+		t.Error("Expect MarshalBinary to still drop the read bytes")
+	}
+}
+
+type checkpoint struct {
+	Seq   int
+	Body  IoBuffer
+	Extra *Buffer
+}
+
+func TestIoBufferGob(t *testing.T) {
+	extra := &Buffer{}
+	extra.WriteString("extra")
+	in := checkpoint{
+		Seq:   7,
+		Body:  MultiIoBuffer(NewIoBufferString("hel"), NewIoBufferString("lo")),
+		Extra: extra,
+	}
+
+	var network bytes.Buffer
+	if err := gob.NewEncoder(&network).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out checkpoint
+	if err := gob.NewDecoder(&network).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Seq != 7 || out.Body.String() != "hello" || out.Extra.String() != "extra" {
+		t.Errorf("unexpected checkpoint: %d, %q, %q", out.Seq, out.Body.String(), out.Extra.String())
+	}
+	out.Body.WriteString(" world")
+	if out.Body.String() != "hello world" {
+		t.Errorf("Expect the decoded buffer to be usable, but got %q", out.Body.String())
+	}
+}
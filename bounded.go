@@ -0,0 +1,496 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrWriteTimeout is returned by writes to a BoundedIoBuffer that waited
+// longer than the write timeout for room.
+var ErrWriteTimeout = errors.New("io buffer: write timeout")
+
+// BoundedIoBuffer is an IoBuffer holding at most limit unread bytes. Writes
+// wait for readers to make room once the limit is reached, giving producers
+// backpressure instead of unbounded memory growth.
+//
+// Write, WriteString, WriteRune, WriteContext, TryWrite, Append, ReadFrom,
+// ReadFromLimit, ReadOnce, Read, ReadRune, WriteTo, Drain, DrainTo,
+// DiscardAll, Len, Reset, Compact and Close are safe for concurrent use, the
+// other methods must not run concurrently with them. WriteTo writes to w
+// without holding the lock, so it must not run concurrently with other
+// reads. In-place rewrites that would grow the buffer past the limit, such
+// as ReplaceRange and SanitizeUTF8, fail with ErrTooLarge instead of
+// waiting.
+type BoundedIoBuffer struct {
+	IoBuffer
+
+	mu      sync.Mutex
+	limit   int
+	timeout time.Duration
+	// space is closed and replaced whenever bytes are consumed
+	space chan struct{}
+}
+
+// NewBoundedIoBuffer returns a BoundedIoBuffer holding at most limit bytes.
+// Writes give up with ErrWriteTimeout after waiting timeout for room,
+// timeout <= 0 means they wait forever.
+func NewBoundedIoBuffer(limit int, timeout time.Duration) *BoundedIoBuffer {
+	if limit <= 0 {
+		panic(opError("bounded", limit, NewIoBuffer(0), ErrNegativeCount))
+	}
+	return &BoundedIoBuffer{
+		IoBuffer: GetIoBuffer(0),
+		limit:    limit,
+		timeout:  timeout,
+		space:    make(chan struct{}),
+	}
+}
+
+// MaxLen returns the maximum number of unread bytes.
+func (b *BoundedIoBuffer) MaxLen() int {
+	return b.limit
+}
+
+// consumed wakes the writers waiting for room, it must be called with b.mu
+// held
+func (b *BoundedIoBuffer) consumed() {
+	close(b.space)
+	b.space = make(chan struct{})
+}
+
+// write appends p in pieces as room becomes available
+func (b *BoundedIoBuffer) write(ctx context.Context, p []byte) (int, error) {
+	if len(p) == 0 {
+		// nothing to wait for, the write still fails on a closed buffer
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.IoBuffer.Write(p)
+	}
+
+	var deadline <-chan time.Time
+	if b.timeout > 0 {
+		t := time.NewTimer(b.timeout)
+		defer t.Stop()
+		deadline = t.C
+	}
+
+	n := 0
+	for {
+		b.mu.Lock()
+		if free := b.limit - b.IoBuffer.Len(); free > 0 {
+			chunk := p[n:]
+			if len(chunk) > free {
+				chunk = chunk[:free]
+			}
+			m, err := b.IoBuffer.Write(chunk)
+			n += m
+			if err != nil || n == len(p) {
+				b.mu.Unlock()
+				return n, err
+			}
+		}
+		space := b.space
+		b.mu.Unlock()
+
+		select {
+		case <-space:
+		case <-deadline:
+			return n, opError("write", len(p), b, ErrWriteTimeout)
+		case <-ctx.Done():
+			return n, opError("write", len(p), b, ctx.Err())
+		}
+	}
+}
+
+// Write appends p, waiting for room whenever the buffer is full.
+func (b *BoundedIoBuffer) Write(p []byte) (int, error) {
+	return b.write(context.Background(), p)
+}
+
+// WriteString appends s, waiting for room whenever the buffer is full.
+func (b *BoundedIoBuffer) WriteString(s string) (int, error) {
+	return b.write(context.Background(), []byte(s))
+}
+
+func (b *BoundedIoBuffer) WriteRune(r rune) (int, error) {
+	var p [utf8.UTFMax]byte
+	n := utf8.EncodeRune(p[:], r)
+	return b.write(context.Background(), p[:n])
+}
+
+// Append appends data, waiting for room whenever the buffer is full.
+func (b *BoundedIoBuffer) Append(data []byte) error {
+	_, err := b.write(context.Background(), data)
+	return err
+}
+
+// AppendByte appends data, waiting for room if the buffer is full.
+func (b *BoundedIoBuffer) AppendByte(data byte) error {
+	return b.Append([]byte{data})
+}
+
+// WriteContext is Write giving up when ctx is done.
+func (b *BoundedIoBuffer) WriteContext(ctx context.Context, p []byte) (int, error) {
+	return b.write(ctx, p)
+}
+
+// TryWrite appends p if it fits in the room left and never blocks. It
+// writes all of p or nothing, reporting whether p was written, so latency
+// critical callers can drop a message instead of stalling.
+func (b *BoundedIoBuffer) TryWrite(p []byte) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.IoBuffer.Len()+len(p) > b.limit {
+		return 0, false
+	}
+	n, err := b.IoBuffer.Write(p)
+	return n, err == nil
+}
+
+// ReadFrom reads r until io.EOF, waiting for room whenever the buffer is
+// full.
+func (b *BoundedIoBuffer) ReadFrom(r io.Reader) (int64, error) {
+	return b.readFrom(r, -1)
+}
+
+// ReadFromLimit is ReadFrom failing with ErrLimitExceeded once r holds more
+// than max bytes, in which case one byte past max has been consumed from r
+// and dropped.
+func (b *BoundedIoBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	if max < 0 {
+		panic(opError("read", int(max), b, ErrNegativeCount))
+	}
+	return b.readFrom(io.LimitReader(r, max+1), max)
+}
+
+// readFrom reads r until io.EOF, max < 0 means no limit
+func (b *BoundedIoBuffer) readFrom(r io.Reader, max int64) (int64, error) {
+	chunk := GetBytes(MinRead)
+	defer PutBytes(chunk)
+
+	var n int64
+	for {
+		m, err := r.Read(*chunk)
+		if max >= 0 && n+int64(m) > max {
+			k, werr := b.Write((*chunk)[:max-n])
+			n += int64(k)
+			if werr != nil {
+				return n, werr
+			}
+			return n, opError("read", int(max), b, ErrLimitExceeded)
+		}
+		if m > 0 {
+			k, werr := b.Write((*chunk)[:m])
+			n += int64(k)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, opError("read", 0, b, err)
+		}
+	}
+}
+
+// ReadFromAsync reads r with ReadFrom in a new goroutine, progress isn't
+// reported.
+func (b *BoundedIoBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, _ func(int64)) (int64, error) {
+		return b.ReadFrom(r)
+	}, r)
+}
+
+// ReadOnce waits for room, then reads r once into the room left, at most
+// MinRead bytes like a chunk of ReadFrom. The read deadline of a net.Conn is
+// set to duration from now.
+func (b *BoundedIoBuffer) ReadOnce(r io.Reader, duration time.Duration) (int64, error) {
+	free, err := b.room()
+	if err != nil {
+		return 0, err
+	}
+	if free > MinRead {
+		free = MinRead
+	}
+	chunk := GetBytes(free)
+	defer PutBytes(chunk)
+
+	if conn, ok := r.(net.Conn); ok {
+		conn.SetReadDeadline(time.Now().Add(duration))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+	m, err := r.Read(*chunk)
+	var n int
+	if m > 0 {
+		var werr error
+		if n, werr = b.Write((*chunk)[:m]); werr != nil {
+			return int64(n), werr
+		}
+	}
+	if err != nil {
+		return int64(n), opError("read", 0, b, err)
+	}
+	return int64(n), nil
+}
+
+// room waits until the buffer isn't full and returns the room left
+func (b *BoundedIoBuffer) room() (int, error) {
+	var deadline <-chan time.Time
+	if b.timeout > 0 {
+		t := time.NewTimer(b.timeout)
+		defer t.Stop()
+		deadline = t.C
+	}
+	for {
+		b.mu.Lock()
+		free := b.limit - b.IoBuffer.Len()
+		space := b.space
+		b.mu.Unlock()
+		if free > 0 {
+			return free, nil
+		}
+
+		select {
+		case <-space:
+		case <-deadline:
+			return 0, opError("read", 0, b, ErrWriteTimeout)
+		}
+	}
+}
+
+// ReplaceRange fails with ErrTooLarge if the replacement would grow the
+// buffer past the limit.
+func (b *BoundedIoBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if d := len(replacement) - (to - from); d > 0 && b.IoBuffer.Len()+d > b.limit {
+		return opError("replace", d, b.IoBuffer, ErrTooLarge)
+	}
+	err := b.IoBuffer.ReplaceRange(from, to, replacement)
+	b.consumed()
+	return err
+}
+
+// SanitizeUTF8 fails with ErrTooLarge, leaving the buffer untouched, if
+// the replacements would grow the buffer past the limit.
+func (b *BoundedIoBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.IoBuffer.ValidUTF8() {
+		return 0, nil
+	}
+	tmp := NewIoBufferBytes(b.IoBuffer.CopyBytes())
+	defer tmp.Free()
+	n, err := tmp.SanitizeUTF8(replacement)
+	if err != nil {
+		return 0, err
+	}
+	if tmp.Len() > b.limit {
+		return 0, opError("sanitize utf8", tmp.Len()-b.IoBuffer.Len(), b.IoBuffer, ErrTooLarge)
+	}
+	if err := b.IoBuffer.ReplaceRange(0, b.IoBuffer.Len(), tmp.Bytes()); err != nil {
+		return 0, err
+	}
+	b.consumed()
+	return n, nil
+}
+
+// UnmarshalJSON fails with ErrTooLarge if the decoded bytes exceed the
+// limit.
+func (b *BoundedIoBuffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, false)
+	if err != nil {
+		return err
+	}
+	if len(p) > b.limit {
+		return opError("unmarshal", len(p), b, ErrTooLarge)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.IoBuffer.Reset()
+	_, err = b.IoBuffer.Write(p)
+	b.consumed()
+	return err
+}
+
+// SpliceTo writes the buffered bytes to dst, then copies at most max bytes
+// from src to dst without going through the buffer, max <= 0 means until
+// io.EOF. io.Copy still splices TCP connections on Linux.
+func (b *BoundedIoBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	n, err := b.WriteTo(dst)
+	if err != nil {
+		return n, err
+	}
+	var r io.Reader = src
+	if max > 0 {
+		r = io.LimitReader(src, int64(max))
+	}
+	m, err := io.Copy(dst, r)
+	n += m
+	if err != nil {
+		return n, opError("splice", max, b, err)
+	}
+	return n, nil
+}
+
+func (b *BoundedIoBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, err := b.IoBuffer.Read(p)
+	if n > 0 {
+		b.consumed()
+	}
+	return n, err
+}
+
+func (b *BoundedIoBuffer) ReadRune() (rune, int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, size, err := b.IoBuffer.ReadRune()
+	if size > 0 {
+		b.consumed()
+	}
+	return r, size, err
+}
+
+func (b *BoundedIoBuffer) UnreadRune() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.IoBuffer.UnreadRune()
+}
+
+// WriteTo copies the unread bytes chunk by chunk under the lock and writes
+// them to w without holding it, so that writers aren't stalled by w.
+func (b *BoundedIoBuffer) WriteTo(w io.Writer) (int64, error) {
+	size := b.limit
+	if size > copyBufferSize {
+		size = copyBufferSize
+	}
+	chunk := GetBytes(size)
+	defer PutBytes(chunk)
+
+	var n int64
+	for {
+		b.mu.Lock()
+		k := b.IoBuffer.Len()
+		if k > size {
+			k = size
+		}
+		p := (*chunk)[:copy(*chunk, b.IoBuffer.Peek(k))]
+		b.mu.Unlock()
+		if len(p) == 0 {
+			return n, nil
+		}
+
+		m, err := w.Write(p)
+		if m > len(p) {
+			panic(opError("write to", m, b, ErrInvalidWriteCount))
+		}
+		if m > 0 {
+			b.mu.Lock()
+			b.IoBuffer.Drain(m)
+			b.consumed()
+			b.mu.Unlock()
+			n += int64(m)
+		}
+		if err != nil {
+			return n, opError("write to", 0, b, err)
+		}
+		if m < len(p) {
+			return n, io.ErrShortWrite
+		}
+	}
+}
+
+func (b *BoundedIoBuffer) Drain(offset int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.IoBuffer.Drain(offset)
+	b.consumed()
+}
+
+func (b *BoundedIoBuffer) DrainTo(w io.Writer, n int) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, err := b.IoBuffer.DrainTo(w, n)
+	if m > 0 {
+		b.consumed()
+	}
+	return m, err
+}
+
+func (b *BoundedIoBuffer) DiscardAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.IoBuffer.DiscardAll()
+	b.consumed()
+}
+
+// Window hands out the unread bytes, the commit function wakes waiting
+// writers.
+func (b *BoundedIoBuffer) Window() ([]byte, func(consumed int)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, commit := b.IoBuffer.Window()
+	return p, func(consumed int) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		commit(consumed)
+		if consumed > 0 {
+			b.consumed()
+		}
+	}
+}
+
+// Limit returns a view consuming the buffer through its locked methods.
+func (b *BoundedIoBuffer) Limit(n int) IoBuffer {
+	return newLimitedIoBuffer(b, n)
+}
+
+func (b *BoundedIoBuffer) consumedBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cc, ok := b.IoBuffer.(consumedCounter); ok {
+		return cc.consumedBytes()
+	}
+	return 0
+}
+
+func (b *BoundedIoBuffer) ReadCloser() io.ReadCloser {
+	return &ioBufferReadCloser{b: b}
+}
+
+func (b *BoundedIoBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.IoBuffer.Len()
+}
+
+func (b *BoundedIoBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.IoBuffer.Reset()
+	b.consumed()
+}
+
+func (b *BoundedIoBuffer) Compact(trim bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.IoBuffer.Compact(trim)
+}
+
+// Close closes the buffer, waiting writers fail with ErrClosedBuffer.
+func (b *BoundedIoBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err := b.IoBuffer.Close()
+	b.consumed()
+	return err
+}
@@ -0,0 +1,211 @@
+package buffer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestBoundedIoBufferBackpressure(t *testing.T) {
+	b := NewBoundedIoBuffer(16, 0)
+	data := bytes.Repeat([]byte("0123456789"), 100)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.Write(data)
+		done <- err
+	}()
+
+	var out bytes.Buffer
+	p := make([]byte, 7)
+	for out.Len() < len(data) {
+		if l := b.Len(); l > 16 {
+			t.Fatalf("Expect at most 16 unread bytes, but got %d", l)
+		}
+		n, _ := b.Read(p)
+		if n == 0 {
+			runtime.Gosched()
+		}
+		out.Write(p[:n])
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("data corrupted")
+	}
+}
+
+func TestBoundedIoBufferTimeout(t *testing.T) {
+	b := NewBoundedIoBuffer(4, 10*time.Millisecond)
+	n, err := b.WriteString("123456")
+	if !errors.Is(err, ErrWriteTimeout) || n != 4 {
+		t.Errorf("Expect ErrWriteTimeout after 4 bytes, but got %d, %v", n, err)
+	}
+}
+
+func TestBoundedIoBufferContext(t *testing.T) {
+	b := NewBoundedIoBuffer(4, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := b.WriteContext(ctx, []byte("123456")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expect context.Canceled, but got %v", err)
+	}
+}
+
+func TestBoundedIoBufferClose(t *testing.T) {
+	b := NewBoundedIoBuffer(4, 0)
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.ReadFrom(bytes.NewReader(make([]byte, 100)))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	b.Close()
+	if err := <-done; !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer, but got %v", err)
+	}
+}
+
+func TestBoundedIoBufferDrain(t *testing.T) {
+	b := NewBoundedIoBuffer(8, time.Second)
+	b.WriteString("12345678")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.DrainTo(ioutil.Discard, 4)
+	}()
+	if _, err := b.WriteString("abcd"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "5678abcd" {
+		t.Errorf("Expect 5678abcd, but got %q", b.String())
+	}
+}
+
+func TestBoundedIoBufferTryWrite(t *testing.T) {
+	b := NewBoundedIoBuffer(8, 0)
+	if n, ok := b.TryWrite([]byte("123456")); !ok || n != 6 {
+		t.Fatalf("Expect the write to fit, but got %d, %v", n, ok)
+	}
+	if n, ok := b.TryWrite([]byte("abc")); ok || n != 0 {
+		t.Errorf("Expect the write to be refused, but got %d, %v", n, ok)
+	}
+	if b.String() != "123456" {
+		t.Errorf("Expect 123456, but got %q", b.String())
+	}
+	b.Drain(2)
+	if _, ok := b.TryWrite([]byte("abcd")); !ok {
+		t.Errorf("Expect the write to fit after Drain")
+	}
+	b.Close()
+	if _, ok := b.TryWrite([]byte("x")); ok {
+		t.Errorf("Expect writes to a closed buffer to be refused")
+	}
+}
+
+func TestBoundedIoBufferEmptyWrite(t *testing.T) {
+	b := NewBoundedIoBuffer(4, 0)
+	b.WriteString("1234")
+	done := make(chan struct{})
+	go func() {
+		b.Write(nil)
+		b.WriteString("")
+		b.Append(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expect empty writes to a full buffer to return immediately")
+	}
+}
+
+func TestBoundedIoBufferWritingMethods(t *testing.T) {
+	b := NewBoundedIoBuffer(8, 10*time.Millisecond)
+	if err := b.Append([]byte("123456789")); !errors.Is(err, ErrWriteTimeout) || b.Len() != 8 {
+		t.Errorf("Expect Append to stop at the limit, but got %d bytes, %v", b.Len(), err)
+	}
+	if err := b.ReplaceRange(0, 1, []byte("xx")); !errors.Is(err, ErrTooLarge) || b.String() != "12345678" {
+		t.Errorf("Expect ReplaceRange past the limit to fail, but got %q, %v", b.String(), err)
+	}
+
+	b.Reset()
+	b.WriteString("123456\xff")
+	if _, err := b.SanitizeUTF8(utf8.RuneError); !errors.Is(err, ErrTooLarge) || b.String() != "123456\xff" {
+		t.Errorf("Expect SanitizeUTF8 past the limit to fail, but got %q, %v", b.String(), err)
+	}
+	if n, err := b.SanitizeUTF8('?'); err != nil || n != 1 || b.String() != "123456?" {
+		t.Errorf("unexpected SanitizeUTF8 result %q, %d, %v", b.String(), n, err)
+	}
+
+	b.Reset()
+	n, err := b.ReadFromLimit(bytes.NewReader(make([]byte, 6)), 4)
+	if !errors.Is(err, ErrLimitExceeded) || n != 4 || b.Len() != 4 {
+		t.Errorf("Expect ErrLimitExceeded after 4 bytes, but got %d, %v", n, err)
+	}
+	n, err = b.ReadOnce(bytes.NewReader(make([]byte, 100)), time.Second)
+	if err != nil || n != 4 || b.Len() != 8 {
+		t.Errorf("Expect ReadOnce to fill the room left, but got %d, %v", n, err)
+	}
+	if _, err := b.ReadOnce(bytes.NewReader(make([]byte, 100)), time.Second); !errors.Is(err, ErrWriteTimeout) {
+		t.Errorf("Expect ReadOnce to time out on a full buffer, but got %v", err)
+	}
+	// a large limit doesn't make ReadOnce take a large scratch slice
+	large := NewBoundedIoBuffer(1<<20, time.Second)
+	if n, err := large.ReadOnce(bytes.NewReader(make([]byte, 3*MinRead)), time.Second); err != nil || n != MinRead {
+		t.Errorf("Expect ReadOnce to read a MinRead chunk, but got %d, %v", n, err)
+	}
+
+	// consuming through a view makes room
+	v := b.Limit(3)
+	v.Drain(3)
+	if _, ok := b.TryWrite([]byte("abc")); !ok {
+		t.Errorf("Expect room after consuming the view")
+	}
+}
+
+// gatedWriter signals started and blocks writes until release is closed
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+	bytes.Buffer
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.Buffer.Write(p)
+}
+
+func TestBoundedIoBufferWriteToUnlocked(t *testing.T) {
+	b := NewBoundedIoBuffer(8, 0)
+	b.WriteString("1234")
+	w := &gatedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.WriteTo(w)
+		done <- err
+	}()
+	<-w.started
+	if _, err := b.WriteString("5678"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 8 {
+		t.Errorf("Expect 8 unread bytes, but got %d", b.Len())
+	}
+	close(w.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "12345678" {
+		t.Errorf("Expect 12345678, but got %q", w.String())
+	}
+}
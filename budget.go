@@ -0,0 +1,69 @@
+package buffer
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrBudgetExceeded is returned by writes and reads of an IoBuffer whose
+// Budget can't cover the memory they need.
+var ErrBudgetExceeded = errors.New("io buffer: budget exceeded")
+
+// Budget caps the memory of a group of IoBuffers, e.g. of one connection of
+// a multi-tenant proxy, on top of the global limits of the pool. Buffers
+// created with WithBudget charge the sizes they take from the pool and
+// release them when growing, on Free and on Close. Writes and reads that
+// would need more than the budget has left fail with ErrBudgetExceeded
+// without growing the buffer. A Budget is safe for concurrent use.
+//
+// Like WithMaxSize, reads may overshoot the budget by up to MinRead bytes
+// to tell whether more data is available.
+type Budget struct {
+	used int64 // accessed atomically, kept first for 64-bit alignment
+	max  int64
+}
+
+// NewBudget returns a Budget of maxBytes bytes.
+func NewBudget(maxBytes int) *Budget {
+	if maxBytes < 0 {
+		panic(&Error{Op: "budget", Size: maxBytes, Err: ErrNegativeCount})
+	}
+	return &Budget{max: int64(maxBytes)}
+}
+
+// Max returns the size of the budget.
+func (g *Budget) Max() int {
+	return int(g.max)
+}
+
+// Used returns the number of bytes charged against the budget.
+func (g *Budget) Used() int {
+	return int(atomic.LoadInt64(&g.used))
+}
+
+// Available returns the number of bytes left.
+func (g *Budget) Available() int {
+	if a := g.max - atomic.LoadInt64(&g.used); a > 0 {
+		return int(a)
+	}
+	return 0
+}
+
+// charge moves the charge of b from b.charged to n bytes
+func (b *ioBuffer) charge(n int) {
+	if b.budget == nil {
+		return
+	}
+	atomic.AddInt64(&b.budget.used, int64(n-b.charged))
+	b.charged = n
+}
+
+// budgetCap returns the capacity the budget allows the buffer, at least the
+// memory it holds
+func (b *ioBuffer) budgetCap() int {
+	c := b.charged + b.budget.Max() - b.budget.Used()
+	if c < cap(b.buf) {
+		return cap(b.buf)
+	}
+	return c
+}
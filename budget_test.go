@@ -0,0 +1,71 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBudget(t *testing.T) {
+	g := NewBudget(1024)
+	a := New(WithBudget(g), WithCapacity(100))
+	b := New(WithBudget(g), WithCapacity(100))
+	if g.Used() != 200 || g.Available() != 824 {
+		t.Fatalf("used %d available %d", g.Used(), g.Available())
+	}
+
+	if _, err := a.Write(make([]byte, 500)); err != nil {
+		t.Fatal(err)
+	}
+	if g.Used() > g.Max() {
+		t.Fatalf("used %d of %d", g.Used(), g.Max())
+	}
+	_, err := b.Write(make([]byte, 1000))
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("len %d after failed write", b.Len())
+	}
+
+	// reads stop at the budget too
+	n, err := b.ReadFrom(bytes.NewReader(make([]byte, 1000)))
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded", err)
+	}
+	if used := g.Used(); used > g.Max()+MinRead {
+		t.Fatalf("used %d of %d", used, g.Max())
+	}
+	if int(n) != b.Len() {
+		t.Fatalf("read %d, len %d", n, b.Len())
+	}
+
+	a.Free()
+	b.Close()
+	if g.Used() != 0 {
+		t.Fatalf("used %d after freeing, want 0", g.Used())
+	}
+
+	// the memory of one buffer can be reused by another
+	if _, err := a.Write(make([]byte, 900)); err != nil {
+		t.Fatal(err)
+	}
+
+	// buffers recycled by the pool are plain buffers again
+	a.Alloc(0)
+	if g.Used() != 0 {
+		t.Fatalf("used %d after Alloc, want 0", g.Used())
+	}
+	if _, err := a.Write(make([]byte, 2048)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBudgetErrorKinds(t *testing.T) {
+	g := NewBudget(1 << 20)
+	b := New(WithBudget(g), WithMaxSize(10))
+	if _, err := b.WriteString("0123456789!"); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+	expectPanic(t, "negative budget", func() { NewBudget(-1) })
+}
@@ -1,8 +1,26 @@
 package buffer
 
 import (
+	"bytes"
+	"errors"
 	"io"
+	"math"
+	"math/big"
 	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The readOp constants describe the last action performed on the buffer,
+// so that UnreadRune and UnreadByte can check for invalid usage.
+const (
+	opRead    = -1 // Any other read operation.
+	opInvalid = 0  // Non-read operation.
+)
+
+var (
+	errUnreadByte = errors.New("buffer: UnreadByte: previous operation was not a successful read")
+	errUnreadRune = errors.New("buffer: UnreadRune: previous operation was not a successful ReadRune")
 )
 
 // Buffer provides byte buffer, which can be used for minimizing
@@ -12,21 +30,110 @@ import (
 // slice. See example code for details.
 //
 // Use Get for obtaining an empty byte buffer.
+//
+// Buffer has unexported fields, so composite literals must name B, e.g.
+// Buffer{B: p}; unkeyed literals such as Buffer{p} no longer compile.
+//
+// Buffer also implements the reading side of bytes.Buffer. Bytes consumed
+// by Read, Next, ReadByte and ReadRune stay in B until the buffer is reset,
+// while Bytes, Len and String only cover the unread portion.
 type Buffer struct {
 	// B is a byte buffer to use in append-like workloads.
 	// See example code for details.
 	B []byte
+
+	off      int // read at &B[off]
+	lastRead int // last read operation, so that Unread* can work correctly
+
+	pool *Pool // the pool the buffer was obtained from, used by Free
+}
+
+// readerSize returns the number of bytes left in r if r tells, like
+// *bytes.Reader, *strings.Reader and *bytes.Buffer do with Len, or an
+// *io.LimitedReader over such a reader.
+func readerSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *io.LimitedReader:
+		size, ok := readerSize(r.R)
+		if r.N < size {
+			size = r.N
+		}
+		if size < 0 {
+			size = 0
+		}
+		return size, ok
+	}
+	return 0, false
 }
 
 // ReadFrom implements io.ReaderFrom.
 //
-// The function appends all the data read from r to b.
+// The function appends all the data read from r to b. Readers telling how
+// many bytes they have left, see readerSize, are read into a buffer grown
+// once to fit them.
+//
+// ReadFrom only returns once r fails or returns io.EOF, use ReadFromN or
+// ReadFromLimit with readers that may never end, like connections.
 func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	return b.readFrom(r, -1)
+}
+
+// ReadFromLimit is ReadFrom appending at most max bytes. It fails with
+// ErrLimitExceeded when r holds more, in which case one byte past max has
+// been consumed from r and dropped.
+func (b *Buffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	if max < 0 {
+		panic("buffer: negative count")
+	}
+	return b.readFrom(r, max)
+}
+
+// ReadFromN appends exactly n bytes read from r, without waiting for r to
+// end. It returns io.ErrUnexpectedEOF when r ends before, keeping the bytes
+// read.
+func (b *Buffer) ReadFromN(r io.Reader, n int64) (int64, error) {
+	if n < 0 {
+		panic("buffer: negative count")
+	}
+	start := len(b.B)
+	b.Grow(int(n))
+	p := b.B[start : start+int(n)]
+	m, err := io.ReadFull(r, p)
+	b.B = b.B[:start+m]
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return int64(m), err
+}
+
+// readFrom implements ReadFrom, max < 0 means no limit
+func (b *Buffer) readFrom(r io.Reader, max int64) (int64, error) {
 	p := b.B
 	nStart := int64(len(p))
 	nMax := int64(cap(p))
 	n := nStart
-	if nMax == 0 {
+	// reading stops at end, one byte past max tells whether r has more
+	end := int64(-1)
+	if max >= 0 {
+		end = nStart + max + 1
+	}
+	size, ok := readerSize(r)
+	if ok && max >= 0 && size > max {
+		size = max
+	}
+	if ok && n+size+1 > nMax {
+		// one spare byte so the read seeing EOF doesn't grow the buffer,
+		// repeated appends still grow it geometrically
+		nMax = n + size + 1
+		if c := 2 * int64(cap(p)); nMax < c {
+			nMax = c
+		}
+		bNew := make([]byte, nMax)
+		copy(bNew, p)
+		p = bNew
+	} else if nMax == 0 {
 		nMax = 64
 		p = make([]byte, nMax)
 	} else {
@@ -39,8 +146,16 @@ func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 			copy(bNew, p)
 			p = bNew
 		}
-		nn, err := r.Read(p[n:])
+		q := p[n:]
+		if end >= 0 && int64(len(q)) > end-n {
+			q = q[:end-n]
+		}
+		nn, err := r.Read(q)
 		n += int64(nn)
+		if end >= 0 && n == end {
+			b.B = p[:n-1]
+			return max, ErrLimitExceeded
+		}
 		if err != nil {
 			b.B = p[:n]
 			n -= nStart
@@ -53,16 +168,153 @@ func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 }
 
 // WriteTo implements io.WriterTo.
+//
+// Unlike bytes.Buffer, the written bytes are not consumed.
 func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
-	n, err := w.Write(b.B)
+	n, err := w.Write(b.B[b.off:])
 	return int64(n), err
 }
 
-// Bytes returns b.B, i.e. all the bytes accumulated in the buffer.
+// Bytes returns the unread bytes accumulated in the buffer.
 //
 // The purpose of this function is bytes.Buffer compatibility.
 func (b *Buffer) Bytes() []byte {
-	return b.B
+	return b.B[b.off:]
+}
+
+// CopyBytes returns a copy of the unread bytes, owned by the caller and
+// unaffected by later use of the buffer, unlike the slice returned by Bytes.
+func (b *Buffer) CopyBytes() []byte {
+	return append([]byte(nil), b.B[b.off:]...)
+}
+
+// Read reads the next len(p) bytes from the buffer or until the buffer
+// is drained. The return value n is the number of bytes read. If the
+// buffer has no data to return, err is io.EOF (unless len(p) is zero).
+//
+// The purpose of this function is bytes.Buffer compatibility.
+func (b *Buffer) Read(p []byte) (int, error) {
+	b.lastRead = opInvalid
+	if b.off >= len(b.B) {
+		b.Reset()
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, b.B[b.off:])
+	b.off += n
+	if n > 0 {
+		b.lastRead = opRead
+	}
+	return n, nil
+}
+
+// Next returns a slice containing the next n bytes from the buffer,
+// advancing the buffer as if the bytes had been returned by Read.
+// If there are fewer than n bytes in the buffer, Next returns the entire buffer.
+//
+// The slice is only valid until the next call to a read or write method.
+func (b *Buffer) Next(n int) []byte {
+	b.lastRead = opInvalid
+	m := b.Len()
+	if n > m {
+		n = m
+	}
+	data := b.B[b.off : b.off+n]
+	b.off += n
+	if n > 0 {
+		b.lastRead = opRead
+	}
+	return data
+}
+
+// ReadByte reads and returns the next byte from the buffer.
+// If no byte is available, it returns error io.EOF.
+func (b *Buffer) ReadByte() (byte, error) {
+	if b.off >= len(b.B) {
+		b.Reset()
+		return 0, io.EOF
+	}
+	c := b.B[b.off]
+	b.off++
+	b.lastRead = opRead
+	return c, nil
+}
+
+// UnreadByte unreads the last byte returned by the most recent successful
+// read operation that read at least one byte.
+func (b *Buffer) UnreadByte() error {
+	if b.lastRead == opInvalid {
+		return errUnreadByte
+	}
+	b.lastRead = opInvalid
+	if b.off > 0 {
+		b.off--
+	}
+	return nil
+}
+
+// ReadRune reads and returns the next UTF-8-encoded Unicode code point
+// from the buffer. If no bytes are available, the error returned is io.EOF.
+// If the bytes are an erroneous UTF-8 encoding, it consumes one byte and
+// returns U+FFFD, 1.
+func (b *Buffer) ReadRune() (r rune, size int, err error) {
+	if b.off >= len(b.B) {
+		b.Reset()
+		return 0, 0, io.EOF
+	}
+	c := b.B[b.off]
+	if c < utf8.RuneSelf {
+		b.off++
+		b.lastRead = 1
+		return rune(c), 1, nil
+	}
+	r, size = utf8.DecodeRune(b.B[b.off:])
+	b.off += size
+	b.lastRead = size
+	return r, size, nil
+}
+
+// UnreadRune unreads the last rune returned by ReadRune.
+// If the most recent read or write operation on the buffer was not
+// a successful ReadRune, UnreadRune returns an error.
+func (b *Buffer) UnreadRune() error {
+	if b.lastRead <= opInvalid {
+		return errUnreadRune
+	}
+	if b.off >= b.lastRead {
+		b.off -= b.lastRead
+	}
+	b.lastRead = opInvalid
+	return nil
+}
+
+// Truncate discards all but the first n unread bytes from the buffer.
+// It panics if n is negative or greater than the length of the buffer.
+func (b *Buffer) Truncate(n int) {
+	if n == 0 {
+		b.Reset()
+		return
+	}
+	b.lastRead = opInvalid
+	if n < 0 || n > b.Len() {
+		panic("buffer: truncation out of range")
+	}
+	b.B = b.B[:b.off+n]
+}
+
+// Grow grows the buffer's capacity, if necessary, to guarantee space for
+// another n bytes. It panics if n is negative.
+func (b *Buffer) Grow(n int) {
+	if n < 0 {
+		panic("buffer: negative count")
+	}
+	if cap(b.B)-len(b.B) < n {
+		bNew := make([]byte, len(b.B), 2*cap(b.B)+n)
+		copy(bNew, b.B)
+		b.B = bNew
+	}
 }
 
 // Write implements io.Writer - it appends p to Buffer.B
@@ -90,27 +342,56 @@ func (b *Buffer) WriteString(s string) (int, error) {
 // Set sets Buffer.B to p.
 func (b *Buffer) Set(p []byte) {
 	b.B = append(b.B[:0], p...)
+	b.off = 0
+	b.lastRead = opInvalid
 }
 
 // SetString sets Buffer.B to s.
 func (b *Buffer) SetString(s string) {
 	b.B = append(b.B[:0], s...)
+	b.off = 0
+	b.lastRead = opInvalid
 }
 
-// String returns string representation of Buffer.B.
+// String returns string representation of the unread portion of Buffer.B.
 func (b *Buffer) String() string {
-	return string(b.B)
+	return string(b.B[b.off:])
 }
 
 // Reset makes Buffer.B empty.
 func (b *Buffer) Reset() {
 	b.B = b.B[:0]
+	b.off = 0
+	b.lastRead = opInvalid
 }
 
 func (b *Buffer) WriteInt(n int64) {
 	b.B = strconv.AppendInt(b.B, n, 10)
 }
 
+// WriteIntPad writes n right-aligned in a field of width bytes, filled
+// with pad on the left. With pad '0' a minus sign precedes the zeros, as in
+// fmt's %05d. Numbers wider than width are written in full.
+func (b *Buffer) WriteIntPad(n int64, width int, pad byte) {
+	var scratch [20]byte
+	p := strconv.AppendInt(scratch[:0], n, 10)
+	if pad == '0' && n < 0 {
+		b.B = append(b.B, '-')
+		p = p[1:]
+		width--
+	}
+	for i := len(p); i < width; i++ {
+		b.B = append(b.B, pad)
+	}
+	b.B = append(b.B, p...)
+}
+
+// WriteIntBase writes n in base, which must be between 2 and 36, with
+// lower-case letters for digits above 9.
+func (b *Buffer) WriteIntBase(n int64, base int) {
+	b.B = strconv.AppendInt(b.B, n, base)
+}
+
 func (b *Buffer) WriteUint(n uint64) {
 	b.B = strconv.AppendUint(b.B, n, 10)
 }
@@ -120,12 +401,89 @@ func (b *Buffer) WriteBool(v bool) {
 }
 
 func (b *Buffer) WriteFloat(f float64, bitSize int) {
-	b.B = strconv.AppendFloat(b.B, f, 'f', -1, bitSize)
+	b.WriteFloatFmt(f, 'f', -1, bitSize)
+}
+
+// WriteFloatFmt writes f as strconv.FormatFloat(f, fmtByte, prec, bitSize)
+// does, e.g. 'g' with prec -1 for the shortest representation that round
+// trips or 'f' with a fixed precision. The shortest representation of
+// small integral values, common in metrics, is written as an integer
+// without going through the float formatting.
+func (b *Buffer) WriteFloatFmt(f float64, fmtByte byte, prec, bitSize int) {
+	if prec < 0 {
+		// integral values below limit are printed as integers, floats
+		// hold integers exactly up to 2^24 or 2^53 and shortest 'g'
+		// switches to an exponent at 1e6
+		limit := float64(0)
+		switch fmtByte {
+		case 'f':
+			limit = 1 << 53
+			if bitSize == 32 {
+				limit = 1 << 24
+			}
+		case 'g', 'G':
+			limit = 1e6
+		}
+		if f > -limit && f < limit && f == math.Trunc(f) && !(f == 0 && math.Signbit(f)) {
+			b.B = strconv.AppendInt(b.B, int64(f), 10)
+			return
+		}
+	}
+	b.B = strconv.AppendFloat(b.B, f, fmtByte, prec, bitSize)
+}
+
+// WriteBigInt writes x in decimal, "<nil>" for a nil x like x.String().
+// Values fitting an int64 are written without allocating.
+func (b *Buffer) WriteBigInt(x *big.Int) {
+	switch {
+	case x == nil:
+		b.B = append(b.B, "<nil>"...)
+	case x.IsInt64():
+		b.B = strconv.AppendInt(b.B, x.Int64(), 10)
+	default:
+		b.B = x.Append(b.B, 10)
+	}
+}
+
+// WriteDecimal writes the fixed-point number units * 10^exponent in
+// decimal notation, e.g. 12345, -2 as "123.45" and 5, -3 as "0.005".
+// Negative exponents give that many fractional digits, trailing zeros
+// included, positive ones append zeros.
+func (b *Buffer) WriteDecimal(units int64, exponent int) {
+	u := uint64(units)
+	if units < 0 {
+		b.B = append(b.B, '-')
+		u = -u
+	}
+	var scratch [20]byte
+	digits := strconv.AppendUint(scratch[:0], u, 10)
+	if exponent >= 0 {
+		b.B = append(b.B, digits...)
+		if u != 0 {
+			for i := 0; i < exponent; i++ {
+				b.B = append(b.B, '0')
+			}
+		}
+		return
+	}
+	scale := -exponent
+	if len(digits) <= scale {
+		b.B = append(b.B, '0', '.')
+		for i := len(digits); i < scale; i++ {
+			b.B = append(b.B, '0')
+		}
+		b.B = append(b.B, digits...)
+		return
+	}
+	point := len(digits) - scale
+	b.B = append(b.B, digits[:point]...)
+	b.B = append(b.B, '.')
+	b.B = append(b.B, digits[point:]...)
 }
 
-// Len returns the size of the byte buffer.
+// Len returns the number of unread bytes in the byte buffer.
 func (b *Buffer) Len() int {
-	return len(b.B)
+	return len(b.B) - b.off
 }
 
 func (b *Buffer) Cap() int {
@@ -134,9 +492,50 @@ func (b *Buffer) Cap() int {
 
 // TrimNewline trims any final "\n" byte from the end of the buffer.
 func (b *Buffer) TrimNewline() {
-	if i := len(b.B) - 1; i >= 0 {
+	if i := len(b.B) - 1; i >= b.off {
 		if b.B[i] == '\n' {
 			b.B = b.B[:i]
 		}
 	}
 }
+
+// TrimSpace trims leading and trailing white space, as defined by Unicode,
+// from the unread portion of Buffer.B.
+func (b *Buffer) TrimSpace() {
+	b.TrimRightFunc(unicode.IsSpace)
+	p := b.B[b.off:]
+	b.trimLeft(len(p) - len(bytes.TrimLeftFunc(p, unicode.IsSpace)))
+}
+
+// TrimPrefix trims the leading prefix from the unread portion of Buffer.B,
+// if present.
+func (b *Buffer) TrimPrefix(prefix string) {
+	if p := b.B[b.off:]; len(p) >= len(prefix) && string(p[:len(prefix)]) == prefix {
+		b.trimLeft(len(prefix))
+	}
+}
+
+// TrimSuffix trims the trailing suffix from the unread portion of Buffer.B,
+// if present.
+func (b *Buffer) TrimSuffix(suffix string) {
+	if p := b.B[b.off:]; len(p) >= len(suffix) && string(p[len(p)-len(suffix):]) == suffix {
+		b.B = b.B[:len(b.B)-len(suffix)]
+	}
+}
+
+// TrimRightFunc trims all trailing runes satisfying f from the unread
+// portion of Buffer.B.
+func (b *Buffer) TrimRightFunc(f func(rune) bool) {
+	b.B = b.B[:b.off+len(bytes.TrimRightFunc(b.B[b.off:], f))]
+}
+
+// trimLeft drops the first n unread bytes by moving the rest down, so that
+// Buffer.B keeps starting with the bytes already read
+func (b *Buffer) trimLeft(n int) {
+	if n == 0 {
+		return
+	}
+	m := copy(b.B[b.off:], b.B[b.off+n:])
+	b.B = b.B[:b.off+m]
+	b.lastRead = opInvalid
+}
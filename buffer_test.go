@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -42,6 +46,73 @@ func TestBufferReadFrom(t *testing.T) {
 	}
 }
 
+func TestBufferReadFromKnownSize(t *testing.T) {
+	data := strings.Repeat("x", 100000)
+	for _, r := range []io.Reader{
+		strings.NewReader(data),
+		bytes.NewReader([]byte(data)),
+		bytes.NewBufferString(data),
+		io.LimitReader(strings.NewReader(data+"more"), int64(len(data))),
+	} {
+		var b Buffer
+		n, err := b.ReadFrom(r)
+		if n != int64(len(data)) || err != nil || b.String() != data {
+			t.Fatalf("unexpected ReadFrom %d, %v", n, err)
+		}
+		if cap(b.B) != len(data)+1 {
+			t.Errorf("Expect %T read into an exactly grown buffer, but got cap %d", r, cap(b.B))
+		}
+	}
+
+	// an unknown size is read growing the buffer as it goes
+	var b Buffer
+	b.ReadFrom(io.LimitReader(onlyReader{strings.NewReader(data)}, 1000))
+	if b.Len() != 1000 || cap(b.B) == 1001 {
+		t.Errorf("unexpected unknown size read: len %d cap %d", b.Len(), cap(b.B))
+	}
+}
+
+func TestBufferReadFromLimit(t *testing.T) {
+	var b Buffer
+	b.WriteString("pre|")
+	n, err := b.ReadFromLimit(onlyReader{strings.NewReader("0123456789")}, 4)
+	if n != 4 || err != ErrLimitExceeded || b.String() != "pre|0123" {
+		t.Errorf("unexpected ReadFromLimit %d, %v, %q", n, err, b.String())
+	}
+	b.Reset()
+	n, err = b.ReadFromLimit(strings.NewReader("0123"), 4)
+	if n != 4 || err != nil || b.String() != "0123" {
+		t.Errorf("Expect a reader of max bytes to fit, but got %d, %v", n, err)
+	}
+	b.Reset()
+	if n, err = b.ReadFromLimit(strings.NewReader("x"), 0); n != 0 || err != ErrLimitExceeded || b.Len() != 0 {
+		t.Errorf("Expect a zero limit to fail, but got %d, %v", n, err)
+	}
+}
+
+func TestBufferReadFromN(t *testing.T) {
+	c1, c2 := tcpPair(t)
+	defer c1.Close()
+	defer c2.Close()
+
+	// the connection never ends, ReadFromN returns once n bytes are read
+	c1.Write([]byte("hello, world"))
+	var b Buffer
+	b.WriteString(">")
+	n, err := b.ReadFromN(c2, 5)
+	if n != 5 || err != nil || b.String() != ">hello" {
+		t.Fatalf("unexpected ReadFromN %d, %v, %q", n, err, b.String())
+	}
+	c1.Close()
+	n, err = b.ReadFromN(c2, 10)
+	if n != 7 || err != io.ErrUnexpectedEOF || b.String() != ">hello, world" {
+		t.Errorf("Expect a short read, but got %d, %v, %q", n, err, b.String())
+	}
+	if n, err = b.ReadFromN(c2, 0); n != 0 || err != nil {
+		t.Errorf("Expect an empty read to succeed, but got %d, %v", n, err)
+	}
+}
+
 func TestBufferWriteTo(t *testing.T) {
 	expectedS := "foobarbaz"
 	var bb Buffer
@@ -136,3 +207,251 @@ func TestBufferGetStringConcurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestBufferRead(t *testing.T) {
+	var bb Buffer
+	bb.WriteString("foobarbaz")
+
+	p := make([]byte, 3)
+	n, err := bb.Read(p)
+	if err != nil || n != 3 || string(p) != "foo" {
+		t.Fatalf("unexpected read: n=%d, err=%v, p=%q", n, err, p)
+	}
+	if bb.String() != "barbaz" || bb.Len() != 6 {
+		t.Fatalf("unexpected unread contents: %q", bb.Bytes())
+	}
+	if s := string(bb.Next(3)); s != "bar" {
+		t.Fatalf("unexpected Next result: %q. Expecting %q", s, "bar")
+	}
+	if err := bb.UnreadByte(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c, err := bb.ReadByte()
+	if err != nil || c != 'r' {
+		t.Fatalf("unexpected ReadByte result: %q, %v", c, err)
+	}
+	if s := string(bb.Next(10)); s != "baz" {
+		t.Fatalf("unexpected Next result: %q. Expecting %q", s, "baz")
+	}
+	if _, err := bb.Read(p); err != io.EOF {
+		t.Fatalf("unexpected error: %v. Expecting io.EOF", err)
+	}
+	if _, err := bb.ReadByte(); err != io.EOF {
+		t.Fatalf("unexpected error: %v. Expecting io.EOF", err)
+	}
+	if err := bb.UnreadByte(); err == nil {
+		t.Fatalf("expected error on UnreadByte after failed read")
+	}
+}
+
+func TestBufferCopyBytes(t *testing.T) {
+	var bb Buffer
+	bb.WriteString("foobar")
+	bb.Next(3)
+	p := bb.CopyBytes()
+	bb.Reset()
+	bb.WriteString("xyz")
+	if string(p) != "bar" {
+		t.Fatalf("unexpected copy: %q. Expecting %q", p, "bar")
+	}
+}
+
+func TestBufferReadRune(t *testing.T) {
+	var bb Buffer
+	bb.WriteString("aé世")
+
+	expected := []rune{'a', 'é', '世'}
+	for i, er := range expected {
+		r, size, err := bb.ReadRune()
+		if err != nil || r != er || size != len(string(er)) {
+			t.Fatalf("unexpected rune %d: %q, size %d, err %v", i, r, size, err)
+		}
+	}
+	if err := bb.UnreadRune(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bb.String() != "世" {
+		t.Fatalf("unexpected contents after UnreadRune: %q", bb.String())
+	}
+	if err := bb.UnreadRune(); err == nil {
+		t.Fatalf("expected error on second UnreadRune")
+	}
+	if _, _, err := bb.ReadRune(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, _, err := bb.ReadRune(); err != io.EOF {
+		t.Fatalf("unexpected error: %v. Expecting io.EOF", err)
+	}
+}
+
+func TestBufferTruncateGrow(t *testing.T) {
+	var bb Buffer
+	bb.WriteString("foobarbaz")
+	bb.Next(3)
+	bb.Truncate(3)
+	if bb.String() != "bar" {
+		t.Fatalf("unexpected contents after Truncate: %q", bb.String())
+	}
+	bb.Grow(100)
+	if cap(bb.B)-len(bb.B) < 100 {
+		t.Fatalf("unexpected free capacity after Grow: %d", cap(bb.B)-len(bb.B))
+	}
+	if bb.String() != "bar" {
+		t.Fatalf("unexpected contents after Grow: %q", bb.String())
+	}
+	bb.Truncate(0)
+	if bb.Len() != 0 {
+		t.Fatalf("unexpected length after Truncate(0): %d", bb.Len())
+	}
+}
+
+func TestBufferTrim(t *testing.T) {
+	var bb Buffer
+	bb.SetString("read \t line one \r\n")
+	bb.Next(5)
+	bb.TrimSpace()
+	if bb.String() != "line one" || string(bb.B) != "read line one" {
+		t.Fatalf("unexpected TrimSpace result: %q, %q", bb.String(), bb.B)
+	}
+
+	bb.TrimPrefix("line ")
+	bb.TrimPrefix("xyz")
+	if bb.String() != "one" {
+		t.Fatalf("unexpected TrimPrefix result: %q", bb.String())
+	}
+	bb.TrimSuffix("ne")
+	bb.TrimSuffix("xyz")
+	if bb.String() != "o" {
+		t.Fatalf("unexpected TrimSuffix result: %q", bb.String())
+	}
+	// the read portion is never trimmed
+	bb.TrimSuffix("do")
+	if bb.String() != "o" {
+		t.Fatalf("unexpected TrimSuffix result: %q", bb.String())
+	}
+
+	bb.SetString("value;;,")
+	bb.TrimRightFunc(func(r rune) bool { return r == ';' || r == ',' })
+	if bb.String() != "value" {
+		t.Fatalf("unexpected TrimRightFunc result: %q", bb.String())
+	}
+	bb.SetString("  \u3000 ")
+	bb.TrimSpace()
+	if bb.Len() != 0 {
+		t.Fatalf("unexpected TrimSpace result: %q", bb.String())
+	}
+}
+
+
+func TestBufferWriteIntPad(t *testing.T) {
+	for _, c := range []struct {
+		n     int64
+		width int
+		pad   byte
+		want  string
+	}{
+		{42, 5, '0', "00042"},
+		{-42, 5, '0', "-0042"},
+		{42, 5, ' ', "   42"},
+		{-42, 5, ' ', "  -42"},
+		{123456, 3, '0', "123456"},
+		{-9223372036854775808, 0, '0', "-9223372036854775808"},
+		{7, 0, '0', "7"},
+	} {
+		var bb Buffer
+		bb.WriteIntPad(c.n, c.width, c.pad)
+		if bb.String() != c.want {
+			t.Errorf("WriteIntPad(%d, %d, %q) = %q, want %q", c.n, c.width, c.pad, bb.String(), c.want)
+		}
+		if want := fmt.Sprintf("%0*d", c.width, c.n); c.pad == '0' && bb.String() != want {
+			t.Errorf("WriteIntPad(%d, %d, '0') = %q, fmt gives %q", c.n, c.width, bb.String(), want)
+		}
+	}
+
+	var bb Buffer
+	bb.WriteIntBase(255, 16)
+	bb.WriteByte(' ')
+	bb.WriteIntBase(-5, 2)
+	if bb.String() != "ff -101" {
+		t.Fatalf("unexpected WriteIntBase result: %q", bb.String())
+	}
+	expectPanic(t, "base 1", func() { bb.WriteIntBase(1, 1) })
+}
+
+func TestBufferWriteFloatFmt(t *testing.T) {
+	values := []float64{0, 1, -1, 42, 999999, 1e6, -1e6, 123456789, 1 << 53, 1<<53 + 2, 1e21, 0.5, -0.25,
+		3.14159, 1e-7, 2.5e10, 1<<24 - 1, 1 << 24, 1<<24 + 1, float64(float32(0.1)), math.Copysign(0, -1), math.Inf(1), math.NaN(), math.MaxFloat64}
+	for _, f := range values {
+		for _, fmtByte := range []byte{'f', 'g', 'G', 'e'} {
+			for _, prec := range []int{-1, 0, 3} {
+				for _, bitSize := range []int{32, 64} {
+					var bb Buffer
+					bb.WriteFloatFmt(f, fmtByte, prec, bitSize)
+					if want := strconv.FormatFloat(f, fmtByte, prec, bitSize); bb.String() != want {
+						t.Errorf("WriteFloatFmt(%v, %q, %d, %d) = %q, want %q", f, fmtByte, prec, bitSize, bb.String(), want)
+					}
+				}
+			}
+		}
+	}
+
+	var bb Buffer
+	bb.WriteFloat(2.5, 64)
+	if bb.String() != "2.5" {
+		t.Fatalf("unexpected WriteFloat result: %q", bb.String())
+	}
+}
+
+func BenchmarkBufferWriteFloatFmt(b *testing.B) {
+	var bb Buffer
+	for i := 0; i < b.N; i++ {
+		bb.Reset()
+		bb.WriteFloatFmt(float64(i%100000), 'g', -1, 64)
+	}
+}
+
+func TestBufferWriteBigInt(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	for _, x := range []*big.Int{nil, big.NewInt(0), big.NewInt(-42), big.NewInt(math.MaxInt64), huge} {
+		var bb Buffer
+		bb.WriteBigInt(x)
+		if bb.String() != x.String() {
+			t.Errorf("WriteBigInt(%v) = %q", x, bb.String())
+		}
+	}
+	x := big.NewInt(1234567)
+	var bb Buffer
+	bb.Grow(64)
+	if n := testing.AllocsPerRun(100, func() {
+		bb.Reset()
+		bb.WriteBigInt(x)
+	}); n != 0 {
+		t.Fatalf("WriteBigInt of an int64 allocates %v times", n)
+	}
+}
+
+func TestBufferWriteDecimal(t *testing.T) {
+	for _, c := range []struct {
+		units    int64
+		exponent int
+		want     string
+	}{
+		{12345, -2, "123.45"},
+		{-12345, -2, "-123.45"},
+		{5, -3, "0.005"},
+		{-5, -3, "-0.005"},
+		{100, -2, "1.00"},
+		{0, -2, "0.00"},
+		{42, 0, "42"},
+		{42, 3, "42000"},
+		{0, 3, "0"},
+		{math.MinInt64, -18, "-9.223372036854775808"},
+		{math.MaxInt64, -19, "0.9223372036854775807"},
+	} {
+		var bb Buffer
+		bb.WriteDecimal(c.units, c.exponent)
+		if bb.String() != c.want {
+			t.Errorf("WriteDecimal(%d, %d) = %q, want %q", c.units, c.exponent, bb.String(), c.want)
+		}
+	}
+}
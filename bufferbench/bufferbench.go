@@ -0,0 +1,230 @@
+// Package bufferbench replays recorded buffer workloads against pool
+// configurations and reports what each costs in allocations, copies and
+// memory, so that size classes and pooling limits can be tuned on real
+// traffic instead of guesswork.
+//
+// Record a Trace in production or a load test with a Recorder, save it with
+// Trace.WriteTo and compare strategies offline:
+//
+//	results := bufferbench.Compare(trace,
+//		bufferbench.Strategy{Name: "direct", Direct: true},
+//		bufferbench.Strategy{Name: "default"},
+//		bufferbench.Strategy{Name: "small", Options: []buffer.PoolOption{
+//			buffer.WithSizeClasses(256, 64<<10),
+//		}},
+//	)
+//	bufferbench.WriteReport(os.Stdout, results)
+package bufferbench
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gottingen/buffer"
+)
+
+// heapSampleEvery is the number of steps between heap samples, reading the
+// memory statistics stops the world
+const heapSampleEvery = 1024
+
+// Strategy is a pool configuration to replay a trace against.
+type Strategy struct {
+	// Name labels the strategy in the results.
+	Name string
+	// Options configure the NamedPool backing the buffers.
+	Options []buffer.PoolOption
+	// Direct allocates every buffer with make and never reuses memory,
+	// the baseline of the comparison. Options are ignored.
+	Direct bool
+}
+
+// Result is what replaying a trace with a strategy cost.
+type Result struct {
+	Name string
+	// Ops is the number of steps replayed.
+	Ops int
+	// Duration is the wall time of the replay.
+	Duration time.Duration
+	// Allocs and AllocBytes are the heap objects and bytes allocated.
+	Allocs     uint64
+	AllocBytes uint64
+	// PoolMisses is the number of slices the pool had to allocate.
+	PoolMisses uint64
+	// Grows is the number of times buffers grew and GrowBytes the unread
+	// bytes copied to the grown memory, CopiedBytes are the bytes slid to
+	// the front of buffers.
+	Grows       int64
+	GrowBytes   int64
+	CopiedBytes int64
+	// PeakHeapInuse is the largest heap in use sampled during the replay.
+	PeakHeapInuse uint64
+	// RSS is the resident set size after the replay, 0 where unknown.
+	RSS uint64
+}
+
+// directPool allocates every slice and drops returned ones
+type directPool struct{}
+
+func (directPool) Get(n int) *[]byte {
+	b := make([]byte, n)
+	return &b
+}
+
+func (directPool) Put(*[]byte) {}
+
+var poolSeq uint64
+
+// Compare replays t with each strategy in turn and returns their results.
+func Compare(t Trace, strategies ...Strategy) []Result {
+	results := make([]Result, 0, len(strategies))
+	for _, s := range strategies {
+		results = append(results, Replay(t, s))
+	}
+	return results
+}
+
+// Replay replays t against a fresh pool configured by s, registered as
+// NamedPool "bufferbench-<n>" while it runs. Grows are replayed by filling the buffer and
+// writing the bytes missing to the new capacity, so they copy what the
+// buffer holds as they would in the traced program.
+func Replay(t Trace, s Strategy) Result {
+	var (
+		pool   *buffer.NamedPool
+		get    func(size int) buffer.IoBuffer
+		put    func(b buffer.IoBuffer)
+		res    = Result{Name: s.Name, Ops: len(t)}
+		bufs   = make(map[int]buffer.IoBuffer)
+		filler = make([]byte, 4096)
+	)
+	if s.Direct {
+		get = func(size int) buffer.IoBuffer {
+			return buffer.New(buffer.WithBytePool(directPool{}), buffer.WithCapacity(size))
+		}
+		put = func(b buffer.IoBuffer) {
+			b.Free()
+		}
+	} else {
+		name := "bufferbench-" + strconv.FormatUint(atomic.AddUint64(&poolSeq, 1), 10)
+		pool = buffer.NewNamedPool(name, s.Options...)
+		defer pool.Unregister()
+		get = pool.GetIoBuffer
+		put = func(b buffer.IoBuffer) {
+			pool.PutIoBuffer(b)
+		}
+	}
+	hooks := &buffer.Hooks{
+		OnGrow: func(b buffer.IoBuffer, oldCap, newCap int) {
+			res.GrowBytes += int64(b.Len())
+		},
+	}
+	for _, op := range t {
+		if op.Kind == OpGrow && op.Size > len(filler) {
+			filler = make([]byte, op.Size)
+		}
+	}
+
+	runtime.GC()
+	var before, ms runtime.MemStats
+	runtime.ReadMemStats(&before)
+	res.PeakHeapInuse = before.HeapInuse
+	start := time.Now()
+
+	release := func(b buffer.IoBuffer) {
+		st := b.Stats()
+		res.Grows += int64(st.Grows)
+		res.CopiedBytes += st.CopiedBytes
+		put(b)
+	}
+	for i, op := range t {
+		switch op.Kind {
+		case OpGet:
+			if b, ok := bufs[op.ID]; ok {
+				release(b)
+			}
+			b := get(op.Size)
+			b.SetHooks(hooks)
+			bufs[op.ID] = b
+		case OpGrow:
+			b, ok := bufs[op.ID]
+			if !ok {
+				continue
+			}
+			// a buffer grows when it is full
+			for n := b.Cap() - b.Len(); n > 0; {
+				m := n
+				if m > len(filler) {
+					m = len(filler)
+				}
+				b.Write(filler[:m])
+				n -= m
+			}
+			if n := op.Size - b.Len(); n > 0 {
+				b.Write(filler[:n])
+			}
+		case OpPut:
+			if b, ok := bufs[op.ID]; ok {
+				release(b)
+				delete(bufs, op.ID)
+			}
+		}
+		if i%heapSampleEvery == heapSampleEvery-1 {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > res.PeakHeapInuse {
+				res.PeakHeapInuse = ms.HeapInuse
+			}
+		}
+	}
+	for _, b := range bufs {
+		release(b)
+	}
+
+	res.Duration = time.Since(start)
+	runtime.ReadMemStats(&ms)
+	if ms.HeapInuse > res.PeakHeapInuse {
+		res.PeakHeapInuse = ms.HeapInuse
+	}
+	res.Allocs = ms.Mallocs - before.Mallocs
+	res.AllocBytes = ms.TotalAlloc - before.TotalAlloc
+	res.RSS = rss()
+	if pool != nil {
+		res.PoolMisses = pool.Stats().Misses
+	}
+	return res
+}
+
+// rss returns the resident set size of the process, 0 if unknown
+func rss() uint64 {
+	p, err := ioutil.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(p))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// WriteReport writes results as an aligned table.
+func WriteReport(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "strategy\tops\ttime\tallocs\talloc bytes\tpool misses\tgrows\tgrow bytes\tcopied bytes\tpeak heap\trss\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+			r.Name, r.Ops, r.Duration.Round(time.Microsecond), r.Allocs, r.AllocBytes,
+			r.PoolMisses, r.Grows, r.GrowBytes, r.CopiedBytes, r.PeakHeapInuse, r.RSS)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,103 @@
+package bufferbench
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gottingen/buffer"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	buffer.SetDefaultHooks(r.Hooks())
+	defer buffer.SetDefaultHooks(nil)
+
+	a := buffer.New(buffer.WithCapacity(16))
+	b := buffer.New(buffer.WithCapacity(16))
+	a.Write(make([]byte, 100))
+	b.Free()
+	a.Free()
+
+	got := r.Trace()
+	if len(got) != 5 {
+		t.Fatalf("got %v", got)
+	}
+	if got[0].Kind != OpGet || got[0].Size < 16 || got[1].Kind != OpGrow || got[1].Size < 100 {
+		t.Fatalf("got %v", got)
+	}
+	if got[2].Kind != OpGet || got[3].Kind != OpPut || got[2].ID != got[3].ID {
+		t.Fatalf("got %v", got)
+	}
+	if got[4] != (Op{Kind: OpPut, ID: got[0].ID}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	want := Trace{
+		{Kind: OpGet, ID: 0, Size: 512},
+		{Kind: OpGet, ID: 1, Size: 64},
+		{Kind: OpGrow, ID: 0, Size: 4096},
+		{Kind: OpPut, ID: 1},
+		{Kind: OpPut, ID: 0},
+	}
+	var w bytes.Buffer
+	if _, err := want.WriteTo(&w); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadTrace(strings.NewReader("# recorded\n\n" + w.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"get 1\n", "take 1 2\n", "get x 2\n", "get 1 -2\n"} {
+		if _, err := ReadTrace(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	var trace Trace
+	for i := 0; i < 2000; i++ {
+		trace = append(trace,
+			Op{Kind: OpGet, ID: i, Size: 256},
+			Op{Kind: OpGrow, ID: i, Size: 2048},
+			Op{Kind: OpPut, ID: i},
+		)
+	}
+	pools := len(buffer.NamedPools())
+	results := Compare(trace,
+		Strategy{Name: "direct", Direct: true},
+		Strategy{Name: "pooled"},
+	)
+	if len(results) != 2 {
+		t.Fatalf("got %d results", len(results))
+	}
+	direct, pooled := results[0], results[1]
+	if direct.Ops != len(trace) || direct.Grows != 2000 || direct.GrowBytes < 2000*256 {
+		t.Fatalf("direct %+v", direct)
+	}
+	if pooled.Grows != direct.Grows || pooled.PoolMisses >= 2000 {
+		t.Fatalf("pooled %+v", pooled)
+	}
+	if n := len(buffer.NamedPools()); n != pools {
+		t.Errorf("Expect the replay pool to be unregistered, but got %d pools, %d before", n, pools)
+	}
+	if pooled.AllocBytes >= direct.AllocBytes {
+		t.Errorf("pooled allocated %d bytes, direct %d", pooled.AllocBytes, direct.AllocBytes)
+	}
+
+	var report bytes.Buffer
+	if err := WriteReport(&report, results); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(report.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[2], "pooled") {
+		t.Fatalf("report:\n%s", report.String())
+	}
+}
@@ -0,0 +1,169 @@
+package bufferbench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gottingen/buffer"
+)
+
+// OpKind is the kind of a trace step.
+type OpKind int
+
+const (
+	// OpGet takes a buffer of Size bytes.
+	OpGet OpKind = iota
+	// OpGrow grows a buffer to a capacity of Size bytes.
+	OpGrow
+	// OpPut returns a buffer.
+	OpPut
+)
+
+var opNames = [...]string{"get", "grow", "put"}
+
+func (k OpKind) String() string {
+	if k < 0 || int(k) >= len(opNames) {
+		return "op(" + strconv.Itoa(int(k)) + ")"
+	}
+	return opNames[k]
+}
+
+// Op is one step of a trace.
+type Op struct {
+	Kind OpKind
+	// ID identifies the buffer, IDs are not reused within a trace.
+	ID int
+	// Size is the requested size of OpGet and the new capacity of OpGrow.
+	Size int
+}
+
+// Trace is a sequence of buffer sizes and lifetimes. The order of the
+// steps is the clock, a buffer lives from its OpGet to its OpPut.
+type Trace []Op
+
+// WriteTo writes the trace in a line based text format, one "get", "grow"
+// or "put" step with its ID and size per line, read by ReadTrace.
+func (t Trace) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, op := range t {
+		m, err := fmt.Fprintf(bw, "%s %d %d\n", op.Kind, op.ID, op.Size)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// ReadTrace reads a trace written by Trace.WriteTo. Empty lines and lines
+// starting with # are skipped.
+func ReadTrace(r io.Reader) (Trace, error) {
+	var t Trace
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		op, err := parseOp(text)
+		if err != nil {
+			return t, fmt.Errorf("bufferbench: line %d: %v", line, err)
+		}
+		t = append(t, op)
+	}
+	return t, s.Err()
+}
+
+func parseOp(text string) (Op, error) {
+	var op Op
+	fields := strings.Fields(text)
+	if len(fields) != 3 {
+		return op, fmt.Errorf("%d fields, want 3", len(fields))
+	}
+	kind := -1
+	for i, name := range opNames {
+		if fields[0] == name {
+			kind = i
+		}
+	}
+	if kind < 0 {
+		return op, fmt.Errorf("unknown op %q", fields[0])
+	}
+	op.Kind = OpKind(kind)
+	var err error
+	if op.ID, err = strconv.Atoi(fields[1]); err != nil {
+		return op, err
+	}
+	if op.Size, err = strconv.Atoi(fields[2]); err != nil {
+		return op, err
+	}
+	if op.Size < 0 {
+		return op, fmt.Errorf("negative size %d", op.Size)
+	}
+	return op, nil
+}
+
+// Recorder records a trace from the memory events of IoBuffers, install
+// its hooks with buffer.SetDefaultHooks or IoBuffer.SetHooks.
+//
+// The hooks don't see buffers being taken, so a buffer enters the trace
+// with its capacity before its first grow or, if it never grows, right
+// before it is released. Such buffers are replayed with no lifetime.
+type Recorder struct {
+	mu    sync.Mutex
+	ids   map[buffer.IoBuffer]int
+	next  int
+	trace Trace
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{ids: make(map[buffer.IoBuffer]int)}
+}
+
+// Hooks returns the hooks feeding the recorder.
+func (r *Recorder) Hooks() *buffer.Hooks {
+	return &buffer.Hooks{
+		OnGrow: r.onGrow,
+		OnFree: r.onFree,
+	}
+}
+
+// id returns the ID of b, recording a get of capacity bytes for new buffers
+func (r *Recorder) id(b buffer.IoBuffer, capacity int) int {
+	id, ok := r.ids[b]
+	if !ok {
+		id = r.next
+		r.next++
+		r.ids[b] = id
+		r.trace = append(r.trace, Op{Kind: OpGet, ID: id, Size: capacity})
+	}
+	return id
+}
+
+func (r *Recorder) onGrow(b buffer.IoBuffer, oldCap, newCap int) {
+	r.mu.Lock()
+	id := r.id(b, oldCap)
+	r.trace = append(r.trace, Op{Kind: OpGrow, ID: id, Size: newCap})
+	r.mu.Unlock()
+}
+
+func (r *Recorder) onFree(b buffer.IoBuffer, capacity int) {
+	r.mu.Lock()
+	id := r.id(b, capacity)
+	delete(r.ids, b)
+	r.trace = append(r.trace, Op{Kind: OpPut, ID: id})
+	r.mu.Unlock()
+}
+
+// Trace returns a copy of the steps recorded so far.
+func (r *Recorder) Trace() Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(Trace(nil), r.trace...)
+}
@@ -0,0 +1,170 @@
+// Package buffertest provides helpers for testing code built on the
+// buffer package.
+package buffertest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gottingen/buffer"
+)
+
+const (
+	contextLines = 4
+	maxDiffLines = 32
+)
+
+// AssertEqual fails the test if the unread region of got differs from want,
+// reporting a hexdump diff around the first mismatch. got is compared
+// through CopyBytes, so a MultiIoBuffer isn't coalesced.
+func AssertEqual(t testing.TB, want []byte, got buffer.IoBuffer) {
+	t.Helper()
+	AssertBytesEqual(t, want, got.CopyBytes())
+}
+
+// AssertBytesEqual is like AssertEqual for plain byte slices.
+func AssertBytesEqual(t testing.TB, want, got []byte) {
+	t.Helper()
+	if bytes.Equal(want, got) {
+		return
+	}
+	t.Errorf("buffer mismatch: want %d bytes, got %d bytes\n%s", len(want), len(got), Diff(want, got))
+}
+
+// Diff returns hexdumps of want and got side by side, 16 bytes per line
+// with offsets and ASCII gutters in the format of buffer.DumpBytes. Lines
+// that differ are prefixed with '!' and followed by a line marking the
+// differing bytes with '^' in both columns; only lines around the first
+// difference are shown.
+func Diff(want, got []byte) string {
+	n := len(want)
+	if len(got) > n {
+		n = len(got)
+	}
+	lines := (n + bytesPerLine - 1) / bytesPerLine
+	first := 0
+	for first < len(want) && first < len(got) && want[first] == got[first] {
+		first++
+	}
+	start := first/bytesPerLine - contextLines
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxDiffLines
+	if end > lines {
+		end = lines
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  %-8s  %-*s | %s\n", "offset", sideWidth, "want", "got")
+	for i := start; i < end; i++ {
+		off := i * bytesPerLine
+		w, g := row(want, off), row(got, off)
+		mark := ' '
+		if !bytes.Equal(w, g) {
+			mark = '!'
+		}
+		l := fmt.Sprintf("%c %08x: %s | %s", mark, off, side(w), side(g))
+		sb.WriteString(strings.TrimRight(l, " "))
+		sb.WriteByte('\n')
+		if mark == '!' {
+			sb.WriteString(markers(w, g))
+		}
+	}
+	if end < lines {
+		fmt.Fprintf(&sb, "  ... %d more lines\n", lines-end)
+	}
+	return sb.String()
+}
+
+const (
+	bytesPerLine = 16
+	// two digits per byte and a space after each pair
+	hexWidth = bytesPerLine*2 + bytesPerLine/2
+	// the hex column, a space and the ASCII gutter
+	sideWidth = hexWidth + 1 + bytesPerLine
+	// the width of "! 00000000: "
+	prefixWidth = 12
+)
+
+// row returns the bytes of p on the line at offset off
+func row(p []byte, off int) []byte {
+	if off >= len(p) {
+		return nil
+	}
+	p = p[off:]
+	if len(p) > bytesPerLine {
+		p = p[:bytesPerLine]
+	}
+	return p
+}
+
+// side formats a line of bytes as a column of the diff, padded to
+// sideWidth
+func side(p []byte) string {
+	out := make([]byte, sideWidth)
+	for i := range out {
+		out[i] = ' '
+	}
+	for i, c := range p {
+		out[hexPos(i)] = hexDigits[c>>4]
+		out[hexPos(i)+1] = hexDigits[c&0xf]
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		out[hexWidth+1+i] = c
+	}
+	return string(out)
+}
+
+// markers returns the line marking the bytes differing between w and g, a
+// byte present on one side only differs
+func markers(w, g []byte) string {
+	out := []byte(strings.Repeat(" ", prefixWidth+2*sideWidth+3))
+	for i := 0; i < bytesPerLine; i++ {
+		inW, inG := i < len(w), i < len(g)
+		if inW == inG && (!inW || w[i] == g[i]) {
+			continue
+		}
+		for _, base := range []int{prefixWidth, prefixWidth + sideWidth + 3} {
+			out[base+hexPos(i)] = '^'
+			out[base+hexPos(i)+1] = '^'
+			out[base+hexWidth+1+i] = '^'
+		}
+	}
+	return strings.TrimRight(string(out), " ") + "\n"
+}
+
+// hexPos returns the position of the digits of byte i in the hex column
+func hexPos(i int) int {
+	return i*2 + i/2
+}
+
+const hexDigits = "0123456789abcdef"
+
+// Sequence returns n bytes counting up from 0 and wrapping at 256.
+func Sequence(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+// Pattern returns n pseudo-random bytes determined by seed.
+func Pattern(n int, seed int64) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+// Fill appends n pseudo-random bytes determined by seed to b and returns
+// the appended bytes.
+func Fill(b buffer.IoBuffer, n int, seed int64) []byte {
+	p := Pattern(n, seed)
+	b.Write(p)
+	return p
+}
@@ -0,0 +1,102 @@
+package buffertest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gottingen/buffer"
+)
+
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+	r.msg = fmt.Sprintf(format, args...)
+}
+
+func TestAssertEqual(t *testing.T) {
+	want := Sequence(40)
+	b := buffer.NewIoBuffer(0)
+	b.Write(want)
+	AssertEqual(t, want, b)
+
+	got := append([]byte(nil), want...)
+	got[20] = 0xff
+	r := &recorder{TB: t}
+	AssertBytesEqual(r, want, got)
+	if !r.failed {
+		t.Fatalf("Expect mismatch to be reported")
+	}
+	if !strings.Contains(r.msg, "! 00000010") {
+		t.Errorf("Expect differing line to be marked, got\n%s", r.msg)
+	}
+	if !strings.Contains(r.msg, "  00000000") {
+		t.Errorf("Expect equal line not to be marked, got\n%s", r.msg)
+	}
+}
+
+func TestDiffMarksBytes(t *testing.T) {
+	want := Sequence(40)
+	got := append([]byte(nil), want[:36]...)
+	got[20] = 0xff
+
+	lines := strings.Split(Diff(want, got), "\n")
+	// the header, three lines and the marks under the last two, the
+	// changed byte and the missing tail
+	if len(lines) != 7 {
+		t.Fatalf("unexpected diff\n%s", strings.Join(lines, "\n"))
+	}
+	line, marks := lines[2], lines[3]
+	var hex []string
+	for i := 0; i < len(marks); {
+		if marks[i] != '^' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(marks) && marks[j] == '^' {
+			j++
+		}
+		hex = append(hex, line[i:j])
+		i = j
+	}
+	expected := []string{"14", ".", "ff", "."}
+	if strings.Join(hex, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expect the marks under %q, but got %q\n%s\n%s", expected, hex, line, marks)
+	}
+
+	tail := lines[5]
+	// four bytes marked on both sides, in the hex columns and the gutters
+	if !strings.HasPrefix(lines[4], "! 00000020") || strings.Count(tail, "^") != 2*(4*2+4) {
+		t.Errorf("Expect the missing bytes to be marked\n%s\n%s", lines[4], tail)
+	}
+}
+
+func TestAssertEqualMulti(t *testing.T) {
+	want := Sequence(40)
+	m := buffer.MultiIoBuffer(buffer.NewIoBufferBytes(want[:20]), buffer.NewIoBufferBytes(want[20:]))
+	AssertEqual(t, want, m)
+	if p, _ := m.Window(); len(p) != 20 {
+		t.Errorf("Expect the segments not to be coalesced, first segment holds %d bytes", len(p))
+	}
+}
+
+func TestPatternDeterministic(t *testing.T) {
+	if !bytes.Equal(Pattern(64, 1), Pattern(64, 1)) {
+		t.Errorf("Expect equal patterns for equal seeds")
+	}
+	if bytes.Equal(Pattern(64, 1), Pattern(64, 2)) {
+		t.Errorf("Expect different patterns for different seeds")
+	}
+	b := buffer.NewIoBuffer(0)
+	p := Fill(b, 100, 7)
+	AssertEqual(t, p, b)
+}
@@ -1,6 +1,13 @@
 package buffer
 
-import "sync"
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
 
 const minShift = 6
 const maxShift = 18
@@ -9,7 +16,7 @@ const errSlot = -1
 var bbPool *byteBufferPool
 
 func init() {
-	bbPool = newByteBufferPool()
+	bbPool = newByteBufferPool(DefaultPoolName, minShift, maxShift)
 }
 
 
@@ -19,30 +26,70 @@ type bufferSlot struct {
 }
 
 type byteBufferPool struct {
+	// settings are accessed atomically and kept first for 64-bit alignment
+
+	// allocations of at least labelThreshold bytes run under pprof labels
+	labelThreshold int64
+	// slices larger than maxPooled bytes bypass the pool
+	maxPooled int64
+	// pages of released slices of at least releaseThreshold bytes are
+	// handed back to the OS
+	releaseThreshold int64
+	// fresh slices of at least hugePageThreshold bytes are backed by
+	// transparent huge pages
+	hugePageThreshold int64
+	// slices are cleared on give when zeroOnPut is non-zero
+	zeroOnPut int32
+	name           atomic.Value // string
+	numa           atomic.Value // *numaCache
+
 	minShift int
 	minSize  int
 	maxSize  int
+	// base addresses and capacities of fresh slices are multiples of
+	// align when it is above 1
+	align int
 
-	pool []*bufferSlot
+	pool   []*bufferSlot
+	shards []poolShard
+	// ib recycles the IoBuffers backed by the pool, nil for the package
+	// level pool
+	ib *IoBufferPool
 }
 
 func newBytes(size int) []byte {
 	return make([]byte, size)
 }
 
-// newByteBufferPool returns byteBufferPool
-func newByteBufferPool() *byteBufferPool {
+// newAlignedBytes returns a slice of len size whose base address and
+// capacity are multiples of align, a power of two
+func newAlignedBytes(size, align int) []byte {
+	c := (size + align - 1) &^ (align - 1)
+	b := make([]byte, c+align)
+	off := 0
+	if r := int(uintptr(unsafe.Pointer(&b[0])) & uintptr(align-1)); r != 0 {
+		off = align - r
+	}
+	return b[off : off+size : off+c]
+}
+
+// newByteBufferPool returns byteBufferPool with size classes
+// from 1<<minShift to 1<<maxShift
+func newByteBufferPool(name string, minShift, maxShift int) *byteBufferPool {
 	p := &byteBufferPool{
 		minShift: minShift,
-		minSize:  1 << minShift,
-		maxSize:  1 << maxShift,
+		minSize:  1 << uint(minShift),
+		maxSize:  1 << uint(maxShift),
 	}
+	p.maxPooled = int64(p.maxSize)
+	p.name.Store(name)
 	for i := 0; i <= maxShift-minShift; i++ {
 		slab := &bufferSlot{
 			defaultSize: 1 << (uint)(i+minShift),
 		}
 		p.pool = append(p.pool, slab)
 	}
+	p.shards = newPoolShards(len(p.pool))
 
 	return p
 }
@@ -70,22 +117,111 @@ func (p *byteBufferPool) slot(size int) int {
 
 // take returns *[]byte from byteBufferPool
 func (p *byteBufferPool) take(size int) *[]byte {
+	b, sh := p.get(size)
+	if debugEnabled() {
+		trackTake(*b)
+	}
+	atomic.AddUint64(&sh.gets, 1)
+	sh.countSize(size)
+	atomic.AddInt64(&sh.inUse, 1)
+	atomic.AddInt64(&sh.inUseBytes, int64(cap(*b)))
+	return b
+}
+
+// get returns *[]byte and the shard of the current P for accounting
+func (p *byteBufferPool) get(size int) (*[]byte, *poolShard) {
 	slot := p.slot(size)
-	if slot == errSlot {
-		b := newBytes(size)
-		return &b
+	if slot == errSlot || poolingDisabled() || int64(size) > atomic.LoadInt64(&p.maxPooled) {
+		sh := p.shard()
+		atomic.AddUint64(&sh.misses, 1)
+		b := p.alloc(size)
+		return &b, sh
+	}
+	if slab := p.slabFor(size); slab != nil {
+		return slab.Get(size), p.shard()
 	}
-	v := p.pool[slot].pool.Get()
-	if v == nil {
-		b := newBytes(p.pool[slot].defaultSize)
+	b, sh := p.getSlot(slot)
+	if b == nil {
+		atomic.AddUint64(&sh.misses, 1)
+		b := p.alloc(p.pool[slot].defaultSize)
 		b = b[0:size]
-		return &b
+		return &b, sh
 	}
-	b := v.(*[]byte)
 	*b = (*b)[0:size]
+	return b, sh
+}
+
+
+// alloc allocates fresh memory, tagging large allocations with pprof labels
+func (p *byteBufferPool) alloc(size int) []byte {
+	threshold := atomic.LoadInt64(&p.labelThreshold)
+	if threshold <= 0 || int64(size) < threshold {
+		return p.newBytes(size)
+	}
+	var b []byte
+	name, _ := p.name.Load().(string)
+	labels := pprof.Labels("buffer_pool", name, "buffer_size", strconv.Itoa(size))
+	pprof.Do(context.Background(), labels, func(context.Context) {
+		b = p.newBytes(size)
+	})
+	return b
+}
+
+func (p *byteBufferPool) newBytes(size int) []byte {
+	if threshold := atomic.LoadInt64(&p.hugePageThreshold); threshold > 0 && int64(size) >= threshold {
+		return newHugePageBytes(size)
+	}
+	if p.align > 1 {
+		return newAlignedBytes(size, p.align)
+	}
+	return newBytes(size)
+}
+
+// slabBackend returns the slab allocator backing the package level pool
+func (p *byteBufferPool) slabBackend() *SlabAllocator {
+	if p != bbPool {
+		return nil
+	}
+	return getSlabBackend()
+}
+
+// slabFor returns the slab allocator serving a pooled slice of len size, nil
+// if the size is outside its classes or the memory must be aligned, backed
+// by huge pages or allocated under pprof labels, which the slabs aren't
+func (p *byteBufferPool) slabFor(size int) *SlabAllocator {
+	slab := p.slabBackend()
+	if slab == nil || slab.class(size) == nil || p.align > 1 {
+		return nil
+	}
+	if threshold := atomic.LoadInt64(&p.hugePageThreshold); threshold > 0 && int64(size) >= threshold {
+		return nil
+	}
+	if threshold := atomic.LoadInt64(&p.labelThreshold); threshold > 0 && int64(size) >= threshold {
+		return nil
+	}
+	return slab
+}
+
+// takeZeroed returns *[]byte from byteBufferPool with zeroed contents
+func (p *byteBufferPool) takeZeroed(size int) *[]byte {
+	b := p.take(size)
+	zeroBytes(*b)
 	return b
 }
 
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func (p *byteBufferPool) setZeroOnPut(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&p.zeroOnPut, v)
+}
 
 // give returns *[]byte to byteBufferPool
 func (p *byteBufferPool) give(buf *[]byte) {
@@ -93,22 +229,124 @@ func (p *byteBufferPool) give(buf *[]byte) {
 		return
 	}
 	size := cap(*buf)
-	slot := p.slot(size)
-	if slot == errSlot {
+	slot := p.reslot(size)
+	// slab chunks go back to their slab whatever the pooling settings,
+	// the slab would never get them back otherwise
+	slab := p.slabBackend()
+	owned := slab != nil && slab.owns(*buf)
+	pooled := owned || slot != errSlot && !poolingDisabled() && int64(size) <= atomic.LoadInt64(&p.maxPooled)
+	if debugEnabled() {
+		trackGive(*buf, pooled)
+	}
+	if atomic.LoadInt32(&p.zeroOnPut) != 0 {
+		zeroBytes((*buf)[:size])
+	}
+	if owned {
+		p.shard().countPut(size)
+		slab.Put(buf)
 		return
 	}
-	if size != int(p.pool[slot].defaultSize) {
+	if threshold := atomic.LoadInt64(&p.releaseThreshold); threshold > 0 && int64(size) >= threshold {
+		releasePages(*buf)
+	}
+	if !pooled {
+		p.shard().countPut(size)
 		return
 	}
-	p.pool[slot].pool.Put(buf)
+	if c := p.pool[slot].defaultSize; size != c {
+		*buf = (*buf)[:0:c]
+	}
+	p.putSlot(slot, buf, size)
+}
+
+// reslot returns the slot a slice of capacity size is pooled in, the largest
+// size class it holds. Slices of odd capacities, e.g. made by callers and
+// handed to PutBytes, are trimmed to that class rather than dropped, at most
+// half of such a slice goes unused.
+func (p *byteBufferPool) reslot(size int) int {
+	if size < p.minSize || size > p.maxSize {
+		return errSlot
+	}
+	slot := p.slot(size)
+	if size != p.pool[slot].defaultSize {
+		slot--
+	}
+	return slot
 }
 
 
+// BytePool supplies the memory of IoBuffers, see WithBytePool. A NamedPool
+// is a BytePool.
+type BytePool interface {
+	// Get returns a slice of len n.
+	Get(n int) *[]byte
+	// Put hands back a slice obtained via Get, which may have been resliced
+	// but not grown.
+	Put(*[]byte)
+}
+
 // GetBytes returns *[]byte from byteBufferPool
 func GetBytes(size int) *[]byte {
 	return bbPool.take(size)
 }
 
+// GetBytesZeroed returns *[]byte from byteBufferPool whose len(*b) bytes
+// are guaranteed to be zero.
+func GetBytesZeroed(size int) *[]byte {
+	return bbPool.takeZeroed(size)
+}
+
+// SetZeroOnPut makes PutBytes clear slices before recycling them, so that
+// data handled on behalf of one tenant can't leak to another via recycled
+// buffers. It also applies to the backing slices of pooled IoBuffers.
+func SetZeroOnPut(on bool) {
+	bbPool.setZeroOnPut(on)
+}
+
+// LabelLargeAllocs makes the package level pool run fresh allocations of
+// at least threshold bytes under the pprof labels buffer_pool=name and
+// buffer_size=<size>. Only CPU and goroutine profiles record labels, so
+// the allocating work shows up under them there; heap profiles don't
+// attribute memory by label. A threshold <= 0 disables labeling.
+func LabelLargeAllocs(name string, threshold int) {
+	bbPool.name.Store(name)
+	atomic.StoreInt64(&bbPool.labelThreshold, int64(threshold))
+}
+
+// setMaxPooledSize sets the pooling threshold, n <= 0 restores the
+// largest size class
+func (p *byteBufferPool) setMaxPooledSize(n int) {
+	if n <= 0 || n > p.maxSize {
+		n = p.maxSize
+	}
+	atomic.StoreInt64(&p.maxPooled, int64(n))
+}
+
+// SetMaxPooledSize sets the size above which GetBytes allocates directly and
+// PutBytes drops the slice, so that a few huge payloads can't permanently
+// occupy pool slots. n <= 0 restores the default, the largest size class.
+func SetMaxPooledSize(n int) {
+	bbPool.setMaxPooledSize(n)
+}
+
+// SetReleaseThreshold makes PutBytes hand the pages of slices of at least
+// n bytes back to the OS (madvise MADV_DONTNEED on Linux, a no-op
+// elsewhere), so RSS drops after traffic spikes while the Go heap keeps the
+// virtual range. n <= 0 disables releasing, which is the default.
+func SetReleaseThreshold(n int) {
+	atomic.StoreInt64(&bbPool.releaseThreshold, int64(n))
+}
+
+// SetHugePageThreshold makes GetBytes back fresh slices of at least n bytes
+// with transparent huge pages, reducing TLB pressure when processing
+// multi-megabyte payloads. Such slices are aligned to and rounded up to
+// whole huge pages, so n should be a few megabytes at least. Where huge pages
+// are unavailable the slices are plain memory. n <= 0 disables huge pages,
+// which is the default.
+func SetHugePageThreshold(n int) {
+	atomic.StoreInt64(&bbPool.hugePageThreshold, int64(n))
+}
+
 // PutBytes Put *[]byte to byteBufferPool
 func PutBytes(buf *[]byte) {
 	bbPool.give(buf)
@@ -0,0 +1,123 @@
+package buffer
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestLabelLargeAllocs(t *testing.T) {
+	LabelLargeAllocs("test", 1024)
+	defer LabelLargeAllocs(DefaultPoolName, 0)
+
+	for _, n := range []int{10, 1024, 1 << 20} {
+		b := GetBytes(n)
+		if len(*b) != n {
+			t.Errorf("Expect len %d, but got %d", n, len(*b))
+		}
+		PutBytes(b)
+	}
+	if name, _ := bbPool.name.Load().(string); name != "test" {
+		t.Errorf("Expect pool name test, but got %q", name)
+	}
+}
+
+func TestSetMaxPooledSize(t *testing.T) {
+	SetMaxPooledSize(1024)
+	defer SetMaxPooledSize(0)
+
+	before := GetPoolStats()
+	for i := 0; i < 5; i++ {
+		b := GetBytes(4096)
+		PutBytes(b)
+	}
+	if s := GetPoolStats(); s.Misses-before.Misses != 5 {
+		t.Errorf("Expect large slices to bypass the pool, but got %d misses", s.Misses-before.Misses)
+	}
+
+	p := NewNamedPool("test-max-pooled", WithMaxPooledSize(256))
+	b := p.Get(512)
+	p.Put(b)
+	b = p.Get(512)
+	p.Put(b)
+	if s := p.Stats(); s.Misses != 2 {
+		t.Errorf("Expect 2 misses, but got %d", s.Misses)
+	}
+}
+
+func TestGetBytesZeroed(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		b := GetBytes(100)
+		for j := range *b {
+			(*b)[j] = 0xff
+		}
+		PutBytes(b)
+
+		b = GetBytesZeroed(100)
+		for j, c := range *b {
+			if c != 0 {
+				t.Fatalf("Expect zeroed byte at %d, but got %x", j, c)
+			}
+		}
+		PutBytes(b)
+	}
+}
+
+func TestZeroOnPut(t *testing.T) {
+	p := NewNamedPool("test-zero-on-put", WithZeroOnPut())
+	b := p.Get(100)
+	for j := range *b {
+		(*b)[j] = 0xff
+	}
+	data := (*b)[:cap(*b)]
+	p.Put(b)
+	for j, c := range data {
+		if c != 0 {
+			t.Fatalf("Expect slice cleared on put, but got %x at %d", c, j)
+		}
+	}
+}
+
+func TestReslot(t *testing.T) {
+	p := newByteBufferPool("test-reslot", 6, 12)
+	for _, c := range []struct{ size, class int }{
+		{32, -1}, {64, 64}, {100, 64}, {4096, 4096}, {6000, -1},
+	} {
+		slot := p.reslot(c.size)
+		if c.class < 0 {
+			if slot != errSlot {
+				t.Errorf("Expect capacity %d not pooled, but got slot %d", c.size, slot)
+			}
+			continue
+		}
+		if slot == errSlot || p.pool[slot].defaultSize != c.class {
+			t.Errorf("Expect capacity %d pooled as %d, but got slot %d", c.size, c.class, slot)
+		}
+	}
+
+	grown := make([]byte, 3000)
+	b := &grown
+	// account the slice as if taken from p
+	atomic.AddInt64(&p.shard().inUse, 1)
+	atomic.AddInt64(&p.shard().inUseBytes, int64(cap(grown)))
+	p.give(b)
+	if cap(*b) != 2048 {
+		t.Errorf("Expect the slice trimmed to 2048 bytes, but got %d", cap(*b))
+	}
+	if _, _, _, inUse, inUseBytes := p.counters(); inUse != 0 || inUseBytes != 0 {
+		t.Errorf("Expect balanced accounting, but got %d slices of %d bytes in use", inUse, inUseBytes)
+	}
+	if !raceEnabled {
+		if b := p.take(2000); &(*b)[0] != &grown[0] {
+			t.Error("Expect the trimmed slice to be reused")
+		}
+	}
+
+	// a pooled IoBuffer that held a huge request comes back small
+	ib := &IoBufferPool{bp: p}
+	buf := ib.take(0)
+	buf.Write(make([]byte, 1<<20))
+	ib.put(buf)
+	if buf = ib.take(0); buf.Cap() > 1<<10 {
+		t.Errorf("Expect a small buffer from the pool, but got capacity %d", buf.Cap())
+	}
+}
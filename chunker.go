@@ -0,0 +1,135 @@
+package buffer
+
+import "math/bits"
+
+// Default chunk sizes of NewChunker.
+const (
+	DefaultMinChunkSize = 2 << 10
+	DefaultAvgChunkSize = 8 << 10
+	DefaultMaxChunkSize = 64 << 10
+)
+
+// gearTable maps bytes to the random values of the Gear rolling hash. It is
+// generated from a fixed seed, chunk boundaries must not change across
+// processes or versions.
+var gearTable = func() (t [256]uint64) {
+	// splitmix64
+	x := uint64(0x6a09e667f3bcc908)
+	for i := range t {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return
+}()
+
+// Chunker splits the data of a buffer at content-defined boundaries found
+// with a Gear rolling hash, so that an edit only changes the chunks around
+// it, e.g. for deduplicating storage or transfer.
+//
+// Normalized chunking as in FastCDC keeps chunk sizes close to the average:
+// boundaries are harder to hit before the average size and easier after.
+type Chunker struct {
+	b             IoBuffer
+	min, avg, max int
+	maskS, maskL  uint64
+	cur           IoBuffer
+
+	// scan state of the pending chunk, so that Next doesn't hash the same
+	// bytes again while waiting for data
+	pos  int
+	hash uint64
+}
+
+// NewChunker returns a Chunker emitting chunks of b between minSize and
+// maxSize bytes long, avgSize long on average. Sizes <= 0 take the defaults.
+// It panics unless minSize <= avgSize <= maxSize.
+func NewChunker(b IoBuffer, minSize, avgSize, maxSize int) *Chunker {
+	if minSize <= 0 {
+		minSize = DefaultMinChunkSize
+	}
+	if avgSize <= 0 {
+		avgSize = DefaultAvgChunkSize
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxChunkSize
+	}
+	if minSize > avgSize || avgSize > maxSize {
+		panic(opError("chunker", avgSize, b, ErrOutOfRange))
+	}
+	n := bits.Len(uint(avgSize)) - 1
+	return &Chunker{
+		b:     b,
+		min:   minSize,
+		avg:   avgSize,
+		max:   maxSize,
+		maskS: topBits(n + 1),
+		maskL: topBits(n - 1),
+	}
+}
+
+// topBits returns a mask of the n most significant bits, they depend on the
+// most bytes of the Gear hash
+func topBits(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ^uint64(0) << uint(64-n)
+}
+
+// Next returns a view over the next chunk without copying. It is nil when
+// the buffer doesn't hold a complete chunk yet, so more data should be read
+// into the buffer, atEOF makes the remaining bytes the last chunk instead.
+//
+// The view is valid until the next call of Next, which skips whatever of
+// the previous chunk is left unread. The buffer must only be appended to
+// between calls.
+func (c *Chunker) Next(atEOF bool) IoBuffer {
+	if c.cur != nil {
+		c.cur.DiscardAll()
+		c.cur = nil
+	}
+	p := c.b.Bytes()
+	if len(p) == 0 {
+		return nil
+	}
+	n := c.cut(p)
+	if n == 0 {
+		if !atEOF {
+			return nil
+		}
+		n = len(p)
+	}
+	c.pos, c.hash = 0, 0
+	c.cur = c.b.Limit(n)
+	return c.cur
+}
+
+// cut returns the length of the chunk at the front of p, 0 if p ends first
+func (c *Chunker) cut(p []byte) int {
+	limit := len(p)
+	if limit > c.max {
+		limit = c.max
+	}
+	i, h := c.pos, c.hash
+	if i < c.min {
+		i = c.min
+	}
+	for ; i < limit; i++ {
+		h = h<<1 + gearTable[p[i]]
+		mask := c.maskL
+		if i < c.avg {
+			mask = c.maskS
+		}
+		if h&mask == 0 {
+			return i + 1
+		}
+	}
+	if limit == c.max {
+		return c.max
+	}
+	c.pos, c.hash = i, h
+	return 0
+}
@@ -0,0 +1,87 @@
+package buffer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+)
+
+// chunkAll returns the chunks of data, read into the buffer step bytes at a
+// time
+func chunkAll(t *testing.T, data []byte, step int) [][]byte {
+	t.Helper()
+	b := NewIoBuffer(0)
+	c := NewChunker(b, 256, 1024, 4096)
+	var chunks [][]byte
+	for len(data) > 0 || b.Len() > 0 {
+		n := step
+		if n > len(data) {
+			n = len(data)
+		}
+		b.Write(data[:n])
+		data = data[n:]
+		for {
+			v := c.Next(len(data) == 0)
+			if v == nil {
+				break
+			}
+			chunks = append(chunks, v.CopyBytes())
+		}
+	}
+	return chunks
+}
+
+func TestChunker(t *testing.T) {
+	data := make([]byte, 256<<10)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	chunks := chunkAll(t, data, len(data))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("Expect the chunks to add up to the data")
+	}
+	for i, c := range chunks {
+		if len(c) > 4096 || (len(c) < 256 && i != len(chunks)-1) {
+			t.Errorf("chunk %d out of bounds: %d bytes", i, len(c))
+		}
+	}
+	if avg := len(data) / len(chunks); avg < 512 || avg > 2048 {
+		t.Errorf("Expect chunks of about 1024 bytes, but got %d on average", avg)
+	}
+
+	// feeding the buffer piecewise finds the same boundaries
+	if small := chunkAll(t, data, 333); len(small) != len(chunks) {
+		t.Errorf("Expect %d chunks, but got %d", len(chunks), len(small))
+	}
+
+	// an insert near the front only changes the chunks around it
+	edited := append([]byte("inserted"), data...)
+	seen := make(map[[32]byte]bool)
+	for _, c := range chunks {
+		seen[sha256.Sum256(c)] = true
+	}
+	shared := 0
+	for _, c := range chunkAll(t, edited, len(edited)) {
+		if seen[sha256.Sum256(c)] {
+			shared++
+		}
+	}
+	if shared < len(chunks)-3 {
+		t.Errorf("Expect nearly all of %d chunks to survive the insert, but got %d", len(chunks), shared)
+	}
+}
+
+func TestChunkerSkipsUnread(t *testing.T) {
+	data := make([]byte, 64<<10)
+	rand.New(rand.NewSource(2)).Read(data)
+	b := NewIoBufferBytes(data)
+	c := NewChunker(b, 0, 0, 0)
+	total := 0
+	for v := c.Next(true); v != nil; v = c.Next(true) {
+		total += v.Len()
+	}
+	if total != len(data) || b.Len() != 0 {
+		t.Errorf("Expect the chunks to cover %d bytes, but got %d, %d left", len(data), total, b.Len())
+	}
+	expectPanic(t, "inconsistent chunk sizes", func() { NewChunker(b, 10, 5, 20) })
+}
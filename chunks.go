@@ -0,0 +1,57 @@
+package buffer
+
+import "io"
+
+// maxConsecutiveEmptyReads is the number of reads returning no bytes and no
+// error ReadChunks retries before failing with io.ErrNoProgress
+const maxConsecutiveEmptyReads = 100
+
+// ReadChunks reads r until io.EOF into pooled buffers of chunkSize bytes,
+// handing each to fn as soon as it is full, so pipeline stages like hashing
+// or multipart uploads work without one big contiguous buffer. The last
+// chunk may be shorter. chunkSize <= 0 means 32KB.
+//
+// fn owns the chunk and returns it to the pool with PutIoBuffer when done.
+// ReadChunks stops at the first error returned by fn and returns it. Reads
+// returning no bytes and no error are retried, a reader making no progress
+// for 100 reads in a row fails with io.ErrNoProgress.
+func ReadChunks(r io.Reader, chunkSize int, fn func(chunk IoBuffer) error) error {
+	if chunkSize <= 0 {
+		chunkSize = copyBufferSize
+	}
+	for {
+		b := GetIoBuffer(chunkSize).(*ioBuffer)
+		var err error
+		for empty := 0; b.Len() < chunkSize; {
+			var m int
+			m, err = b.readInto(r, chunkSize-b.Len())
+			b.wrote(m)
+			if err != nil {
+				break
+			}
+			if m > 0 {
+				empty = 0
+			} else if empty++; empty == maxConsecutiveEmptyReads {
+				err = io.ErrNoProgress
+				break
+			}
+		}
+
+		// a short chunk means r is exhausted
+		done := err != nil || b.Len() < chunkSize
+		if err == io.EOF {
+			err = nil
+		} else if err != nil {
+			err = opError("read", chunkSize, b, err)
+		}
+
+		if b.Len() == 0 {
+			PutIoBuffer(b)
+		} else if e := fn(b); e != nil {
+			return e
+		}
+		if done {
+			return err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	var sizes []int
+	var out bytes.Buffer
+	err := ReadChunks(iotest.HalfReader(bytes.NewReader(data)), 4096, func(chunk IoBuffer) error {
+		sizes = append(sizes, chunk.Len())
+		out.Write(chunk.Bytes())
+		return PutIoBuffer(chunk)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 3 || sizes[0] != 4096 || sizes[1] != 4096 || sizes[2] != 10000-8192 {
+		t.Errorf("unexpected chunk sizes %v", sizes)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("chunks don't add up to the input")
+	}
+}
+
+func TestReadChunksExact(t *testing.T) {
+	calls := 0
+	err := ReadChunks(bytes.NewReader(make([]byte, 200)), 100, func(chunk IoBuffer) error {
+		calls++
+		return PutIoBuffer(chunk)
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Expect 2 chunks, but got %d, %v", calls, err)
+	}
+}
+
+func TestReadChunksErrors(t *testing.T) {
+	errStop := errors.New("stop")
+	err := ReadChunks(bytes.NewReader(make([]byte, 200)), 100, func(chunk IoBuffer) error {
+		PutIoBuffer(chunk)
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("Expect errStop, but got %v", err)
+	}
+
+	calls := 0
+	r := io.MultiReader(bytes.NewReader(make([]byte, 50)), iotest.ErrReader(iotest.ErrTimeout))
+	err = ReadChunks(r, 100, func(chunk IoBuffer) error {
+		calls++
+		return PutIoBuffer(chunk)
+	})
+	if !errors.Is(err, iotest.ErrTimeout) || calls != 1 {
+		t.Errorf("Expect the partial chunk and ErrTimeout, but got %d, %v", calls, err)
+	}
+}
+
+// emptyReader returns no bytes and no error for the first n reads
+type emptyReader struct {
+	n int
+	r io.Reader
+}
+
+func (e *emptyReader) Read(p []byte) (int, error) {
+	if e.n > 0 {
+		e.n--
+		return 0, nil
+	}
+	return e.r.Read(p)
+}
+
+func TestReadChunksEmptyReads(t *testing.T) {
+	var total int
+	err := ReadChunks(&emptyReader{n: 3, r: bytes.NewReader(make([]byte, 250))}, 100, func(chunk IoBuffer) error {
+		total += chunk.Len()
+		return PutIoBuffer(chunk)
+	})
+	if err != nil || total != 250 {
+		t.Errorf("Expect empty reads to be retried, but got %d bytes, %v", total, err)
+	}
+
+	err = ReadChunks(&emptyReader{n: 1000}, 100, func(chunk IoBuffer) error {
+		return PutIoBuffer(chunk)
+	})
+	if !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("Expect io.ErrNoProgress, but got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package buffer
+
+func (b *ioBuffer) Compact(trim bool) {
+	if b.closed {
+		return
+	}
+	if b.off > 0 {
+		b.copy(0)
+		b.offMark = ResetOffMark
+	}
+	// buffers wrapping memory they don't own keep it, as with ResetPolicy
+	if !trim || b.b == nil || b.fixed {
+		return
+	}
+	n := len(b.buf)
+	if n < DefaultSize {
+		n = DefaultSize
+	}
+	p := b.makeSlice(n)
+	if cap(*p) >= cap(b.buf) {
+		b.putSlice(p)
+		return
+	}
+	m := copy(*p, b.buf)
+	b.onFree(cap(b.buf))
+	b.putSlice(b.b)
+	b.b = p
+	b.buf = (*p)[:m]
+	b.charge(n)
+}
+
+// Compact compacts every buffer, trimming also merges them into one
+func (m *multiIoBuffer) Compact(trim bool) {
+	if trim {
+		m.coalesce()
+	} else {
+		m.releaseExhausted()
+	}
+	for _, b := range m.bufs {
+		b.Compact(trim)
+	}
+}
+
+// Compact is a no-op, the view doesn't own the memory it reads
+func (v *limitedIoBuffer) Compact(bool) {}
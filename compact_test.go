@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIoBufferCompact(t *testing.T) {
+	b := NewIoBuffer(1 << 10)
+	b.Write(bytes.Repeat([]byte("a"), 64<<10))
+	b.WriteString("tail")
+	b.Drain(64 << 10)
+
+	c := b.Cap()
+	b.Compact(false)
+	if b.String() != "tail" || b.Cap() != c || b.Stats().CopiedBytes != 4 {
+		t.Errorf("Expect the unread bytes slid down, but got %q cap %d", b.String(), b.Cap())
+	}
+	b.Compact(true)
+	if b.String() != "tail" || b.Cap() >= c || b.Cap() < DefaultSize {
+		t.Errorf("Expect the memory trimmed, but got %q cap %d", b.String(), b.Cap())
+	}
+	b.WriteString(" and more")
+	if b.String() != "tail and more" {
+		t.Errorf("unexpected contents %q", b.String())
+	}
+
+	wrapped := NewIoBufferBytes(make([]byte, 100))
+	wrapped.Drain(90)
+	wrapped.Compact(true)
+	if wrapped.Len() != 10 || wrapped.Cap() != 100 {
+		t.Errorf("Expect wrapped memory kept, but got len %d cap %d", wrapped.Len(), wrapped.Cap())
+	}
+
+	m := newMulti()
+	m.Drain(3)
+	m.Compact(true)
+	if m.String() != "der|body|trailer" || len(m.(*multiIoBuffer).bufs) != 1 {
+		t.Errorf("Expect one merged buffer, but got %q", m.String())
+	}
+
+	v := NewIoBufferString("hello").Limit(3)
+	v.Compact(true)
+	if v.String() != "hel" {
+		t.Errorf("Expect the view untouched, but got %q", v.String())
+	}
+}
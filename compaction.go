@@ -0,0 +1,73 @@
+package buffer
+
+import "sync/atomic"
+
+const (
+	defaultReadOnceSlide    = 4 * MinRead
+	defaultGrowSlidePercent = 50
+)
+
+// Compaction tunes when IoBuffers slide their unread bytes down to the
+// front of their memory, a memmove of all unread bytes, instead of
+// allocating more memory. Zero fields select the defaults.
+type Compaction struct {
+	// ReadOnceSlide makes ReadOnce slide the unread bytes before reading
+	// when fewer than this many are left, so most reads start at the front.
+	// The default is 4*MinRead, a negative value never slides up front.
+	ReadOnceSlide int
+	// GrowSlidePercent makes a write that doesn't fit at the end slide the
+	// unread bytes while they and the written bytes fill at most this
+	// percentage of the capacity, and grow the buffer otherwise. The
+	// default is 50, which leaves room to the next slide. Buffers holding
+	// large unread prefixes copy less with lower values at the cost of
+	// more memory. Values outside 1 to 100 are clamped.
+	GrowSlidePercent int
+}
+
+var compaction struct {
+	readOnceSlide    int64
+	growSlidePercent int64
+}
+
+func init() {
+	SetCompaction(Compaction{})
+}
+
+// SetCompaction sets the compaction parameters of all IoBuffers, see
+// BenchmarkCompaction for their effect.
+func SetCompaction(c Compaction) {
+	if c.ReadOnceSlide == 0 {
+		c.ReadOnceSlide = defaultReadOnceSlide
+	}
+	if c.GrowSlidePercent == 0 {
+		c.GrowSlidePercent = defaultGrowSlidePercent
+	}
+	if c.GrowSlidePercent < 1 {
+		c.GrowSlidePercent = 1
+	}
+	if c.GrowSlidePercent > 100 {
+		c.GrowSlidePercent = 100
+	}
+	atomic.StoreInt64(&compaction.readOnceSlide, int64(c.ReadOnceSlide))
+	atomic.StoreInt64(&compaction.growSlidePercent, int64(c.GrowSlidePercent))
+}
+
+// GetCompaction returns the compaction parameters in effect.
+func GetCompaction() Compaction {
+	return Compaction{
+		ReadOnceSlide:    int(atomic.LoadInt64(&compaction.readOnceSlide)),
+		GrowSlidePercent: int(atomic.LoadInt64(&compaction.growSlidePercent)),
+	}
+}
+
+// slideBeforeRead reports whether ReadOnce should slide the unread bytes
+// before reading
+func (b *ioBuffer) slideBeforeRead() bool {
+	return b.off > 0 && int64(len(b.buf)-b.off) < atomic.LoadInt64(&compaction.readOnceSlide)
+}
+
+// slideToGrow reports whether m unread bytes and n more bytes should be made
+// room for by sliding instead of growing
+func (b *ioBuffer) slideToGrow(m, n int) bool {
+	return int64(m+n)*100 <= int64(cap(b.buf))*atomic.LoadInt64(&compaction.growSlidePercent)
+}
@@ -0,0 +1,88 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestCompaction(t *testing.T) {
+	defer SetCompaction(Compaction{})
+	if c := GetCompaction(); c.ReadOnceSlide != 4*MinRead || c.GrowSlidePercent != 50 {
+		t.Errorf("unexpected defaults %+v", c)
+	}
+
+	// 600 unread bytes in a 1024 byte buffer, writing 300 more fits when
+	// slid but exceeds half the capacity
+	fill := func() IoBuffer {
+		b := NewIoBuffer(1024)
+		b.Write(make([]byte, 1000))
+		b.Drain(400)
+		return b
+	}
+
+	b := fill()
+	b.Write(make([]byte, 300))
+	if s := b.Stats(); s.Grows != 1 || s.CopiedBytes != 0 {
+		t.Errorf("Expect the default to grow, but got %+v", s)
+	}
+
+	SetCompaction(Compaction{GrowSlidePercent: 100})
+	b = fill()
+	b.Write(make([]byte, 300))
+	if s := b.Stats(); s.Grows != 0 || s.CopiedBytes != 600 || b.Cap() != 1024 {
+		t.Errorf("Expect a slide when it fits, but got %+v", s)
+	}
+
+	SetCompaction(Compaction{GrowSlidePercent: -1})
+	if c := GetCompaction(); c.GrowSlidePercent != 1 {
+		t.Errorf("Expect the percentage clamped to 1, but got %d", c.GrowSlidePercent)
+	}
+	b = NewIoBuffer(1024)
+	b.Write(make([]byte, 1000))
+	b.Drain(990)
+	b.Write(make([]byte, 100))
+	if s := b.Stats(); s.Grows != 1 || s.CopiedBytes != 0 {
+		t.Errorf("Expect a low percentage to grow, but got %+v", s)
+	}
+
+	SetCompaction(Compaction{ReadOnceSlide: -1})
+	b = NewIoBuffer(1024)
+	b.Write(make([]byte, 100))
+	b.Drain(50)
+	b.ReadOnce(bytes.NewReader(make([]byte, 100)), 0)
+	if s := b.Stats(); s.CopiedBytes != 0 || b.Len() != 150 {
+		t.Errorf("Expect ReadOnce not to slide, but got %+v", s)
+	}
+	SetCompaction(Compaction{})
+	b.ReadOnce(bytes.NewReader(make([]byte, 100)), 0)
+	if s := b.Stats(); s.CopiedBytes != 150 || b.Len() != 250 {
+		t.Errorf("Expect ReadOnce to slide by default, but got %+v", s)
+	}
+}
+
+// BenchmarkCompaction streams through a buffer retaining a large unread
+// prefix, the case where eager sliding spends its time in memmove.
+func BenchmarkCompaction(b *testing.B) {
+	chunk := make([]byte, 1<<10)
+	for _, retained := range []int{1 << 10, 64 << 10} {
+		for _, percent := range []int{10, 25, 50, 100} {
+			b.Run(fmt.Sprintf("retained=%d/percent=%d", retained, percent), func(b *testing.B) {
+				SetCompaction(Compaction{GrowSlidePercent: percent})
+				defer SetCompaction(Compaction{})
+				buf := NewIoBuffer(2 * retained)
+				buf.Write(make([]byte, retained))
+				b.SetBytes(int64(len(chunk)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					buf.Write(chunk)
+					buf.Drain(len(chunk))
+				}
+				s := buf.Stats()
+				b.ReportMetric(float64(s.CopiedBytes)/float64(b.N), "copied/op")
+				b.ReportMetric(float64(buf.Cap()), "cap")
+			})
+		}
+	}
+}
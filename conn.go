@@ -0,0 +1,208 @@
+package buffer
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// bufferAddr is the net.Addr of the in-memory connections.
+type bufferAddr string
+
+func (a bufferAddr) Network() string {
+	return "buffer"
+}
+
+func (a bufferAddr) String() string {
+	return string(a)
+}
+
+// timeoutError is returned when a deadline of an in-memory connection
+// is exceeded.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "io buffer: i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// bufferPipe is a one-directional byte stream backed by an IoBuffer.
+type bufferPipe struct {
+	mu        sync.Mutex
+	buf       IoBuffer
+	closed    bool
+	rdeadline time.Time
+	wdeadline time.Time
+	notify    chan struct{}
+}
+
+func newBufferPipe() *bufferPipe {
+	return &bufferPipe{
+		buf:    NewIoBuffer(MinRead),
+		notify: make(chan struct{}),
+	}
+}
+
+// signal wakes up blocked readers, p.mu must be held
+func (p *bufferPipe) signal() {
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+func (p *bufferPipe) read(b []byte) (int, error) {
+	for {
+		p.mu.Lock()
+		if p.buf.Len() > 0 {
+			n, err := p.buf.Read(b)
+			p.mu.Unlock()
+			return n, err
+		}
+		if p.closed {
+			p.mu.Unlock()
+			return 0, io.EOF
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !p.rdeadline.IsZero() {
+			d := time.Until(p.rdeadline)
+			if d <= 0 {
+				p.mu.Unlock()
+				return 0, timeoutError{}
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+		notify := p.notify
+		p.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (p *bufferPipe) write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if !p.wdeadline.IsZero() && !time.Now().Before(p.wdeadline) {
+		return 0, timeoutError{}
+	}
+	n, err := p.buf.Write(b)
+	p.signal()
+	return n, err
+}
+
+func (p *bufferPipe) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		p.signal()
+	}
+	p.mu.Unlock()
+}
+
+func (p *bufferPipe) setReadDeadline(t time.Time) {
+	p.mu.Lock()
+	p.rdeadline = t
+	p.signal()
+	p.mu.Unlock()
+}
+
+func (p *bufferPipe) setWriteDeadline(t time.Time) {
+	p.mu.Lock()
+	p.wdeadline = t
+	p.mu.Unlock()
+}
+
+// bufferConn is an in-memory net.Conn reading from one pipe and writing
+// to the other.
+type bufferConn struct {
+	rp, wp        *bufferPipe
+	local, remote net.Addr
+
+	once   sync.Once
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewBufferConn returns a pair of connected in-memory net.Conn.
+//
+// Data written to one side is buffered in an IoBuffer until it is read from
+// the other side, so writes never block. Read deadlines are honored, which
+// makes the pair suitable for testing code built on IoBuffer.ReadOnce
+// without real sockets.
+func NewBufferConn() (client, server net.Conn) {
+	c2s := newBufferPipe()
+	s2c := newBufferPipe()
+	clientAddr := bufferAddr("client")
+	serverAddr := bufferAddr("server")
+	client = &bufferConn{rp: s2c, wp: c2s, local: clientAddr, remote: serverAddr}
+	server = &bufferConn{rp: c2s, wp: s2c, local: serverAddr, remote: clientAddr}
+	return
+}
+
+func (c *bufferConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *bufferConn) Read(b []byte) (int, error) {
+	if c.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := c.rp.read(b)
+	if err == io.EOF && c.isClosed() {
+		err = io.ErrClosedPipe
+	}
+	return n, err
+}
+
+func (c *bufferConn) Write(b []byte) (int, error) {
+	if c.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	return c.wp.write(b)
+}
+
+func (c *bufferConn) Close() error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		c.wp.close()
+		c.rp.close()
+	})
+	return nil
+}
+
+func (c *bufferConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *bufferConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *bufferConn) SetDeadline(t time.Time) error {
+	c.rp.setReadDeadline(t)
+	c.wp.setWriteDeadline(t)
+	return nil
+}
+
+func (c *bufferConn) SetReadDeadline(t time.Time) error {
+	c.rp.setReadDeadline(t)
+	return nil
+}
+
+func (c *bufferConn) SetWriteDeadline(t time.Time) error {
+	c.wp.setWriteDeadline(t)
+	return nil
+}
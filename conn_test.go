@@ -0,0 +1,81 @@
+package buffer
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBufferConnReadWrite(t *testing.T) {
+	client, server := NewBufferConn()
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := client.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 16)
+	n, err := server.Read(p)
+	if err != nil || string(p[:n]) != "ping" {
+		t.Fatalf("unexpected read: %q, %v", p[:n], err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		server.Write([]byte("pong"))
+	}()
+	n, err = client.Read(p)
+	if err != nil || string(p[:n]) != "pong" {
+		t.Fatalf("unexpected read: %q, %v", p[:n], err)
+	}
+}
+
+func TestBufferConnDeadline(t *testing.T) {
+	client, server := NewBufferConn()
+	defer client.Close()
+	defer server.Close()
+
+	server.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err := server.Read(make([]byte, 1))
+	if te, ok := err.(net.Error); !ok || !te.Timeout() {
+		t.Fatalf("Expect timeout error, but got %v", err)
+	}
+}
+
+func TestBufferConnReadOnce(t *testing.T) {
+	client, server := NewBufferConn()
+	defer client.Close()
+	defer server.Close()
+
+	client.Write([]byte("hello"))
+	b := NewIoBuffer(0)
+	n, err := b.ReadOnce(server, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || b.String() != "hello" {
+		t.Fatalf("unexpected ReadOnce result: %d, %q", n, b.String())
+	}
+}
+
+func TestBufferConnClose(t *testing.T) {
+	client, server := NewBufferConn()
+	client.Write([]byte("bye"))
+	client.Close()
+
+	p := make([]byte, 16)
+	n, err := server.Read(p)
+	if err != nil || string(p[:n]) != "bye" {
+		t.Fatalf("unexpected read: %q, %v", p[:n], err)
+	}
+	if _, err := server.Read(p); err != io.EOF {
+		t.Fatalf("Expect io.EOF, but got %v", err)
+	}
+	if _, err := client.Write(p); err != io.ErrClosedPipe {
+		t.Fatalf("Expect io.ErrClosedPipe, but got %v", err)
+	}
+	if _, err := server.Write(p); err != io.ErrClosedPipe {
+		t.Fatalf("Expect io.ErrClosedPipe, but got %v", err)
+	}
+}
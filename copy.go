@@ -0,0 +1,22 @@
+package buffer
+
+import "io"
+
+// copyBufferSize is the size of the scratch slice used by Copy
+const copyBufferSize = 32 << 10
+
+// Copy is like io.Copy, but the 32KB scratch slice is taken from the byte
+// pool and returned afterwards instead of being allocated per call. No
+// slice is taken when src implements io.WriterTo or dst implements
+// io.ReaderFrom.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	if wt, ok := src.(io.WriterTo); ok {
+		return wt.WriteTo(dst)
+	}
+	if rf, ok := dst.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	buf := GetBytes(copyBufferSize)
+	defer PutBytes(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
@@ -0,0 +1,57 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// onlyReader hides the io.WriterTo of the wrapped reader
+type onlyReader struct {
+	io.Reader
+}
+
+// onlyWriter hides the io.ReaderFrom of the wrapped writer
+type onlyWriter struct {
+	io.Writer
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("copy", 50000)
+	var out bytes.Buffer
+	n, err := Copy(onlyWriter{&out}, onlyReader{strings.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || out.String() != data {
+		t.Errorf("Expect %d bytes copied, but got %d", len(data), n)
+	}
+}
+
+func TestCopyPooled(t *testing.T) {
+	src := onlyReader{strings.NewReader("pooled")}
+	var out bytes.Buffer
+	before := GetPoolStats()
+	Copy(onlyWriter{&out}, src)
+	after := GetPoolStats()
+	if after.Gets-before.Gets != 1 || after.Puts-before.Puts != 1 {
+		t.Errorf("Expect one pooled slice taken and returned, but got %d gets, %d puts",
+			after.Gets-before.Gets, after.Puts-before.Puts)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	data := make([]byte, 4096)
+	for i := 0; i < b.N; i++ {
+		Copy(onlyWriter{ioutil.Discard}, onlyReader{bytes.NewReader(data)})
+	}
+}
+
+func BenchmarkIoCopy(b *testing.B) {
+	data := make([]byte, 4096)
+	for i := 0; i < b.N; i++ {
+		io.Copy(onlyWriter{ioutil.Discard}, onlyReader{bytes.NewReader(data)})
+	}
+}
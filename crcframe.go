@@ -0,0 +1,68 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// CRCFrameOverhead is the number of bytes WriteCRCFrame adds to a payload:
+// a big endian uint32 payload length before it and a big endian uint32
+// CRC32C of the length and payload after it.
+const CRCFrameOverhead = 8
+
+// ErrCRCMismatch is returned by ReadCRCFrame when a frame fails checksum
+// verification.
+var ErrCRCMismatch = errors.New("io buffer: crc mismatch")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WriteCRCFrame appends payload as a frame with length prefix and CRC32C
+// trailer, see CRCFrameOverhead.
+func (b *Buffer) WriteCRCFrame(payload []byte) {
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(payload)))
+	crc := crc32.Update(crc32.Checksum(hdr[:], castagnoli), castagnoli, payload)
+	b.B = append(b.B, hdr[:]...)
+	b.B = append(b.B, payload...)
+	var trailer [4]byte
+	binary.BigEndian.PutUint32(trailer[:], crc)
+	b.B = append(b.B, trailer[:]...)
+}
+
+// ReadCRCFrame reads the next frame written by WriteCRCFrame and returns
+// its payload, which aliases the buffer and is valid until the buffer is
+// modified. It returns io.EOF when no bytes are left, io.ErrUnexpectedEOF
+// for an incomplete frame and ErrCRCMismatch for a corrupted one, both of
+// which leave the frame unread.
+func (b *Buffer) ReadCRCFrame() ([]byte, error) {
+	payload, n, err := parseCRCFrame(b.B[b.off:])
+	if err != nil {
+		return nil, err
+	}
+	b.off += n
+	b.lastRead = opInvalid
+	return payload, nil
+}
+
+// parseCRCFrame returns the payload of the frame at the front of p and the
+// frame length
+func parseCRCFrame(p []byte) ([]byte, int, error) {
+	if len(p) == 0 {
+		return nil, 0, io.EOF
+	}
+	if len(p) < CRCFrameOverhead {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	size := uint64(binary.BigEndian.Uint32(p))
+	if size > uint64(len(p)-CRCFrameOverhead) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	n := int(size) + CRCFrameOverhead
+	crc := crc32.Checksum(p[:n-4], castagnoli)
+	if crc != binary.BigEndian.Uint32(p[n-4:]) {
+		return nil, 0, ErrCRCMismatch
+	}
+	return p[4 : n-4], n, nil
+}
@@ -0,0 +1,48 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCRCFrame(t *testing.T) {
+	var bb Buffer
+	bb.WriteCRCFrame([]byte("first"))
+	bb.WriteCRCFrame(nil)
+	bb.WriteCRCFrame(bytes.Repeat([]byte("x"), 1000))
+	if bb.Len() != 3*CRCFrameOverhead+1005 {
+		t.Fatalf("unexpected framed length: %d", bb.Len())
+	}
+
+	for _, want := range []int{5, 0, 1000} {
+		p, err := bb.ReadCRCFrame()
+		if err != nil || len(p) != want {
+			t.Fatalf("Expect a %d byte payload, but got %d, %v", want, len(p), err)
+		}
+	}
+	if _, err := bb.ReadCRCFrame(); err != io.EOF {
+		t.Errorf("Expect io.EOF, but got %v", err)
+	}
+
+	bb.Reset()
+	bb.WriteCRCFrame([]byte("payload"))
+	full := bb.CopyBytes()
+
+	bb.Set(full[:len(full)-1])
+	if _, err := bb.ReadCRCFrame(); err != io.ErrUnexpectedEOF || bb.Len() != len(full)-1 {
+		t.Errorf("Expect io.ErrUnexpectedEOF leaving the frame unread, but got %v", err)
+	}
+
+	for _, i := range []int{0, 6, len(full) - 1} {
+		corrupt := append([]byte(nil), full...)
+		corrupt[i] ^= 0x01
+		bb.Set(corrupt)
+		if _, err := bb.ReadCRCFrame(); err != ErrCRCMismatch && err != io.ErrUnexpectedEOF {
+			t.Errorf("byte %d: Expect the corruption to be detected, but got %v", i, err)
+		}
+		if bb.Len() != len(full) {
+			t.Errorf("byte %d: Expect the frame to stay unread", i)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package buffer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// DebugEnv is the environment variable honored at init: when set to a true
+// value (see strconv.ParseBool), debug mode starts enabled.
+const DebugEnv = "BUFFER_DEBUG"
+
+var debugMode int32
+
+func init() {
+	if v, err := strconv.ParseBool(os.Getenv(DebugEnv)); err == nil && v {
+		SetDebug(true)
+	}
+}
+
+// SetDebug turns debug mode on or off.
+//
+// In debug mode the pools track the slices they issue and hold, so that
+// putting the same slice or IoBuffer twice, or putting a slice that was
+// never issued by GetBytes, panics instead of silently poisoning the pool
+// with aliased or wrongly-sized memory. Tracking costs a global lock per Get
+// and Put. Debug mode should be turned on before the pools are used, since
+// slices issued earlier are not known to the tracker.
+func SetDebug(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&debugMode, v)
+}
+
+// Debug reports whether debug mode is on.
+func Debug() bool {
+	return debugEnabled()
+}
+
+func debugEnabled() bool {
+	return atomic.LoadInt32(&debugMode) != 0
+}
+
+// tracked holds, in debug mode, the slices issued by GetBytes and not yet
+// returned and the slices sitting in a pool, keyed by the address of their
+// backing array
+var tracked = struct {
+	sync.Mutex
+	issued map[uintptr]int // capacity at issue time
+	pooled map[uintptr]struct{}
+}{
+	issued: make(map[uintptr]int),
+	pooled: make(map[uintptr]struct{}),
+}
+
+func sliceKey(b []byte) uintptr {
+	if cap(b) == 0 {
+		return 0
+	}
+	return uintptr(unsafe.Pointer(&b[:cap(b)][0]))
+}
+
+// trackTake records b as issued
+func trackTake(b []byte) {
+	key := sliceKey(b)
+	if key == 0 {
+		return
+	}
+	tracked.Lock()
+	delete(tracked.pooled, key)
+	tracked.issued[key] = cap(b)
+	tracked.Unlock()
+}
+
+// trackGive records b as returned, and as sitting in the pool if pooled is
+// true. It panics if b is in the pool already or was never issued.
+func trackGive(b []byte, pooled bool) {
+	key := sliceKey(b)
+	if key == 0 {
+		return
+	}
+	tracked.Lock()
+	_, dup := tracked.pooled[key]
+	issuedCap, issued := tracked.issued[key]
+	if !dup && issued && issuedCap == cap(b) {
+		delete(tracked.issued, key)
+		if pooled {
+			tracked.pooled[key] = struct{}{}
+		}
+	}
+	tracked.Unlock()
+
+	switch {
+	case dup:
+		panic("buffer: PutBytes of a slice that is already in the pool")
+	case !issued:
+		panic(fmt.Sprintf("buffer: PutBytes of a foreign slice (cap %d) that was not issued by GetBytes or was returned already", cap(b)))
+	case issuedCap != cap(b):
+		panic(fmt.Sprintf("buffer: PutBytes of a slice with capacity %d, issued with capacity %d", cap(b), issuedCap))
+	}
+}
@@ -0,0 +1,101 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+)
+
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expect %s to panic", name)
+		}
+	}()
+	f()
+}
+
+func TestDebugDoublePutBytes(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	b := GetBytes(100)
+	PutBytes(b)
+	expectPanic(t, "second PutBytes", func() {
+		PutBytes(b)
+	})
+
+	b = GetBytes(100)
+	PutBytes(b)
+}
+
+func TestDebugDoublePutIoBuffer(t *testing.T) {
+	buf := GetIoBuffer(10)
+	if err := PutIoBuffer(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := PutIoBuffer(buf); !errors.Is(err, ErrDuplicatePut) {
+		t.Fatalf("Expect ErrDuplicatePut, but got %v", err)
+	}
+	if c := buf.Count(0); c != 0 {
+		t.Errorf("Expect count to be restored to 0, but got %d", c)
+	}
+
+	SetDebug(true)
+	defer SetDebug(false)
+	expectPanic(t, "duplicate PutIoBuffer", func() {
+		PutIoBuffer(buf)
+	})
+}
+
+func TestDebugForeignPutBytes(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	foreign := make([]byte, 128)
+	expectPanic(t, "PutBytes of a foreign slice", func() {
+		PutBytes(&foreign)
+	})
+
+	b := GetBytes(1 << 20)
+	PutBytes(b)
+	expectPanic(t, "second PutBytes of an unpooled slice", func() {
+		PutBytes(b)
+	})
+
+	b = GetBytes(100)
+	shrunk := (*b)[:10:10]
+	expectPanic(t, "PutBytes of a resliced slice", func() {
+		PutBytes(&shrunk)
+	})
+	PutBytes(b)
+}
+
+func TestDebugStalePutIoBuffer(t *testing.T) {
+	buf := GetIoBuffer(10)
+	gen := IoBufferGeneration(buf)
+	if gen == 0 {
+		t.Fatal("Expect a pooled buffer to have a generation")
+	}
+	// as if buf had been put and taken again by someone else since
+	if err := PutIoBufferGeneration(buf, gen-1); !errors.Is(err, ErrDuplicatePut) {
+		t.Fatalf("Expect ErrDuplicatePut, but got %v", err)
+	}
+	if c := buf.Count(0); c != 1 {
+		t.Errorf("Expect the stale put to leave count at 1, but got %d", c)
+	}
+
+	SetDebug(true)
+	expectPanic(t, "stale PutIoBufferGeneration", func() {
+		PutIoBufferGeneration(buf, gen-1)
+	})
+	SetDebug(false)
+
+	if err := PutIoBufferGeneration(buf, gen); err != nil {
+		t.Fatal(err)
+	}
+	if buf = GetIoBuffer(10); IoBufferGeneration(buf) == 0 {
+		t.Error("Expect a retaken buffer to have a generation")
+	}
+	PutIoBuffer(buf)
+}
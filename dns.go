@@ -0,0 +1,49 @@
+package buffer
+
+import "encoding/binary"
+
+// MaxDNSMessageSize is the largest DNS message the 2 byte length prefix of
+// the TCP transport can announce.
+const MaxDNSMessageSize = 1<<16 - 1
+
+// WriteDNSTCPMessage writes msg to b with the big endian uint16 length
+// prefix of DNS over TCP (RFC 1035 4.2.2). Messages larger than
+// MaxDNSMessageSize fail with ErrFrameTooLarge.
+func WriteDNSTCPMessage(b IoBuffer, msg []byte) error {
+	if len(msg) > MaxDNSMessageSize {
+		return opError("write dns message", len(msg), b, ErrFrameTooLarge)
+	}
+	var hdr [2]byte
+	binary.BigEndian.PutUint16(hdr[:], uint16(len(msg)))
+	return writeFrame(b, hdr[:], msg)
+}
+
+// ReadDNSTCPMessage reads the next length prefixed DNS message from b and
+// returns a copy of it. As DNS over TCP gives no guarantee about how
+// messages are split across reads, ErrNeedMore is returned, and nothing
+// consumed, until the message is complete:
+//
+//	for {
+//		msg, err := buffer.ReadDNSTCPMessage(b)
+//		if err == buffer.ErrNeedMore {
+//			_, err = b.ReadOnce(conn, timeout)
+//			...
+//			continue
+//		}
+//		...
+//	}
+func ReadDNSTCPMessage(b IoBuffer) ([]byte, error) {
+	hdr := b.Peek(2)
+	if hdr == nil {
+		return nil, ErrNeedMore
+	}
+	n := 2 + int(binary.BigEndian.Uint16(hdr))
+	p := b.Peek(n)
+	if p == nil {
+		return nil, ErrNeedMore
+	}
+	msg := make([]byte, n-2)
+	copy(msg, p[2:])
+	b.Drain(n)
+	return msg, nil
+}
@@ -0,0 +1,64 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDNSTCPMessage(t *testing.T) {
+	query := bytes.Repeat([]byte{0xab}, 300)
+	b := NewIoBuffer(0)
+	if err := WriteDNSTCPMessage(b, query); err != nil {
+		t.Fatal(err)
+	}
+	WriteDNSTCPMessage(b, []byte("second"))
+	if err := WriteDNSTCPMessage(b, make([]byte, MaxDNSMessageSize+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("got %v, want ErrFrameTooLarge", err)
+	}
+	if got := b.Peek(2); got[0] != 1 || got[1] != 44 {
+		t.Errorf("unexpected prefix % x", got)
+	}
+
+	// messages split across reads at every byte are reassembled
+	client, server := tcpPair(t)
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		p := b.Bytes()
+		for i := range p {
+			client.Write(p[i : i+1])
+			time.Sleep(10 * time.Microsecond)
+		}
+	}()
+	in := NewIoBuffer(0)
+	for _, want := range [][]byte{query, []byte("second")} {
+		for {
+			msg, err := ReadDNSTCPMessage(in)
+			if err == ErrNeedMore {
+				if _, err := in.ReadOnce(server, time.Second); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			if err != nil || !bytes.Equal(msg, want) {
+				t.Fatalf("got %q, %v, want %q", msg, err, want)
+			}
+			break
+		}
+	}
+	if in.Len() != 0 {
+		t.Errorf("%d bytes left", in.Len())
+	}
+}
+
+func TestWriteDNSTCPMessageAtomic(t *testing.T) {
+	b := New(WithMaxSize(2 + 4))
+	if err := WriteDNSTCPMessage(b, []byte("12345")); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Expect ErrTooLarge, but got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("Expect no partial message, but got %d bytes", b.Len())
+	}
+}
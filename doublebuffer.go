@@ -0,0 +1,104 @@
+package buffer
+
+import (
+	"io"
+	"sync"
+)
+
+// DoubleBuffer is a pair of pooled Buffers for log style workloads:
+// producers append to the front buffer while a flusher swaps the buffers and
+// drains the back one, so producers only wait for the swap, never for the
+// flush itself. It is safe for concurrent use.
+type DoubleBuffer struct {
+	mu    sync.Mutex // guards front
+	front *Buffer
+
+	flushMu sync.Mutex // serializes flushes, guards back
+	back    *Buffer
+}
+
+// NewDoubleBuffer returns a DoubleBuffer backed by buffers from the default
+// Buffer pool. Release returns them.
+func NewDoubleBuffer() *DoubleBuffer {
+	return &DoubleBuffer{front: Get(), back: Get()}
+}
+
+// Write appends p to the front buffer.
+func (d *DoubleBuffer) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	n, err := d.front.Write(p)
+	d.mu.Unlock()
+	return n, err
+}
+
+// WriteString appends s to the front buffer.
+func (d *DoubleBuffer) WriteString(s string) (int, error) {
+	d.mu.Lock()
+	n, err := d.front.WriteString(s)
+	d.mu.Unlock()
+	return n, err
+}
+
+// Append calls fn with the front buffer locked, so a record can be
+// formatted straight into it with the Buffer append methods.
+func (d *DoubleBuffer) Append(fn func(b *Buffer)) {
+	d.mu.Lock()
+	fn(d.front)
+	d.mu.Unlock()
+}
+
+// Len returns the number of bytes waiting in the front buffer.
+func (d *DoubleBuffer) Len() int {
+	d.mu.Lock()
+	n := d.front.Len()
+	d.mu.Unlock()
+	return n
+}
+
+// FlushTo swaps the buffers and writes the back buffer to w.
+//
+// Bytes left over by a failed flush are written first on the next call,
+// so nothing is lost or reordered when w recovers.
+func (d *DoubleBuffer) FlushTo(w io.Writer) (int64, error) {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+
+	var written int64
+	if d.back.Len() > 0 {
+		n, err := d.drain(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	d.mu.Lock()
+	d.front, d.back = d.back, d.front
+	d.mu.Unlock()
+
+	n, err := d.drain(w)
+	return written + n, err
+}
+
+// drain writes the back buffer to w, dropping what was written
+func (d *DoubleBuffer) drain(w io.Writer) (int64, error) {
+	n, err := d.back.WriteTo(w)
+	if err != nil {
+		d.back.Next(int(n))
+		return n, err
+	}
+	d.back.Reset()
+	return n, nil
+}
+
+// Release returns both buffers to the pool, dropping unflushed bytes. The
+// DoubleBuffer mustn't be used afterwards.
+func (d *DoubleBuffer) Release() {
+	d.flushMu.Lock()
+	d.mu.Lock()
+	Put(d.front)
+	Put(d.back)
+	d.front, d.back = nil, nil
+	d.mu.Unlock()
+	d.flushMu.Unlock()
+}
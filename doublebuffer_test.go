@@ -0,0 +1,108 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDoubleBuffer(t *testing.T) {
+	d := NewDoubleBuffer()
+	defer d.Release()
+
+	d.WriteString("first ")
+	d.Append(func(b *Buffer) {
+		b.WriteInt(42)
+		b.WriteByte(' ')
+	})
+	var out bytes.Buffer
+	n, err := d.FlushTo(&out)
+	if err != nil || n != 9 || out.String() != "first 42 " {
+		t.Fatalf("unexpected flush %d, %v, %q", n, err, out.String())
+	}
+	if d.Len() != 0 {
+		t.Errorf("Expect the front buffer to be empty, but got %d", d.Len())
+	}
+
+	d.Write([]byte("second"))
+	d.FlushTo(&out)
+	if out.String() != "first 42 second" {
+		t.Errorf("Expect first 42 second, but got %q", out.String())
+	}
+}
+
+// failingWriter accepts limit bytes, then fails
+type failingWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+var errWriterFull = errors.New("writer full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) <= w.limit {
+		return w.Buffer.Write(p)
+	}
+	n := w.limit - w.Len()
+	w.Buffer.Write(p[:n])
+	return n, errWriterFull
+}
+
+func TestDoubleBufferFlushError(t *testing.T) {
+	d := NewDoubleBuffer()
+	defer d.Release()
+
+	w := &failingWriter{limit: 4}
+	d.WriteString("abcdefgh")
+	if _, err := d.FlushTo(w); err != errWriterFull {
+		t.Fatalf("Expect errWriterFull, but got %v", err)
+	}
+	d.WriteString("ijkl")
+
+	w.limit = 100
+	if _, err := d.FlushTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "abcdefghijkl" {
+		t.Errorf("Expect abcdefghijkl, but got %q", w.String())
+	}
+}
+
+func TestDoubleBufferConcurrent(t *testing.T) {
+	d := NewDoubleBuffer()
+	defer d.Release()
+
+	var out bytes.Buffer
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				d.WriteString("line\n")
+			}
+		}()
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				d.FlushTo(&out)
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+	<-stopped
+	d.FlushTo(&out)
+	if n := strings.Count(out.String(), "line\n"); n != 4000 {
+		t.Errorf("Expect 4000 lines, but got %d", n)
+	}
+}
@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"strconv"
+)
+
+const dumpBytesPerLine = 16
+
+const hexDigits = "0123456789abcdef"
+
+// dump returns an xxd-style hexdump of at most maxBytes bytes of p.
+// maxBytes <= 0 dumps everything.
+func dump(p []byte, maxBytes int) string {
+	rest := 0
+	if maxBytes > 0 && len(p) > maxBytes {
+		rest = len(p) - maxBytes
+		p = p[:maxBytes]
+	}
+
+	lines := (len(p) + dumpBytesPerLine - 1) / dumpBytesPerLine
+	out := make([]byte, 0, lines*68+32)
+	for off := 0; off < len(p); off += dumpBytesPerLine {
+		line := p[off:]
+		if len(line) > dumpBytesPerLine {
+			line = line[:dumpBytesPerLine]
+		}
+
+		// offset
+		for shift := 28; shift >= 0; shift -= 4 {
+			out = append(out, hexDigits[(off>>uint(shift))&0xf])
+		}
+		out = append(out, ':', ' ')
+
+		// hex, grouped in pairs
+		for i := 0; i < dumpBytesPerLine; i++ {
+			if i < len(line) {
+				out = append(out, hexDigits[line[i]>>4], hexDigits[line[i]&0xf])
+			} else {
+				out = append(out, ' ', ' ')
+			}
+			if i%2 == 1 {
+				out = append(out, ' ')
+			}
+		}
+		out = append(out, ' ')
+
+		// ASCII gutter
+		for _, c := range line {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			out = append(out, c)
+		}
+		out = append(out, '\n')
+	}
+	if rest > 0 {
+		out = append(out, "... "...)
+		out = strconv.AppendInt(out, int64(rest), 10)
+		out = append(out, " more bytes\n"...)
+	}
+	return string(out)
+}
+
+// DumpBytes returns an xxd-style hexdump of at most maxBytes bytes of p
+// with offsets and an ASCII gutter, the format of the Dump methods.
+// maxBytes <= 0 dumps everything.
+func DumpBytes(p []byte, maxBytes int) string {
+	return dump(p, maxBytes)
+}
+
+// Dump returns an xxd-style hexdump of at most maxBytes unread bytes with
+// offsets and an ASCII gutter. maxBytes <= 0 dumps the whole unread region.
+func (b *Buffer) Dump(maxBytes int) string {
+	return dump(b.Bytes(), maxBytes)
+}
@@ -0,0 +1,31 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	b := NewIoBufferString("xxHello, world!\n\x00\x01abcdefghij")
+	b.Drain(2)
+
+	expected := "00000000: 4865 6c6c 6f2c 2077 6f72 6c64 210a 0001  Hello, world!...\n" +
+		"00000010: 6162 6364 6566 6768 696a                 abcdefghij\n"
+	if s := b.Dump(0); s != expected {
+		t.Errorf("Expect\n%s, but got\n%s", expected, s)
+	}
+
+	expected = "00000000: 4865 6c6c                                Hell\n" +
+		"... 22 more bytes\n"
+	if s := b.Dump(4); s != expected {
+		t.Errorf("Expect\n%s, but got\n%s", expected, s)
+	}
+
+	if s := DumpBytes([]byte("Hello, world!\n\x00\x01abcdefghij"), 4); s != expected {
+		t.Errorf("Expect\n%s, but got\n%s", expected, s)
+	}
+
+	var bb Buffer
+	if s := bb.Dump(0); s != "" {
+		t.Errorf("Expect empty dump, but got %q", s)
+	}
+}
@@ -0,0 +1,72 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Error is returned by buffer operations that fail, it records the
+// operation and the state of the buffer at the time.
+//
+// Err is one of the package's sentinel errors, such as ErrTooLarge, or the
+// error of the underlying reader or writer, so callers can test for either
+// with errors.Is and errors.As. Error implements net.Error, reporting the
+// timeout of the underlying error.
+type Error struct {
+	// Op is the failed operation, such as "read" or "append".
+	Op string
+	// Size is the number of bytes requested by the operation, if any.
+	Size int
+	// Len is the number of unread bytes in the buffer.
+	Len int
+	// Cap is the capacity of the buffer.
+	Cap int
+	// Err is the cause of the failure.
+	Err error
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString("io buffer: ")
+	sb.WriteString(e.Op)
+	sb.WriteString(" (")
+	if e.Size != 0 {
+		sb.WriteString("size ")
+		sb.WriteString(strconv.Itoa(e.Size))
+		sb.WriteString(", ")
+	}
+	sb.WriteString("len ")
+	sb.WriteString(strconv.Itoa(e.Len))
+	sb.WriteString(", cap ")
+	sb.WriteString(strconv.Itoa(e.Cap))
+	sb.WriteString("): ")
+	sb.WriteString(strings.TrimPrefix(e.Err.Error(), "io buffer: "))
+	return sb.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the underlying error is a timeout.
+func (e *Error) Timeout() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Timeout()
+}
+
+// Temporary reports whether the underlying error is temporary.
+func (e *Error) Temporary() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Temporary()
+}
+
+// opError returns an *Error of op on b, io.EOF is returned as is
+func opError(op string, size int, b IoBuffer, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return &Error{Op: op, Size: size, Len: b.Len(), Cap: b.Cap(), Err: err}
+}
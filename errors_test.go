@@ -0,0 +1,71 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestErrorReadTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	b := NewIoBuffer(0)
+	_, err := b.ReadOnce(server, 10*time.Millisecond)
+
+	var be *Error
+	if !errors.As(err, &be) || be.Op != "read" {
+		t.Fatalf("Expect *Error of read, but got %v", err)
+	}
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("Expect a net.Error timeout, but got %v", err)
+	}
+}
+
+func TestErrorEOFNotWrapped(t *testing.T) {
+	client, server := net.Pipe()
+	client.Close()
+
+	b := NewIoBuffer(0)
+	if _, err := b.ReadOnce(server, time.Second); err != io.EOF {
+		t.Errorf("Expect io.EOF, but got %v", err)
+	}
+}
+
+func TestErrorWriteTo(t *testing.T) {
+	client, server := net.Pipe()
+	client.Close()
+
+	b := NewIoBufferBytes(fixedBytes("data"))
+	_, err := b.WriteTo(server)
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("Expect io.ErrClosedPipe, but got %v", err)
+	}
+	if err.Error() != "io buffer: write to (len 4, cap 4): io: read/write on closed pipe" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestErrorNegativeCount(t *testing.T) {
+	b := NewIoBufferBytes(fixedBytes("data"))
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrNegativeCount) {
+			t.Errorf("Expect ErrNegativeCount panic, but got %v", err)
+		}
+		if err.Error() != "io buffer: drain (size -1, len 4, cap 4): negative count" {
+			t.Errorf("unexpected message %q", err.Error())
+		}
+	}()
+	b.Drain(-1)
+}
+
+// fixedBytes returns s as a slice whose capacity is exactly len(s)
+func fixedBytes(s string) []byte {
+	b := make([]byte, len(s), len(s))
+	copy(b, s)
+	return b
+}
@@ -0,0 +1,34 @@
+package buffer
+
+import (
+	"expvar"
+	"sync"
+)
+
+var publishExpvarsOnce sync.Once
+
+// PublishExpvars publishes the pool counters returned by GetPoolStats
+// under buffer.* expvar keys, e.g. buffer.pool.gets.
+//
+// The values are computed when the variables are read. It is safe to call
+// PublishExpvars more than once.
+func PublishExpvars() {
+	publishExpvarsOnce.Do(func() {
+		vars := map[string]func(s PoolStats) interface{}{
+			"buffer.pool.gets":         func(s PoolStats) interface{} { return s.Gets },
+			"buffer.pool.puts":         func(s PoolStats) interface{} { return s.Puts },
+			"buffer.pool.misses":       func(s PoolStats) interface{} { return s.Misses },
+			"buffer.pool.in_use":       func(s PoolStats) interface{} { return s.InUse },
+			"buffer.pool.in_use_bytes": func(s PoolStats) interface{} { return s.InUseBytes },
+			"buffer.pool.pooled_bytes": func(s PoolStats) interface{} { return s.PooledBytes },
+			"buffer.iobuffer.gets":     func(s PoolStats) interface{} { return s.IoBufferGets },
+			"buffer.iobuffer.puts":     func(s PoolStats) interface{} { return s.IoBufferPuts },
+		}
+		for name, f := range vars {
+			f := f
+			expvar.Publish(name, expvar.Func(func() interface{} {
+				return f(GetPoolStats())
+			}))
+		}
+	})
+}
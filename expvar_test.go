@@ -0,0 +1,30 @@
+package buffer
+
+import (
+	"expvar"
+	"strconv"
+	"testing"
+)
+
+func TestPublishExpvars(t *testing.T) {
+	PublishExpvars()
+	PublishExpvars()
+
+	PutBytes(GetBytes(10))
+	v := expvar.Get("buffer.pool.gets")
+	if v == nil {
+		t.Fatalf("buffer.pool.gets not published")
+	}
+	n, err := strconv.ParseUint(v.String(), 10, 64)
+	if err != nil {
+		t.Fatalf("unexpected value %q: %s", v.String(), err)
+	}
+	if n == 0 {
+		t.Errorf("Expect non-zero gets")
+	}
+	for _, name := range []string{"buffer.pool.pooled_bytes", "buffer.iobuffer.puts"} {
+		if expvar.Get(name) == nil {
+			t.Errorf("%s not published", name)
+		}
+	}
+}
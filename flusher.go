@@ -0,0 +1,165 @@
+package buffer
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Flusher coalesces IoBuffers queued for the same connection and writes
+// them with one vectored write, a writev on connections of package net,
+// once flushBytes are queued or the delay has passed since the first
+// unflushed buffer, a Nagle algorithm in userspace for chatty protocols.
+// It is safe for concurrent use.
+type Flusher struct {
+	mu         sync.Mutex
+	w          io.Writer
+	queue      []IoBuffer
+	queued     int
+	vec        net.Buffers // reused across flushes
+	flushBytes int
+	delay      time.Duration
+	timer      *time.Timer
+	armed      bool
+	// err is the error of a timer flush, returned by the next call
+	err    error
+	stats  FlushStats
+	closed bool
+}
+
+// NewFlusher returns a Flusher writing to w. flushBytes <= 0 means 4096
+// bytes, delay <= 0 disables the timer so buffers are only written once
+// flushBytes are queued or on Flush.
+func NewFlusher(w io.Writer, flushBytes int, delay time.Duration) *Flusher {
+	if flushBytes <= 0 {
+		flushBytes = defaultFlushBytes
+	}
+	return &Flusher{
+		w:          w,
+		flushBytes: flushBytes,
+		delay:      delay,
+	}
+}
+
+// Enqueue queues b for writing, flushing when the threshold is reached. The
+// Flusher takes ownership of b and puts it back to the pool once written,
+// also when Enqueue fails.
+func (f *Flusher) Enqueue(b IoBuffer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		PutIoBuffer(b)
+		return ErrClosedWriter
+	}
+	err := f.err
+	f.err = nil
+	if err != nil {
+		PutIoBuffer(b)
+		return err
+	}
+	if b.Len() == 0 {
+		PutIoBuffer(b)
+		return nil
+	}
+	f.queue = append(f.queue, b)
+	f.queued += b.Len()
+
+	if f.queued >= f.flushBytes {
+		f.stats.SizeFlushes++
+		return f.flush()
+	}
+	if f.delay > 0 && !f.armed {
+		f.armed = true
+		if f.timer == nil {
+			f.timer = time.AfterFunc(f.delay, f.onTimer)
+		} else {
+			f.timer.Reset(f.delay)
+		}
+	}
+	return nil
+}
+
+func (f *Flusher) onTimer() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.armed || f.closed {
+		return
+	}
+	f.stats.TimerFlushes++
+	if err := f.flush(); err != nil {
+		f.err = err
+	}
+}
+
+// flush writes the queued buffers, keeping what wasn't written on error
+func (f *Flusher) flush() error {
+	f.armed = false
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	if len(f.queue) == 0 {
+		return nil
+	}
+	n, vec, err := writeAllTo(f.w, f.queue, nil, f.vec)
+	f.vec = vec
+	f.queued -= int(n)
+	f.stats.BytesFlushed += n
+
+	// put back the buffers written in full
+	i := 0
+	for ; i < len(f.queue) && f.queue[i].Len() == 0; i++ {
+		PutIoBuffer(f.queue[i])
+		f.queue[i] = nil
+	}
+	f.queue = f.queue[:copy(f.queue, f.queue[i:])]
+	if err != nil {
+		f.stats.Errors++
+	}
+	return err
+}
+
+// Flush writes the queued buffers to the underlying writer.
+func (f *Flusher) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosedWriter
+	}
+	f.err = nil
+	f.stats.ExplicitFlushes++
+	return f.flush()
+}
+
+// Buffered returns the number of bytes waiting to be flushed.
+func (f *Flusher) Buffered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queued
+}
+
+// Stats returns the flush counters.
+func (f *Flusher) Stats() FlushStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// Close flushes the queued buffers, stops the timer and puts back the
+// buffers that couldn't be written. It doesn't close the underlying writer.
+func (f *Flusher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosedWriter
+	}
+	f.stats.ExplicitFlushes++
+	err := f.flush()
+	f.closed = true
+	for _, b := range f.queue {
+		PutIoBuffer(b)
+	}
+	f.queue = nil
+	f.queued = 0
+	return err
+}
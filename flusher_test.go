@@ -0,0 +1,99 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlusher(t *testing.T) {
+	out := &syncWriter{}
+	f := NewFlusher(out, 16, time.Hour)
+	f.Enqueue(NewIoBufferString("hello "))
+	f.Enqueue(NewIoBufferString(""))
+	if out.String() != "" || f.Buffered() != 6 {
+		t.Fatalf("Expect the buffer queued, but got %q", out.String())
+	}
+	f.Enqueue(NewIoBufferString("world, "))
+	f.Enqueue(NewIoBufferString("flushed"))
+	if out.String() != "hello world, flushed" || f.Buffered() != 0 {
+		t.Errorf("Expect a size flush, but got %q", out.String())
+	}
+
+	f.Enqueue(NewIoBufferString("explicit"))
+	f.Flush()
+	if out.String() != "hello world, flushedexplicit" {
+		t.Errorf("Expect an explicit flush, but got %q", out.String())
+	}
+	f.Close()
+	if err := f.Enqueue(NewIoBufferString("late")); err != ErrClosedWriter {
+		t.Errorf("Expect ErrClosedWriter, but got %v", err)
+	}
+	if s := f.Stats(); s.SizeFlushes != 1 || s.ExplicitFlushes != 2 || s.BytesFlushed != 28 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestFlusherDelay(t *testing.T) {
+	out := &syncWriter{}
+	f := NewFlusher(out, 0, 10*time.Millisecond)
+	defer f.Close()
+	for i := 0; i < 10; i++ {
+		f.Enqueue(NewIoBufferString("x"))
+	}
+	deadline := time.Now().Add(time.Second)
+	for out.String() != strings.Repeat("x", 10) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expect a timer flush, but got %q", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := f.Stats(); s.TimerFlushes != 1 {
+		t.Errorf("Expect one timer flush, but got %+v", s)
+	}
+}
+
+func TestFlusherPartialWrite(t *testing.T) {
+	w := &failingWriter{limit: 8}
+	f := NewFlusher(w, 100, 0)
+	f.Enqueue(NewIoBufferString("12345"))
+	f.Enqueue(NewIoBufferString("67890"))
+	if err := f.Flush(); err != errWriterFull {
+		t.Fatalf("Expect the writer error, but got %v", err)
+	}
+	if f.Buffered() != 2 {
+		t.Errorf("Expect the unwritten bytes kept, but got %d", f.Buffered())
+	}
+	w.limit = 100
+	f.Flush()
+	if w.String() != "1234567890" {
+		t.Errorf("Expect the rest written in order, but got %q", w.String())
+	}
+}
+
+func TestFlusherConn(t *testing.T) {
+	c1, c2 := tcpPair(t)
+	defer c1.Close()
+	defer c2.Close()
+
+	f := NewFlusher(c1, 1<<20, 0)
+	var want strings.Builder
+	for i := 0; i < 2000; i++ {
+		s := randString(randN(64) + 1)
+		want.WriteString(s)
+		f.Enqueue(NewIoBufferString(s))
+	}
+	done := make(chan string)
+	go func() {
+		got, _ := ioutil.ReadAll(io.LimitReader(c2, int64(want.Len())))
+		done <- string(got)
+	}()
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-done; got != want.String() {
+		t.Errorf("Expect %d bytes over the connection, but got %d", want.Len(), len(got))
+	}
+}
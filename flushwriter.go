@@ -0,0 +1,186 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultFlushBytes is the flush threshold of a FlushWriter by default
+const defaultFlushBytes = 4096
+
+// ErrClosedWriter is returned by writes to a closed FlushWriter or
+// AsyncWriter.
+var ErrClosedWriter = errors.New("buffer: writer closed")
+
+// FlushStats are the flush counters of a FlushWriter.
+type FlushStats struct {
+	// SizeFlushes is the number of flushes triggered by the size threshold.
+	SizeFlushes int64
+	// TimerFlushes is the number of flushes triggered by the timer.
+	TimerFlushes int64
+	// ExplicitFlushes is the number of flushes by Flush and Close.
+	ExplicitFlushes int64
+	// BytesFlushed is the number of bytes written to the underlying writer.
+	BytesFlushed int64
+	// Errors is the number of failed flushes.
+	Errors int64
+}
+
+// FlushWriter buffers writes in a pooled Buffer and writes them to the
+// underlying writer once flushBytes are buffered or flushEvery has passed
+// since the first unflushed write. It is safe for concurrent use.
+type FlushWriter struct {
+	mu         sync.Mutex
+	w          io.Writer
+	buf        *Buffer
+	flushBytes int
+	flushEvery time.Duration
+	timer      *time.Timer
+	armed      bool
+	// err is the error of a timer flush, returned by the next call
+	err    error
+	stats  FlushStats
+	closed bool
+}
+
+// NewFlushWriter returns a FlushWriter writing to w. flushBytes <= 0 means
+// 4096 bytes, flushEvery <= 0 disables the timer.
+func NewFlushWriter(w io.Writer, flushBytes int, flushEvery time.Duration) *FlushWriter {
+	if flushBytes <= 0 {
+		flushBytes = defaultFlushBytes
+	}
+	return &FlushWriter{
+		w:          w,
+		buf:        Get(),
+		flushBytes: flushBytes,
+		flushEvery: flushEvery,
+	}
+}
+
+// Write buffers p, flushing when the threshold is reached.
+func (f *FlushWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.pending(); err != nil {
+		return 0, err
+	}
+	f.buf.Write(p)
+	return len(p), f.written()
+}
+
+// WriteString buffers s, flushing when the threshold is reached.
+func (f *FlushWriter) WriteString(s string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.pending(); err != nil {
+		return 0, err
+	}
+	f.buf.WriteString(s)
+	return len(s), f.written()
+}
+
+// pending returns the error to report before buffering more
+func (f *FlushWriter) pending() error {
+	if f.closed {
+		return ErrClosedWriter
+	}
+	err := f.err
+	f.err = nil
+	return err
+}
+
+// written flushes or arms the timer after buffering
+func (f *FlushWriter) written() error {
+	if f.buf.Len() >= f.flushBytes {
+		f.stats.SizeFlushes++
+		return f.flush()
+	}
+	if f.flushEvery > 0 && !f.armed && f.buf.Len() > 0 {
+		f.armed = true
+		if f.timer == nil {
+			f.timer = time.AfterFunc(f.flushEvery, f.onTimer)
+		} else {
+			f.timer.Reset(f.flushEvery)
+		}
+	}
+	return nil
+}
+
+func (f *FlushWriter) onTimer() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.armed || f.closed {
+		return
+	}
+	f.stats.TimerFlushes++
+	if err := f.flush(); err != nil {
+		f.err = err
+	}
+}
+
+// flush writes the buffered bytes, keeping what wasn't written on error
+func (f *FlushWriter) flush() error {
+	f.armed = false
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	if f.buf.Len() == 0 {
+		return nil
+	}
+	n, err := f.buf.WriteTo(f.w)
+	f.stats.BytesFlushed += n
+	if err != nil {
+		f.stats.Errors++
+		f.buf.Next(int(n))
+		return err
+	}
+	f.buf.Reset()
+	return nil
+}
+
+// Flush writes the buffered bytes to the underlying writer.
+func (f *FlushWriter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosedWriter
+	}
+	f.err = nil
+	f.stats.ExplicitFlushes++
+	return f.flush()
+}
+
+// Buffered returns the number of bytes waiting to be flushed.
+func (f *FlushWriter) Buffered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0
+	}
+	return f.buf.Len()
+}
+
+// Stats returns the flush counters.
+func (f *FlushWriter) Stats() FlushStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// Close flushes the buffered bytes, stops the timer and returns the buffer
+// to the pool. It doesn't close the underlying writer.
+func (f *FlushWriter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosedWriter
+	}
+	f.stats.ExplicitFlushes++
+	err := f.flush()
+	f.closed = true
+	Put(f.buf)
+	f.buf = nil
+	return err
+}
@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncWriter is a bytes.Buffer safe for the flush timer goroutine
+type syncWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *syncWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestFlushWriterSize(t *testing.T) {
+	var out bytes.Buffer
+	f := NewFlushWriter(&out, 8, 0)
+	f.WriteString("1234")
+	if out.Len() != 0 || f.Buffered() != 4 {
+		t.Fatalf("Expect 4 bytes buffered, but got %d flushed", out.Len())
+	}
+	f.Write([]byte("5678"))
+	if out.String() != "12345678" || f.Buffered() != 0 {
+		t.Errorf("Expect a size flush, but got %q", out.String())
+	}
+
+	f.WriteString("tail")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "12345678tail" {
+		t.Errorf("Expect Close to flush, but got %q", out.String())
+	}
+	s := f.Stats()
+	if s.SizeFlushes != 1 || s.ExplicitFlushes != 1 || s.BytesFlushed != 12 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if _, err := f.WriteString("x"); err != ErrClosedWriter {
+		t.Errorf("Expect ErrClosedWriter, but got %v", err)
+	}
+}
+
+func TestFlushWriterTimer(t *testing.T) {
+	out := &syncWriter{}
+	f := NewFlushWriter(out, 1024, 10*time.Millisecond)
+	defer f.Close()
+
+	f.WriteString("tick")
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != "tick" {
+		if time.Now().After(deadline) {
+			t.Fatal("timer flush didn't happen")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := f.Stats(); s.TimerFlushes != 1 {
+		t.Errorf("Expect 1 timer flush, but got %+v", s)
+	}
+}
+
+func TestFlushWriterError(t *testing.T) {
+	w := &failingWriter{limit: 4}
+	f := NewFlushWriter(w, 8, 0)
+	if _, err := f.WriteString("abcdefgh"); err != errWriterFull {
+		t.Fatalf("Expect errWriterFull, but got %v", err)
+	}
+	if f.Buffered() != 4 {
+		t.Errorf("Expect the unwritten bytes to be kept, but got %d", f.Buffered())
+	}
+	w.limit = 100
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "abcdefgh" {
+		t.Errorf("Expect abcdefgh, but got %q", w.String())
+	}
+	if s := f.Stats(); s.Errors != 1 {
+		t.Errorf("Expect 1 error, but got %+v", s)
+	}
+}
@@ -0,0 +1,91 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// formatPreviewBytes is how many bytes %v shows by default
+	formatPreviewBytes = 64
+	// formatDumpBytes is how many bytes %+v dumps by default
+	formatDumpBytes = 512
+)
+
+// formatBytes implements fmt.Formatter for the unread bytes p, see
+// IoBuffer.Format
+func formatBytes(f fmt.State, verb rune, p []byte) {
+	switch verb {
+	case 'v':
+		limit, ok := f.Precision()
+		if f.Flag('+') {
+			if !ok {
+				limit = formatDumpBytes
+			}
+			io.WriteString(f, dump(p, limit))
+			return
+		}
+		if !ok {
+			limit = formatPreviewBytes
+		}
+		io.WriteString(f, preview(p, limit))
+	case 's', 'q', 'x', 'X':
+		fmt.Fprintf(f, directive(f, verb), p)
+	default:
+		fmt.Fprintf(f, "%%!%c(buffer len=%d)", verb, len(p))
+	}
+}
+
+// preview returns the length and at most max escaped bytes of p
+func preview(p []byte, max int) string {
+	out := make([]byte, 0, 32+max*2)
+	out = append(out, "len="...)
+	out = strconv.AppendInt(out, int64(len(p)), 10)
+	out = append(out, ' ')
+	truncated := len(p) > max
+	if truncated {
+		p = p[:max]
+	}
+	out = strconv.AppendQuote(out, unsafeString(p))
+	if truncated {
+		out = append(out, "..."...)
+	}
+	return string(out)
+}
+
+// directive rebuilds the formatting directive of f for verb
+func directive(f fmt.State, verb rune) string {
+	d := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			d = append(d, byte(flag))
+		}
+	}
+	if w, ok := f.Width(); ok {
+		d = strconv.AppendInt(d, int64(w), 10)
+	}
+	if prec, ok := f.Precision(); ok {
+		d = append(d, '.')
+		d = strconv.AppendInt(d, int64(prec), 10)
+	}
+	return string(append(d, byte(verb)))
+}
+
+// Format implements fmt.Formatter, %v prints a truncated escaped preview
+// instead of the raw contents and %+v a hexdump, see IoBuffer.Format.
+func (b *Buffer) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, b.B[b.off:])
+}
+
+func (b *ioBuffer) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, b.buf[b.off:])
+}
+
+func (m *multiIoBuffer) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, m.CopyBytes())
+}
+
+func (v *limitedIoBuffer) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, v.Bytes())
+}
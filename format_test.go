@@ -0,0 +1,50 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	b := NewIoBufferString("hello\x00world\n")
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"%v", `len=12 "hello\x00world\n"`},
+		{"%.5v", `len=12 "hello"...`},
+		{"%s", "hello\x00world\n"},
+		{"%q", `"hello\x00world\n"`},
+		{"%x", "68656c6c6f00776f726c640a"},
+		{"%8.2X", "    6865"},
+		{"%d", "%!d(buffer len=12)"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprintf(c.format, b); got != c.want {
+			t.Errorf("%s: Expect %q, but got %q", c.format, c.want, got)
+		}
+	}
+	if got := fmt.Sprintf("%+v", b); got != b.Dump(0) {
+		t.Errorf("Expect %%+v to dump, but got %q", got)
+	}
+	if b.Len() != 12 {
+		t.Error("Expect formatting not to consume the buffer")
+	}
+
+	var bb Buffer
+	bb.Write(bytes.Repeat([]byte{0xff}, 1<<20))
+	s := fmt.Sprintf("%v", &bb)
+	if !strings.HasPrefix(s, `len=1048576 "\xff\xff`) || len(s) > 300 {
+		t.Errorf("Expect a short preview, but got %d bytes", len(s))
+	}
+	if s := fmt.Sprintf("%+v", &bb); strings.Count(s, "\n") > 40 {
+		t.Errorf("Expect a truncated dump, but got %d lines", strings.Count(s, "\n"))
+	}
+
+	m := MultiIoBuffer(NewIoBufferString("ab"), NewIoBufferString("cd"))
+	if s := fmt.Sprint(m, m.Limit(1)); s != `len=4 "abcd" len=1 "a"` {
+		t.Errorf("unexpected multi buffer preview: %q", s)
+	}
+}
@@ -0,0 +1,124 @@
+// Package grpcbuffer provides a gRPC codec and a shared buffer pool whose
+// wire buffers come from the buffer package's byte pool.
+//
+// Codec implements encoding.CodecV2. Marshaled messages are written to
+// buffers taken from Pool, which gRPC frees back to it once they have been
+// written to the transport, so sending a message doesn't allocate its wire
+// buffer. Pool satisfies mem.BufferPool and can serve gRPC's receive
+// buffers too, see experimental.WithBufferPool.
+//
+// Importing the package doesn't register Codec. Its content-subtype, Name,
+// differs from gRPC's default "proto" codec, so registering it with
+// encoding.RegisterCodecV2 leaves the default in place for every other
+// service, and clients opt in with grpc.CallContentSubtype(grpcbuffer.Name).
+// grpc.ForceCodecV2 and grpc.ForceServerCodecV2 use it without registering.
+//
+// The package is a module of its own so that the buffer package doesn't
+// depend on gRPC.
+package grpcbuffer
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/mem"
+)
+
+// Name is the content-subtype of the codec, distinct from gRPC's default
+// "proto" codec so that registering Codec doesn't replace it.
+const Name = "buffer-proto"
+
+// ErrNotMessage is returned when a value implements neither the sized
+// marshaling interface nor Marshal/Unmarshal and Codec has no Fallback.
+var ErrNotMessage = errors.New("grpcbuffer: value is not a marshalable message")
+
+var _ encoding.CodecV2 = Codec{}
+
+// sizedMarshaler is implemented by gogo/protobuf and vtprotobuf style
+// generated messages that can marshal into a caller supplied slice.
+type sizedMarshaler interface {
+	Size() int
+	MarshalTo(data []byte) (int, error)
+}
+
+type marshaler interface {
+	Marshal() ([]byte, error)
+}
+
+type unmarshaler interface {
+	Unmarshal(data []byte) error
+}
+
+// Codec is a gRPC codec for gogo/protobuf and vtprotobuf style messages.
+//
+// Messages implementing Size and MarshalTo are marshaled into a buffer of
+// exactly their size taken from Pool, other messages with Marshal and
+// Unmarshal use those. Messages with neither, such as
+// google.golang.org/protobuf messages, go to Fallback, e.g.
+// encoding.GetCodecV2("proto"), or fail with ErrNotMessage.
+type Codec struct {
+	Fallback encoding.CodecV2
+}
+
+// Marshal returns the wire format of v. Buffers above gRPC's pooling
+// threshold come from Pool and go back to it when gRPC frees them.
+func (c Codec) Marshal(v interface{}) (mem.BufferSlice, error) {
+	switch m := v.(type) {
+	case sizedMarshaler:
+		size := m.Size()
+		if mem.IsBelowBufferPoolingThreshold(size) {
+			// gRPC never frees small buffers, see mem.NewBuffer
+			data := make([]byte, size)
+			n, err := m.MarshalTo(data)
+			if err != nil {
+				return nil, err
+			}
+			return mem.BufferSlice{mem.SliceBuffer(data[:n])}, nil
+		}
+		var pool Pool
+		buf := pool.Get(size)
+		n, err := m.MarshalTo(*buf)
+		if err != nil {
+			pool.Put(buf)
+			return nil, err
+		}
+		*buf = (*buf)[:n]
+		return mem.BufferSlice{mem.NewBuffer(buf, pool)}, nil
+	case marshaler:
+		data, err := m.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		return mem.BufferSlice{mem.SliceBuffer(data)}, nil
+	}
+	if c.Fallback != nil {
+		return c.Fallback.Marshal(v)
+	}
+	return nil, fmt.Errorf("%w: %T", ErrNotMessage, v)
+}
+
+// Unmarshal parses the wire format data into v. Messages must not retain
+// the slice passed to their Unmarshal method, it goes back to Pool.
+func (c Codec) Unmarshal(data mem.BufferSlice, v interface{}) error {
+	if m, ok := v.(unmarshaler); ok {
+		switch {
+		case len(data) == 1:
+			return m.Unmarshal(data[0].ReadOnlyData())
+		case mem.IsBelowBufferPoolingThreshold(data.Len()):
+			return m.Unmarshal(data.Materialize())
+		}
+		buf := data.MaterializeToBuffer(Pool{})
+		defer buf.Free()
+		return m.Unmarshal(buf.ReadOnlyData())
+	}
+	if c.Fallback != nil {
+		return c.Fallback.Unmarshal(data, v)
+	}
+	return fmt.Errorf("%w: %T", ErrNotMessage, v)
+}
+
+// Name returns the content-subtype of the codec.
+func (Codec) Name() string {
+	return Name
+}
@@ -0,0 +1,158 @@
+package grpcbuffer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gottingen/buffer"
+	"google.golang.org/grpc/mem"
+)
+
+type sizedMessage struct {
+	s string
+}
+
+func (m *sizedMessage) Size() int {
+	return len(m.s)
+}
+
+func (m *sizedMessage) MarshalTo(data []byte) (int, error) {
+	return copy(data, m.s), nil
+}
+
+func (m *sizedMessage) Unmarshal(data []byte) error {
+	m.s = string(data)
+	return nil
+}
+
+type plainMessage struct {
+	s string
+}
+
+func (m *plainMessage) Marshal() ([]byte, error) {
+	return []byte(m.s), nil
+}
+
+func TestCodecPooledSendBuffers(t *testing.T) {
+	var c Codec
+	in := &sizedMessage{s: strings.Repeat("x", 4096)}
+	before := buffer.GetPoolStats().InUse
+	data, err := c.Marshal(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data.Materialize()) != in.s {
+		t.Fatal("unexpected wire data")
+	}
+	if n := buffer.GetPoolStats().InUse; n != before+1 {
+		t.Fatalf("Expect the send buffer taken from the pool, but got %d in use, %d before", n, before)
+	}
+	// gRPC frees the buffers once sent
+	data.Free()
+	if n := buffer.GetPoolStats().InUse; n != before {
+		t.Fatalf("Expect the send buffer back in the pool, but got %d in use, %d before", n, before)
+	}
+
+	// received messages may span several buffers
+	recv := mem.BufferSlice{mem.SliceBuffer(in.s[:3000]), mem.SliceBuffer(in.s[3000:])}
+	var out sizedMessage
+	if err := c.Unmarshal(recv, &out); err != nil || out.s != in.s {
+		t.Fatalf("unexpected result: %d bytes, %v", len(out.s), err)
+	}
+	if n := buffer.GetPoolStats().InUse; n != before {
+		t.Fatalf("Expect the receive buffer back in the pool, but got %d in use, %d before", n, before)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	var c Codec
+	for i := 0; i < 10; i++ {
+		in := &sizedMessage{s: "hello grpc"}
+		data, err := c.Marshal(in)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(data.Materialize()) != in.s {
+			t.Fatalf("unexpected wire data: %q. Expecting %q", data.Materialize(), in.s)
+		}
+		var out sizedMessage
+		if err := c.Unmarshal(data, &out); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if out.s != in.s {
+			t.Fatalf("unexpected message: %q. Expecting %q", out.s, in.s)
+		}
+	}
+}
+
+func TestCodecFallback(t *testing.T) {
+	var c Codec
+	data, err := c.Marshal(&plainMessage{s: "plain"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data.Materialize()) != "plain" {
+		t.Fatalf("unexpected wire data: %q", data.Materialize())
+	}
+	if _, err := c.Marshal(42); !errors.Is(err, ErrNotMessage) {
+		t.Fatalf("unexpected error: %v. Expecting ErrNotMessage", err)
+	}
+	if err := c.Unmarshal(data, &plainMessage{}); !errors.Is(err, ErrNotMessage) {
+		t.Fatalf("unexpected error: %v. Expecting ErrNotMessage", err)
+	}
+	if c.Name() != Name {
+		t.Fatalf("unexpected name: %q", c.Name())
+	}
+}
+
+// fallbackCodec stands in for gRPC's proto codec
+type fallbackCodec struct{}
+
+type reflectMessage struct {
+	s string
+}
+
+func (fallbackCodec) Marshal(v interface{}) (mem.BufferSlice, error) {
+	return mem.BufferSlice{mem.SliceBuffer(v.(*reflectMessage).s)}, nil
+}
+
+func (fallbackCodec) Unmarshal(data mem.BufferSlice, v interface{}) error {
+	v.(*reflectMessage).s = string(data.Materialize())
+	return nil
+}
+
+func (fallbackCodec) Name() string {
+	return "proto"
+}
+
+func TestCodecFallbackCodec(t *testing.T) {
+	c := Codec{Fallback: fallbackCodec{}}
+	data, err := c.Marshal(&reflectMessage{s: "v2"})
+	if err != nil || string(data.Materialize()) != "v2" {
+		t.Fatalf("unexpected result: %q, %v", data.Materialize(), err)
+	}
+	var out reflectMessage
+	if err := c.Unmarshal(data, &out); err != nil || out.s != "v2" {
+		t.Fatalf("unexpected result: %q, %v", out.s, err)
+	}
+	// messages the codec handles itself don't reach the fallback
+	data, err = c.Marshal(&sizedMessage{s: "sized"})
+	if err != nil || string(data.Materialize()) != "sized" {
+		t.Fatalf("unexpected result: %q, %v", data.Materialize(), err)
+	}
+	if Name == "proto" {
+		t.Fatal("the codec must not replace gRPC's default proto codec")
+	}
+}
+
+func TestPoolGetPut(t *testing.T) {
+	var p Pool
+	for _, n := range []int{0, 1, 100, 4096, 1 << 20} {
+		b := p.Get(n)
+		if len(*b) != n {
+			t.Fatalf("unexpected len: %d. Expecting %d", len(*b), n)
+		}
+		p.Put(b)
+	}
+}
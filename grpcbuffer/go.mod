@@ -0,0 +1,15 @@
+module github.com/gottingen/buffer/grpcbuffer
+
+go 1.22
+
+replace github.com/gottingen/buffer => ../
+
+require (
+	github.com/gottingen/buffer v0.0.0
+	google.golang.org/grpc v1.66.2
+)
+
+require (
+	github.com/gottingen/atomic v1.0.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
@@ -0,0 +1,26 @@
+package grpcbuffer
+
+import (
+	"github.com/gottingen/buffer"
+	"google.golang.org/grpc/mem"
+)
+
+var _ mem.BufferPool = Pool{}
+
+// Pool is a shared buffer pool backed by the buffer package's byte pool.
+//
+// It is a gRPC mem.BufferPool, so receive and send buffers of a gRPC server
+// or client can share memory with the rest of the application.
+type Pool struct{}
+
+// Get returns a slice of the given length from the byte pool.
+func (Pool) Get(length int) *[]byte {
+	return buffer.GetBytes(length)
+}
+
+// Put returns buf to the byte pool.
+//
+// buf mustn't be touched after returning it to the pool.
+func (Pool) Put(buf *[]byte) {
+	buffer.PutBytes(buf)
+}
@@ -0,0 +1,65 @@
+package buffer
+
+import (
+	"sync/atomic"
+)
+
+// Hooks are instrumentation callbacks fired on IoBuffer memory events.
+//
+// Callbacks run synchronously on the goroutine using the buffer, so they
+// should be cheap and must not use the buffer passed to them. Any of the
+// callbacks may be nil.
+type Hooks struct {
+	// OnGrow is called when the capacity grows from oldCap to newCap.
+	OnGrow func(b IoBuffer, oldCap, newCap int)
+
+	// OnCopy is called when n unread bytes are slid to the front of the
+	// buffer to reclaim consumed space, capacity is left unchanged.
+	OnCopy func(b IoBuffer, n, capacity int)
+
+	// OnFree is called when the backing slice of capacity bytes is released.
+	OnFree func(b IoBuffer, capacity int)
+}
+
+var defaultHooks atomic.Value // *Hooks
+
+// SetDefaultHooks sets the hooks used by buffers that have no hooks of
+// their own. Passing nil removes the default hooks.
+func SetDefaultHooks(h *Hooks) {
+	defaultHooks.Store(h)
+}
+
+// DefaultHooks returns the hooks set by SetDefaultHooks.
+func DefaultHooks() *Hooks {
+	h, _ := defaultHooks.Load().(*Hooks)
+	return h
+}
+
+func (b *ioBuffer) SetHooks(h *Hooks) {
+	b.hooks = h
+}
+
+func (b *ioBuffer) getHooks() *Hooks {
+	if b.hooks != nil {
+		return b.hooks
+	}
+	return DefaultHooks()
+}
+
+func (b *ioBuffer) onGrow(oldCap, newCap int) {
+	if h := b.getHooks(); h != nil && h.OnGrow != nil {
+		h.OnGrow(b, oldCap, newCap)
+	}
+}
+
+func (b *ioBuffer) onCopy(n int) {
+	if h := b.getHooks(); h != nil && h.OnCopy != nil {
+		h.OnCopy(b, n, cap(b.buf))
+	}
+}
+
+func (b *ioBuffer) onFree(capacity int) {
+	if h := b.getHooks(); h != nil && h.OnFree != nil {
+		h.OnFree(b, capacity)
+	}
+}
@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestIoBufferHooks(t *testing.T) {
+	var grows, copies, frees int
+	h := &Hooks{
+		OnGrow: func(b IoBuffer, oldCap, newCap int) {
+			if newCap <= oldCap {
+				t.Errorf("Expect growth, but got %d -> %d", oldCap, newCap)
+			}
+			grows++
+		},
+		OnCopy: func(b IoBuffer, n, capacity int) {
+			copies++
+		},
+		OnFree: func(b IoBuffer, capacity int) {
+			frees++
+		},
+	}
+
+	b := NewIoBuffer(16)
+	b.SetHooks(h)
+	b.Write(make([]byte, 128))
+	if grows != 1 {
+		t.Errorf("Expect 1 grow, but got %d", grows)
+	}
+
+	b.Drain(60)
+	b.(*ioBuffer).copy(0)
+	if copies != 1 {
+		t.Errorf("Expect 1 copy, but got %d", copies)
+	}
+
+	b.Free()
+	if frees != 1 {
+		t.Errorf("Expect 1 free, but got %d", frees)
+	}
+}
+
+func TestDefaultHooks(t *testing.T) {
+	var grows int
+	SetDefaultHooks(&Hooks{
+		OnGrow: func(b IoBuffer, oldCap, newCap int) {
+			grows++
+		},
+	})
+	defer SetDefaultHooks(nil)
+
+	b := NewIoBuffer(16)
+	b.Write(make([]byte, 128))
+	if grows != 1 {
+		t.Errorf("Expect 1 grow, but got %d", grows)
+	}
+
+	b.SetHooks(&Hooks{})
+	b.Write(make([]byte, 1024))
+	if grows != 1 {
+		t.Errorf("Expect per-buffer hooks to override defaults, but got %d grows", grows)
+	}
+}
+
+func TestHooksResetByPool(t *testing.T) {
+	var grows int
+	h := &Hooks{
+		OnGrow: func(b IoBuffer, oldCap, newCap int) {
+			grows++
+		},
+	}
+
+	b := GetIoBuffer(16)
+	b.SetHooks(h)
+	PutIoBuffer(b)
+	b2 := GetIoBuffer(16)
+	if !raceEnabled && b2 != b {
+		t.Fatal("Expect the buffer to be reused")
+	}
+	b2.Write(make([]byte, 1024))
+	if grows != 0 {
+		t.Errorf("Expect the hooks of the previous owner to be dropped, but got %d grows", grows)
+	}
+	PutIoBuffer(b2)
+}
@@ -0,0 +1,178 @@
+// Package httpbuffer provides net/http helpers backed by the buffer
+// package's pools.
+package httpbuffer
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gottingen/buffer"
+)
+
+// BufferedResponseWriter wraps an http.ResponseWriter and captures status,
+// headers and body into a pooled buffer.Buffer until Send is called.
+//
+// The captured response may be inspected and rewritten before sending,
+// e.g. for compression, ETag calculation or body rewriting.
+type BufferedResponseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	body        *buffer.Buffer
+	wroteHeader bool
+	// sent is set once status and headers went out, header is then the
+	// header map of w
+	sent bool
+	err  error
+}
+
+// NewBufferedResponseWriter returns a BufferedResponseWriter wrapping w.
+//
+// Release must be called once the writer is no longer used.
+func NewBufferedResponseWriter(w http.ResponseWriter) *BufferedResponseWriter {
+	return &BufferedResponseWriter{
+		w:      w,
+		header: make(http.Header),
+		status: http.StatusOK,
+		body:   buffer.Get(),
+	}
+}
+
+// Header returns the captured header map, or once the headers have been
+// sent, the header map of the underlying writer, e.g. for trailers.
+func (bw *BufferedResponseWriter) Header() http.Header {
+	return bw.header
+}
+
+// WriteHeader captures the status code. Only the first call has effect.
+func (bw *BufferedResponseWriter) WriteHeader(statusCode int) {
+	if bw.wroteHeader {
+		return
+	}
+	bw.wroteHeader = true
+	bw.status = statusCode
+}
+
+// Write appends p to the captured body.
+func (bw *BufferedResponseWriter) Write(p []byte) (int, error) {
+	bw.wroteHeader = true
+	return bw.body.Write(p)
+}
+
+// Status returns the captured status code.
+func (bw *BufferedResponseWriter) Status() int {
+	return bw.status
+}
+
+// SetStatus overrides the captured status code.
+func (bw *BufferedResponseWriter) SetStatus(statusCode int) {
+	bw.status = statusCode
+}
+
+// Body returns the captured body. It may be modified in place.
+func (bw *BufferedResponseWriter) Body() *buffer.Buffer {
+	return bw.body
+}
+
+// Flush sends the status, headers and body captured so far to the
+// underlying http.ResponseWriter and flushes it, if it is an http.Flusher.
+// As the body may go on, Content-Length isn't set and net/http falls back
+// to chunked encoding. Flush implements http.Flusher, write errors are
+// reported by Send.
+func (bw *BufferedResponseWriter) Flush() {
+	bw.sendHeader(false)
+	bw.sendBody()
+	if f, ok := bw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Send sends the captured status and headers, if not sent yet, followed by
+// the captured body to the underlying http.ResponseWriter, and empties the
+// body. If nothing has been flushed before, Content-Length is set unless
+// the handler set it already. Send returns the first write error, of Flush
+// too.
+func (bw *BufferedResponseWriter) Send() error {
+	bw.sendHeader(true)
+	bw.sendBody()
+	return bw.err
+}
+
+// sendHeader sends the status and headers once, final means the captured
+// body is the complete body
+func (bw *BufferedResponseWriter) sendHeader(final bool) {
+	if bw.sent {
+		return
+	}
+	h := bw.w.Header()
+	for k, v := range bw.header {
+		h[k] = v
+	}
+	if final && h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" {
+		h.Set("Content-Length", strconv.Itoa(bw.body.Len()))
+	}
+	bw.header = h
+	bw.sent = true
+	bw.w.WriteHeader(bw.status)
+}
+
+func (bw *BufferedResponseWriter) sendBody() {
+	if bw.body.Len() == 0 {
+		return
+	}
+	if _, err := bw.body.WriteTo(bw.w); err != nil && bw.err == nil {
+		bw.err = err
+	}
+	bw.body.Reset()
+}
+
+// Release returns the captured body to the pool.
+//
+// The writer mustn't be accessed after calling Release.
+func (bw *BufferedResponseWriter) Release() {
+	if bw.body != nil {
+		buffer.Put(bw.body)
+		bw.body = nil
+	}
+}
+
+// Middleware returns a middleware buffering responses of the wrapped
+// handler. process, if not nil, is called with the captured response
+// before it is sent; an error from process results in a 500 response, or
+// if the handler flushed the response already, in aborting it with
+// http.ErrAbortHandler. Errors sending the response are logged to the
+// ErrorLog of the server.
+func Middleware(process func(bw *BufferedResponseWriter) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bw := NewBufferedResponseWriter(w)
+			defer bw.Release()
+
+			next.ServeHTTP(bw, r)
+			if process != nil {
+				if err := process(bw); err != nil {
+					if bw.sent {
+						// status and headers are out, the client can only
+						// tell from a broken response
+						panic(http.ErrAbortHandler)
+					}
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+			}
+			if err := bw.Send(); err != nil {
+				logf(r, "httpbuffer: sending response: %v", err)
+			}
+		})
+	}
+}
+
+// logf logs to the ErrorLog of the server serving r, like net/http does
+func logf(r *http.Request, format string, args ...interface{}) {
+	if srv, ok := r.Context().Value(http.ServerContextKey).(*http.Server); ok && srv.ErrorLog != nil {
+		srv.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
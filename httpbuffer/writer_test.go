@@ -0,0 +1,137 @@
+package httpbuffer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBufferedResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec)
+	defer bw.Release()
+
+	bw.Header().Set("X-Test", "1")
+	bw.WriteHeader(http.StatusCreated)
+	bw.Write([]byte("hello"))
+
+	if rec.Body.Len() != 0 {
+		t.Fatalf("unexpected body written before Send: %q", rec.Body.String())
+	}
+	if bw.Status() != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", bw.Status())
+	}
+	bw.Body().Set(bytes.ToUpper(bw.Body().Bytes()))
+
+	if err := bw.Send(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d. Expecting %d", rec.Code, http.StatusCreated)
+	}
+	if rec.Body.String() != "HELLO" {
+		t.Fatalf("unexpected body: %q. Expecting %q", rec.Body.String(), "HELLO")
+	}
+	if rec.Header().Get("X-Test") != "1" || rec.Header().Get("Content-Length") != "5" {
+		t.Fatalf("unexpected headers: %v", rec.Header())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	})
+	mw := Middleware(func(bw *BufferedResponseWriter) error {
+		bw.Header().Set("ETag", `"x"`)
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	mw(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Body.String() != "body" || rec.Header().Get("ETag") != `"x"` {
+		t.Fatalf("unexpected response: %q %v", rec.Body.String(), rec.Header())
+	}
+
+	failing := Middleware(func(bw *BufferedResponseWriter) error {
+		return errors.New("failed")
+	})
+	rec = httptest.NewRecorder()
+	failing(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status: %d. Expecting %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestBufferedResponseWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec)
+	defer bw.Release()
+
+	var _ http.Flusher = bw
+
+	bw.Write([]byte("foo"))
+	bw.Flush()
+	if !rec.Flushed || rec.Body.String() != "foo" {
+		t.Fatalf("unexpected response after Flush: %q flushed=%v", rec.Body.String(), rec.Flushed)
+	}
+	// the header map stays usable once sent
+	bw.Header().Set("X-Trailer", "1")
+	bw.Write([]byte("bar"))
+	if err := bw.Send(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rec.Body.String() != "foobar" {
+		t.Fatalf("unexpected body: %q. Expecting %q", rec.Body.String(), "foobar")
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("unexpected Content-Length on a flushed response: %v", rec.Header())
+	}
+}
+
+func TestMiddlewareFlushedFailure(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("head"))
+		w.(http.Flusher).Flush()
+	})
+	failing := Middleware(func(bw *BufferedResponseWriter) error {
+		return errors.New("failed")
+	})
+
+	rec := httptest.NewRecorder()
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("Expect a panic with http.ErrAbortHandler, but got %v", v)
+		}
+		if rec.Code != http.StatusOK || rec.Body.String() != "head" {
+			t.Fatalf("unexpected response: %d %q", rec.Code, rec.Body.String())
+		}
+	}()
+	failing(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+}
+
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestMiddlewareSendError(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	})
+	var logged bytes.Buffer
+	srv := &http.Server{ErrorLog: log.New(&logged, "", 0)}
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), http.ServerContextKey, srv))
+
+	Middleware(nil)(h).ServeHTTP(failingWriter{httptest.NewRecorder()}, r)
+	if !bytes.Contains(logged.Bytes(), []byte("broken pipe")) {
+		t.Fatalf("Expect the send error to be logged, but got %q", logged.String())
+	}
+}
@@ -0,0 +1,190 @@
+package buffer
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidHead is returned for HTTP heads with malformed or forbidden
+// bytes, such as line breaks in header values.
+var ErrInvalidHead = errors.New("io buffer: invalid http head")
+
+// Body lengths of EndHead besides the actual length of the body.
+const (
+	// BodyChunked marks a body of unknown length, sent chunked.
+	BodyChunked int64 = -1
+	// BodyNone marks a message without body and framing headers, e.g. a
+	// GET request or a 204, 304 or HEAD response.
+	BodyNone int64 = -2
+)
+
+// The HTTP/1.1 head helpers write request and response heads to an
+// IoBuffer without net/http:
+//
+//	WriteRequestLine(b, method, target)
+//	WriteHeader(b, k, v) // for each header
+//	EndHead(b, contentLength)
+//
+// The input is validated before anything is written, so a failed call
+// leaves b as it was unless b itself fails.
+
+// WriteRequestLine writes "method target HTTP/1.1".
+func WriteRequestLine(b IoBuffer, method, target []byte) error {
+	if !isToken(method) || len(target) == 0 {
+		return opError("write request line", 0, b, ErrInvalidHead)
+	}
+	for _, c := range target {
+		if c <= ' ' || c == 0x7f {
+			return opError("write request line", 0, b, ErrInvalidHead)
+		}
+	}
+	if _, err := b.Write(method); err != nil {
+		return err
+	}
+	if _, err := b.WriteString(" "); err != nil {
+		return err
+	}
+	if _, err := b.Write(target); err != nil {
+		return err
+	}
+	_, err := b.WriteString(" HTTP/1.1\r\n")
+	return err
+}
+
+// WriteStatusLine writes "HTTP/1.1 code reason". The code must have three
+// digits, the reason may be empty.
+func WriteStatusLine(b IoBuffer, code int, reason []byte) error {
+	if code < 100 || code > 999 || !isFieldText(reason) {
+		return opError("write status line", 0, b, ErrInvalidHead)
+	}
+	line := [16]byte{'H', 'T', 'T', 'P', '/', '1', '.', '1', ' ',
+		byte('0' + code/100), byte('0' + code/10%10), byte('0' + code%10), ' '}
+	if _, err := b.Write(line[:13]); err != nil {
+		return err
+	}
+	if _, err := b.Write(reason); err != nil {
+		return err
+	}
+	_, err := b.WriteString("\r\n")
+	return err
+}
+
+// WriteHeader writes the header "k: v", trimming the blanks around v.
+// Content-Length and Transfer-Encoding headers are skipped, EndHead writes
+// the framing of the body, so heads copied from a message whose body is
+// re-encoded can't announce the wrong length.
+func WriteHeader(b IoBuffer, k, v []byte) error {
+	v = trimBlanks(v)
+	if !isToken(k) || !isFieldText(v) {
+		return opError("write header", 0, b, ErrInvalidHead)
+	}
+	if equalFoldASCII(k, "content-length") || equalFoldASCII(k, "transfer-encoding") {
+		return nil
+	}
+	if _, err := b.Write(k); err != nil {
+		return err
+	}
+	if _, err := b.WriteString(": "); err != nil {
+		return err
+	}
+	if _, err := b.Write(v); err != nil {
+		return err
+	}
+	_, err := b.WriteString("\r\n")
+	return err
+}
+
+// EndHead writes the framing header for a body of contentLength bytes,
+// BodyChunked or BodyNone, and the empty line ending the head.
+func EndHead(b IoBuffer, contentLength int64) error {
+	var tmp [64]byte
+	p := tmp[:0]
+	switch {
+	case contentLength >= 0:
+		p = append(p, "Content-Length: "...)
+		p = strconv.AppendInt(p, contentLength, 10)
+		p = append(p, "\r\n"...)
+	case contentLength == BodyChunked:
+		p = append(p, "Transfer-Encoding: chunked\r\n"...)
+	case contentLength != BodyNone:
+		return opError("end head", 0, b, ErrInvalidHead)
+	}
+	p = append(p, "\r\n"...)
+	_, err := b.Write(p)
+	return err
+}
+
+// BodyAllowed reports whether a response with the status code to a request
+// with method may have a body, if not EndHead must be passed BodyNone.
+func BodyAllowed(method string, code int) bool {
+	return method != "HEAD" && code >= 200 && code != 204 && code != 304 &&
+		!(method == "CONNECT" && code < 300)
+}
+
+// isToken reports whether p is a non-empty RFC 7230 token
+func isToken(p []byte) bool {
+	if len(p) == 0 {
+		return false
+	}
+	for _, c := range p {
+		if c >= 0x80 || !tokenChars[c] {
+			return false
+		}
+	}
+	return true
+}
+
+var tokenChars = func() (t [128]bool) {
+	for c := '0'; c <= '9'; c++ {
+		t[c] = true
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		t[c] = true
+		t[c-'a'+'A'] = true
+	}
+	for _, c := range "!#$%&'*+-.^_`|~" {
+		t[c] = true
+	}
+	return t
+}()
+
+// isFieldText reports whether p only holds tabs, spaces, visible ASCII
+// and obs-text
+func isFieldText(p []byte) bool {
+	for _, c := range p {
+		if c < ' ' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func trimBlanks(p []byte) []byte {
+	for len(p) > 0 && (p[0] == ' ' || p[0] == '\t') {
+		p = p[1:]
+	}
+	for len(p) > 0 && (p[len(p)-1] == ' ' || p[len(p)-1] == '\t') {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// equalFoldASCII reports whether p equals s ignoring ASCII case
+func equalFoldASCII(p []byte, s string) bool {
+	if len(p) != len(s) {
+		return false
+	}
+	for i, c := range p {
+		d := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if 'A' <= d && d <= 'Z' {
+			d += 'a' - 'A'
+		}
+		if c != d {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package buffer
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWriteHead(t *testing.T) {
+	b := NewIoBuffer(0)
+	if err := WriteRequestLine(b, []byte("POST"), []byte("/upload?x=1")); err != nil {
+		t.Fatal(err)
+	}
+	WriteHeader(b, []byte("Host"), []byte("example.com"))
+	WriteHeader(b, []byte("X-Trace"), []byte(" \tabc def\t "))
+	WriteHeader(b, []byte("content-length"), []byte("999"))
+	WriteHeader(b, []byte("Transfer-Encoding"), []byte("gzip, chunked"))
+	EndHead(b, 5)
+	b.WriteString("hello")
+	want := "POST /upload?x=1 HTTP/1.1\r\nHost: example.com\r\nX-Trace: abc def\r\nContent-Length: 5\r\n\r\nhello"
+	if b.String() != want {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(b.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "POST" || req.Host != "example.com" || req.ContentLength != 5 || req.Header.Get("X-Trace") != "abc def" {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	b.Reset()
+	WriteStatusLine(b, 200, []byte("OK"))
+	EndHead(b, BodyChunked)
+	b.WriteString("5\r\nhello\r\n0\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(b.String())), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	b.Reset()
+	WriteStatusLine(b, 304, nil)
+	EndHead(b, BodyNone)
+	if b.String() != "HTTP/1.1 304 \r\n\r\n" {
+		t.Errorf("got %q", b.String())
+	}
+}
+
+func TestWriteHeadInvalid(t *testing.T) {
+	b := NewIoBuffer(0)
+	for name, err := range map[string]error{
+		"method":       WriteRequestLine(b, []byte("GE T"), []byte("/")),
+		"empty method": WriteRequestLine(b, nil, []byte("/")),
+		"target":       WriteRequestLine(b, []byte("GET"), []byte("/a b")),
+		"empty target": WriteRequestLine(b, []byte("GET"), nil),
+		"code":         WriteStatusLine(b, 99, []byte("OK")),
+		"reason":       WriteStatusLine(b, 200, []byte("O\r\nK")),
+		"name":         WriteHeader(b, []byte("X:Y"), []byte("v")),
+		"value":        WriteHeader(b, []byte("X"), []byte("a\r\nInjected: 1")),
+		"nul":          WriteHeader(b, []byte("X"), []byte("a\x00")),
+		"length":       EndHead(b, -3),
+	} {
+		if !errors.Is(err, ErrInvalidHead) {
+			t.Errorf("%s: got %v, want ErrInvalidHead", name, err)
+		}
+	}
+	if b.Len() != 0 {
+		t.Errorf("invalid heads wrote %q", b.String())
+	}
+}
+
+func TestBodyAllowed(t *testing.T) {
+	for _, c := range []struct {
+		method string
+		code   int
+		want   bool
+	}{
+		{"GET", 200, true},
+		{"HEAD", 200, false},
+		{"GET", 101, false},
+		{"GET", 204, false},
+		{"GET", 304, false},
+		{"CONNECT", 200, false},
+		{"CONNECT", 407, true},
+	} {
+		if got := BodyAllowed(c.method, c.code); got != c.want {
+			t.Errorf("BodyAllowed(%s, %d) = %v", c.method, c.code, got)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNeedMore is returned by HeadParser while the unread bytes don't hold
+// a complete head yet, read more, e.g. with ReadOnce, and parse again.
+var ErrNeedMore = errors.New("io buffer: need more data")
+
+// DefaultMaxHeadSize is the head size limit of a HeadParser without
+// MaxSize.
+const DefaultMaxHeadSize = 64 << 10
+
+// Header is a header field of a Head.
+type Header struct {
+	Key   []byte
+	Value []byte
+}
+
+// Head is a parsed HTTP/1.x request or response head. Its slices alias
+// the unread bytes of the parsed buffer and are valid until the next Drain
+// or write to it.
+type Head struct {
+	// Method and Target are set for requests, Code and Reason for
+	// responses.
+	Method []byte
+	Target []byte
+	Code   int
+	Reason []byte
+	// Proto is the protocol version, such as "HTTP/1.1".
+	Proto []byte
+	// Headers is reused by the next parse into the Head.
+	Headers []Header
+	// Len is the size of the head including the empty line ending it, the
+	// body starts after Drain(Len).
+	Len int
+}
+
+// Get returns the value of the first header named key, compared ignoring
+// case, or nil.
+func (h *Head) Get(key string) []byte {
+	for _, f := range h.Headers {
+		if equalFoldASCII(f.Key, key) {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// HeadParser parses HTTP/1.x heads from the unread bytes of an IoBuffer
+// without copying or consuming them. It remembers how far it has looked
+// for the end of an incomplete head, so parsing again after each read
+// doesn't rescan the bytes:
+//
+//	for {
+//		err := p.ParseRequest(b, &h)
+//		if err != buffer.ErrNeedMore {
+//			...
+//		}
+//		if _, err := b.ReadOnce(conn, timeout); err != nil {
+//			...
+//		}
+//	}
+//
+// Lines may end with CRLF or LF, folded header lines are rejected.
+type HeadParser struct {
+	// MaxSize is the limit of a head, larger heads fail with ErrTooLarge.
+	// 0 means DefaultMaxHeadSize.
+	MaxSize int
+
+	scanned int
+}
+
+// Reset forgets the progress of an incomplete head, call it before parsing
+// a different buffer.
+func (p *HeadParser) Reset() {
+	p.scanned = 0
+}
+
+// ParseRequest parses the request head at the start of the unread bytes
+// of b into h.
+func (p *HeadParser) ParseRequest(b IoBuffer, h *Head) error {
+	return p.parse(b, h, true)
+}
+
+// ParseResponse parses the response head at the start of the unread bytes
+// of b into h.
+func (p *HeadParser) ParseResponse(b IoBuffer, h *Head) error {
+	return p.parse(b, h, false)
+}
+
+func (p *HeadParser) parse(b IoBuffer, h *Head, request bool) error {
+	data := b.Bytes()
+	// empty lines before a request line are ignored
+	start := 0
+	for request && start < len(data) && (data[start] == '\r' || data[start] == '\n') {
+		start++
+	}
+	end := headEnd(data, start, p.scanned)
+	max := p.MaxSize
+	if max <= 0 {
+		max = DefaultMaxHeadSize
+	}
+	if end > max || end < 0 && len(data) > max {
+		p.scanned = 0
+		return opError("parse head", max, b, ErrTooLarge)
+	}
+	if end < 0 {
+		p.scanned = len(data)
+		return ErrNeedMore
+	}
+	p.scanned = 0
+
+	line, rest := nextLine(data[start:end])
+	var ok bool
+	if request {
+		ok = parseRequestLine(line, h)
+	} else {
+		ok = parseStatusLine(line, h)
+	}
+	if !ok {
+		return opError("parse head", 0, b, ErrInvalidHead)
+	}
+	h.Headers = h.Headers[:0]
+	for {
+		line, rest = nextLine(rest)
+		if len(line) == 0 {
+			break
+		}
+		i := bytes.IndexByte(line, ':')
+		if i < 0 || !isToken(line[:i]) {
+			return opError("parse head", 0, b, ErrInvalidHead)
+		}
+		v := trimBlanks(line[i+1:])
+		if !isFieldText(v) {
+			return opError("parse head", 0, b, ErrInvalidHead)
+		}
+		h.Headers = append(h.Headers, Header{Key: line[:i], Value: v})
+	}
+	h.Len = end
+	return nil
+}
+
+// headEnd returns the offset after the empty line ending the head starting
+// at start of data, or -1. The search resumes close to scanned.
+func headEnd(data []byte, start, scanned int) int {
+	i := scanned - 2
+	if i < start {
+		i = start
+	}
+	for {
+		j := bytes.IndexByte(data[i:], '\n')
+		if j < 0 {
+			return -1
+		}
+		i += j + 1
+		switch {
+		case i < len(data) && data[i] == '\n':
+			return i + 1
+		case i+1 < len(data) && data[i] == '\r' && data[i+1] == '\n':
+			return i + 2
+		}
+	}
+}
+
+// nextLine splits the first line of p, without line break, from the rest
+func nextLine(p []byte) (line, rest []byte) {
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		return p, nil
+	}
+	line, rest = p[:i], p[i+1:]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line, rest
+}
+
+func parseRequestLine(line []byte, h *Head) bool {
+	i := bytes.IndexByte(line, ' ')
+	if i < 0 {
+		return false
+	}
+	j := bytes.IndexByte(line[i+1:], ' ')
+	if j < 0 {
+		return false
+	}
+	h.Method, h.Target, h.Proto = line[:i], line[i+1:i+1+j], line[i+2+j:]
+	h.Code, h.Reason = 0, nil
+	if !isToken(h.Method) || len(h.Target) == 0 || !isHTTPVersion(h.Proto) {
+		return false
+	}
+	for _, c := range h.Target {
+		if c <= ' ' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func parseStatusLine(line []byte, h *Head) bool {
+	if len(line) < 12 || line[8] != ' ' || len(line) > 12 && line[12] != ' ' {
+		return false
+	}
+	h.Method, h.Target, h.Proto = nil, nil, line[:8]
+	h.Code = 0
+	for _, c := range line[9:12] {
+		if c < '0' || c > '9' {
+			return false
+		}
+		h.Code = h.Code*10 + int(c-'0')
+	}
+	h.Reason = nil
+	if len(line) > 12 {
+		h.Reason = line[13:]
+	}
+	return isHTTPVersion(h.Proto) && h.Code >= 100 && isFieldText(h.Reason)
+}
+
+// isHTTPVersion reports whether p is "HTTP/d.d"
+func isHTTPVersion(p []byte) bool {
+	return len(p) == 8 && string(p[:5]) == "HTTP/" &&
+		'0' <= p[5] && p[5] <= '9' && p[6] == '.' && '0' <= p[7] && p[7] <= '9'
+}
@@ -0,0 +1,130 @@
+package buffer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestHeadParserRequest(t *testing.T) {
+	raw := "\r\nPOST /upload?x=1 HTTP/1.1\r\nHost: example.com\r\nX-Trace:  abc def \r\ncontent-length: 5\r\n\r\nhelloGET"
+	r := iotest.OneByteReader(strings.NewReader(raw))
+	b := NewIoBuffer(0)
+	var (
+		p     HeadParser
+		h     Head
+		reads int
+	)
+	for {
+		err := p.ParseRequest(b, &h)
+		if err == nil {
+			break
+		}
+		if err != ErrNeedMore {
+			t.Fatal(err)
+		}
+		if _, err := b.ReadOnce(r, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		reads++
+	}
+	if reads != strings.Index(raw, "hello") {
+		t.Errorf("parsed after %d bytes", reads)
+	}
+	if string(h.Method) != "POST" || string(h.Target) != "/upload?x=1" || string(h.Proto) != "HTTP/1.1" {
+		t.Errorf("unexpected request line %q %q %q", h.Method, h.Target, h.Proto)
+	}
+	if len(h.Headers) != 3 || string(h.Get("host")) != "example.com" ||
+		string(h.Get("X-TRACE")) != "abc def" || string(h.Get("Content-Length")) != "5" || h.Get("Accept") != nil {
+		t.Errorf("unexpected headers %q", h.Headers)
+	}
+	b.Drain(h.Len)
+	b.ReadFrom(r)
+	if b.String() != "helloGET" {
+		t.Errorf("body %q", b.String())
+	}
+}
+
+func TestHeadParserResponse(t *testing.T) {
+	var (
+		p HeadParser
+		h Head
+	)
+	for _, c := range []struct {
+		raw    string
+		code   int
+		reason string
+	}{
+		{"HTTP/1.1 200 OK\r\nServer: x\r\n\r\n", 200, "OK"},
+		{"HTTP/1.0 404 Not Found\n\n", 404, "Not Found"},
+		{"HTTP/1.1 204\r\n\r\n", 204, ""},
+		{"HTTP/1.1 304 \r\n\r\n", 304, ""},
+	} {
+		b := NewIoBufferString(c.raw)
+		if err := p.ParseResponse(b, &h); err != nil {
+			t.Errorf("%q: %v", c.raw, err)
+			continue
+		}
+		if h.Code != c.code || string(h.Reason) != c.reason || h.Len != len(c.raw) {
+			t.Errorf("%q: got %d %q len %d", c.raw, h.Code, h.Reason, h.Len)
+		}
+	}
+}
+
+func TestHeadParserInvalid(t *testing.T) {
+	var (
+		p HeadParser
+		h Head
+	)
+	for _, raw := range []string{
+		"GET /\r\n\r\n",
+		"GET  / HTTP/1.1\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost : x\r\n\r\n",
+		"GET / HTTP/1.1\r\nNo-Colon\r\n\r\n",
+		"GET / HTTP/1.1\r\nX: a\r\n folded\r\n\r\n",
+		"GET / HTTP/1.1\r\nX: a\x00b\r\n\r\n",
+		"GET / HTTP/2\r\n\r\n",
+	} {
+		if err := p.ParseRequest(NewIoBufferString(raw), &h); !errors.Is(err, ErrInvalidHead) {
+			t.Errorf("%q: got %v, want ErrInvalidHead", raw, err)
+		}
+	}
+	for _, raw := range []string{
+		"HTTP/1.1 20 OK\r\n\r\n",
+		"HTTP/1.1 2000 OK\r\n\r\n",
+		"HTTP/1.1 099 X\r\n\r\n",
+		"HTTP/1.1 200 O\x01K\r\n\r\n",
+	} {
+		if err := p.ParseResponse(NewIoBufferString(raw), &h); !errors.Is(err, ErrInvalidHead) {
+			t.Errorf("%q: got %v, want ErrInvalidHead", raw, err)
+		}
+	}
+
+	p.MaxSize = 32
+	b := NewIoBufferString("GET / HTTP/1.1\r\nX-Long: " + strings.Repeat("a", 16))
+	if err := p.ParseRequest(b, &h); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+	b = NewIoBufferString("GET / HTTP/1.1\r\nX: " + strings.Repeat("a", 16) + "\r\n\r\n")
+	if err := p.ParseRequest(b, &h); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
+
+func BenchmarkHeadParser(b *testing.B) {
+	raw := "GET /index.html HTTP/1.1\r\nHost: example.com\r\nUser-Agent: bench\r\nAccept: */*\r\n\r\n"
+	buf := NewIoBufferString(raw)
+	var (
+		p HeadParser
+		h Head
+	)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	for i := 0; i < b.N; i++ {
+		if err := p.ParseRequest(buf, &h); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
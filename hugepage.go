@@ -0,0 +1,13 @@
+package buffer
+
+// newHugePageBytes returns a slice of len size aligned to and rounded up to
+// whole huge pages, advised to be backed by transparent huge pages.
+//
+// The memory comes from the Go heap rather than a MAP_HUGETLB mapping, so
+// slices dropped by the pool are still collected, and it degrades to plain
+// pages when the kernel has no huge pages to spare.
+func newHugePageBytes(size int) []byte {
+	b := newAlignedBytes(size, hugePageSize)
+	adviseHugePages(b)
+	return b
+}
@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package buffer
+
+import "syscall"
+
+// hugePageSize is the size of a transparent huge page on x86-64 and arm64
+// with 4K base pages
+const hugePageSize = 2 << 20
+
+// adviseHugePages asks the kernel to back b with transparent huge pages,
+// failures such as THP being disabled are ignored
+func adviseHugePages(b []byte) {
+	if cap(b) == 0 {
+		return
+	}
+	syscall.Madvise(b[:cap(b)], syscall.MADV_HUGEPAGE)
+}
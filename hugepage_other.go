@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package buffer
+
+// hugePageSize only aligns slices on platforms without madvise(MADV_HUGEPAGE)
+const hugePageSize = 2 << 20
+
+func adviseHugePages(b []byte) {}
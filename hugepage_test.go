@@ -0,0 +1,33 @@
+package buffer
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestHugePages(t *testing.T) {
+	hugeAligned := func(b []byte) bool {
+		return uintptr(unsafe.Pointer(&b[0]))%hugePageSize == 0 && cap(b)%hugePageSize == 0
+	}
+
+	p := NewNamedPool("test-huge", WithHugePages(1<<20), WithSizeClasses(64, 4<<20))
+	for _, size := range []int{1 << 20, 3 << 20, 5 << 20} {
+		b := p.Get(size)
+		if len(*b) != size || !hugeAligned(*b) {
+			t.Errorf("Expect a huge page aligned slice of %d bytes, but got len %d cap %d", size, len(*b), cap(*b))
+		}
+		p.Put(b)
+	}
+	if b := p.Get(1000); cap(*b) != 1024 {
+		t.Errorf("Expect small slices unaffected, but got cap %d", cap(*b))
+	}
+
+	SetHugePageThreshold(2 << 20)
+	defer SetHugePageThreshold(0)
+	b := GetBytes(3 << 20)
+	if !hugeAligned(*b) {
+		t.Errorf("Expect a huge page aligned slice from GetBytes, but got cap %d", cap(*b))
+	}
+	(*b)[len(*b)-1] = 1
+	PutBytes(b)
+}
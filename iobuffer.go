@@ -1,10 +1,12 @@
 package buffer
 
 import (
+	"context"
 	"errors"
 	"github.com/gottingen/atomic"
 	"io"
 	"net"
+	"runtime/trace"
 	"time"
 )
 
@@ -13,6 +15,8 @@ const MaxRead = 1 << 17
 const ResetOffMark = -1
 const DefaultSize = 1 << 4
 
+const maxInt = int(^uint(0) >> 1)
+
 var nullByte []byte
 
 var (
@@ -20,6 +24,10 @@ var (
 	ErrTooLarge          = errors.New("io buffer: too large")
 	ErrNegativeCount     = errors.New("io buffer: negative count")
 	ErrInvalidWriteCount = errors.New("io buffer: invalid write count")
+	ErrClosedBuffer      = errors.New("io buffer: closed")
+	ErrLimitExceeded     = errors.New("io buffer: read limit exceeded")
+	ErrUnreadRune        = errors.New("io buffer: previous operation was not a successful ReadRune")
+	ErrOutOfRange        = errors.New("io buffer: offset out of range")
 )
 
 // ioBuffer
@@ -28,14 +36,56 @@ type ioBuffer struct {
 	off     int    // read from &buf[off], write to &buf[len(buf)]
 	offMark int
 	count   *atomic.Int32
+	// times the buffer has been taken from a pool, see IoBufferGeneration
+	gen     atomic.Uint32
 	eof     bool
-
-	b *[]byte
+	autoEOF bool // set eof when ReadFrom or ReadOnce hits io.EOF
+	closed  bool
+	tee     IoBuffer // receives the bytes consumed from the buffer
+	hooks   *Hooks
+	stats   BufferStats
+
+	// bytes consumed or dropped before off, see consumedBytes
+	dropped int64
+
+	// size of the rune returned by ReadRune and the offset after it, so
+	// that UnreadRune can tell whether the buffer moved since
+	lastRune int
+	runeOff  int
+
+	// set by the options of New
+	maxSize     int // caps Len, 0 means no cap
+	growth      GrowthStrategy
+	zeroOnFree  bool
+	resetPolicy ResetPolicy
+
+	// buf is external memory that can't be replaced, like a file mapping,
+	// the buffer slides its contents within it instead of growing
+	fixed bool
+
+	b  *[]byte
+	bp *byteBufferPool // nil means the package level byte pool
+	// segment readv reads into past the free space, see readInto, given
+	// back whenever the buffer is emptied
+	spare *[]byte
+	// set by WithBytePool, overrides bp
+	pool BytePool
+	// set by WithBudget, charged is the size of b charged against it
+	budget  *Budget
+	charged int
+
+	// lifecycle task between taking and putting back the buffer, see
+	// SetLifecycleTracing
+	task    *trace.Task
+	taskCtx context.Context
 }
 
 func (b *ioBuffer) Read(p []byte) (n int, err error) {
+	if b.closed {
+		return 0, opError("read", len(p), b, ErrClosedBuffer)
+	}
 	if b.off >= len(b.buf) {
-		b.Reset()
+		b.reset()
 
 		if len(p) == 0 {
 			return
@@ -45,7 +95,9 @@ func (b *ioBuffer) Read(p []byte) (n int, err error) {
 	}
 
 	n = copy(p, b.buf[b.off:])
+	b.teeBytes(p[:n])
 	b.off += n
+	b.stats.BytesRead += int64(n)
 
 	return
 }
@@ -58,15 +110,19 @@ func (b *ioBuffer) ReadOnce(r io.Reader, duration time.Duration) (n int64, e err
 		loop, ok, first = true, true, true
 	)
 
+	if b.closed {
+		return 0, opError("read", 0, b, ErrClosedBuffer)
+	}
+
 	if conn, ok = r.(net.Conn); !ok {
 		loop = false
 	}
 
 	if b.off >= len(b.buf) {
-		b.Reset()
+		b.reset()
 	}
 
-	if b.off > 0 && len(b.buf)-b.off < 4*MinRead {
+	if b.slideBeforeRead() {
 		b.copy(0)
 	}
 
@@ -90,6 +146,15 @@ func (b *ioBuffer) ReadOnce(r io.Reader, duration time.Duration) (n int64, e err
 
 		l := cap(b.buf) - len(b.buf)
 
+		limit := 0
+		if room := b.room(); room == 0 {
+			err := opError("read", 0, b, b.overflow(1))
+			b.allocFailed(1, err)
+			return n, err
+		} else if room > 0 {
+			limit = room
+		}
+
 		if conn != nil {
 			if first {
 				// TODO: support configure
@@ -98,32 +163,36 @@ func (b *ioBuffer) ReadOnce(r io.Reader, duration time.Duration) (n int64, e err
 				conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
 			}
 
-			m, e = r.Read(b.buf[len(b.buf):cap(b.buf)])
+			m, e = b.readInto(r, limit)
 
 			// Reset read deadline
 			conn.SetReadDeadline(zeroTime)
 
 		} else {
-			m, e = r.Read(b.buf[len(b.buf):cap(b.buf)])
+			m, e = b.readInto(r, limit)
 		}
 
 		if m > 0 {
-			b.buf = b.buf[0 : len(b.buf)+m]
 			n += int64(m)
+			b.wrote(m)
 		}
 
 		if e != nil {
+			if e == io.EOF && b.autoEOF {
+				b.eof = true
+			}
 			if te, ok := e.(net.Error); ok && te.Timeout() && !first {
 				return n, nil
 			}
-			return n, e
+			return n, opError("read", 0, b, e)
 		}
 
-		if l != m {
+		// readv may read past the free space into a spare segment
+		if m < l {
 			loop = false
 		}
 
-		if n > MaxRead {
+		if n > MaxRead || b.room() == 0 {
 			loop = false
 		}
 
@@ -138,8 +207,33 @@ func (b *ioBuffer) ReadOnce(r io.Reader, duration time.Duration) (n int64, e err
 }
 
 func (b *ioBuffer) ReadFrom(r io.Reader) (n int64, err error) {
+	return b.readFrom(r, -1, nil)
+}
+
+func (b *ioBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	if max < 0 {
+		panic(opError("read", int(max), b, ErrNegativeCount))
+	}
+	return b.readFrom(r, max, nil)
+}
+
+// readFrom reads r until io.EOF or until more than max bytes arrived,
+// max < 0 means no limit. progress is called with the total number of bytes
+// read after every read.
+func (b *ioBuffer) readFrom(r io.Reader, max int64, progress func(int64)) (n int64, err error) {
+	if b.closed {
+		return 0, opError("read", 0, b, ErrClosedBuffer)
+	}
 	if b.off >= len(b.buf) {
-		b.Reset()
+		b.reset()
+	}
+
+	// the max size and the budget cap reads like max, overflowing them is
+	// ErrTooLarge and ErrBudgetExceeded
+	limitErr := ErrLimitExceeded
+	if room := int64(b.room()); room >= 0 && (max < 0 || room < max) {
+		max = room
+		limitErr = b.overflow(int(room) + 1)
 	}
 
 	for {
@@ -154,12 +248,38 @@ func (b *ioBuffer) ReadFrom(r io.Reader) (n int64, err error) {
 			}
 		}
 
-		m, e := r.Read(b.buf[len(b.buf):cap(b.buf)])
+		limit := 0
+		if max >= 0 {
+			// one byte past max tells whether r has more
+			if rest := max + 1 - n; rest < int64(maxInt) {
+				limit = int(rest)
+			}
+		}
+		m, e := b.readInto(r, limit)
+
+		if max >= 0 && n+int64(m) > max {
+			over := int(n + int64(m) - max)
+			b.buf = b.buf[:len(b.buf)-over]
+			m -= over
+			n += int64(m)
+			b.wrote(m)
+			err := opError("read", int(max), b, limitErr)
+			if limitErr != ErrLimitExceeded {
+				b.allocFailed(1, err)
+			}
+			return n, err
+		}
 
-		b.buf = b.buf[0 : len(b.buf)+m]
 		n += int64(m)
+		b.wrote(m)
+		if progress != nil && m > 0 {
+			progress(n)
+		}
 
 		if e == io.EOF {
+			if b.autoEOF {
+				b.eof = true
+			}
 			break
 		}
 
@@ -168,31 +288,101 @@ func (b *ioBuffer) ReadFrom(r io.Reader) (n int64, err error) {
 		}
 
 		if e != nil {
-			return n, e
+			return n, opError("read", 0, b, e)
 		}
 	}
 
 	return
 }
 
+// readPlain reads at most max bytes from r into the free space at the end of
+// the buffer, max <= 0 means no limit
+func (b *ioBuffer) readPlain(r io.Reader, max int) (int, error) {
+	p := b.buf[len(b.buf):cap(b.buf)]
+	if max > 0 && len(p) > max {
+		p = p[:max]
+	}
+	m, e := r.Read(p)
+	if m > 0 {
+		b.buf = b.buf[0 : len(b.buf)+m]
+	}
+	return m, e
+}
+
 func (b *ioBuffer) Write(p []byte) (n int, err error) {
+	if b.closed {
+		return 0, opError("write", len(p), b, ErrClosedBuffer)
+	}
+	if err := b.checkGrow("write", len(p)); err != nil {
+		return 0, err
+	}
 	m, ok := b.tryGrowByReslice(len(p))
 
 	if !ok {
 		m = b.grow(len(p))
 	}
 
-	return copy(b.buf[m:], p), nil
+	n = copy(b.buf[m:], p)
+	b.wrote(n)
+
+	return n, nil
 }
 
 func (b *ioBuffer) WriteString(s string) (n int, err error) {
+	if b.closed {
+		return 0, opError("write", len(s), b, ErrClosedBuffer)
+	}
+	if err := b.checkGrow("write", len(s)); err != nil {
+		return 0, err
+	}
 	m, ok := b.tryGrowByReslice(len(s))
 
 	if !ok {
 		m = b.grow(len(s))
 	}
 
-	return copy(b.buf[m:], s), nil
+	n = copy(b.buf[m:], s)
+	b.wrote(n)
+
+	return n, nil
+}
+
+// fits reports whether n more bytes fit under the max size and the budget
+func (b *ioBuffer) fits(n int) bool {
+	return b.overflow(n) == nil
+}
+
+// overflow returns the error of n more bytes not fitting under the max size
+// or the budget, nil if they fit
+func (b *ioBuffer) overflow(n int) error {
+	if b.maxSize > 0 && n > b.maxSize-b.Len() {
+		return ErrTooLarge
+	}
+	if b.budget != nil && n > b.budgetCap()-b.Len() {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// room returns how many more bytes fit under the max size and the budget,
+// -1 means no cap
+func (b *ioBuffer) room() int {
+	if b.maxSize <= 0 && b.budget == nil {
+		return -1
+	}
+	r := maxInt
+	if b.maxSize > 0 {
+		r = b.maxSize - b.Len()
+	}
+	if b.budget != nil {
+		if br := b.budgetCap() - b.Len(); br < r {
+			r = br
+		}
+	}
+	if r > 0 {
+		return r
+	}
+	return 0
 }
 
 func (b *ioBuffer) tryGrowByReslice(n int) (int, bool) {
@@ -210,7 +400,7 @@ func (b *ioBuffer) grow(n int) int {
 
 	// If buffer is empty, reset to recover space.
 	if m == 0 && b.off != 0 {
-		b.Reset()
+		b.reset()
 	}
 
 	// Try to grow by means of a reslice.
@@ -218,17 +408,21 @@ func (b *ioBuffer) grow(n int) int {
 		return i
 	}
 
-	if m+n <= cap(b.buf)/2 {
+	if b.slideToGrow(m, n) {
 		// We can slide things down instead of allocating a new
 		// slice. We only need m+n <= cap(b.buf) to slide, but
-		// we instead let capacity get twice as large so we
-		// don't spend all our time copying.
+		// by default we instead let capacity get twice as large
+		// so we don't spend all our time copying, see SetCompaction.
 		b.copy(0)
 	} else {
 		// Not enough space anywhere, we need to allocate.
 		b.copy(n)
 	}
 
+	if b.fixed && m+n > cap(b.buf) {
+		panic(opError("grow", n, b, ErrTooLarge))
+	}
+
 	// Restore b.off and len(b.buf).
 	b.off = 0
 	b.buf = b.buf[:m+n]
@@ -237,19 +431,24 @@ func (b *ioBuffer) grow(n int) int {
 }
 
 func (b *ioBuffer) WriteTo(w io.Writer) (n int64, err error) {
+	if b.closed {
+		return 0, opError("write to", 0, b, ErrClosedBuffer)
+	}
 	for b.off < len(b.buf) {
 		nBytes := b.Len()
 		m, e := w.Write(b.buf[b.off:])
 
 		if m > nBytes {
-			panic(ErrInvalidWriteCount)
+			panic(&Error{Op: "write to", Size: m, Len: nBytes, Cap: b.Cap(), Err: ErrInvalidWriteCount})
 		}
 
+		b.teeBytes(b.buf[b.off : b.off+m])
 		b.off += m
 		n += int64(m)
+		b.stats.BytesRead += int64(m)
 
 		if e != nil {
-			return n, e
+			return n, opError("write to", 0, b, e)
 		}
 
 		if m == 0 || m == nBytes {
@@ -261,11 +460,17 @@ func (b *ioBuffer) WriteTo(w io.Writer) (n int64, err error) {
 }
 
 func (b *ioBuffer) Append(data []byte) error {
+	if b.closed {
+		return opError("append", len(data), b, ErrClosedBuffer)
+	}
 	if b.off >= len(b.buf) {
-		b.Reset()
+		b.reset()
 	}
 
 	dataLen := len(data)
+	if err := b.checkGrow("append", dataLen); err != nil {
+		return err
+	}
 
 	if free := cap(b.buf) - len(b.buf); free < dataLen {
 		// not enough space at end
@@ -280,6 +485,7 @@ func (b *ioBuffer) Append(data []byte) error {
 
 	m := copy(b.buf[len(b.buf):len(b.buf)+dataLen], data)
 	b.buf = b.buf[0 : len(b.buf)+m]
+	b.wrote(m)
 
 	return nil
 }
@@ -304,6 +510,7 @@ func (b *ioBuffer) Restore() {
 	if b.offMark != ResetOffMark {
 		b.off = b.offMark
 		b.offMark = ResetOffMark
+		b.lastRune = 0
 	}
 }
 
@@ -311,7 +518,56 @@ func (b *ioBuffer) Bytes() []byte {
 	return b.buf[b.off:]
 }
 
+func (b *ioBuffer) CopyBytes() []byte {
+	return append([]byte(nil), b.buf[b.off:]...)
+}
+
+func (b *ioBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	if b.closed {
+		return opError("replace", len(replacement), b, ErrClosedBuffer)
+	}
+	if from < 0 || from > to || to > b.Len() {
+		panic(opError("replace", to-from, b, ErrOutOfRange))
+	}
+
+	// slide the smaller side of the range, the unread bytes before it can
+	// move into the read space
+	d := len(replacement) - (to - from)
+	if d > 0 {
+		if err := b.checkGrow("replace", d); err != nil {
+			return err
+		}
+	}
+	head, tail := from, b.Len()-to
+	switch {
+	case d < 0 && head < tail:
+		copy(b.buf[b.off-d:], b.buf[b.off:b.off+from])
+		b.off -= d
+		b.dropped += int64(d)
+		b.offMark = ResetOffMark
+	case d < 0:
+		copy(b.buf[b.off+to+d:], b.buf[b.off+to:])
+		b.buf = b.buf[:len(b.buf)+d]
+	case d > 0 && head < tail && b.off >= d:
+		copy(b.buf[b.off-d:], b.buf[b.off:b.off+from])
+		b.off -= d
+		b.dropped += int64(d)
+		b.offMark = ResetOffMark
+	case d > 0:
+		if _, ok := b.tryGrowByReslice(d); !ok {
+			b.grow(d)
+		}
+		copy(b.buf[b.off+to+d:], b.buf[b.off+to:len(b.buf)-d])
+	}
+	copy(b.buf[b.off+from:], replacement)
+	b.lastRune = 0
+	return nil
+}
+
 func (b *ioBuffer) Cut(offset int) IoBuffer {
+	if offset < 0 {
+		panic(opError("cut", offset, b, ErrNegativeCount))
+	}
 	if b.off+offset > len(b.buf) {
 		return nil
 	}
@@ -319,7 +575,9 @@ func (b *ioBuffer) Cut(offset int) IoBuffer {
 	buf := make([]byte, offset)
 
 	copy(buf, b.buf[b.off:b.off+offset])
+	b.teeBytes(buf)
 	b.off += offset
+	b.stats.BytesRead += int64(offset)
 	b.offMark = ResetOffMark
 
 	return &ioBuffer{
@@ -329,18 +587,57 @@ func (b *ioBuffer) Cut(offset int) IoBuffer {
 }
 
 func (b *ioBuffer) Drain(offset int) {
+	if offset < 0 {
+		panic(opError("drain", offset, b, ErrNegativeCount))
+	}
 	if b.off+offset > len(b.buf) {
 		return
 	}
 
+	b.teeBytes(b.buf[b.off : b.off+offset])
 	b.off += offset
 	b.offMark = ResetOffMark
+	b.stats.BytesRead += int64(offset)
+}
+
+func (b *ioBuffer) DiscardAll() {
+	b.Drain(b.Len())
+}
+
+func (b *ioBuffer) DrainTo(w io.Writer, n int) (int, error) {
+	if n < 0 {
+		panic(opError("drain to", n, b, ErrNegativeCount))
+	}
+	if b.closed {
+		return 0, opError("drain to", n, b, ErrClosedBuffer)
+	}
+	if l := b.Len(); n > l {
+		n = l
+	}
+
+	p := b.buf[b.off : b.off+n]
+	m, err := w.Write(p)
+	if m > n {
+		panic(&Error{Op: "drain to", Size: m, Len: n, Cap: b.Cap(), Err: ErrInvalidWriteCount})
+	}
+	b.Drain(m)
+	if err != nil {
+		return m, opError("drain to", n, b, err)
+	}
+	if m < n {
+		return m, io.ErrShortWrite
+	}
+	return m, nil
 }
 
 func (b *ioBuffer) String() string {
 	return string(b.buf[b.off:])
 }
 
+func (b *ioBuffer) Dump(maxBytes int) string {
+	return dump(b.buf[b.off:], maxBytes)
+}
+
 func (b *ioBuffer) Len() int {
 	return len(b.buf) - b.off
 }
@@ -349,11 +646,27 @@ func (b *ioBuffer) Cap() int {
 	return cap(b.buf)
 }
 
+// Reset empties the buffer, then keeps, shrinks or releases its memory as
+// set by WithResetPolicy.
 func (b *ioBuffer) Reset() {
+	used := len(b.buf)
+	b.reset()
+	b.applyResetPolicy(used)
+}
+
+// reset empties the buffer keeping its memory, for internal use where the
+// buffer is about to be filled again
+func (b *ioBuffer) reset() {
+	b.dropped += int64(len(b.buf))
 	b.buf = b.buf[:0]
 	b.off = 0
 	b.offMark = ResetOffMark
 	b.eof = false
+	b.lastRune = 0
+	if b.spare != nil {
+		PutBytes(b.spare)
+		b.spare = nil
+	}
 }
 
 func (b *ioBuffer) available() int {
@@ -370,7 +683,10 @@ func (b *ioBuffer) Clone() IoBuffer {
 }
 
 func (b *ioBuffer) Free() {
-	b.Reset()
+	b.reset()
+	if b.b != nil {
+		b.onFree(cap(*b.b))
+	}
 	b.giveSlice()
 }
 
@@ -378,12 +694,33 @@ func (b *ioBuffer) Alloc(size int) {
 	if b.buf != nil {
 		b.Free()
 	}
+	b.pool = nil
+	b.budget = nil
+	b.hooks = nil
 	if size <= 0 {
 		size = DefaultSize
 	}
 	b.b = b.makeSlice(size)
 	b.buf = *b.b
 	b.buf = b.buf[:0]
+	b.stats = BufferStats{}
+	b.autoEOF = false
+	b.closed = false
+	b.tee = nil
+	b.maxSize = 0
+	b.growth = nil
+	b.zeroOnFree = false
+	b.resetPolicy = ResetKeep
+}
+
+func (b *ioBuffer) Close() error {
+	if b.closed {
+		return opError("close", 0, b, ErrClosedBuffer)
+	}
+	b.Free()
+	b.buf = nullByte
+	b.closed = true
+	return nil
 }
 
 func (b *ioBuffer) Count(count int32) int32 {
@@ -398,73 +735,189 @@ func (b *ioBuffer) SetEOF(eof bool) {
 	b.eof = eof
 }
 
+func (b *ioBuffer) SetAutoEOF(on bool) {
+	b.autoEOF = on
+}
+
+func (b *ioBuffer) Tee(dst IoBuffer) {
+	b.tee = dst
+}
+
+// teeBytes appends bytes about to be consumed to the tee buffer
+func (b *ioBuffer) teeBytes(p []byte) {
+	if b.tee != nil && len(p) > 0 {
+		b.tee.Write(p)
+	}
+}
+
+func (b *ioBuffer) ReadCloser() io.ReadCloser {
+	return &ioBufferReadCloser{b: b}
+}
+
+// ioBufferReadCloser reads an IoBuffer and puts it back to the pool on Close
+type ioBufferReadCloser struct {
+	b IoBuffer
+}
+
+func (rc *ioBufferReadCloser) Read(p []byte) (int, error) {
+	if rc.b == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return rc.b.Read(p)
+}
+
+func (rc *ioBufferReadCloser) WriteTo(w io.Writer) (int64, error) {
+	if rc.b == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return rc.b.WriteTo(w)
+}
+
+func (rc *ioBufferReadCloser) Close() error {
+	if rc.b == nil {
+		return nil
+	}
+	b := rc.b
+	rc.b = nil
+	return PutIoBuffer(b)
+}
+
 func (b *ioBuffer) copy(expand int) {
 	var newBuf []byte
 	var bufp *[]byte
 
-	if expand > 0 {
-		bufp = b.makeSlice(2*cap(b.buf) + expand)
+	if expand > 0 && !b.fixed {
+		oldCap := cap(b.buf)
+		if expand > maxInt-2*oldCap {
+			panic(opError("grow", expand, b, ErrTooLarge))
+		}
+		newCap := b.growCap(expand)
+		bufp = b.makeSlice(newCap)
 		newBuf = *bufp
 		copy(newBuf, b.buf[b.off:])
-		PutBytes(b.b)
+		b.putSlice(b.b)
 		b.b = bufp
+		b.charge(newCap)
+		b.stats.Grows++
+		b.onGrow(oldCap, cap(newBuf))
+		b.traceGrow(oldCap, cap(newBuf))
 	} else {
 		newBuf = b.buf
-		copy(newBuf, b.buf[b.off:])
+		n := copy(newBuf, b.buf[b.off:])
+		if b.off > 0 {
+			b.stats.CopiedBytes += int64(n)
+			b.onCopy(n)
+		}
 	}
 	b.buf = newBuf[:len(b.buf)-b.off]
+	b.dropped += int64(b.off)
 	b.off = 0
+	b.lastRune = 0
+}
+
+// growCap returns the capacity to grow to for expand more bytes
+func (b *ioBuffer) growCap(expand int) int {
+	oldCap := cap(b.buf)
+	newCap := 2*oldCap + expand
+	need := len(b.buf) - b.off + expand
+	if b.growth != nil {
+		newCap = b.growth(oldCap, need)
+		if newCap < need {
+			newCap = need
+		}
+	}
+	if b.maxSize > 0 && newCap > b.maxSize && need <= b.maxSize {
+		newCap = b.maxSize
+	}
+	if b.budget != nil {
+		if limit := b.budgetCap(); newCap > limit {
+			newCap = limit
+			if newCap < need {
+				newCap = need
+			}
+		}
+	}
+	return newCap
+}
+
+func (b *ioBuffer) bytePool() *byteBufferPool {
+	if b.bp != nil {
+		return b.bp
+	}
+	return bbPool
 }
 
 func (b *ioBuffer) makeSlice(n int) *[]byte {
-	return GetBytes(n)
+	if b.pool != nil {
+		return b.pool.Get(n)
+	}
+	return b.bytePool().take(n)
+}
+
+// putSlice hands p back to the pool, cleared if zeroOnFree is set
+func (b *ioBuffer) putSlice(p *[]byte) {
+	if p == nil {
+		return
+	}
+	if b.zeroOnFree {
+		zeroBytes((*p)[:cap(*p)])
+	}
+	if b.pool != nil {
+		b.pool.Put(p)
+		return
+	}
+	b.bytePool().give(p)
 }
 
 func (b *ioBuffer) giveSlice() {
 	if b.b != nil {
-		PutBytes(b.b)
+		b.putSlice(b.b)
 		b.b = nil
 		b.buf = nullByte
+		b.charge(0)
 	}
 }
 
+// NewIoBuffer returns an IoBuffer with the given initial capacity, it is
+// New(WithCapacity(capacity)).
 func NewIoBuffer(capacity int) IoBuffer {
+	return New(WithCapacity(capacity))
+}
+
+func newIoBuffer(capacity int, bp *byteBufferPool) IoBuffer {
 	buffer := &ioBuffer{
 		offMark: ResetOffMark,
 		count:   atomic.NewInt32(1),
+		bp:      bp,
 	}
 	if capacity <= 0 {
 		capacity = DefaultSize
 	}
-	buffer.b = GetBytes(capacity)
+	buffer.b = buffer.makeSlice(capacity)
 	buffer.buf = (*buffer.b)[:0]
 	return buffer
 }
 
+// NewIoBufferString returns an IoBuffer holding a copy of s.
 func NewIoBufferString(s string) IoBuffer {
 	if s == "" {
-		return NewIoBuffer(0)
-	}
-	return &ioBuffer{
-		buf:     []byte(s),
-		offMark: ResetOffMark,
-		count:   atomic.NewInt32(1),
+		return New()
 	}
+	return New(withBytes([]byte(s)))
 }
 
+// NewIoBufferBytes returns an IoBuffer holding bytes, which are used as is
+// and not returned to the pool.
 func NewIoBufferBytes(bytes []byte) IoBuffer {
 	if bytes == nil {
-		return NewIoBuffer(0)
-	}
-	return &ioBuffer{
-		buf:     bytes,
-		offMark: ResetOffMark,
-		count:   atomic.NewInt32(1),
+		return New()
 	}
+	return New(withBytes(bytes))
 }
 
+// NewIoBufferEOF returns an empty IoBuffer with the EOF flag set.
 func NewIoBufferEOF() IoBuffer {
-	buf := NewIoBuffer(0)
+	buf := New()
 	buf.SetEOF(true)
 	return buf
 }
@@ -3,49 +3,141 @@ package buffer
 
 import (
 	"sync"
+	"sync/atomic"
 	"errors"
 )
 
 var ibPool IoBufferPool
 
+// ErrDuplicatePut is returned by PutIoBuffer when the buffer is no longer
+// referenced, i.e. it has been put back already, and by
+// PutIoBufferGeneration when it has been taken again since.
+var ErrDuplicatePut = errors.New("PutIoBuffer duplicate")
+
 // IoBufferPool is Iobuffer Pool
 type IoBufferPool struct {
+	gets uint64
+	puts uint64
+
 	pool sync.Pool
+	// bp backs the pooled buffers, nil means the package level byte pool
+	bp *byteBufferPool
 }
 
 // take returns IoBuffer from IoBufferPool
 func (p *IoBufferPool) take(size int) (buf IoBuffer) {
-	v := p.pool.Get()
-	if v == nil {
-		buf = NewIoBuffer(size)
+	atomic.AddUint64(&p.gets, 1)
+	if poolingDisabled() {
+		buf = newIoBuffer(size, p.bp)
+	} else if v := p.pool.Get(); v == nil {
+		buf = newIoBuffer(size, p.bp)
 	} else {
 		buf = v.(IoBuffer)
+		// the buffer may have been created by New(WithPool) and put back
+		// here, it takes its memory from this pool from now on
+		buf.(*ioBuffer).bp = p.bp
 		buf.Alloc(size)
 		buf.Count(1)
 	}
+	buf.(*ioBuffer).gen.Inc()
+	if lifecycleTracingEnabled() {
+		if b, ok := buf.(*ioBuffer); ok {
+			b.startTask(size)
+		}
+	}
 	return
 }
 
 // give returns IoBuffer to IoBufferPool
 func (p *IoBufferPool) give(buf IoBuffer) {
+	atomic.AddUint64(&p.puts, 1)
+	if b, ok := buf.(*ioBuffer); ok {
+		b.endTask()
+	}
+	if poolingDisabled() {
+		return
+	}
 	buf.Free()
-	p.pool.Put(buf)
+	// only plain buffers are recycled, wrappers and views like the buffer
+	// returned by Limit just release what they hold
+	if _, ok := buf.(*ioBuffer); ok {
+		p.pool.Put(buf)
+	}
 }
 
-// GetIoBuffer returns IoBuffer from pool
-func GetIoBuffer(size int) IoBuffer {
-	return ibPool.take(size)
+// home returns the IoBufferPool of the named pool the memory of buf comes
+// from, so that PutIoBuffer of a buffer created by New(WithPool) returns it
+// to its own pool
+func (p *IoBufferPool) home(buf IoBuffer) *IoBufferPool {
+	b, ok := buf.(*ioBuffer)
+	if !ok || b.bp == p.bp {
+		return p
+	}
+	if b.bp == nil || b.bp.ib == nil {
+		return &ibPool
+	}
+	return b.bp.ib
 }
 
-// PutIoBuffer returns IoBuffer to pool
-func PutIoBuffer(buf IoBuffer) error {
+// put drops a reference of IoBuffer and gives it back once unreferenced
+func (p *IoBufferPool) put(buf IoBuffer) error {
 	count := buf.Count(-1)
 	if count > 0 {
 		return nil
 	} else if count < 0 {
-		return errors.New("PutIoBuffer duplicate")
+		// undo, so the pooled buffer is handed out with a sane count again
+		buf.Count(1)
+		return duplicatePut(buf)
 	}
-	ibPool.give(buf)
+	p.home(buf).give(buf)
 	return nil
 }
 
+// putGeneration is put failing if buf has been taken again since it was
+// taken as generation gen
+func (p *IoBufferPool) putGeneration(buf IoBuffer, gen uint32) error {
+	if b, ok := buf.(*ioBuffer); ok && b.gen.Load() != gen {
+		return duplicatePut(buf)
+	}
+	return p.put(buf)
+}
+
+func duplicatePut(buf IoBuffer) error {
+	if debugEnabled() {
+		panic("buffer: " + ErrDuplicatePut.Error())
+	}
+	return opError("put", 0, buf, ErrDuplicatePut)
+}
+
+// GetIoBuffer returns IoBuffer from pool
+func GetIoBuffer(size int) IoBuffer {
+	return ibPool.take(size)
+}
+
+// PutIoBuffer returns IoBuffer to pool
+//
+// A second put is only detected while the buffer sits in the pool. Once it
+// has been taken again, a second put by the former owner releases the
+// buffer of the new owner, see PutIoBufferGeneration.
+func PutIoBuffer(buf IoBuffer) error {
+	return ibPool.put(buf)
+}
+
+// IoBufferGeneration returns how many times buf has been taken from a pool,
+// 0 for buffers not taken from one. Owners read it after GetIoBuffer and
+// hand it to PutIoBufferGeneration.
+func IoBufferGeneration(buf IoBuffer) uint32 {
+	if b, ok := buf.(*ioBuffer); ok {
+		return b.gen.Load()
+	}
+	return 0
+}
+
+// PutIoBufferGeneration is PutIoBuffer failing with ErrDuplicatePut, or
+// panicking in debug mode, when buf has been taken again since the owner
+// read gen, i.e. when the owner puts it back a second time after someone
+// else took it.
+func PutIoBufferGeneration(buf IoBuffer, gen uint32) error {
+	return ibPool.putGeneration(buf, gen)
+}
+
@@ -3,7 +3,9 @@ package buffer
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"testing"
 	"time"
@@ -47,6 +49,65 @@ func TestNewIoBufferBytes(t *testing.T) {
 	}
 }
 
+func TestIoBufferCopyBytes(t *testing.T) {
+	b := NewIoBufferString("hello world")
+	b.Drain(6)
+	p := b.CopyBytes()
+	b.Reset()
+	b.WriteString("xxxxxxxxxxx")
+	if string(p) != "world" {
+		t.Errorf("Expect world, but got %q", p)
+	}
+
+	m := MultiIoBuffer(NewIoBufferString("ab"), NewIoBufferString("cd"))
+	if p := m.CopyBytes(); string(p) != "abcd" || m.Len() != 4 {
+		t.Errorf("Expect abcd, but got %q", p)
+	}
+	if p := m.Limit(3).CopyBytes(); string(p) != "abc" {
+		t.Errorf("Expect abc, but got %q", p)
+	}
+}
+
+func TestIoBufferReplaceRange(t *testing.T) {
+	for i := 0; i < 500; i++ {
+		prefix := randString(rand.Intn(40))
+		s := randString(rand.Intn(100))
+		from := rand.Intn(len(s) + 1)
+		to := from + rand.Intn(len(s)-from+1)
+		repl := randString(rand.Intn(30))
+
+		b := NewIoBufferString(prefix + s)
+		b.Drain(len(prefix))
+		if err := b.ReplaceRange(from, to, []byte(repl)); err != nil {
+			t.Fatal(err)
+		}
+		if want := s[:from] + repl + s[to:]; b.String() != want {
+			t.Fatalf("ReplaceRange(%d, %d, %q) on %q: Expect %q, but got %q", from, to, repl, s, want, b.String())
+		}
+	}
+
+	// shrinking near the front slides the head
+	b := NewIoBufferString("ab" + randString(100)).(*ioBuffer)
+	b.ReplaceRange(1, 2, nil)
+	if b.off != 1 || b.String()[0] != 'a' {
+		t.Errorf("Expect the head to slide, but got off %d", b.off)
+	}
+
+	m := MultiIoBuffer(NewIoBufferString("hello "), NewIoBufferString("world"))
+	if err := m.ReplaceRange(4, 7, []byte("-W")); err != nil || m.String() != "hell-World" {
+		t.Errorf("unexpected multi buffer result: %q, %v", m.String(), err)
+	}
+	if err := NewIoBufferString("hello").Limit(2).ReplaceRange(0, 1, nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly, but got %v", err)
+	}
+	if err := New(WithMaxSize(4)).ReplaceRange(0, 0, []byte("12345")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expect ErrTooLarge, but got %v", err)
+	}
+	expectPanic(t, "ReplaceRange out of range", func() {
+		NewIoBufferString("abc").ReplaceRange(2, 4, nil)
+	})
+}
+
 func TestIoBufferCopy(t *testing.T) {
 	bi := NewIoBuffer(1)
 	b := bi.(*ioBuffer)
@@ -315,3 +376,133 @@ func TestIoBufferZero(t *testing.T) {
 		t.Errorf("Expect 0, but got %d", len(b.Bytes()))
 	}
 }
+
+func TestIoBufferReadCloser(t *testing.T) {
+	b := GetIoBuffer(0)
+	b.WriteString("hello world")
+
+	rc := b.ReadCloser()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("Expect hello world, but got %s", string(data))
+	}
+
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Errorf("Expect second close to be a no-op, but got %v", err)
+	}
+	if _, err := rc.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Expect read after close to fail")
+	}
+}
+
+func TestIoBufferAutoEOF(t *testing.T) {
+	b := NewIoBuffer(0)
+	if _, err := b.ReadFrom(bytes.NewReader([]byte("data"))); err != nil {
+		t.Fatal(err)
+	}
+	if b.EOF() {
+		t.Errorf("Expect EOF not to be set without SetAutoEOF")
+	}
+
+	b.SetAutoEOF(true)
+	if _, err := b.ReadFrom(bytes.NewReader([]byte("data"))); err != nil {
+		t.Fatal(err)
+	}
+	if !b.EOF() {
+		t.Errorf("Expect EOF to be set by ReadFrom")
+	}
+
+	b = NewIoBuffer(0)
+	b.SetAutoEOF(true)
+	if _, err := b.ReadOnce(bytes.NewReader(nil), time.Second); err != io.EOF {
+		t.Fatalf("Expect io.EOF, but got %v", err)
+	}
+	if !b.EOF() {
+		t.Errorf("Expect EOF to be set by ReadOnce")
+	}
+
+	b.Alloc(0)
+	b.ReadFrom(bytes.NewReader(nil))
+	if b.EOF() {
+		t.Errorf("Expect Alloc to turn auto EOF off")
+	}
+}
+
+func TestIoBufferClose(t *testing.T) {
+	b := GetIoBuffer(0)
+	b.WriteString("data")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 || b.Cap() != 0 {
+		t.Errorf("Expect closed buffer to be empty, but got len %d, cap %d", b.Len(), b.Cap())
+	}
+
+	if _, err := b.Write([]byte("x")); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer from Write, but got %v", err)
+	}
+	if _, err := b.Read(make([]byte, 1)); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer from Read, but got %v", err)
+	}
+	if _, err := b.ReadFrom(bytes.NewReader([]byte("x"))); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer from ReadFrom, but got %v", err)
+	}
+	if _, err := b.WriteTo(ioutil.Discard); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer from WriteTo, but got %v", err)
+	}
+	if err := b.Close(); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer from second Close, but got %v", err)
+	}
+
+	b.Alloc(0)
+	if _, err := b.WriteString("reopened"); err != nil || b.String() != "reopened" {
+		t.Errorf("Expect Alloc to reopen the buffer, but got %v", err)
+	}
+	PutIoBuffer(b)
+}
+
+func TestIoBufferDrainTo(t *testing.T) {
+	b := NewIoBufferString("skipkeep")
+	var out bytes.Buffer
+	n, err := b.DrainTo(&out, 4)
+	if err != nil || n != 4 || out.String() != "skip" {
+		t.Fatalf("unexpected DrainTo result %d, %v, %q", n, err, out.String())
+	}
+	if b.String() != "keep" {
+		t.Errorf("Expect keep, but got %q", b.String())
+	}
+
+	n, err = b.DrainTo(ioutil.Discard, 100)
+	if err != nil || n != 4 || b.Len() != 0 {
+		t.Errorf("Expect DrainTo to be clamped to the unread bytes, but got %d, %v", n, err)
+	}
+
+	b.WriteString("dropped")
+	b.DiscardAll()
+	if b.Len() != 0 {
+		t.Errorf("Expect DiscardAll to drop everything, but got %q", b.String())
+	}
+}
+
+func TestIoBufferReadFromLimit(t *testing.T) {
+	b := NewIoBuffer(0)
+	n, err := b.ReadFromLimit(bytes.NewReader(make([]byte, 1000)), 1000)
+	if err != nil || n != 1000 || b.Len() != 1000 {
+		t.Fatalf("Expect 1000 bytes within the limit, but got %d, %v", n, err)
+	}
+
+	b = NewIoBuffer(0)
+	n, err = b.ReadFromLimit(bytes.NewReader(make([]byte, 5000)), 1000)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expect ErrLimitExceeded, but got %v", err)
+	}
+	if n != 1000 || b.Len() != 1000 {
+		t.Errorf("Expect 1000 bytes buffered, but got %d, %d", n, b.Len())
+	}
+}
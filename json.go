@@ -0,0 +1,130 @@
+package buffer
+
+import (
+	"encoding/json"
+)
+
+// JSONStringBuffer is a Buffer represented in JSON as a plain string
+// instead of base64, invalid UTF-8 is replaced by U+FFFD. Use it for struct
+// fields holding text.
+type JSONStringBuffer struct {
+	Buffer
+}
+
+// MarshalJSON encodes the unread bytes as a plain string.
+func (b *JSONStringBuffer) MarshalJSON() ([]byte, error) {
+	return marshalJSON(b.B[b.off:], true)
+}
+
+// UnmarshalJSON replaces the contents with the decoded string.
+func (b *JSONStringBuffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, true)
+	if err != nil {
+		return err
+	}
+	b.Set(p)
+	return nil
+}
+
+// JSONStringIoBuffer is an IoBuffer represented in JSON as a plain string
+// instead of base64, invalid UTF-8 is replaced by U+FFFD. UnmarshalJSON
+// allocates the IoBuffer with NewIoBuffer if it is nil.
+type JSONStringIoBuffer struct {
+	IoBuffer
+}
+
+// MarshalJSON encodes the unread bytes as a plain string without consuming
+// them.
+func (b JSONStringIoBuffer) MarshalJSON() ([]byte, error) {
+	if b.IoBuffer == nil {
+		return []byte("null"), nil
+	}
+	return marshalJSON(b.CopyBytes(), true)
+}
+
+// UnmarshalJSON replaces the contents with the decoded string.
+func (b *JSONStringIoBuffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, true)
+	if err != nil {
+		return err
+	}
+	if b.IoBuffer == nil {
+		b.IoBuffer = NewIoBuffer(len(p))
+	}
+	b.Reset()
+	_, err = b.Write(p)
+	return err
+}
+
+// marshalJSON encodes p as a base64 string like []byte, or as a plain
+// string
+func marshalJSON(p []byte, str bool) ([]byte, error) {
+	if str {
+		return json.Marshal(unsafeString(p))
+	}
+	return json.Marshal(p)
+}
+
+// unmarshalJSON decodes data, null decodes to no bytes
+func unmarshalJSON(data []byte, str bool) ([]byte, error) {
+	if str {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return UnsafeBytes(s), err
+	}
+	var p []byte
+	err := json.Unmarshal(data, &p)
+	return p, err
+}
+
+// MarshalJSON implements json.Marshaler, encoding the unread bytes as a
+// base64 string like []byte, see JSONStringBuffer for plain strings.
+func (b *Buffer) MarshalJSON() ([]byte, error) {
+	return marshalJSON(b.B[b.off:], false)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents.
+func (b *Buffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, false)
+	if err != nil {
+		return err
+	}
+	b.Set(p)
+	return nil
+}
+
+func (b *ioBuffer) MarshalJSON() ([]byte, error) {
+	return marshalJSON(b.buf[b.off:], false)
+}
+
+func (b *ioBuffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, false)
+	if err != nil {
+		return err
+	}
+	b.reset()
+	_, err = b.Write(p)
+	return err
+}
+
+func (m *multiIoBuffer) MarshalJSON() ([]byte, error) {
+	return marshalJSON(m.CopyBytes(), false)
+}
+
+func (m *multiIoBuffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, false)
+	if err != nil {
+		return err
+	}
+	m.Reset()
+	_, err = m.Write(p)
+	return err
+}
+
+func (v *limitedIoBuffer) MarshalJSON() ([]byte, error) {
+	return marshalJSON(v.Bytes(), false)
+}
+
+func (v *limitedIoBuffer) UnmarshalJSON(data []byte) error {
+	return v.readOnly("unmarshal", len(data))
+}
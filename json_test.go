@@ -0,0 +1,86 @@
+package buffer
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type jsonConfig struct {
+	Name string
+	Body IoBuffer
+	Raw  *Buffer
+}
+
+func TestJSONBase64(t *testing.T) {
+	raw := &Buffer{}
+	raw.WriteString("raw")
+	c := jsonConfig{Name: "c", Body: NewIoBufferString("hello"), Raw: raw}
+	data, err := json.Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); s != `{"Name":"c","Body":"aGVsbG8=","Raw":"cmF3"}` {
+		t.Errorf("unexpected json: %s", s)
+	}
+	if c.Body.Len() != 5 {
+		t.Error("Expect MarshalJSON not to consume the buffer")
+	}
+
+	d := jsonConfig{Body: NewIoBufferString("old"), Raw: &Buffer{}}
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Body.String() != "hello" || d.Raw.String() != "raw" {
+		t.Errorf("unexpected contents: %q, %q", d.Body.String(), d.Raw.String())
+	}
+
+	if err := json.Unmarshal([]byte(`"%%%"`), d.Raw); err == nil {
+		t.Error("Expect invalid base64 to fail")
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	m := MultiIoBuffer(NewIoBufferString("hel"), NewIoBufferString("lo\n"))
+	data, err := json.Marshal(JSONStringIoBuffer{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); s != `"hello\n"` {
+		t.Errorf("unexpected json: %s", s)
+	}
+	if m.Len() != 6 {
+		t.Error("Expect MarshalJSON not to consume the buffer")
+	}
+	// the mode is per value, the buffer itself still encodes as base64
+	if data, _ := json.Marshal(m); string(data) != `"aGVsbG8K"` {
+		t.Errorf("unexpected json: %s", data)
+	}
+
+	var b JSONStringIoBuffer
+	if err := json.Unmarshal([]byte(`"a \"quoted\" value"`), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != `a "quoted" value` {
+		t.Errorf("unexpected contents: %q", b.String())
+	}
+	if err := json.Unmarshal([]byte(`null`), &b); err != nil || b.Len() != 0 {
+		t.Errorf("Expect null to empty the buffer, but got %q, %v", b.String(), err)
+	}
+
+	var raw JSONStringBuffer
+	if err := json.Unmarshal([]byte(`"raw"`), &raw); err != nil || raw.String() != "raw" {
+		t.Errorf("unexpected contents: %q, %v", raw.String(), err)
+	}
+	if data, _ := json.Marshal(&raw); string(data) != `"raw"` {
+		t.Errorf("unexpected json: %s", data)
+	}
+
+	v := JSONStringIoBuffer{NewIoBufferString("hello").Limit(4)}
+	if data, _ := json.Marshal(v); string(data) != `"hell"` {
+		t.Errorf("unexpected json: %s", data)
+	}
+	if err := json.Unmarshal([]byte(`"x"`), &v); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly, but got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package buffer
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// JSONEncoder writes a JSON document to a Buffer token by token, without
+// reflection, inserting the separators and escaping strings:
+//
+//	e := buffer.GetJSONEncoder(b)
+//	e.BeginObject()
+//	e.Field("status")
+//	e.Int(200)
+//	e.Field("path")
+//	e.String(path)
+//	e.EndObject()
+//	buffer.PutJSONEncoder(e)
+//
+// The encoder doesn't check that the tokens form valid JSON, such as a
+// Field for every value of an object. Top level values are written back
+// to back, write a newline in between for JSON lines.
+type JSONEncoder struct {
+	b *Buffer
+	// nonEmpty records for each open object or array whether it has
+	// members yet
+	nonEmpty []bool
+	// afterField is set between a Field and its value
+	afterField bool
+}
+
+var jsonEncoderPool = sync.Pool{
+	New: func() interface{} {
+		return &JSONEncoder{nonEmpty: make([]bool, 0, 8)}
+	},
+}
+
+// GetJSONEncoder returns a pooled encoder writing to b.
+func GetJSONEncoder(b *Buffer) *JSONEncoder {
+	e := jsonEncoderPool.Get().(*JSONEncoder)
+	e.Reset(b)
+	return e
+}
+
+// PutJSONEncoder returns an encoder obtained via GetJSONEncoder to the
+// pool, it mustn't be used anymore.
+func PutJSONEncoder(e *JSONEncoder) {
+	e.Reset(nil)
+	jsonEncoderPool.Put(e)
+}
+
+// Reset makes e write a new document to b.
+func (e *JSONEncoder) Reset(b *Buffer) {
+	e.b = b
+	e.nonEmpty = e.nonEmpty[:0]
+	e.afterField = false
+}
+
+// Depth returns the number of open objects and arrays.
+func (e *JSONEncoder) Depth() int {
+	return len(e.nonEmpty)
+}
+
+// BeginObject opens an object.
+func (e *JSONEncoder) BeginObject() {
+	e.value()
+	e.b.B = append(e.b.B, '{')
+	e.nonEmpty = append(e.nonEmpty, false)
+}
+
+// EndObject closes the innermost object.
+func (e *JSONEncoder) EndObject() {
+	e.end('}')
+}
+
+// BeginArray opens an array.
+func (e *JSONEncoder) BeginArray() {
+	e.value()
+	e.b.B = append(e.b.B, '[')
+	e.nonEmpty = append(e.nonEmpty, false)
+}
+
+// EndArray closes the innermost array.
+func (e *JSONEncoder) EndArray() {
+	e.end(']')
+}
+
+// Field writes the name of the next member of the innermost object.
+func (e *JSONEncoder) Field(name string) {
+	e.value()
+	e.b.writeJSONString(name)
+	e.b.B = append(e.b.B, ':')
+	e.afterField = true
+}
+
+// String writes s as a string. Invalid UTF-8 is replaced by U+FFFD.
+func (e *JSONEncoder) String(s string) {
+	e.value()
+	e.b.writeJSONString(s)
+}
+
+// Int writes n.
+func (e *JSONEncoder) Int(n int64) {
+	e.value()
+	e.b.B = strconv.AppendInt(e.b.B, n, 10)
+}
+
+// Uint writes n.
+func (e *JSONEncoder) Uint(n uint64) {
+	e.value()
+	e.b.B = strconv.AppendUint(e.b.B, n, 10)
+}
+
+// Float writes f as encoding/json does, in exponent notation only for very
+// large and small magnitudes. NaN and infinities, which JSON can't
+// represent, are written as null.
+func (e *JSONEncoder) Float(f float64) {
+	e.value()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		e.b.B = append(e.b.B, "null"...)
+		return
+	}
+	fmtByte := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		fmtByte = 'e'
+	}
+	e.b.WriteFloatFmt(f, fmtByte, -1, 64)
+	if fmtByte == 'e' {
+		// e-07 to e-7
+		p := e.b.B
+		if n := len(p); n >= 4 && p[n-4] == 'e' && p[n-3] == '-' && p[n-2] == '0' {
+			p[n-2] = p[n-1]
+			e.b.B = p[:n-1]
+		}
+	}
+}
+
+// Bool writes v.
+func (e *JSONEncoder) Bool(v bool) {
+	e.value()
+	e.b.B = strconv.AppendBool(e.b.B, v)
+}
+
+// Null writes null.
+func (e *JSONEncoder) Null() {
+	e.value()
+	e.b.B = append(e.b.B, "null"...)
+}
+
+// value writes the separator before a value or field name
+func (e *JSONEncoder) value() {
+	if e.afterField {
+		e.afterField = false
+		return
+	}
+	if n := len(e.nonEmpty); n > 0 {
+		if e.nonEmpty[n-1] {
+			e.b.B = append(e.b.B, ',')
+		}
+		e.nonEmpty[n-1] = true
+	}
+}
+
+func (e *JSONEncoder) end(c byte) {
+	if len(e.nonEmpty) == 0 {
+		panic("buffer: json encoder: end without begin")
+	}
+	e.nonEmpty = e.nonEmpty[:len(e.nonEmpty)-1]
+	e.afterField = false
+	e.b.B = append(e.b.B, c)
+}
+
+// writeJSONString writes s quoted and escaped as encoding/json does,
+// without HTML escaping
+func (b *Buffer) writeJSONString(s string) {
+	b.B = append(b.B, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' && c < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if c < utf8.RuneSelf {
+			b.B = append(b.B, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b.B = append(b.B, '\\', c)
+			case '\n':
+				b.B = append(b.B, '\\', 'n')
+			case '\r':
+				b.B = append(b.B, '\\', 'r')
+			case '\t':
+				b.B = append(b.B, '\\', 't')
+			default:
+				b.B = append(b.B, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.B = append(b.B, s[start:i]...)
+			b.B = append(b.B, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			// line separators break JavaScript parsers
+			b.B = append(b.B, s[start:i]...)
+			b.B = append(b.B, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	b.B = append(b.B, s[start:]...)
+	b.B = append(b.B, '"')
+}
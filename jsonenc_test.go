@@ -0,0 +1,92 @@
+package buffer
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestJSONEncoder(t *testing.T) {
+	var bb Buffer
+	e := GetJSONEncoder(&bb)
+	e.BeginObject()
+	e.Field("status")
+	e.Int(200)
+	e.Field("bytes")
+	e.Uint(math.MaxUint64)
+	e.Field("path")
+	e.String("/a\"b\\c\n\t\x01<>&\u2028é\xff")
+	e.Field("latency")
+	e.Float(0.25)
+	e.Field("cached")
+	e.Bool(false)
+	e.Field("tags")
+	e.BeginArray()
+	e.String("a")
+	e.BeginObject()
+	e.EndObject()
+	e.BeginArray()
+	e.EndArray()
+	e.Null()
+	e.EndArray()
+	e.Field("nan")
+	e.Float(math.NaN())
+	e.EndObject()
+	if e.Depth() != 0 {
+		t.Errorf("depth %d", e.Depth())
+	}
+	PutJSONEncoder(e)
+
+	want := `{"status":200,"bytes":18446744073709551615,"path":"/a\"b\\c\n\t\u0001<>&\u2028é\ufffd",` +
+		`"latency":0.25,"cached":false,"tags":["a",{},[],null],"nan":null}`
+	if bb.String() != want {
+		t.Fatalf("got  %s\nwant %s", bb.String(), want)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(bb.B, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["path"] != "/a\"b\\c\n\t\x01<>&\u2028é\ufffd" {
+		t.Errorf("path decoded to %q", v["path"])
+	}
+
+	expectPanic(t, "unbalanced", func() {
+		e := GetJSONEncoder(&bb)
+		defer PutJSONEncoder(e)
+		e.EndArray()
+	})
+}
+
+func TestJSONEncoderFloat(t *testing.T) {
+	for _, f := range []float64{0, 1, -1, 1.5, 1e20, 1e21, 1e-6, 1e-7, 123456789, -2.5e-10, math.MaxFloat64, math.SmallestNonzeroFloat64} {
+		var bb Buffer
+		e := GetJSONEncoder(&bb)
+		e.Float(f)
+		PutJSONEncoder(e)
+		want, _ := json.Marshal(f)
+		if bb.String() != string(want) {
+			t.Errorf("%v: got %s, want %s", f, bb.String(), want)
+		}
+	}
+}
+
+func TestJSONEncoderAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector drops pooled items at random")
+	}
+	var bb Buffer
+	bb.Grow(256)
+	if n := testing.AllocsPerRun(100, func() {
+		bb.Reset()
+		e := GetJSONEncoder(&bb)
+		e.BeginObject()
+		e.Field("method")
+		e.String("GET")
+		e.Field("duration")
+		e.Float(0.0123)
+		e.EndObject()
+		PutJSONEncoder(e)
+	}); n != 0 {
+		t.Errorf("%v allocations per document", n)
+	}
+}
@@ -0,0 +1,434 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidJSON is returned by JSONTokenizer for malformed JSON.
+var ErrInvalidJSON = errors.New("io buffer: invalid json")
+
+// DefaultMaxJSONDepth is the nesting limit of a JSONTokenizer without
+// MaxDepth.
+const DefaultMaxJSONDepth = 10000
+
+// JSONKind is the kind of a JSONToken.
+type JSONKind int
+
+// The kinds of JSON tokens. Strings are JSONKey in the name position of an
+// object member and JSONStringValue elsewhere.
+const (
+	JSONObjectStart JSONKind = iota
+	JSONObjectEnd
+	JSONArrayStart
+	JSONArrayEnd
+	JSONKey
+	JSONStringValue
+	JSONNumber
+	JSONBool
+	JSONNull
+)
+
+// JSONToken is a token of a JSON text.
+type JSONToken struct {
+	Kind JSONKind
+	// Raw is the token as in the input, without the quotes of strings. It
+	// aliases the scanned buffer and is valid until the next call of Next.
+	Raw []byte
+	// Escaped is set for strings with escape sequences, AppendUnescaped
+	// decodes them.
+	Escaped bool
+}
+
+// AppendUnescaped appends the decoded value of a string token to dst.
+func (t *JSONToken) AppendUnescaped(dst []byte) []byte {
+	if !t.Escaped {
+		return append(dst, t.Raw...)
+	}
+	p := t.Raw
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\\')
+		if i < 0 {
+			return append(dst, p...)
+		}
+		dst = append(dst, p[:i]...)
+		c := p[i+1]
+		p = p[i+2:]
+		switch c {
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case 'u':
+			r := hexRune(p)
+			p = p[4:]
+			if utf16.IsSurrogate(r) {
+				r2 := rune(-1)
+				if len(p) >= 6 && p[0] == '\\' && p[1] == 'u' {
+					r2 = hexRune(p[2:])
+				}
+				if r = utf16.DecodeRune(r, r2); r != utf8.RuneError {
+					p = p[6:]
+				}
+			}
+			dst = appendRune(dst, r)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func appendRune(p []byte, r rune) []byte {
+	var tmp [utf8.UTFMax]byte
+	n := utf8.EncodeRune(tmp[:], r)
+	return append(p, tmp[:n]...)
+}
+
+// hexRune decodes 4 hex digits, the tokenizer validated them
+func hexRune(p []byte) rune {
+	var r rune
+	for _, c := range p[:4] {
+		r <<= 4
+		switch {
+		case c <= '9':
+			r |= rune(c - '0')
+		case c >= 'a':
+			r |= rune(c - 'a' + 10)
+		default:
+			r |= rune(c - 'A' + 10)
+		}
+	}
+	return r
+}
+
+// what the tokenizer expects next
+const (
+	jsonValue = iota
+	jsonValueOrEnd
+	jsonKey
+	jsonKeyOrEnd
+	jsonColon
+	jsonCommaOrEnd
+)
+
+// JSONTokenizer splits JSON texts, as they arrive in an IoBuffer, into
+// tokens without copying, so that proxies can filter or rewrite JSON
+// bodies as a stream:
+//
+//	for {
+//		tok, err := t.Next(b)
+//		if err == buffer.ErrNeedMore {
+//			_, err = b.ReadOnce(conn, timeout)
+//			...
+//			continue
+//		}
+//		...
+//	}
+//
+// Next returns ErrNeedMore when the buffered bytes end within a token and
+// resumes without rescanning once more arrived. A number at the end of the
+// bytes may go on, it is only returned once followed by another byte or
+// when b is at EOF. The tokenizer validates the structure of the input,
+// separators and whitespace are consumed silently. A sequence of top level
+// values, such as JSON lines, is tokenized one value after the other.
+type JSONTokenizer struct {
+	// MaxDepth is the nesting limit, deeper texts fail with
+	// ErrInvalidJSON. 0 means DefaultMaxJSONDepth.
+	MaxDepth int
+
+	// stack holds '{' or '[' for each open object or array
+	stack   []byte
+	state   int
+	pending int
+	// scanned and escaped keep the progress through an incomplete string
+	scanned int
+	escaped bool
+}
+
+// Reset makes t tokenize a new input.
+func (t *JSONTokenizer) Reset() {
+	t.stack = t.stack[:0]
+	t.state = jsonValue
+	t.pending = 0
+	t.scanned = 0
+	t.escaped = false
+}
+
+// Depth returns the number of open objects and arrays.
+func (t *JSONTokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// Next returns the next token of the JSON at the start of the unread bytes
+// of b, consuming the previous token. It returns io.EOF at the end of a
+// complete text when b is at EOF.
+func (t *JSONTokenizer) Next(b IoBuffer) (JSONToken, error) {
+	b.Drain(t.pending)
+	t.pending = 0
+	for {
+		data := b.Bytes()
+		i := 0
+		for i < len(data) && isJSONSpace(data[i]) {
+			i++
+		}
+		if i > 0 {
+			b.Drain(i)
+			data = data[i:]
+		}
+		if len(data) == 0 {
+			if !b.EOF() {
+				return JSONToken{}, ErrNeedMore
+			}
+			if t.state == jsonValue && len(t.stack) == 0 {
+				return JSONToken{}, io.EOF
+			}
+			return JSONToken{}, opError("json", 0, b, io.ErrUnexpectedEOF)
+		}
+
+		c := data[0]
+		switch t.state {
+		case jsonColon:
+			if c != ':' {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+			b.Drain(1)
+			t.state = jsonValue
+			continue
+		case jsonCommaOrEnd:
+			if c == ',' {
+				b.Drain(1)
+				t.state = jsonValue
+				if t.stack[len(t.stack)-1] == '{' {
+					t.state = jsonKey
+				}
+				continue
+			}
+			if c != '}' && c != ']' {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+		case jsonKey:
+			if c != '"' {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+		case jsonKeyOrEnd:
+			if c != '"' && c != '}' {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+		case jsonValue:
+			if c == '}' || c == ']' {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+		case jsonValueOrEnd:
+			if c == '}' {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+		}
+		return t.token(b, data)
+	}
+}
+
+// token scans the token starting at data[0], a valid position for it
+func (t *JSONTokenizer) token(b IoBuffer, data []byte) (JSONToken, error) {
+	var tok JSONToken
+	n := 0
+	switch c := data[0]; c {
+	case '{', '[':
+		max := t.MaxDepth
+		if max <= 0 {
+			max = DefaultMaxJSONDepth
+		}
+		if len(t.stack) >= max {
+			return tok, opError("json", max, b, ErrInvalidJSON)
+		}
+		t.stack = append(t.stack, c)
+		tok.Kind, t.state = JSONObjectStart, jsonKeyOrEnd
+		if c == '[' {
+			tok.Kind, t.state = JSONArrayStart, jsonValueOrEnd
+		}
+		t.pending = 1
+		tok.Raw = data[:1]
+		return tok, nil
+	case '}', ']':
+		open := byte('{')
+		tok.Kind = JSONObjectEnd
+		if c == ']' {
+			open, tok.Kind = '[', JSONArrayEnd
+		}
+		if t.stack[len(t.stack)-1] != open {
+			return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+		}
+		t.stack = t.stack[:len(t.stack)-1]
+		n = 1
+	case '"':
+		end, err := t.scanString(b, data)
+		if end < 0 {
+			return JSONToken{}, err
+		}
+		tok.Kind = JSONStringValue
+		if t.state == jsonKey || t.state == jsonKeyOrEnd {
+			tok.Kind = JSONKey
+		}
+		tok.Raw, tok.Escaped = data[1:end-1], t.escaped
+		t.scanned, t.escaped = 0, false
+		t.pending = end
+		if tok.Kind == JSONKey {
+			t.state = jsonColon
+			return tok, nil
+		}
+	case 't', 'f', 'n':
+		lit := "null"
+		tok.Kind = JSONNull
+		if c != 'n' {
+			lit, tok.Kind = "true", JSONBool
+			if c == 'f' {
+				lit = "false"
+			}
+		}
+		if len(data) < len(lit) {
+			if !bytes.HasPrefix([]byte(lit), data) {
+				return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+			}
+			if b.EOF() {
+				return JSONToken{}, opError("json", 0, b, io.ErrUnexpectedEOF)
+			}
+			return JSONToken{}, ErrNeedMore
+		}
+		if string(data[:len(lit)]) != lit || len(data) > len(lit) && !isJSONDelim(data[len(lit)]) {
+			return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+		}
+		n = len(lit)
+	default:
+		for n < len(data) && !isJSONDelim(data[n]) {
+			n++
+		}
+		if n == len(data) && !b.EOF() {
+			return JSONToken{}, ErrNeedMore
+		}
+		if !isJSONNumber(data[:n]) {
+			return JSONToken{}, opError("json", 0, b, ErrInvalidJSON)
+		}
+		tok.Kind = JSONNumber
+	}
+	if n > 0 {
+		tok.Raw = data[:n]
+		t.pending = n
+	}
+	t.state = jsonValue
+	if len(t.stack) > 0 {
+		t.state = jsonCommaOrEnd
+	}
+	return tok, nil
+}
+
+// scanString returns the offset after the closing quote of the string at
+// data[0], or -1 with ErrNeedMore or an error
+func (t *JSONTokenizer) scanString(b IoBuffer, data []byte) (int, error) {
+	i := t.scanned
+	if i == 0 {
+		i = 1
+	}
+	for i < len(data) {
+		switch c := data[i]; {
+		case c == '"':
+			return i + 1, nil
+		case c == '\\':
+			if i+1 >= len(data) {
+				break
+			}
+			switch data[i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				i += 2
+			case 'u':
+				if i+6 > len(data) {
+					t.scanned = i
+					return -1, t.needMore(b)
+				}
+				for _, h := range data[i+2 : i+6] {
+					if !isHexDigit(h) {
+						return -1, opError("json", 0, b, ErrInvalidJSON)
+					}
+				}
+				i += 6
+			default:
+				return -1, opError("json", 0, b, ErrInvalidJSON)
+			}
+			t.escaped = true
+			continue
+		case c < 0x20:
+			return -1, opError("json", 0, b, ErrInvalidJSON)
+		default:
+			i++
+			continue
+		}
+		break
+	}
+	t.scanned = i
+	return -1, t.needMore(b)
+}
+
+// needMore returns ErrNeedMore, or an unexpected EOF error if b is at EOF
+func (t *JSONTokenizer) needMore(b IoBuffer) error {
+	if b.EOF() {
+		return opError("json", 0, b, io.ErrUnexpectedEOF)
+	}
+	return ErrNeedMore
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isJSONDelim reports whether c may follow a number or literal
+func isJSONDelim(c byte) bool {
+	return isJSONSpace(c) || c == ',' || c == ':' || c == '}' || c == ']' || c == '{' || c == '[' || c == '"'
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// isJSONNumber reports whether p is a number of the JSON grammar
+func isJSONNumber(p []byte) bool {
+	i := 0
+	if i < len(p) && p[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(p) && '0' <= p[i] && p[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(p) && p[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(p) && p[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(p) && (p[i] == 'e' || p[i] == 'E') {
+		i++
+		if i < len(p) && (p[i] == '+' || p[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(p)
+}
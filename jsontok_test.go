@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// tokenizeJSON feeds raw to a tokenizer chunk bytes at a time and returns
+// the tokens as kind:raw strings
+func tokenizeJSON(t *testing.T, raw string, chunk int) ([]string, error) {
+	var (
+		tk   JSONTokenizer
+		toks []string
+	)
+	b := NewIoBuffer(0)
+	for {
+		tok, err := tk.Next(b)
+		if err == ErrNeedMore {
+			if raw == "" {
+				b.SetEOF(true)
+				continue
+			}
+			n := chunk
+			if n > len(raw) {
+				n = len(raw)
+			}
+			b.WriteString(raw[:n])
+			raw = raw[n:]
+			continue
+		}
+		if err == io.EOF {
+			return toks, nil
+		}
+		if err != nil {
+			return toks, err
+		}
+		s := string(tok.Raw)
+		if tok.Escaped {
+			s = string(tok.AppendUnescaped(nil))
+		}
+		toks = append(toks, [...]string{"{", "}", "[", "]", "k", "s", "n", "b", "null"}[tok.Kind]+":"+s)
+	}
+}
+
+func TestJSONTokenizer(t *testing.T) {
+	raw := ` {"name": "caf\u00e9 \"x\"\n", "n": [1, -0.5, 2e10, 0 ,{}, []], "ok" :true,"none":null,
+		"emoji":"\ud83d\ude00", "bad":"\ud83d", "esc":"a\/b\\c\t"}
+		42 "next"`
+	want := []string{
+		"{:{", "k:name", "s:café \"x\"\n",
+		"k:n", "[:[", "n:1", "n:-0.5", "n:2e10", "n:0", "{:{", "}:}", "[:[", "]:]", "]:]",
+		"k:ok", "b:true", "k:none", "null:null",
+		"k:emoji", "s:😀", "k:bad", "s:\ufffd", "k:esc", "s:a/b\\c\t", "}:}",
+		"n:42", "s:next",
+	}
+	for _, chunk := range []int{1, 2, 3, 5, 16, 1024} {
+		toks, err := tokenizeJSON(t, raw, chunk)
+		if err != nil {
+			t.Fatalf("chunk %d: %v after %q", chunk, err, toks)
+		}
+		if strings.Join(toks, "|") != strings.Join(want, "|") {
+			t.Errorf("chunk %d:\ngot  %q\nwant %q", chunk, toks, want)
+		}
+	}
+}
+
+func TestJSONTokenizerDepth(t *testing.T) {
+	tk := JSONTokenizer{MaxDepth: 2}
+	b := NewIoBufferString(`[[[1]]]`)
+	var err error
+	for err == nil {
+		_, err = tk.Next(b)
+		if err == nil && tk.Depth() > 2 {
+			t.Fatalf("depth %d", tk.Depth())
+		}
+	}
+	if !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("got %v, want ErrInvalidJSON", err)
+	}
+}
+
+func TestJSONTokenizerInvalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"a" 1}`,
+		`{"a":1,}`,
+		`[1,]`,
+		`[1 2]`,
+		`{1:2}`,
+		`{"a":1]`,
+		`]`,
+		`[}`,
+		`"a\x"`,
+		"\"a\x01\"",
+		`"\u12g4"`,
+		`01`,
+		`1.`,
+		`-`,
+		`1e`,
+		`+1`,
+		`tru`,
+		`trux`,
+		`nul,`,
+		`[true1]`,
+		`{"a":1`,
+		`"open`,
+	} {
+		if _, err := tokenizeJSON(t, raw, 3); !errors.Is(err, ErrInvalidJSON) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: got %v, want an error", raw, err)
+		}
+	}
+}
+
+func BenchmarkJSONTokenizer(b *testing.B) {
+	raw := `{"method":"GET","path":"/api/v1/users","status":200,"latency":0.0123,"tags":["a","b"],"ok":true}`
+	buf := NewIoBuffer(len(raw))
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	for i := 0; i < b.N; i++ {
+		var tk JSONTokenizer
+		buf.Reset()
+		buf.WriteString(raw)
+		buf.SetEOF(true)
+		for {
+			if _, err := tk.Next(buf); err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// Kafka record batch layout, see the protocol guide: the CRC32C at
+// kafkaCRCOffset covers the bytes from the attributes to the end of the
+// batch, whose size is kafkaLengthEnd plus the batch length.
+const (
+	kafkaLengthOffset = 8
+	kafkaLengthEnd    = 12
+	kafkaMagicOffset  = 16
+	kafkaCRCOffset    = 17
+	kafkaCRCEnd       = 21
+	kafkaMagic        = 2
+)
+
+var (
+	// ErrVarintOverflow is returned when a varint is longer than its type
+	// allows.
+	ErrVarintOverflow = errors.New("io buffer: varint overflow")
+	// ErrInvalidKafkaBatch is returned for record batches with a bad length
+	// or a magic other than 2.
+	ErrInvalidKafkaBatch = errors.New("io buffer: invalid kafka record batch")
+)
+
+// WriteVarint writes v as a zigzag varint, the Kafka varint type.
+func WriteVarint(b IoBuffer, v int32) error {
+	return WriteVarlong(b, int64(v))
+}
+
+// WriteVarlong writes v as a zigzag varint, the Kafka varlong type.
+func WriteVarlong(b IoBuffer, v int64) error {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	_, err := b.Write(tmp[:n])
+	return err
+}
+
+// ReadVarint reads a zigzag varint of at most 5 bytes. ErrNeedMore is
+// returned, and nothing consumed, while the varint is incomplete.
+func ReadVarint(b IoBuffer) (int32, error) {
+	v, err := readZigzag(b, binary.MaxVarintLen32, "read varint")
+	return int32(v), err
+}
+
+// ReadVarlong reads a zigzag varint of at most 10 bytes. ErrNeedMore is
+// returned, and nothing consumed, while the varint is incomplete.
+func ReadVarlong(b IoBuffer) (int64, error) {
+	return readZigzag(b, binary.MaxVarintLen64, "read varlong")
+}
+
+// readZigzag reads a zigzag varint of at most size bytes
+func readZigzag(b IoBuffer, size int, op string) (int64, error) {
+	n := size
+	if l := b.Len(); l < n {
+		n = l
+	}
+	bits := uint(64)
+	if size == binary.MaxVarintLen32 {
+		bits = 32
+	}
+	var u uint64
+	for i, c := range b.Peek(n) {
+		u |= uint64(c&0x7f) << (7 * uint(i))
+		if c < 0x80 {
+			// the last byte may only hold the bits left of the type
+			if i == size-1 && c>>(bits-7*uint(i)) != 0 {
+				return 0, opError(op, 0, b, ErrVarintOverflow)
+			}
+			b.Drain(i + 1)
+			return int64(u>>1) ^ -int64(u&1), nil
+		}
+	}
+	if n == size {
+		return 0, opError(op, 0, b, ErrVarintOverflow)
+	}
+	return 0, ErrNeedMore
+}
+
+// ValidateKafkaBatchCRC checks the CRC of the Kafka record batch at the
+// start of the unread bytes of b and returns its size, without consuming
+// it. ErrNeedMore is returned while the batch is incomplete,
+// ErrCRCMismatch for a corrupted batch and ErrInvalidKafkaBatch for one
+// that isn't a v2 record batch.
+func ValidateKafkaBatchCRC(b IoBuffer) (int, error) {
+	p, err := peekKafkaBatch(b, "validate kafka batch")
+	if err != nil {
+		return 0, err
+	}
+	if crc32.Checksum(p[kafkaCRCEnd:], castagnoli) != binary.BigEndian.Uint32(p[kafkaCRCOffset:]) {
+		return 0, opError("validate kafka batch", len(p), b, ErrCRCMismatch)
+	}
+	return len(p), nil
+}
+
+// UpdateKafkaBatchCRC recomputes the CRC of the Kafka record batch at the
+// start of the unread bytes of b in place, after its attributes or records
+// have been rewritten, and returns its size. The base offset and partition
+// leader epoch aren't covered by the CRC and may change freely.
+func UpdateKafkaBatchCRC(b IoBuffer) (int, error) {
+	p, err := peekKafkaBatch(b, "update kafka batch")
+	if err != nil {
+		return 0, err
+	}
+	binary.BigEndian.PutUint32(p[kafkaCRCOffset:], crc32.Checksum(p[kafkaCRCEnd:], castagnoli))
+	return len(p), nil
+}
+
+// peekKafkaBatch returns the record batch at the start of the unread bytes
+// of b
+func peekKafkaBatch(b IoBuffer, op string) ([]byte, error) {
+	hdr := b.Peek(kafkaCRCEnd)
+	if hdr == nil {
+		return nil, ErrNeedMore
+	}
+	n := int64(int32(binary.BigEndian.Uint32(hdr[kafkaLengthOffset:])))
+	if hdr[kafkaMagicOffset] != kafkaMagic || n < kafkaCRCEnd-kafkaLengthEnd || n > int64(maxInt-kafkaLengthEnd) {
+		return nil, opError(op, 0, b, ErrInvalidKafkaBatch)
+	}
+	p := b.Peek(kafkaLengthEnd + int(n))
+	if p == nil {
+		return nil, ErrNeedMore
+	}
+	return p, nil
+}
@@ -0,0 +1,108 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+func TestVarint(t *testing.T) {
+	b := NewIoBuffer(0)
+	ints := []int32{0, -1, 1, 63, -64, 64, 300, math.MaxInt32, math.MinInt32}
+	for _, v := range ints {
+		WriteVarint(b, v)
+	}
+	longs := []int64{0, -1, 1 << 40, math.MaxInt64, math.MinInt64}
+	for _, v := range longs {
+		WriteVarlong(b, v)
+	}
+	// encodings from the Kafka protocol guide
+	if got := b.Bytes()[:4]; string(got) != "\x00\x01\x02\x7e" {
+		t.Errorf("got % x", got)
+	}
+	for _, want := range ints {
+		if v, err := ReadVarint(b); v != want || err != nil {
+			t.Errorf("got %d, %v, want %d", v, err, want)
+		}
+	}
+	for _, want := range longs {
+		if v, err := ReadVarlong(b); v != want || err != nil {
+			t.Errorf("got %d, %v, want %d", v, err, want)
+		}
+	}
+
+	b.Write([]byte{0x80, 0x80})
+	if _, err := ReadVarint(b); err != ErrNeedMore || b.Len() != 2 {
+		t.Errorf("got %v, want ErrNeedMore leaving the bytes", err)
+	}
+	b.Reset()
+	b.Write([]byte{0xff, 0xff, 0xff, 0xff, 0x10})
+	if _, err := ReadVarint(b); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("got %v, want ErrVarintOverflow", err)
+	}
+	b.Reset()
+	b.Write([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01})
+	if _, err := ReadVarint(b); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("6 bytes: got %v, want ErrVarintOverflow", err)
+	}
+	if v, err := ReadVarlong(b); v != 1<<34 || err != nil {
+		t.Errorf("got %d, %v", v, err)
+	}
+	b.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02})
+	if _, err := ReadVarlong(b); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("got %v, want ErrVarintOverflow", err)
+	}
+}
+
+// kafkaBatch returns a v2 record batch with the given records section
+func kafkaBatch(records []byte) []byte {
+	p := make([]byte, 61, 61+len(records))
+	binary.BigEndian.PutUint64(p, 100)
+	p[kafkaMagicOffset] = kafkaMagic
+	p = append(p, records...)
+	binary.BigEndian.PutUint32(p[kafkaLengthOffset:], uint32(len(p)-kafkaLengthEnd))
+	binary.BigEndian.PutUint32(p[kafkaCRCOffset:], crc32.Checksum(p[kafkaCRCEnd:], castagnoli))
+	return p
+}
+
+func TestValidateKafkaBatchCRC(t *testing.T) {
+	batch := kafkaBatch([]byte("records"))
+	b := NewIoBuffer(0)
+	for i := 0; i < len(batch); i += 16 {
+		if _, err := ValidateKafkaBatchCRC(b); err != ErrNeedMore {
+			t.Fatalf("%d bytes: got %v, want ErrNeedMore", b.Len(), err)
+		}
+		end := i + 16
+		if end > len(batch) {
+			end = len(batch)
+		}
+		b.Write(batch[i:end])
+	}
+	b.Write([]byte("next"))
+	if n, err := ValidateKafkaBatchCRC(b); n != len(batch) || err != nil {
+		t.Fatalf("got %d, %v, want %d", n, err, len(batch))
+	}
+
+	// the base offset isn't covered, the records are
+	b.Bytes()[0] = 0xff
+	if _, err := ValidateKafkaBatchCRC(b); err != nil {
+		t.Errorf("base offset change: %v", err)
+	}
+	b.Bytes()[len(batch)-1] = 'S'
+	if _, err := ValidateKafkaBatchCRC(b); !errors.Is(err, ErrCRCMismatch) {
+		t.Errorf("got %v, want ErrCRCMismatch", err)
+	}
+	if n, err := UpdateKafkaBatchCRC(b); n != len(batch) || err != nil {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if _, err := ValidateKafkaBatchCRC(b); err != nil {
+		t.Errorf("after update: %v", err)
+	}
+
+	b.Bytes()[kafkaMagicOffset] = 1
+	if _, err := ValidateKafkaBatchCRC(b); !errors.Is(err, ErrInvalidKafkaBatch) {
+		t.Errorf("got %v, want ErrInvalidKafkaBatch", err)
+	}
+}
@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// DisablePoolingEnv is the environment variable honored at init: when set
+// to a true value (see strconv.ParseBool), pooling starts disabled.
+const DisablePoolingEnv = "BUFFER_DISABLE_POOLING"
+
+var disabled int32
+
+func init() {
+	if v, err := strconv.ParseBool(os.Getenv(DisablePoolingEnv)); err == nil && v {
+		DisablePooling()
+	}
+}
+
+// DisablePooling makes GetBytes, GetIoBuffer and their NamedPool
+// counterparts always allocate fresh memory, while PutBytes and
+// PutIoBuffer stop recycling anything.
+//
+// It is meant for ruling pooling bugs in or out when chasing data
+// corruption; reference counting and counters keep working.
+func DisablePooling() {
+	atomic.StoreInt32(&disabled, 1)
+}
+
+// EnablePooling turns pooling back on after DisablePooling.
+func EnablePooling() {
+	atomic.StoreInt32(&disabled, 0)
+}
+
+// PoolingDisabled reports whether pooling is disabled.
+func PoolingDisabled() bool {
+	return poolingDisabled()
+}
+
+func poolingDisabled() bool {
+	return atomic.LoadInt32(&disabled) != 0
+}
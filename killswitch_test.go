@@ -0,0 +1,35 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestDisablePooling(t *testing.T) {
+	DisablePooling()
+	defer EnablePooling()
+	if !PoolingDisabled() {
+		t.Fatalf("Expect pooling to be disabled")
+	}
+
+	before := GetPoolStats()
+	for i := 0; i < 10; i++ {
+		b := GetBytes(100)
+		(*b)[0] = 0xff
+		PutBytes(b)
+	}
+	if s := GetPoolStats(); s.Misses-before.Misses != 10 {
+		t.Errorf("Expect every get to allocate, but got %d misses", s.Misses-before.Misses)
+	}
+
+	buf := GetIoBuffer(10)
+	buf.WriteString("hello")
+	if err := PutIoBuffer(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "hello" {
+		t.Errorf("Expect put buffer to be left untouched, but got %q", buf.String())
+	}
+	if err := PutIoBuffer(buf); err == nil {
+		t.Errorf("Expect duplicate put to be detected")
+	}
+}
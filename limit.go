@@ -0,0 +1,300 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/gottingen/atomic"
+)
+
+// ErrReadOnly is returned by the write methods of the view returned by Limit.
+var ErrReadOnly = errors.New("io buffer: read-only view")
+
+// consumedCounter is implemented by buffers counting the bytes that left
+// the front of the buffer, whether consumed or dropped by a reset, so that
+// views can tell where their bytes went
+type consumedCounter interface {
+	consumedBytes() int64
+}
+
+func (b *ioBuffer) consumedBytes() int64 {
+	return b.dropped + int64(b.off)
+}
+
+func (m *multiIoBuffer) consumedBytes() int64 {
+	return m.dropped + m.stats.BytesRead
+}
+
+// limitedIoBuffer is a read-only view over the next n unread bytes of b
+type limitedIoBuffer struct {
+	b IoBuffer
+	n int
+	// cc is b as a consumedCounter, end is the count b reaches once the
+	// view is consumed, nil if b doesn't count and only its Len is known
+	cc     consumedCounter
+	end    int64
+	count  *atomic.Int32
+	closed bool
+	tee    IoBuffer
+	stats  BufferStats
+	// size of the rune returned by ReadRune, for UnreadRune
+	lastRune int
+}
+
+func (b *ioBuffer) Limit(n int) IoBuffer {
+	return newLimitedIoBuffer(b, n)
+}
+
+func newLimitedIoBuffer(b IoBuffer, n int) *limitedIoBuffer {
+	if n < 0 {
+		panic(opError("limit", n, b, ErrNegativeCount))
+	}
+	if l := b.Len(); n > l {
+		n = l
+	}
+	v := &limitedIoBuffer{b: b, n: n, end: int64(n), count: atomic.NewInt32(1)}
+	if cc, ok := b.(consumedCounter); ok {
+		v.cc = cc
+		v.end += cc.consumedBytes()
+	}
+	return v
+}
+
+func (v *limitedIoBuffer) consumedBytes() int64 {
+	if v.cc != nil {
+		return v.cc.consumedBytes()
+	}
+	return v.end - int64(v.n)
+}
+
+// avail returns the unread bytes of the view, the underlying buffer may have
+// been consumed, reset and refilled behind its back
+func (v *limitedIoBuffer) avail() int {
+	if v.cc != nil {
+		n := v.end - v.cc.consumedBytes()
+		if n < 0 {
+			n = 0
+		}
+		if n < int64(v.n) {
+			v.n = int(n)
+		}
+	}
+	if l := v.b.Len(); l < v.n {
+		v.n = l
+	}
+	return v.n
+}
+
+// consumed accounts p read through the view
+func (v *limitedIoBuffer) consumed(p []byte) {
+	v.lastRune = 0
+	v.n -= len(p)
+	v.stats.BytesRead += int64(len(p))
+	if v.tee != nil && len(p) > 0 {
+		v.tee.Write(p)
+	}
+}
+
+func (v *limitedIoBuffer) Read(p []byte) (int, error) {
+	if v.closed {
+		return 0, opError("read", len(p), v, ErrClosedBuffer)
+	}
+	n := v.avail()
+	if n == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	if len(p) > n {
+		p = p[:n]
+	}
+	m, err := v.b.Read(p)
+	v.consumed(p[:m])
+	return m, err
+}
+
+func (v *limitedIoBuffer) WriteTo(w io.Writer) (int64, error) {
+	if v.closed {
+		return 0, opError("write to", 0, v, ErrClosedBuffer)
+	}
+	p := v.b.Peek(v.avail())
+	m, err := w.Write(p)
+	if m > len(p) {
+		panic(opError("write to", m, v, ErrInvalidWriteCount))
+	}
+	v.consumed(p[:m])
+	v.b.Drain(m)
+	if err != nil {
+		return int64(m), opError("write to", 0, v, err)
+	}
+	if m < len(p) {
+		return int64(m), io.ErrShortWrite
+	}
+	return int64(m), nil
+}
+
+func (v *limitedIoBuffer) readOnly(op string, size int) error {
+	return opError(op, size, v, ErrReadOnly)
+}
+
+func (v *limitedIoBuffer) ReadFrom(r io.Reader) (int64, error) {
+	return 0, v.readOnly("read", 0)
+}
+
+func (v *limitedIoBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	return 0, v.readOnly("read", int(max))
+}
+
+func (v *limitedIoBuffer) ReadOnce(r io.Reader, duration time.Duration) (int64, error) {
+	return 0, v.readOnly("read", 0)
+}
+
+func (v *limitedIoBuffer) Write(p []byte) (int, error) {
+	return 0, v.readOnly("write", len(p))
+}
+
+func (v *limitedIoBuffer) WriteString(s string) (int, error) {
+	return 0, v.readOnly("write", len(s))
+}
+
+func (v *limitedIoBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	return v.readOnly("replace", len(replacement))
+}
+
+func (v *limitedIoBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	return 0, v.readOnly("splice", max)
+}
+
+func (v *limitedIoBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, _ func(int64)) (int64, error) {
+		return v.ReadFrom(r)
+	}, r)
+}
+
+func (v *limitedIoBuffer) Peek(n int) []byte {
+	if n > v.avail() {
+		return nil
+	}
+	return v.b.Peek(n)
+}
+
+func (v *limitedIoBuffer) Bytes() []byte {
+	return v.b.Peek(v.avail())
+}
+
+func (v *limitedIoBuffer) CopyBytes() []byte {
+	return append([]byte(nil), v.Bytes()...)
+}
+
+func (v *limitedIoBuffer) Drain(offset int) {
+	if offset < 0 {
+		panic(opError("drain", offset, v, ErrNegativeCount))
+	}
+	if offset > v.avail() {
+		return
+	}
+	v.consumed(v.b.Peek(offset))
+	v.b.Drain(offset)
+}
+
+func (v *limitedIoBuffer) DiscardAll() {
+	v.Drain(v.avail())
+}
+
+func (v *limitedIoBuffer) DrainTo(w io.Writer, n int) (int, error) {
+	if n < 0 {
+		panic(opError("drain to", n, v, ErrNegativeCount))
+	}
+	if v.closed {
+		return 0, opError("drain to", n, v, ErrClosedBuffer)
+	}
+	if a := v.avail(); n > a {
+		n = a
+	}
+	p := v.b.Peek(n)
+	m, err := v.b.DrainTo(w, n)
+	v.consumed(p[:m])
+	return m, err
+}
+
+func (v *limitedIoBuffer) Alloc(int) {
+	v.DiscardAll()
+}
+
+func (v *limitedIoBuffer) Free() {
+	v.DiscardAll()
+}
+
+func (v *limitedIoBuffer) Reset() {
+	v.DiscardAll()
+}
+
+func (v *limitedIoBuffer) Close() error {
+	if v.closed {
+		return opError("close", 0, v, ErrClosedBuffer)
+	}
+	v.DiscardAll()
+	v.closed = true
+	return nil
+}
+
+func (v *limitedIoBuffer) Len() int {
+	return v.avail()
+}
+
+func (v *limitedIoBuffer) Cap() int {
+	return v.avail()
+}
+
+func (v *limitedIoBuffer) Clone() IoBuffer {
+	buf := GetIoBuffer(v.avail())
+	buf.Write(v.Bytes())
+	buf.SetEOF(true)
+	return buf
+}
+
+func (v *limitedIoBuffer) String() string {
+	return string(v.Bytes())
+}
+
+func (v *limitedIoBuffer) Count(count int32) int32 {
+	return v.count.Add(count)
+}
+
+// EOF is always true, no more bytes become visible through the view
+func (v *limitedIoBuffer) EOF() bool {
+	return true
+}
+
+func (v *limitedIoBuffer) SetEOF(bool) {}
+
+func (v *limitedIoBuffer) SetAutoEOF(bool) {}
+
+func (v *limitedIoBuffer) SetHooks(*Hooks) {}
+
+func (v *limitedIoBuffer) Tee(dst IoBuffer) {
+	v.tee = dst
+}
+
+func (v *limitedIoBuffer) ReadCloser() io.ReadCloser {
+	return &ioBufferReadCloser{b: v}
+}
+
+func (v *limitedIoBuffer) Dump(maxBytes int) string {
+	return dump(v.Bytes(), maxBytes)
+}
+
+func (v *limitedIoBuffer) Stats() BufferStats {
+	return v.stats
+}
+
+func (v *limitedIoBuffer) Limit(n int) IoBuffer {
+	return newLimitedIoBuffer(v, n)
+}
+
+func (m *multiIoBuffer) Limit(n int) IoBuffer {
+	return newLimitedIoBuffer(m, n)
+}
@@ -0,0 +1,108 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestIoBufferLimit(t *testing.T) {
+	b := NewIoBufferString("first|second|third")
+
+	v := b.Limit(6)
+	if v.Len() != 6 || v.String() != "first|" {
+		t.Fatalf("Expect first|, but got %q", v.String())
+	}
+	data, err := ioutil.ReadAll(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first|" {
+		t.Errorf("Expect first|, but got %q", data)
+	}
+	if b.String() != "second|third" {
+		t.Errorf("Expect the view to consume the buffer, but got %q", b.String())
+	}
+
+	v = b.Limit(7)
+	if p := v.Peek(8); p != nil {
+		t.Errorf("Expect Peek beyond the view to fail, but got %q", p)
+	}
+	var out bytes.Buffer
+	if _, err := v.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "second|" || b.String() != "third" {
+		t.Errorf("unexpected WriteTo result %q, left %q", out.String(), b.String())
+	}
+
+	if v := b.Limit(100); v.Len() != 5 {
+		t.Errorf("Expect the view to be clamped to 5, but got %d", v.Len())
+	}
+}
+
+func TestIoBufferLimitReadOnly(t *testing.T) {
+	b := NewIoBufferString("data")
+	v := b.Limit(2)
+	if _, err := v.Write([]byte("x")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly, but got %v", err)
+	}
+	if b.String() != "data" {
+		t.Errorf("Expect the buffer to be unchanged, but got %q", b.String())
+	}
+}
+
+func TestIoBufferLimitClose(t *testing.T) {
+	b := NewIoBufferString("bodynext")
+	v := b.Limit(4)
+	v.Drain(1)
+	rc := v.ReadCloser()
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "next" {
+		t.Errorf("Expect Close to skip the rest of the view, but got %q", b.String())
+	}
+
+	if _, ok := GetIoBuffer(0).(*ioBuffer); !ok {
+		t.Errorf("Expect the view not to be pooled")
+	}
+}
+
+func TestIoBufferLimitDrainTo(t *testing.T) {
+	b := NewIoBufferString("bodynext")
+	v := b.Limit(4)
+	n, err := v.DrainTo(ioutil.Discard, 10)
+	if err != nil || n != 4 {
+		t.Fatalf("Expect 4 bytes drained, but got %d, %v", n, err)
+	}
+	if b.String() != "next" {
+		t.Errorf("Expect next, but got %q", b.String())
+	}
+}
+
+func TestIoBufferLimitConsumedBehindItsBack(t *testing.T) {
+	for _, b := range []IoBuffer{NewIoBufferString("first|second|"), MultiIoBuffer(NewIoBufferString("first|"), NewIoBufferString("second|"))} {
+		v := b.Limit(6)
+		b.Drain(2)
+		b.WriteString("third|")
+		if v.String() != "rst|" {
+			t.Errorf("Expect the view to shrink to rst|, but got %q", v.String())
+		}
+
+		b.Reset()
+		b.WriteString("fourth|")
+		if v.Len() != 0 {
+			t.Errorf("Expect the view to be empty after a reset, but got %q", v.String())
+		}
+	}
+
+	b := NewIoBufferString("first|second|")
+	v := b.Limit(6)
+	nested := v.Limit(4)
+	b.Drain(5)
+	if v.String() != "|" || nested.Len() != 0 {
+		t.Errorf("unexpected views %q and %q", v.String(), nested.String())
+	}
+}
@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package buffer
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var pageSize = uintptr(os.Getpagesize())
+
+// releasePages advises the kernel that the whole pages backing b are no
+// longer needed, so they stop counting towards RSS. Their contents read as
+// zero afterwards.
+func releasePages(b []byte) {
+	if cap(b) == 0 {
+		return
+	}
+	b = b[:cap(b)]
+	addr := uintptr(unsafe.Pointer(&b[0]))
+	start := (addr + pageSize - 1) &^ (pageSize - 1)
+	end := (addr + uintptr(len(b))) &^ (pageSize - 1)
+	if end <= start {
+		return
+	}
+	syscall.Madvise(b[start-addr:end-addr], syscall.MADV_DONTNEED)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+// +build !linux
+
+package buffer
+
+// releasePages is a no-op on platforms without madvise(MADV_DONTNEED)
+func releasePages(b []byte) {}
@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestReleasePages(t *testing.T) {
+	b := make([]byte, 1<<20)
+	for i := range b {
+		b[i] = 0xff
+	}
+	releasePages(b)
+	if runtime.GOOS != "linux" {
+		return
+	}
+	zeros := 0
+	for _, c := range b {
+		if c == 0 {
+			zeros++
+		}
+	}
+	if zeros < len(b)/2 {
+		t.Errorf("Expect released pages to read as zero, but got %d zero bytes", zeros)
+	}
+}
+
+func TestSetReleaseThreshold(t *testing.T) {
+	SetReleaseThreshold(64 << 10)
+	defer SetReleaseThreshold(0)
+
+	for i := 0; i < 3; i++ {
+		b := GetBytes(128 << 10)
+		(*b)[0] = 1
+		PutBytes(b)
+	}
+}
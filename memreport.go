@@ -0,0 +1,67 @@
+package buffer
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// MemoryUsage summarizes the memory held by the buffer layer.
+type MemoryUsage struct {
+	// InUseBytes is the capacity of the slices handed out by all named
+	// pools, including the package level pool, and not yet put back.
+	InUseBytes int64
+	// PooledBytes is the capacity of the slices cached per P and per NUMA
+	// node by all named pools. Slices in the sync.Pools behind those
+	// caches aren't counted, the GC drops them at will.
+	PooledBytes int64
+	// HeapBytes is the heap memory held by the pools, InUseBytes plus
+	// PooledBytes.
+	HeapBytes int64
+
+	// OffHeapBytes is the size of the file mappings of the open
+	// MmapIoBuffers, which the GC doesn't see.
+	OffHeapBytes int64
+	// MmapBuffers is the number of open MmapIoBuffers.
+	MmapBuffers int64
+
+	// GCPercent is the GOGC setting the estimate below is based on, -1
+	// means the GC is off.
+	GCPercent int
+	// HeapGoalBytes estimates how much HeapBytes raise the heap goal of the
+	// GC: live heap grows the goal by GOGC percent on top of itself.
+	HeapGoalBytes int64
+}
+
+// MemoryReport returns the memory held by all named pools and mappings, so
+// capacity planners can see what the buffer layer costs.
+func MemoryReport() MemoryUsage {
+	var r MemoryUsage
+	for _, p := range NamedPools() {
+		_, _, _, _, inUseBytes := p.bp.counters()
+		r.InUseBytes += inUseBytes
+		r.PooledBytes += p.bp.cachedBytes()
+	}
+	r.HeapBytes = r.InUseBytes + r.PooledBytes
+	r.OffHeapBytes = atomic.LoadInt64(&mappings.bytes)
+	r.MmapBuffers = atomic.LoadInt64(&mappings.buffers)
+
+	r.GCPercent = gcPercent()
+	if r.GCPercent >= 0 {
+		r.HeapGoalBytes = r.HeapBytes + r.HeapBytes*int64(r.GCPercent)/100
+	}
+	return r
+}
+
+// gcPercent returns the GOGC setting from the environment, the runtime has
+// no way to read it back without changing it
+func gcPercent() int {
+	s := os.Getenv("GOGC")
+	if s == "off" {
+		return -1
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return 100
+}
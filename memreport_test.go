@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMemoryReport(t *testing.T) {
+	before := MemoryReport()
+	p := NewNamedPool("test-report")
+	b := p.Get(100 << 10)
+	r := MemoryReport()
+	if d := r.InUseBytes - before.InUseBytes; d < 100<<10 {
+		t.Errorf("Expect in-use bytes to grow by the slice, but got %d", d)
+	}
+	if r.HeapBytes != r.InUseBytes+r.PooledBytes {
+		t.Errorf("Expect heap bytes to add up: %+v", r)
+	}
+	if r.GCPercent == 100 && r.HeapGoalBytes != 2*r.HeapBytes {
+		t.Errorf("Expect the heap goal to double heap bytes: %+v", r)
+	}
+
+	p.Put(b)
+	r = MemoryReport()
+	if r.InUseBytes != before.InUseBytes {
+		t.Errorf("Expect in-use bytes back to %d, but got %d", before.InUseBytes, r.InUseBytes)
+	}
+	if !raceEnabled && r.PooledBytes < 100<<10 {
+		t.Errorf("Expect the slice to be counted as pooled, but got %d", r.PooledBytes)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	path := tempPath(t, "report")
+	defer os.RemoveAll(filepath.Dir(path))
+	m, err := NewMmapIoBuffer(path, os.O_RDWR|os.O_CREATE, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = MemoryReport()
+	if r.OffHeapBytes-before.OffHeapBytes != 1<<20+1 || r.MmapBuffers != before.MmapBuffers+1 {
+		t.Errorf("Expect the mapping counted off heap: %+v", r)
+	}
+	m.Close()
+	if r = MemoryReport(); r.OffHeapBytes != before.OffHeapBytes || r.MmapBuffers != before.MmapBuffers {
+		t.Errorf("Expect the mapping released: %+v", r)
+	}
+}
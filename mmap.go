@@ -0,0 +1,349 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrMmapUnsupported is returned by NewMmapIoBuffer on platforms without
+// file mappings.
+var ErrMmapUnsupported = errors.New("io buffer: mmap not supported")
+
+// mappings counts the open MmapIoBuffers and the bytes they map
+var mappings struct {
+	buffers int64
+	bytes   int64
+}
+
+// MmapIoBuffer is an IoBuffer whose memory is a shared mapping of a file.
+// Reads come straight out of the page cache without heap copies, which
+// makes it cheap to serve large static payloads, and writes land in the file
+// once flushed with Sync.
+//
+// The buffer never grows beyond the mapping: writes that don't fit fail with
+// ErrTooLarge. It slides its unread bytes down to the start of the file to
+// make room, like a pooled buffer does in its slice. A file opened read-only
+// is mapped read-only and writes fail with ErrReadOnly.
+type MmapIoBuffer struct {
+	*ioBuffer
+
+	f    *os.File
+	data []byte
+	// the file is mapped PROT_READ, see readOnlyError
+	readOnly bool
+	// the mapping has a byte past the max size, see readFrom
+	spare bool
+	// the size of the file when mapped and the end of the bytes ever
+	// written to the mapping, Close truncates an extended file to the
+	// larger of the two
+	fileSize int64
+	high     int
+	extended bool
+}
+
+// NewMmapIoBuffer maps the first size bytes of the file at path, opened with
+// flag as by os.OpenFile, and returns them as a buffer holding the current
+// contents of the file. size <= 0 maps the whole file.
+//
+// With os.O_RDONLY the mapping is read-only and the buffer only serves the
+// contents, a file shorter than size is mapped up to its end. With
+// os.O_RDWR a file shorter than size is extended so that writes have room,
+// and Close truncates it back to the end of the bytes written, never below
+// its original size. Add os.O_CREATE to create a missing file.
+func NewMmapIoBuffer(path string, flag int, size int) (*MmapIoBuffer, error) {
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	readOnly := flag&(os.O_WRONLY|os.O_RDWR) == 0
+	fileSize := fi.Size()
+	if size <= 0 || readOnly && int64(size) > fileSize {
+		// pages past the end of the file can't be touched
+		if fileSize > int64(maxInt-1) {
+			f.Close()
+			return nil, &Error{Op: "mmap", Err: ErrTooLarge}
+		}
+		size = int(fileSize)
+	}
+
+	// one spare byte past size lets ReadFrom tell a reader that overflows
+	// the buffer from one that fills it exactly, as it does for pooled
+	// buffers. A file covering size is left alone and readFrom probes
+	// the reader instead.
+	mapped := size
+	extended := false
+	if !readOnly && fileSize != int64(size) {
+		mapped = size + 1
+		extended = fileSize < int64(mapped)
+	}
+	if extended {
+		if err := f.Truncate(int64(mapped)); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	data := []byte{}
+	if mapped > 0 {
+		data, err = mmapFile(f, mapped, !readOnly)
+	}
+	if err != nil {
+		if extended {
+			f.Truncate(fileSize)
+		}
+		f.Close()
+		return nil, err
+	}
+
+	n := size
+	if fileSize < int64(size) {
+		n = int(fileSize)
+	}
+	atomic.AddInt64(&mappings.buffers, 1)
+	atomic.AddInt64(&mappings.bytes, int64(len(data)))
+	b := New(withBytes(data[:n]), WithMaxSize(size)).(*ioBuffer)
+	b.fixed = true
+	return &MmapIoBuffer{
+		ioBuffer: b,
+		f:        f,
+		data:     data,
+		readOnly: readOnly,
+		spare:    mapped > size,
+		fileSize: fileSize,
+		high:     n,
+		extended: extended,
+	}, nil
+}
+
+// mark records the end of the bytes written to the mapping
+func (m *MmapIoBuffer) mark() {
+	if l := len(m.buf); l > m.high {
+		m.high = l
+	}
+}
+
+// readOnlyError fails writes to a read-only mapping, which would fault
+func (m *MmapIoBuffer) readOnlyError(op string, size int) error {
+	return opError(op, size, m, ErrReadOnly)
+}
+
+func (m *MmapIoBuffer) Write(p []byte) (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("write", len(p))
+	}
+	defer m.mark()
+	return m.ioBuffer.Write(p)
+}
+
+func (m *MmapIoBuffer) WriteString(s string) (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("write", len(s))
+	}
+	defer m.mark()
+	return m.ioBuffer.WriteString(s)
+}
+
+func (m *MmapIoBuffer) WriteRune(r rune) (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("write", utf8.RuneLen(r))
+	}
+	defer m.mark()
+	return m.ioBuffer.WriteRune(r)
+}
+
+func (m *MmapIoBuffer) Append(data []byte) error {
+	if m.readOnly {
+		return m.readOnlyError("append", len(data))
+	}
+	defer m.mark()
+	return m.ioBuffer.Append(data)
+}
+
+func (m *MmapIoBuffer) AppendByte(data byte) error {
+	if m.readOnly {
+		return m.readOnlyError("append", 1)
+	}
+	defer m.mark()
+	return m.ioBuffer.AppendByte(data)
+}
+
+func (m *MmapIoBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	if m.readOnly {
+		return m.readOnlyError("replace", len(replacement))
+	}
+	defer m.mark()
+	return m.ioBuffer.ReplaceRange(from, to, replacement)
+}
+
+func (m *MmapIoBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("sanitize", 0)
+	}
+	defer m.mark()
+	return m.ioBuffer.SanitizeUTF8(replacement)
+}
+
+// ToUpperASCII panics on a read-only mapping.
+func (m *MmapIoBuffer) ToUpperASCII() {
+	if m.readOnly {
+		panic(m.readOnlyError("upper", 0))
+	}
+	m.ioBuffer.ToUpperASCII()
+}
+
+// ToLowerASCII panics on a read-only mapping.
+func (m *MmapIoBuffer) ToLowerASCII() {
+	if m.readOnly {
+		panic(m.readOnlyError("lower", 0))
+	}
+	m.ioBuffer.ToLowerASCII()
+}
+
+// ReplaceByte panics on a read-only mapping.
+func (m *MmapIoBuffer) ReplaceByte(old, new byte) {
+	if m.readOnly {
+		panic(m.readOnlyError("replace", 0))
+	}
+	m.ioBuffer.ReplaceByte(old, new)
+}
+
+func (m *MmapIoBuffer) UnmarshalJSON(data []byte) error {
+	if m.readOnly {
+		return m.readOnlyError("unmarshal", len(data))
+	}
+	defer m.mark()
+	return m.ioBuffer.UnmarshalJSON(data)
+}
+
+func (m *MmapIoBuffer) UnmarshalBinary(data []byte) error {
+	if m.readOnly {
+		return m.readOnlyError("unmarshal", len(data))
+	}
+	defer m.mark()
+	return m.ioBuffer.UnmarshalBinary(data)
+}
+
+func (m *MmapIoBuffer) ReadOnce(r io.Reader, duration time.Duration) (int64, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("read", 0)
+	}
+	defer m.mark()
+	return m.ioBuffer.ReadOnce(r, duration)
+}
+
+func (m *MmapIoBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("splice", max)
+	}
+	defer m.mark()
+	return m.ioBuffer.SpliceTo(dst, src, max)
+}
+
+func (m *MmapIoBuffer) ReadFrom(r io.Reader) (int64, error) {
+	return m.readFrom(r, -1, nil)
+}
+
+func (m *MmapIoBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	if max < 0 {
+		panic(opError("read", int(max), m, ErrNegativeCount))
+	}
+	return m.readFrom(r, max, nil)
+}
+
+func (m *MmapIoBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, progress func(int64)) (int64, error) {
+		return m.readFrom(r, -1, progress)
+	}, r)
+}
+
+// readFrom is ioBuffer.readFrom, a mapping without the spare byte past the
+// max size probes r for one more byte once full to report the overflow
+func (m *MmapIoBuffer) readFrom(r io.Reader, max int64, progress func(int64)) (int64, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("read", 0)
+	}
+	defer m.mark()
+	n, err := m.ioBuffer.readFrom(r, max, progress)
+	if err != nil || m.spare || m.Len() < m.maxSize {
+		return n, err
+	}
+	var probe [1]byte
+	k, e := r.Read(probe[:])
+	if k == 0 {
+		if e == io.EOF {
+			if m.autoEOF {
+				m.eof = true
+			}
+		} else if e != nil {
+			return n, opError("read", 0, m, e)
+		}
+		return n, nil
+	}
+	limitErr := ErrLimitExceeded
+	if max < 0 || n < max {
+		limitErr = m.overflow(1)
+	}
+	err = opError("read", int(max), m, limitErr)
+	if limitErr != ErrLimitExceeded {
+		m.allocFailed(1, err)
+	}
+	return n, err
+}
+
+// Sync flushes the mapped memory to the file.
+func (m *MmapIoBuffer) Sync() error {
+	if m.closed {
+		return opError("sync", 0, m, ErrClosedBuffer)
+	}
+	return msyncFile(m.data)
+}
+
+// Alloc resets the buffer, its memory stays the file mapping.
+func (m *MmapIoBuffer) Alloc(int) {
+	m.Reset()
+}
+
+// Close flushes and unmaps the file, then closes it. The buffer mustn't be
+// used afterwards.
+func (m *MmapIoBuffer) Close() error {
+	if m.closed {
+		return opError("close", 0, m, ErrClosedBuffer)
+	}
+	m.mark()
+	end := int64(m.high)
+	if end < m.fileSize {
+		end = m.fileSize
+	}
+	m.Reset()
+	m.buf = nullByte
+	m.closed = true
+
+	var err error
+	if len(m.data) > 0 {
+		err = msyncFile(m.data)
+		if uerr := munmapFile(m.data); err == nil {
+			err = uerr
+		}
+	}
+	atomic.AddInt64(&mappings.buffers, -1)
+	atomic.AddInt64(&mappings.bytes, -int64(len(m.data)))
+	m.data = nil
+	if m.extended {
+		if terr := m.f.Truncate(end); err == nil {
+			err = terr
+		}
+	}
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
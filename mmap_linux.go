@@ -0,0 +1,43 @@
+//go:build linux
+// +build linux
+
+package buffer
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mmapFile maps the first size bytes of f shared, writable if f was opened
+// for writing
+func mmapFile(f *os.File, size int, writable bool) ([]byte, error) {
+	prot := syscall.PROT_READ
+	if writable {
+		prot |= syscall.PROT_WRITE
+	}
+	p, err := syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+	return p, nil
+}
+
+func munmapFile(p []byte) error {
+	if err := syscall.Munmap(p); err != nil {
+		return os.NewSyscallError("munmap", err)
+	}
+	return nil
+}
+
+// msyncFile writes the dirty pages of the mapping p back to the file
+func msyncFile(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&p[0])), uintptr(len(p)), syscall.MS_SYNC)
+	if errno != 0 {
+		return os.NewSyscallError("msync", errno)
+	}
+	return nil
+}
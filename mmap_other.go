@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package buffer
+
+import "os"
+
+// mmapFile fails on platforms without file mappings
+func mmapFile(f *os.File, size int, writable bool) ([]byte, error) {
+	return nil, ErrMmapUnsupported
+}
+
+func munmapFile(p []byte) error {
+	return nil
+}
+
+func msyncFile(p []byte) error {
+	return nil
+}
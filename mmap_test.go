@@ -0,0 +1,185 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func tempPath(t *testing.T, name string) string {
+	dir, err := ioutil.TempDir("", "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, name)
+}
+
+func TestMmapIoBufferRead(t *testing.T) {
+	path := tempPath(t, "static")
+	defer os.RemoveAll(filepath.Dir(path))
+	content := []byte(randString(10000))
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewMmapIoBuffer(path, os.O_RDONLY, 0)
+	if runtime.GOOS != "linux" {
+		if err != ErrMmapUnsupported {
+			t.Fatalf("Expect ErrMmapUnsupported, but got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), content) {
+		t.Fatal("Expect the buffer to hold the file")
+	}
+	if _, err := b.Write([]byte("x")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly writing to a read-only mapping, but got %v", err)
+	}
+	if _, err := b.ReadFrom(strings.NewReader("x")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly reading into a read-only mapping, but got %v", err)
+	}
+	var out bytes.Buffer
+	if n, err := b.WriteTo(&out); n != int64(len(content)) || err != nil || !bytes.Equal(out.Bytes(), content) {
+		t.Errorf("WriteTo failed: %d, %v", n, err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect ErrClosedBuffer, but got %v", err)
+	}
+	if got, _ := ioutil.ReadFile(path); !bytes.Equal(got, content) {
+		t.Error("Expect the file to be left intact")
+	}
+}
+
+func TestMmapIoBufferWrite(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("mmap not supported")
+	}
+	path := tempPath(t, "log")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	b, err := NewMmapIoBuffer(path, os.O_RDWR|os.O_CREATE, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if b.Len() != 0 {
+		t.Fatalf("Expect an empty buffer for a new file, but got %d bytes", b.Len())
+	}
+
+	b.WriteString(strings.Repeat("a", 60))
+	if err := b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(path); !bytes.HasPrefix(got, []byte(strings.Repeat("a", 60))) {
+		t.Error("Expect synced writes in the file")
+	}
+
+	// consumed bytes make room by sliding the rest down
+	b.Drain(50)
+	if _, err := b.WriteString(strings.Repeat("b", 80)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != strings.Repeat("a", 10)+strings.Repeat("b", 80) {
+		t.Errorf("unexpected contents %q", b.String())
+	}
+
+	// ReadFrom fills the mapping and reports the overflow
+	n, err := b.ReadFrom(strings.NewReader(strings.Repeat("c", 20)))
+	if n != 10 || !errors.Is(err, ErrTooLarge) || b.Len() != 100 {
+		t.Errorf("Expect ReadFrom to stop at the mapping, but got %d, %v", n, err)
+	}
+	b.Reset()
+	if n, err := b.ReadFrom(strings.NewReader(strings.Repeat("d", 100))); n != 100 || err != nil {
+		t.Errorf("Expect ReadFrom to fill the mapping exactly, but got %d, %v", n, err)
+	}
+	b.Drain(40)
+	b.Alloc(0)
+	b.WriteString("tail")
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the file keeps everything ever written, not just the buffered bytes
+	if got, _ := ioutil.ReadFile(path); string(got) != "tail"+strings.Repeat("d", 96) {
+		t.Errorf("Expect the file truncated to the written bytes, but got %q", got)
+	}
+}
+
+func TestMmapIoBufferExistingFile(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("mmap not supported")
+	}
+	path := tempPath(t, "existing")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if _, err := NewMmapIoBuffer(path, os.O_RDONLY, 0); !os.IsNotExist(err) {
+		t.Fatalf("Expect a missing file not to be created, but got %v", err)
+	}
+
+	content := []byte(randString(1000))
+	if err := ioutil.WriteFile(path, content, 0444); err != nil {
+		t.Fatal(err)
+	}
+	// reading the file to the end must leave it intact
+	b, err := NewMmapIoBuffer(path, os.O_RDONLY, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadAll(b); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("unexpected contents: %d bytes, %v", len(got), err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(path); !bytes.Equal(got, content) {
+		t.Fatal("Expect the file to be left intact")
+	}
+
+	os.Chmod(path, 0644)
+	b, err = NewMmapIoBuffer(path, os.O_RDWR, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(path); fi.Size() != 1000 {
+		t.Errorf("Expect a file covering the mapping not to change, but got %d bytes", fi.Size())
+	}
+	ioutil.ReadAll(b)
+	b.WriteString("head")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != "head"+string(content[4:]) {
+		t.Error("Expect the file to keep its size and the bytes not written")
+	}
+
+	// a mapping of the whole file has no spare byte, a full buffer still
+	// tells a reader that overflows it
+	b, err = NewMmapIoBuffer(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.Reset()
+	if n, err := b.ReadFrom(strings.NewReader(strings.Repeat("e", 1000))); n != 1000 || err != nil {
+		t.Errorf("Expect ReadFrom to fill the mapping exactly, but got %d, %v", n, err)
+	}
+	b.Reset()
+	if n, err := b.ReadFrom(strings.NewReader(strings.Repeat("e", 1001))); n != 1000 || !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expect ReadFrom to stop at the mapping, but got %d, %v", n, err)
+	}
+	b.Reset()
+	if n, err := b.ReadFromLimit(strings.NewReader(strings.Repeat("e", 1001)), 1000); n != 1000 || !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expect ReadFromLimit to report the limit, but got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,438 @@
+package buffer
+
+import (
+	"io"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gottingen/atomic"
+)
+
+// multiIoBuffer reads across a sequence of buffers, putting each back to the
+// pool once it is exhausted
+type multiIoBuffer struct {
+	bufs    []IoBuffer
+	count   *atomic.Int32
+	eof     bool
+	autoEOF bool
+	closed  bool
+	hooks   *Hooks
+	tee     IoBuffer
+	// bytes of the rune returned by ReadRune, for UnreadRune
+	lastRune    [utf8.UTFMax]byte
+	lastRuneLen int
+	// stats of the multi buffer, Grows and CopiedBytes include the buffers
+	// already released
+	stats BufferStats
+	// unread bytes dropped by Reset and the bytes read before Alloc, see
+	// consumedBytes
+	dropped int64
+}
+
+// MultiIoBuffer returns an IoBuffer that is the logical concatenation of
+// bufs. Reads go through the buffers in sequence and each buffer is put back
+// to the pool via PutIoBuffer as soon as it is exhausted, writes append to
+// the last buffer.
+//
+// Bytes and Peek across buffer boundaries coalesce the buffers into one.
+// bufs are owned by the returned buffer and mustn't be used by the caller
+// anymore.
+func MultiIoBuffer(bufs ...IoBuffer) IoBuffer {
+	m := &multiIoBuffer{count: atomic.NewInt32(1)}
+	for _, b := range bufs {
+		if b != nil {
+			m.bufs = append(m.bufs, b)
+		}
+	}
+	m.stats.PeakLen = m.Len()
+	return m
+}
+
+// release puts the first buffer back to the pool
+func (m *multiIoBuffer) release() {
+	b := m.bufs[0]
+	s := b.Stats()
+	m.stats.Grows += s.Grows
+	m.stats.CopiedBytes += s.CopiedBytes
+	PutIoBuffer(b)
+	m.bufs[0] = nil
+	m.bufs = m.bufs[1:]
+}
+
+func (m *multiIoBuffer) releaseAll() {
+	for len(m.bufs) > 0 {
+		m.release()
+	}
+	m.bufs = nil
+}
+
+// releaseExhausted releases the empty buffers at the front
+func (m *multiIoBuffer) releaseExhausted() {
+	for len(m.bufs) > 0 && m.bufs[0].Len() == 0 {
+		m.release()
+	}
+}
+
+// last returns the buffer writes go to
+func (m *multiIoBuffer) last() IoBuffer {
+	if len(m.bufs) == 0 {
+		b := GetIoBuffer(0)
+		b.SetHooks(m.hooks)
+		b.SetAutoEOF(m.autoEOF)
+		b.Tee(m.tee)
+		m.bufs = append(m.bufs, b)
+	}
+	return m.bufs[len(m.bufs)-1]
+}
+
+// coalesce merges the buffers into one
+func (m *multiIoBuffer) coalesce() {
+	m.releaseExhausted()
+	if len(m.bufs) <= 1 {
+		return
+	}
+	b := GetIoBuffer(m.Len())
+	b.SetHooks(m.hooks)
+	b.SetAutoEOF(m.autoEOF)
+	b.Tee(m.tee)
+	eof := false
+	for _, buf := range m.bufs {
+		b.Write(buf.Bytes())
+		eof = eof || buf.EOF()
+	}
+	b.SetEOF(eof)
+	m.releaseAll()
+	m.bufs = []IoBuffer{b}
+}
+
+func (m *multiIoBuffer) wrote(n int64) {
+	m.stats.BytesWritten += n
+	if l := m.Len(); l > m.stats.PeakLen {
+		m.stats.PeakLen = l
+	}
+}
+
+func (m *multiIoBuffer) Read(p []byte) (n int, err error) {
+	if m.closed {
+		return 0, opError("read", len(p), m, ErrClosedBuffer)
+	}
+	m.lastRuneLen = 0
+	for n < len(p) && len(m.bufs) > 0 {
+		k, _ := m.bufs[0].Read(p[n:])
+		n += k
+		if m.bufs[0].Len() == 0 {
+			m.release()
+		}
+	}
+	m.stats.BytesRead += int64(n)
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (m *multiIoBuffer) ReadFrom(r io.Reader) (int64, error) {
+	if m.closed {
+		return 0, opError("read", 0, m, ErrClosedBuffer)
+	}
+	n, err := m.last().ReadFrom(r)
+	m.wrote(n)
+	return n, err
+}
+
+func (m *multiIoBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	if m.closed {
+		return 0, opError("read", 0, m, ErrClosedBuffer)
+	}
+	n, err := m.last().ReadFromLimit(r, max)
+	m.wrote(n)
+	return n, err
+}
+
+func (m *multiIoBuffer) ReadOnce(r io.Reader, duration time.Duration) (int64, error) {
+	if m.closed {
+		return 0, opError("read", 0, m, ErrClosedBuffer)
+	}
+	n, err := m.last().ReadOnce(r, duration)
+	m.wrote(n)
+	return n, err
+}
+
+func (m *multiIoBuffer) Write(p []byte) (int, error) {
+	if m.closed {
+		return 0, opError("write", len(p), m, ErrClosedBuffer)
+	}
+	n, err := m.last().Write(p)
+	m.wrote(int64(n))
+	return n, err
+}
+
+func (m *multiIoBuffer) WriteString(s string) (int, error) {
+	if m.closed {
+		return 0, opError("write", len(s), m, ErrClosedBuffer)
+	}
+	n, err := m.last().WriteString(s)
+	m.wrote(int64(n))
+	return n, err
+}
+
+func (m *multiIoBuffer) WriteTo(w io.Writer) (n int64, err error) {
+	if m.closed {
+		return 0, opError("write to", 0, m, ErrClosedBuffer)
+	}
+	m.lastRuneLen = 0
+	for len(m.bufs) > 0 {
+		k, e := m.bufs[0].WriteTo(w)
+		n += k
+		m.stats.BytesRead += k
+		if m.bufs[0].Len() == 0 {
+			m.release()
+		}
+		if e != nil {
+			return n, e
+		}
+		if k == 0 && len(m.bufs) > 0 && m.bufs[0].Len() > 0 {
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+func (m *multiIoBuffer) Peek(n int) []byte {
+	m.releaseExhausted()
+	if len(m.bufs) > 0 && m.bufs[0].Len() >= n {
+		return m.bufs[0].Peek(n)
+	}
+	if m.Len() < n {
+		return nil
+	}
+	m.coalesce()
+	return m.bufs[0].Peek(n)
+}
+
+func (m *multiIoBuffer) Bytes() []byte {
+	m.coalesce()
+	if len(m.bufs) == 0 {
+		return nullByte
+	}
+	return m.bufs[0].Bytes()
+}
+
+// CopyBytes copies buffer by buffer, without coalescing
+func (m *multiIoBuffer) CopyBytes() []byte {
+	p := make([]byte, 0, m.Len())
+	for _, b := range m.bufs {
+		p = append(p, b.Bytes()...)
+	}
+	return p
+}
+
+func (m *multiIoBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	if m.closed {
+		return opError("replace", len(replacement), m, ErrClosedBuffer)
+	}
+	m.coalesce()
+	m.lastRuneLen = 0
+	return m.last().ReplaceRange(from, to, replacement)
+}
+
+func (m *multiIoBuffer) Drain(offset int) {
+	if offset < 0 {
+		panic(opError("drain", offset, m, ErrNegativeCount))
+	}
+	m.lastRuneLen = 0
+	if offset > m.Len() {
+		return
+	}
+	m.stats.BytesRead += int64(offset)
+	for offset > 0 {
+		l := m.bufs[0].Len()
+		if offset < l {
+			m.bufs[0].Drain(offset)
+			return
+		}
+		m.bufs[0].Drain(l)
+		offset -= l
+		m.release()
+	}
+	m.releaseExhausted()
+}
+
+func (m *multiIoBuffer) DiscardAll() {
+	m.Drain(m.Len())
+}
+
+func (m *multiIoBuffer) DrainTo(w io.Writer, n int) (int, error) {
+	if n < 0 {
+		panic(opError("drain to", n, m, ErrNegativeCount))
+	}
+	if m.closed {
+		return 0, opError("drain to", n, m, ErrClosedBuffer)
+	}
+	m.lastRuneLen = 0
+	written := 0
+	for n > 0 && len(m.bufs) > 0 {
+		k, err := m.bufs[0].DrainTo(w, n)
+		written += k
+		n -= k
+		m.stats.BytesRead += int64(k)
+		if m.bufs[0].Len() == 0 {
+			m.release()
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (m *multiIoBuffer) Alloc(size int) {
+	m.dropped += m.stats.BytesRead + int64(m.Len())
+	m.releaseAll()
+	m.bufs = []IoBuffer{GetIoBuffer(size)}
+	m.stats = BufferStats{}
+	m.autoEOF = false
+	m.closed = false
+	m.tee = nil
+	m.lastRuneLen = 0
+}
+
+func (m *multiIoBuffer) Free() {
+	m.Reset()
+}
+
+func (m *multiIoBuffer) Len() int {
+	n := 0
+	for _, b := range m.bufs {
+		n += b.Len()
+	}
+	return n
+}
+
+func (m *multiIoBuffer) Cap() int {
+	n := 0
+	for _, b := range m.bufs {
+		n += b.Cap()
+	}
+	return n
+}
+
+func (m *multiIoBuffer) Reset() {
+	m.dropped += int64(m.Len())
+	m.releaseAll()
+	m.eof = false
+	m.lastRuneLen = 0
+}
+
+func (m *multiIoBuffer) Clone() IoBuffer {
+	buf := GetIoBuffer(m.Len())
+	for _, b := range m.bufs {
+		buf.Write(b.Bytes())
+	}
+	buf.SetEOF(m.EOF())
+	return buf
+}
+
+func (m *multiIoBuffer) String() string {
+	var sb strings.Builder
+	sb.Grow(m.Len())
+	for _, b := range m.bufs {
+		sb.Write(b.Bytes())
+	}
+	return sb.String()
+}
+
+func (m *multiIoBuffer) Count(count int32) int32 {
+	return m.count.Add(count)
+}
+
+func (m *multiIoBuffer) EOF() bool {
+	if m.eof {
+		return true
+	}
+	for _, b := range m.bufs {
+		if b.EOF() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiIoBuffer) SetEOF(eof bool) {
+	m.eof = eof
+	if !eof {
+		for _, b := range m.bufs {
+			b.SetEOF(false)
+		}
+	}
+}
+
+func (m *multiIoBuffer) SetAutoEOF(on bool) {
+	m.autoEOF = on
+	for _, b := range m.bufs {
+		b.SetAutoEOF(on)
+	}
+}
+
+func (m *multiIoBuffer) Tee(dst IoBuffer) {
+	m.tee = dst
+	for _, b := range m.bufs {
+		b.Tee(dst)
+	}
+}
+
+func (m *multiIoBuffer) Close() error {
+	if m.closed {
+		return opError("close", 0, m, ErrClosedBuffer)
+	}
+	m.dropped += int64(m.Len())
+	m.releaseAll()
+	m.closed = true
+	return nil
+}
+
+func (m *multiIoBuffer) ReadCloser() io.ReadCloser {
+	return &ioBufferReadCloser{b: m}
+}
+
+func (m *multiIoBuffer) Dump(maxBytes int) string {
+	return dump([]byte(m.String()), maxBytes)
+}
+
+func (m *multiIoBuffer) SetHooks(h *Hooks) {
+	m.hooks = h
+	for _, b := range m.bufs {
+		b.SetHooks(h)
+	}
+}
+
+func (m *multiIoBuffer) Stats() BufferStats {
+	s := m.stats
+	for _, b := range m.bufs {
+		bs := b.Stats()
+		s.Grows += bs.Grows
+		s.CopiedBytes += bs.CopiedBytes
+	}
+	return s
+}
+
+func (m *multiIoBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	if m.closed {
+		return 0, opError("splice", max, m, ErrClosedBuffer)
+	}
+	written, err := m.WriteTo(dst)
+	if err != nil {
+		return written, err
+	}
+	n, err := m.last().SpliceTo(dst, src, max)
+	m.releaseExhausted()
+	return written + n, err
+}
+
+func (m *multiIoBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, _ func(int64)) (int64, error) {
+		return m.ReadFrom(r)
+	}, r)
+}
@@ -0,0 +1,94 @@
+package buffer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func newMulti() IoBuffer {
+	header := GetIoBuffer(0)
+	header.WriteString("header|")
+	body := GetIoBuffer(0)
+	body.WriteString("body|")
+	trailer := GetIoBuffer(0)
+	trailer.WriteString("trailer")
+	return MultiIoBuffer(header, body, trailer)
+}
+
+func TestMultiIoBufferRead(t *testing.T) {
+	m := newMulti()
+	if m.Len() != 19 {
+		t.Fatalf("Expect 19, but got %d", m.Len())
+	}
+
+	p := make([]byte, 4)
+	n, _ := m.Read(p)
+	if n != 4 || string(p) != "head" {
+		t.Errorf("Expect head, but got %q", p[:n])
+	}
+	rest, err := ioutil.ReadAll(m.ReadCloser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "er|body|trailer" {
+		t.Errorf("Expect er|body|trailer, but got %q", rest)
+	}
+	if len(m.(*multiIoBuffer).bufs) != 0 {
+		t.Errorf("Expect exhausted buffers to be released")
+	}
+}
+
+func TestMultiIoBufferPut(t *testing.T) {
+	before := GetPoolStats().IoBufferPuts
+	m := newMulti()
+	m.Drain(len("header|"))
+	if puts := GetPoolStats().IoBufferPuts - before; puts != 1 {
+		t.Errorf("Expect the header buffer to be put, but got %d puts", puts)
+	}
+
+	var out bytes.Buffer
+	m.WriteTo(&out)
+	if out.String() != "body|trailer" {
+		t.Errorf("Expect body|trailer, but got %q", out.String())
+	}
+	if puts := GetPoolStats().IoBufferPuts - before; puts != 3 {
+		t.Errorf("Expect all buffers to be put, but got %d puts", puts)
+	}
+}
+
+func TestMultiIoBufferBytes(t *testing.T) {
+	m := newMulti()
+	if p := m.Peek(10); string(p) != "header|bod" {
+		t.Errorf("Expect header|bod, but got %q", p)
+	}
+	m.WriteString("!")
+	if string(m.Bytes()) != "header|body|trailer!" {
+		t.Errorf("Expect header|body|trailer!, but got %q", m.Bytes())
+	}
+	if m.String() != "header|body|trailer!" {
+		t.Errorf("Expect header|body|trailer!, but got %q", m.String())
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Write([]byte("x")); err == nil {
+		t.Error("Expect write after Close to fail")
+	}
+}
+
+func TestMultiIoBufferDrainTo(t *testing.T) {
+	m := newMulti()
+	var out bytes.Buffer
+	n, err := m.DrainTo(&out, 10)
+	if err != nil || n != 10 || out.String() != "header|bod" {
+		t.Fatalf("unexpected DrainTo result %d, %v, %q", n, err, out.String())
+	}
+	if m.String() != "y|trailer" {
+		t.Errorf("Expect y|trailer, but got %q", m.String())
+	}
+	m.DiscardAll()
+	if m.Len() != 0 || len(m.(*multiIoBuffer).bufs) != 0 {
+		t.Errorf("Expect DiscardAll to release all buffers")
+	}
+}
@@ -0,0 +1,207 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrInvalidMultipart is returned for malformed multipart bodies and
+// boundaries.
+var ErrInvalidMultipart = errors.New("io buffer: invalid multipart body")
+
+// maxBoundaryPadding is the longest transport padding accepted after a
+// boundary
+const maxBoundaryPadding = 256
+
+// MultipartKind is the kind of a MultipartToken.
+type MultipartKind int
+
+const (
+	// MultipartHeader starts a part, it carries the part's headers.
+	MultipartHeader MultipartKind = iota
+	// MultipartBody is a piece of the body of the current part.
+	MultipartBody
+	// MultipartEnd follows the last part.
+	MultipartEnd
+)
+
+// MultipartToken is a piece of a multipart body. Its slices alias the
+// scanned buffer and are valid until the next call of Next.
+type MultipartToken struct {
+	Kind    MultipartKind
+	Headers []Header
+	Body    []byte
+}
+
+const (
+	multipartPreamble = iota
+	multipartBoundary
+	multipartHeaders
+	multipartBody
+	multipartDone
+)
+
+// MultipartScanner splits a multipart body (RFC 2046), such as a form
+// upload, into part headers and body pieces as it arrives in an IoBuffer,
+// without copying:
+//
+//	for {
+//		tok, err := s.Next(b)
+//		if err == buffer.ErrNeedMore {
+//			_, err = b.ReadOnce(conn, timeout)
+//			...
+//			continue
+//		}
+//		...
+//	}
+//
+// Body pieces end where the buffered bytes could be the start of a
+// boundary, so a part's body usually comes in several pieces, and a
+// boundary split across reads is recognized once it is complete. The
+// preamble is discarded, the epilogue left unread.
+type MultipartScanner struct {
+	// MaxHeaderSize is the limit of the headers of a part, larger headers
+	// fail with ErrTooLarge. 0 means DefaultMaxHeadSize.
+	MaxHeaderSize int
+
+	// delim is CRLF, "--" and the boundary
+	delim   []byte
+	state   int
+	pending int
+	headers []Header
+}
+
+// NewMultipartScanner returns a scanner of the multipart body delimited by
+// boundary, the boundary parameter of its content type.
+func NewMultipartScanner(boundary string) (*MultipartScanner, error) {
+	if len(boundary) == 0 || len(boundary) > 70 {
+		return nil, &Error{Op: "multipart scanner", Size: len(boundary), Err: ErrInvalidMultipart}
+	}
+	return &MultipartScanner{delim: []byte("\r\n--" + boundary)}, nil
+}
+
+// Next returns the next token of the multipart body at the start of the
+// unread bytes of b, consuming the previous token. It returns ErrNeedMore
+// while no token is complete and io.EOF after MultipartEnd.
+func (s *MultipartScanner) Next(b IoBuffer) (MultipartToken, error) {
+	b.Drain(s.pending)
+	s.pending = 0
+	for {
+		data := b.Bytes()
+		switch s.state {
+		case multipartPreamble:
+			// the first boundary may lack the leading CRLF
+			dash := s.delim[2:]
+			if bytes.HasPrefix(data, dash) {
+				b.Drain(len(dash))
+				s.state = multipartBoundary
+				continue
+			}
+			if bytes.HasPrefix(dash, data) {
+				return MultipartToken{}, ErrNeedMore
+			}
+			if i := bytes.Index(data, s.delim); i >= 0 {
+				b.Drain(i + len(s.delim))
+				s.state = multipartBoundary
+				continue
+			}
+			b.Drain(len(data) - s.heldBack(data))
+			return MultipartToken{}, ErrNeedMore
+
+		case multipartBoundary:
+			if len(data) < 2 {
+				return MultipartToken{}, ErrNeedMore
+			}
+			if data[0] == '-' && data[1] == '-' {
+				b.Drain(2)
+				s.state = multipartDone
+				return MultipartToken{Kind: MultipartEnd}, nil
+			}
+			i := 0
+			for i < len(data) && i <= maxBoundaryPadding && (data[i] == ' ' || data[i] == '\t') {
+				i++
+			}
+			switch {
+			case i > maxBoundaryPadding:
+				return MultipartToken{}, opError("multipart", 0, b, ErrInvalidMultipart)
+			case i+2 > len(data):
+				return MultipartToken{}, ErrNeedMore
+			case data[i] != '\r' || data[i+1] != '\n':
+				return MultipartToken{}, opError("multipart", 0, b, ErrInvalidMultipart)
+			}
+			b.Drain(i + 2)
+			s.state = multipartHeaders
+
+		case multipartHeaders:
+			return s.parseHeaders(b, data)
+
+		case multipartBody:
+			i := bytes.Index(data, s.delim)
+			if i == 0 {
+				b.Drain(len(s.delim))
+				s.state = multipartBoundary
+				continue
+			}
+			if i < 0 {
+				i = len(data) - s.heldBack(data)
+				if i == 0 {
+					return MultipartToken{}, ErrNeedMore
+				}
+			}
+			s.pending = i
+			return MultipartToken{Kind: MultipartBody, Body: data[:i]}, nil
+
+		default:
+			return MultipartToken{}, io.EOF
+		}
+	}
+}
+
+func (s *MultipartScanner) parseHeaders(b IoBuffer, data []byte) (MultipartToken, error) {
+	max := s.MaxHeaderSize
+	if max <= 0 {
+		max = DefaultMaxHeadSize
+	}
+	end := 2
+	if !bytes.HasPrefix(data, []byte("\r\n")) {
+		end = headEnd(data, 0, 0)
+	}
+	if end > max || end < 0 && len(data) > max {
+		return MultipartToken{}, opError("multipart", max, b, ErrTooLarge)
+	}
+	if end < 0 {
+		return MultipartToken{}, ErrNeedMore
+	}
+	s.headers = s.headers[:0]
+	for rest := data[:end]; ; {
+		var line []byte
+		line, rest = nextLine(rest)
+		if len(line) == 0 {
+			break
+		}
+		i := bytes.IndexByte(line, ':')
+		if i < 0 || !isToken(line[:i]) {
+			return MultipartToken{}, opError("multipart", 0, b, ErrInvalidMultipart)
+		}
+		s.headers = append(s.headers, Header{Key: line[:i], Value: trimBlanks(line[i+1:])})
+	}
+	s.pending = end
+	s.state = multipartBody
+	return MultipartToken{Kind: MultipartHeader, Headers: s.headers}, nil
+}
+
+// heldBack returns the length of the longest suffix of data that may be
+// the start of a delimiter
+func (s *MultipartScanner) heldBack(data []byte) int {
+	n := len(s.delim) - 1
+	if n > len(data) {
+		n = len(data)
+	}
+	for ; n > 0; n-- {
+		if bytes.HasPrefix(s.delim, data[len(data)-n:]) {
+			break
+		}
+	}
+	return n
+}
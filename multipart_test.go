@@ -0,0 +1,132 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+type scannedPart struct {
+	headers map[string]string
+	body    string
+}
+
+// scanMultipart feeds raw to a scanner chunk bytes at a time
+func scanMultipart(t *testing.T, boundary string, raw []byte, chunk int) ([]scannedPart, IoBuffer) {
+	s, err := NewMultipartScanner(boundary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewIoBuffer(0)
+	var parts []scannedPart
+	for {
+		tok, err := s.Next(b)
+		if err == ErrNeedMore {
+			if len(raw) == 0 {
+				t.Fatalf("chunk %d: need more after the end", chunk)
+			}
+			n := chunk
+			if n > len(raw) {
+				n = len(raw)
+			}
+			b.Write(raw[:n])
+			raw = raw[n:]
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("chunk %d: %v", chunk, err)
+		}
+		switch tok.Kind {
+		case MultipartHeader:
+			p := scannedPart{headers: make(map[string]string)}
+			for _, h := range tok.Headers {
+				p.headers[string(h.Key)] = string(h.Value)
+			}
+			parts = append(parts, p)
+		case MultipartBody:
+			parts[len(parts)-1].body += string(tok.Body)
+		}
+	}
+	b.Write(raw)
+	return parts, b
+}
+
+func TestMultipartScanner(t *testing.T) {
+	var body bytes.Buffer
+	body.WriteString("preamble\r\n")
+	w := multipart.NewWriter(&body)
+	w.WriteField("name", "value")
+	fw, _ := w.CreateFormFile("upload", "a.txt")
+	content := strings.Repeat("line\r\n--not the boundary\r\n", 100)
+	fw.Write([]byte(content))
+	hdr := textproto.MIMEHeader{}
+	pw, _ := w.CreatePart(hdr)
+	pw.Write([]byte("no headers"))
+	w.Close()
+	body.WriteString("epilogue")
+
+	for _, chunk := range []int{1, 2, 7, 64, 4096} {
+		parts, rest := scanMultipart(t, w.Boundary(), body.Bytes(), chunk)
+		if len(parts) != 3 {
+			t.Fatalf("chunk %d: got %d parts", chunk, len(parts))
+		}
+		if parts[0].headers["Content-Disposition"] != `form-data; name="name"` || parts[0].body != "value" {
+			t.Errorf("chunk %d: first part %+v", chunk, parts[0])
+		}
+		if parts[1].headers["Content-Type"] != "application/octet-stream" || parts[1].body != content {
+			t.Errorf("chunk %d: second part %+v", chunk, parts[1].headers)
+		}
+		if len(parts[2].headers) != 0 || parts[2].body != "no headers" {
+			t.Errorf("chunk %d: third part %+v", chunk, parts[2])
+		}
+		if rest.String() != "\r\nepilogue" {
+			t.Errorf("chunk %d: epilogue %q", chunk, rest.String())
+		}
+	}
+}
+
+func TestMultipartScannerPadding(t *testing.T) {
+	raw := "--b \t\r\nA: 1\r\n\r\nx\r\n--b--"
+	parts, _ := scanMultipart(t, "b", []byte(raw), 3)
+	if len(parts) != 1 || parts[0].headers["A"] != "1" || parts[0].body != "x" {
+		t.Errorf("got %+v", parts)
+	}
+}
+
+func TestMultipartScannerInvalid(t *testing.T) {
+	if _, err := NewMultipartScanner(""); !errors.Is(err, ErrInvalidMultipart) {
+		t.Errorf("got %v, want ErrInvalidMultipart", err)
+	}
+	if _, err := NewMultipartScanner(strings.Repeat("b", 71)); !errors.Is(err, ErrInvalidMultipart) {
+		t.Errorf("got %v, want ErrInvalidMultipart", err)
+	}
+	for _, raw := range []string{
+		"--bx\r\n\r\n",
+		"--b\r\nno colon\r\n\r\n",
+		"--b" + strings.Repeat(" ", 300) + "\r\n",
+	} {
+		s, _ := NewMultipartScanner("b")
+		b := NewIoBufferString(raw)
+		var err error
+		for err == nil {
+			_, err = s.Next(b)
+		}
+		if !errors.Is(err, ErrInvalidMultipart) {
+			t.Errorf("%q: got %v, want ErrInvalidMultipart", raw, err)
+		}
+	}
+
+	s, _ := NewMultipartScanner("b")
+	s.MaxHeaderSize = 16
+	b := NewIoBufferString("--b\r\nX-Long: " + strings.Repeat("a", 16))
+	if _, err := s.Next(b); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
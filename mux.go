@@ -0,0 +1,112 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// MuxHeaderSize is the size of the frame header written by Mux: a big
+// endian uint32 stream ID followed by a big endian uint32 payload length.
+const MuxHeaderSize = 8
+
+// ErrFrameTooLarge is returned when a frame payload exceeds the size limit.
+var ErrFrameTooLarge = errors.New("io buffer: frame too large")
+
+// Mux interleaves writes of several logical streams into one IoBuffer,
+// framing each write with its stream ID. It is safe for concurrent use.
+type Mux struct {
+	mu sync.Mutex
+	b  IoBuffer
+}
+
+// NewMux returns a Mux writing frames to b.
+func NewMux(b IoBuffer) *Mux {
+	return &Mux{b: b}
+}
+
+// Write appends p to the buffer as one frame of the given stream.
+func (m *Mux) Write(stream uint32, p []byte) (int, error) {
+	if uint64(len(p)) > 1<<32-1 {
+		return 0, opError("mux write", len(p), m.b, ErrFrameTooLarge)
+	}
+	var hdr [MuxHeaderSize]byte
+	binary.BigEndian.PutUint32(hdr[0:], stream)
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(p)))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := writeFrame(m.b, hdr[:], p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFrame writes hdr followed by p to b with a single Write, so that a
+// write failing e.g. past WithMaxSize leaves no header without payload
+// behind
+func writeFrame(b IoBuffer, hdr, p []byte) error {
+	frame := GetBytes(len(hdr) + len(p))
+	n := copy(*frame, hdr)
+	copy((*frame)[n:], p)
+	_, err := b.Write(*frame)
+	PutBytes(frame)
+	return err
+}
+
+// Stream returns an io.Writer writing frames of the given stream.
+func (m *Mux) Stream(stream uint32) io.Writer {
+	return &muxStream{m: m, id: stream}
+}
+
+type muxStream struct {
+	m  *Mux
+	id uint32
+}
+
+func (s *muxStream) Write(p []byte) (int, error) {
+	return s.m.Write(s.id, p)
+}
+
+// Demux splits a buffer filled by a Mux back into per-stream payloads.
+type Demux struct {
+	b   IoBuffer
+	max int
+	cur IoBuffer
+}
+
+// NewDemux returns a Demux reading frames from b. Frames with payloads
+// larger than maxFrameSize fail with ErrFrameTooLarge, maxFrameSize <= 0
+// means no limit.
+func NewDemux(b IoBuffer, maxFrameSize int) *Demux {
+	return &Demux{b: b, max: maxFrameSize}
+}
+
+// Next returns the stream ID and a view over the payload of the next frame,
+// without copying. The payload is nil when no complete frame is buffered
+// yet, so more data should be read into the buffer.
+//
+// The view is valid until the next call of Next, which skips whatever of the
+// previous payload is left unread.
+func (d *Demux) Next() (stream uint32, payload IoBuffer, err error) {
+	if d.cur != nil {
+		d.cur.DiscardAll()
+		d.cur = nil
+	}
+	hdr := d.b.Peek(MuxHeaderSize)
+	if hdr == nil {
+		return 0, nil, nil
+	}
+	stream = binary.BigEndian.Uint32(hdr[0:])
+	n := int64(binary.BigEndian.Uint32(hdr[4:]))
+	if (d.max > 0 && n > int64(d.max)) || n > int64(maxInt-MuxHeaderSize) {
+		return stream, nil, opError("demux", int(n), d.b, ErrFrameTooLarge)
+	}
+	if int64(d.b.Len()) < MuxHeaderSize+n {
+		return 0, nil, nil
+	}
+	d.b.Drain(MuxHeaderSize)
+	d.cur = d.b.Limit(int(n))
+	return stream, d.cur, nil
+}
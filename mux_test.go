@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestMux(t *testing.T) {
+	b := NewIoBuffer(0)
+	mux := NewMux(b)
+	mux.Write(1, []byte("one"))
+	fmt.Fprintf(mux.Stream(2), "two %d", 2)
+	mux.Write(1, nil)
+
+	d := NewDemux(b, 0)
+	expect := []struct {
+		stream  uint32
+		payload string
+	}{{1, "one"}, {2, "two 2"}, {1, ""}}
+	for _, e := range expect {
+		stream, payload, err := d.Next()
+		if err != nil || payload == nil {
+			t.Fatalf("Expect a frame, but got %v, %v", payload, err)
+		}
+		if stream != e.stream || payload.String() != e.payload {
+			t.Errorf("Expect %d %q, but got %d %q", e.stream, e.payload, stream, payload.String())
+		}
+	}
+	if _, payload, err := d.Next(); payload != nil || err != nil {
+		t.Errorf("Expect no frame left, but got %v, %v", payload, err)
+	}
+}
+
+func TestDemuxPartial(t *testing.T) {
+	b := NewIoBuffer(0)
+	NewMux(b).Write(7, []byte("payload"))
+	whole := b.Bytes()
+	partial := NewIoBuffer(0)
+	partial.Write(whole[:10])
+
+	d := NewDemux(partial, 0)
+	if _, payload, err := d.Next(); payload != nil || err != nil {
+		t.Fatalf("Expect an incomplete frame, but got %v, %v", payload, err)
+	}
+	partial.Write(whole[10:])
+	stream, payload, err := d.Next()
+	if err != nil || stream != 7 {
+		t.Fatalf("Expect stream 7, but got %d, %v", stream, err)
+	}
+	data, _ := ioutil.ReadAll(payload)
+	if string(data) != "payload" {
+		t.Errorf("Expect payload, but got %q", data)
+	}
+}
+
+func TestDemuxSkipsUnread(t *testing.T) {
+	b := NewIoBuffer(0)
+	mux := NewMux(b)
+	mux.Write(1, []byte("skipped"))
+	mux.Write(2, []byte("read"))
+
+	d := NewDemux(b, 0)
+	d.Next()
+	stream, payload, _ := d.Next()
+	if stream != 2 || payload.String() != "read" {
+		t.Errorf("Expect stream 2 read, but got %d %q", stream, payload.String())
+	}
+}
+
+func TestDemuxTooLarge(t *testing.T) {
+	b := NewIoBuffer(0)
+	NewMux(b).Write(1, make([]byte, 100))
+	if _, _, err := NewDemux(b, 10).Next(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Expect ErrFrameTooLarge, but got %v", err)
+	}
+}
+
+func TestMuxConcurrent(t *testing.T) {
+	b := NewIoBuffer(0)
+	mux := NewMux(b)
+	var wg sync.WaitGroup
+	for i := uint32(0); i < 4; i++ {
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			w := mux.Stream(id)
+			for j := 0; j < 100; j++ {
+				fmt.Fprintf(w, "%d-%d", id, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	d := NewDemux(b, 0)
+	next := make(map[uint32]int)
+	for {
+		stream, payload, err := d.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload == nil {
+			break
+		}
+		if expect := fmt.Sprintf("%d-%d", stream, next[stream]); payload.String() != expect {
+			t.Fatalf("Expect %s, but got %s", expect, payload.String())
+		}
+		next[stream]++
+	}
+	for i := uint32(0); i < 4; i++ {
+		if next[i] != 100 {
+			t.Errorf("Expect 100 frames of stream %d, but got %d", i, next[i])
+		}
+	}
+}
+
+func TestMuxWriteAtomic(t *testing.T) {
+	b := New(WithMaxSize(MuxHeaderSize + 4))
+	m := NewMux(b)
+	if _, err := m.Write(1, []byte("12345")); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Expect ErrTooLarge, but got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("Expect no partial frame, but got %d bytes", b.Len())
+	}
+	if n, err := m.Write(1, []byte("1234")); err != nil || n != 4 {
+		t.Fatalf("unexpected result %d, %v", n, err)
+	}
+}
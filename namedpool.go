@@ -0,0 +1,263 @@
+package buffer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultPoolName is the name of the package level pool in the registry.
+const DefaultPoolName = "default"
+
+// PoolOption configures a NamedPool.
+type PoolOption func(*poolOptions)
+
+type poolOptions struct {
+	minShift       int
+	maxShift       int
+	labelThreshold int
+	maxPooledSize  int
+	releaseSize    int
+	zeroOnPut      bool
+	align          int
+	hugePageSize   int
+	numa           bool
+}
+
+// WithSizeClasses sets the smallest and largest size class of the pool,
+// both rounded up to a power of two. Slices larger than maxSize are
+// allocated directly and never pooled.
+func WithSizeClasses(minSize, maxSize int) PoolOption {
+	return func(o *poolOptions) {
+		o.minShift = shiftOf(minSize)
+		o.maxShift = shiftOf(maxSize)
+		if o.maxShift < o.minShift {
+			o.maxShift = o.minShift
+		}
+	}
+}
+
+// WithAllocLabels makes fresh allocations of at least threshold bytes run
+// under pprof labels carrying the pool name, see LabelLargeAllocs.
+func WithAllocLabels(threshold int) PoolOption {
+	return func(o *poolOptions) {
+		o.labelThreshold = threshold
+	}
+}
+
+// WithMaxPooledSize sets the size above which slices bypass the pool,
+// see SetMaxPooledSize.
+func WithMaxPooledSize(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.maxPooledSize = n
+	}
+}
+
+// WithReleaseThreshold makes the pool hand the pages of released slices of
+// at least n bytes back to the OS, see SetReleaseThreshold.
+func WithReleaseThreshold(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.releaseSize = n
+	}
+}
+
+// WithZeroOnPut makes the pool clear slices before recycling them,
+// see SetZeroOnPut.
+func WithZeroOnPut() PoolOption {
+	return func(o *poolOptions) {
+		o.zeroOnPut = true
+	}
+}
+
+// WithHugePages makes the pool back fresh slices of at least n bytes with
+// transparent huge pages, see SetHugePageThreshold.
+func WithHugePages(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.hugePageSize = n
+	}
+}
+
+// WithNUMAShards makes the pool keep slices in per NUMA node free lists,
+// see SetNUMAShards.
+func WithNUMAShards() PoolOption {
+	return func(o *poolOptions) {
+		o.numa = true
+	}
+}
+
+// DirectIOAlignment is the alignment O_DIRECT file descriptors require of
+// buffer addresses and lengths on common file systems.
+const DirectIOAlignment = 4096
+
+// WithAlignment makes the pool hand out slices whose base address and
+// capacity are multiples of n, a power of two, by over-allocating fresh
+// memory. Size classes below n are raised to n. With n = DirectIOAlignment
+// the slices can be used with O_DIRECT file descriptors as long as the
+// lengths read or written are multiples of n too.
+//
+// An IoBuffer taken from such a pool keeps the alignment at the start of
+// its memory, i.e. while nothing has been read from it.
+func WithAlignment(n int) PoolOption {
+	if n <= 0 || n&(n-1) != 0 {
+		panic(fmt.Sprintf("buffer: alignment %d is not a power of two", n))
+	}
+	return func(o *poolOptions) {
+		o.align = n
+	}
+}
+
+// shiftOf returns the smallest shift with 1<<shift >= size
+func shiftOf(size int) int {
+	shift := 0
+	for 1<<uint(shift) < size {
+		shift++
+	}
+	return shift
+}
+
+// NamedPool is an isolated byte and IoBuffer pool with its own size
+// classes and counters, so that one hot path can't pollute the pools of
+// other subsystems.
+type NamedPool struct {
+	name string
+	bp   *byteBufferPool
+	ib   *IoBufferPool
+}
+
+var poolRegistry = struct {
+	sync.RWMutex
+	pools map[string]*NamedPool
+}{
+	pools: make(map[string]*NamedPool),
+}
+
+func init() {
+	poolRegistry.pools[DefaultPoolName] = &NamedPool{
+		name: DefaultPoolName,
+		bp:   bbPool,
+		ib:   &ibPool,
+	}
+}
+
+// NewNamedPool creates and registers a pool under name.
+//
+// It panics if a pool with the same name is already registered.
+func NewNamedPool(name string, opts ...PoolOption) *NamedPool {
+	o := poolOptions{
+		minShift: minShift,
+		maxShift: maxShift,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.align > 1 {
+		if s := shiftOf(o.align); o.minShift < s {
+			o.minShift = s
+		}
+		if o.maxShift < o.minShift {
+			o.maxShift = o.minShift
+		}
+	}
+
+	bp := newByteBufferPool(name, o.minShift, o.maxShift)
+	bp.align = o.align
+	atomic.StoreInt64(&bp.labelThreshold, int64(o.labelThreshold))
+	bp.setMaxPooledSize(o.maxPooledSize)
+	atomic.StoreInt64(&bp.releaseThreshold, int64(o.releaseSize))
+	bp.setZeroOnPut(o.zeroOnPut)
+	atomic.StoreInt64(&bp.hugePageThreshold, int64(o.hugePageSize))
+	bp.setNUMAShards(o.numa)
+	p := &NamedPool{
+		name: name,
+		bp:   bp,
+		ib:   &IoBufferPool{bp: bp},
+	}
+	bp.ib = p.ib
+
+	poolRegistry.Lock()
+	defer poolRegistry.Unlock()
+	if _, ok := poolRegistry.pools[name]; ok {
+		panic(fmt.Sprintf("buffer: pool %q already registered", name))
+	}
+	poolRegistry.pools[name] = p
+	return p
+}
+
+// Unregister removes the pool from the registry, so that its name can be
+// used again and it no longer shows up in NamedPools. The pool itself stays
+// usable. The package level pool can't be unregistered.
+func (p *NamedPool) Unregister() {
+	if p.name == DefaultPoolName {
+		return
+	}
+	poolRegistry.Lock()
+	defer poolRegistry.Unlock()
+	if poolRegistry.pools[p.name] == p {
+		delete(poolRegistry.pools, p.name)
+	}
+}
+
+// LookupNamedPool returns the pool registered under name, or nil.
+func LookupNamedPool(name string) *NamedPool {
+	poolRegistry.RLock()
+	defer poolRegistry.RUnlock()
+	return poolRegistry.pools[name]
+}
+
+// NamedPools returns all registered pools sorted by name, including the
+// package level pool registered as DefaultPoolName.
+func NamedPools() []*NamedPool {
+	poolRegistry.RLock()
+	pools := make([]*NamedPool, 0, len(poolRegistry.pools))
+	for _, p := range poolRegistry.pools {
+		pools = append(pools, p)
+	}
+	poolRegistry.RUnlock()
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].name < pools[j].name
+	})
+	return pools
+}
+
+// Name returns the name the pool is registered under.
+func (p *NamedPool) Name() string {
+	return p.name
+}
+
+// Get returns *[]byte of the given size from the pool.
+func (p *NamedPool) Get(size int) *[]byte {
+	return p.bp.take(size)
+}
+
+// GetZeroed returns *[]byte of the given size with zeroed contents.
+func (p *NamedPool) GetZeroed(size int) *[]byte {
+	return p.bp.takeZeroed(size)
+}
+
+// Put returns *[]byte obtained via Get to the pool.
+func (p *NamedPool) Put(buf *[]byte) {
+	p.bp.give(buf)
+}
+
+// GetIoBuffer returns an IoBuffer whose memory comes from the pool.
+func (p *NamedPool) GetIoBuffer(size int) IoBuffer {
+	return p.ib.take(size)
+}
+
+// PutIoBuffer returns an IoBuffer obtained via GetIoBuffer to the pool.
+func (p *NamedPool) PutIoBuffer(buf IoBuffer) error {
+	return p.ib.put(buf)
+}
+
+// PutIoBufferGeneration is PutIoBuffer detecting puts after the buffer has
+// been taken again, see the package level PutIoBufferGeneration.
+func (p *NamedPool) PutIoBufferGeneration(buf IoBuffer, gen uint32) error {
+	return p.ib.putGeneration(buf, gen)
+}
+
+// Stats returns a snapshot of the pool counters.
+func (p *NamedPool) Stats() PoolStats {
+	return poolStats(p.bp, p.ib)
+}
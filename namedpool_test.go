@@ -0,0 +1,125 @@
+package buffer
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestNamedPool(t *testing.T) {
+	p := NewNamedPool("test-ingress", WithSizeClasses(100, 1000))
+	if LookupNamedPool("test-ingress") != p {
+		t.Fatalf("Expect pool to be registered")
+	}
+	if p.bp.minSize != 128 || p.bp.maxSize != 1024 {
+		t.Errorf("unexpected size classes: %d-%d", p.bp.minSize, p.bp.maxSize)
+	}
+
+	before := GetPoolStats()
+	b := p.Get(10)
+	if len(*b) != 10 || cap(*b) != 128 {
+		t.Errorf("unexpected slice: len %d, cap %d", len(*b), cap(*b))
+	}
+	p.Put(b)
+
+	buf := p.GetIoBuffer(2000)
+	buf.Write(make([]byte, 4000))
+	if err := p.PutIoBuffer(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	s := p.Stats()
+	if s.Gets < 3 || s.IoBufferGets != 1 || s.IoBufferPuts != 1 || s.InUse != 0 {
+		t.Errorf("unexpected named pool stats: %+v", s)
+	}
+	if after := GetPoolStats(); after.Gets != before.Gets {
+		t.Errorf("Expect the default pool to be untouched, but got %d gets", after.Gets-before.Gets)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expect panic on duplicate pool name")
+			}
+		}()
+		NewNamedPool("test-ingress")
+	}()
+
+	var names []string
+	for _, np := range NamedPools() {
+		names = append(names, np.Name())
+	}
+	if len(names) < 2 || names[0] != DefaultPoolName {
+		t.Errorf("unexpected registered pools: %v", names)
+	}
+}
+
+func TestNamedPoolUnregister(t *testing.T) {
+	p := NewNamedPool("test-unregister")
+	p.Unregister()
+	if LookupNamedPool("test-unregister") != nil {
+		t.Fatal("Expect pool to be unregistered")
+	}
+	b := p.Get(10)
+	p.Put(b)
+
+	// the name can be reused, unregistering the old pool again is a no-op
+	q := NewNamedPool("test-unregister")
+	p.Unregister()
+	if LookupNamedPool("test-unregister") != q {
+		t.Error("Expect the new pool to stay registered")
+	}
+	q.Unregister()
+
+	LookupNamedPool(DefaultPoolName).Unregister()
+	if LookupNamedPool(DefaultPoolName) == nil {
+		t.Error("Expect the default pool to stay registered")
+	}
+}
+
+func TestNamedPoolAlignment(t *testing.T) {
+	p := NewNamedPool("test-direct", WithAlignment(DirectIOAlignment), WithSizeClasses(64, 1<<16), WithAllocLabels(1))
+	aligned := func(b []byte) bool {
+		return uintptr(unsafe.Pointer(&b[:1][0]))%DirectIOAlignment == 0 && cap(b)%DirectIOAlignment == 0
+	}
+	for _, size := range []int{1, 100, 4096, 5000, 1 << 16, 1<<16 + 1} {
+		for i := 0; i < 3; i++ {
+			b := p.Get(size)
+			if len(*b) != size || !aligned(*b) {
+				t.Errorf("Expect an aligned slice of %d bytes, but got len %d cap %d", size, len(*b), cap(*b))
+			}
+			p.Put(b)
+		}
+	}
+
+	buf := New(WithPool("test-direct"), WithCapacity(10000))
+	buf.Write(make([]byte, 20000))
+	if !aligned(buf.Bytes()) {
+		t.Error("Expect an aligned IoBuffer")
+	}
+
+	expectPanic(t, "WithAlignment", func() {
+		WithAlignment(1000)
+	})
+}
+
+func TestNamedPoolPutIoBufferHome(t *testing.T) {
+	p := NewNamedPool("test-home")
+	b := New(WithPool("test-home"))
+	b.WriteString("x")
+	if err := PutIoBuffer(b); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.IoBufferPuts != 1 {
+		t.Errorf("Expect the buffer to return to its own pool, but got %d puts", s.IoBufferPuts)
+	}
+
+	// a buffer of the named pool handed out by the default pool is plain
+	ibPool.give(New(WithPool("test-home")))
+	before := p.Stats()
+	buf := GetIoBuffer(0)
+	buf.Write(make([]byte, 4000))
+	if s := p.Stats(); s.Gets != before.Gets {
+		t.Errorf("Expect the default pool to stay clear of the named pool, but got %d gets", s.Gets-before.Gets)
+	}
+	PutIoBuffer(buf)
+}
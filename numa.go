@@ -0,0 +1,145 @@
+package buffer
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// numaCacheSize is the number of slices each NUMA node caches per size
+// class
+const numaCacheSize = 16
+
+// numaNodeRefresh is the number of node cache accesses after which a P
+// looks up its node again, as Ps move between threads and threads between
+// CPUs
+const numaNodeRefresh = 64
+
+// numaCache holds per NUMA node free lists between the per-P shard caches
+// and the shared sync.Pools, which hand slices to any P and so move memory
+// across sockets.
+type numaCache struct {
+	nodes []numaNode
+	// the node each P ran on when last looked up, the lookup is a syscall
+	procs []numaProc
+}
+
+type numaProc struct {
+	// node + 1, 0 until looked up, and accesses since, used atomically as
+	// a P may have moved on by the time it accesses the cache
+	node int32
+	uses uint32
+
+	_ [cacheLineSize]byte
+}
+
+type numaNode struct {
+	mu    sync.Mutex
+	slots [][]*[]byte
+	bytes int64 // capacity of the cached slices
+
+	_ [cacheLineSize]byte
+}
+
+func newNUMACache(nodes, slots int) *numaCache {
+	c := &numaCache{
+		nodes: make([]numaNode, nodes),
+		procs: make([]numaProc, runtime.GOMAXPROCS(0)),
+	}
+	for i := range c.nodes {
+		c.nodes[i].slots = make([][]*[]byte, slots)
+	}
+	return c
+}
+
+// node returns the free lists of the node P pid runs on, as last looked up
+func (c *numaCache) node(pid int) *numaNode {
+	pr := &c.procs[pid%len(c.procs)]
+	node := atomic.LoadInt32(&pr.node)
+	if atomic.AddUint32(&pr.uses, 1)%numaNodeRefresh == 0 || node == 0 {
+		node = int32(currentNUMANode()) + 1
+		atomic.StoreInt32(&pr.node, node)
+	}
+	return &c.nodes[int(node-1)%len(c.nodes)]
+}
+
+func (c *numaCache) get(pid, slot int) *[]byte {
+	n := c.node(pid)
+	n.mu.Lock()
+	var b *[]byte
+	if s := n.slots[slot]; len(s) > 0 {
+		b = s[len(s)-1]
+		s[len(s)-1] = nil
+		n.slots[slot] = s[:len(s)-1]
+		n.bytes -= int64(cap(*b))
+	}
+	n.mu.Unlock()
+	return b
+}
+
+// put caches b and reports whether there was room for it
+func (c *numaCache) put(pid, slot int, b *[]byte) bool {
+	n := c.node(pid)
+	n.mu.Lock()
+	ok := len(n.slots[slot]) < numaCacheSize
+	if ok {
+		n.slots[slot] = append(n.slots[slot], b)
+		n.bytes += int64(cap(*b))
+	}
+	n.mu.Unlock()
+	return ok
+}
+
+// sweep empties the free lists, handing every slice to put
+func (c *numaCache) sweep(put func(slot int, b *[]byte)) {
+	for i := range c.nodes {
+		n := &c.nodes[i]
+		n.mu.Lock()
+		for slot, s := range n.slots {
+			for j, b := range s {
+				put(slot, b)
+				s[j] = nil
+			}
+			n.slots[slot] = s[:0]
+		}
+		n.bytes = 0
+		n.mu.Unlock()
+	}
+}
+
+func (c *numaCache) cachedBytes() int64 {
+	var bytes int64
+	for i := range c.nodes {
+		n := &c.nodes[i]
+		n.mu.Lock()
+		bytes += n.bytes
+		n.mu.Unlock()
+	}
+	return bytes
+}
+
+// numaCache returns the node caches of the pool, nil when disabled
+func (p *byteBufferPool) numaCache() *numaCache {
+	c, _ := p.numa.Load().(*numaCache)
+	return c
+}
+
+// setNUMAShards enables the node caches, they are a no-op on machines with
+// a single node
+func (p *byteBufferPool) setNUMAShards(on bool) {
+	var c *numaCache
+	if nodes := numaNodes(); on && nodes > 1 {
+		c = newNUMACache(nodes, len(p.pool))
+	}
+	p.numa.Store(c)
+}
+
+// SetNUMAShards makes the package level pool keep slices that miss the
+// per-P caches in per NUMA node free lists before falling back to the
+// shared pools, so that a buffer put back on one socket is handed out on
+// the same socket again. Each node caches up to 16 slices per size class.
+// It has no effect on machines with a single node or where the node can't
+// be detected, i.e. outside Linux.
+func SetNUMAShards(on bool) {
+	bbPool.setNUMAShards(on)
+}
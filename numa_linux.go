@@ -0,0 +1,46 @@
+//go:build linux
+// +build linux
+
+package buffer
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// numaNodes returns the number of possible NUMA nodes, 1 when unknown
+func numaNodes() int {
+	p, err := ioutil.ReadFile("/sys/devices/system/node/possible")
+	if err != nil {
+		return 1
+	}
+	// a list of ranges like "0-3" or "0,2-3"
+	max := 0
+	for _, r := range strings.Split(strings.TrimSpace(string(p)), ",") {
+		if i := strings.IndexByte(r, '-'); i >= 0 {
+			r = r[i+1:]
+		}
+		n, err := strconv.Atoi(r)
+		if err != nil {
+			return 1
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return max + 1
+}
+
+// currentNUMANode returns the node the calling thread runs on, the
+// goroutine may migrate right after, so it is only a hint
+func currentNUMANode() int {
+	var cpu, node uint32
+	_, _, errno := syscall.RawSyscall(sysGetcpu, uintptr(unsafe.Pointer(&cpu)), uintptr(unsafe.Pointer(&node)), 0)
+	if errno != 0 {
+		return 0
+	}
+	return int(node)
+}
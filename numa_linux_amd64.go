@@ -0,0 +1,4 @@
+package buffer
+
+// sysGetcpu is missing from package syscall on linux/amd64
+const sysGetcpu = 309
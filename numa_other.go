@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package buffer
+
+func numaNodes() int {
+	return 1
+}
+
+func currentNUMANode() int {
+	return 0
+}
@@ -0,0 +1,68 @@
+package buffer
+
+import "testing"
+
+func TestNUMACache(t *testing.T) {
+	if n := numaNodes(); n < 1 {
+		t.Fatalf("Expect at least one NUMA node, but got %d", n)
+	}
+	if node := currentNUMANode(); node < 0 || node >= numaNodes() {
+		t.Errorf("Expect the current node within the possible nodes, but got %d", node)
+	}
+
+	// force node caches on a single node machine
+	p := newByteBufferPool("test-numa", minShift, maxShift)
+	p.numa.Store(newNUMACache(2, len(p.pool)))
+
+	slot := p.slot(1000)
+	var put []*[]byte
+	for i := 0; i < numaCacheSize+shardCacheSize+1; i++ {
+		b := p.take(1000)
+		put = append(put, b)
+	}
+	for _, b := range put {
+		p.give(b)
+	}
+	node := p.numaCache().node(0)
+	if n := len(node.slots[slot]); n != numaCacheSize {
+		t.Errorf("Expect a full node cache, but got %d slices", n)
+	}
+	for i := 0; i < numaCacheSize; i++ {
+		if b := p.numaCache().get(0, slot); b == nil || cap(*b) != 1024 {
+			t.Fatal("Expect slices from the node cache")
+		}
+	}
+	if b := p.numaCache().get(0, slot); b != nil {
+		t.Error("Expect an empty node cache")
+	}
+
+	p.setNUMAShards(false)
+	if p.numaCache() != nil {
+		t.Error("Expect node caches disabled")
+	}
+	SetNUMAShards(true)
+	defer SetNUMAShards(false)
+	PutBytes(GetBytes(1000))
+	if numaNodes() == 1 && bbPool.numaCache() != nil {
+		t.Error("Expect no node caches on a single node machine")
+	}
+}
+
+func TestNUMACacheNodeRefresh(t *testing.T) {
+	c := newNUMACache(2, 1)
+	current := &c.nodes[currentNUMANode()%2]
+	if n := c.node(0); n != current {
+		t.Fatal("Expect the first access to look up the node")
+	}
+	// as if the P had moved to the other node since
+	other := 1 - currentNUMANode()%2
+	c.procs[0].node = int32(other) + 1
+	for i := 2; i < numaNodeRefresh; i++ {
+		if n := c.node(0); n != &c.nodes[other] {
+			t.Fatalf("Expect the cached node on access %d", i)
+		}
+	}
+	if n := c.node(0); n != current {
+		t.Errorf("Expect the node to be looked up again after %d accesses", numaNodeRefresh)
+	}
+}
@@ -0,0 +1,161 @@
+package buffer
+
+import (
+	"fmt"
+
+	"github.com/gottingen/atomic"
+)
+
+// Option configures an IoBuffer created by New.
+type Option func(*ioBufferOptions)
+
+type ioBufferOptions struct {
+	capacity   int
+	maxSize    int
+	pool       string
+	bytePool   BytePool
+	budget     *Budget
+	growth     GrowthStrategy
+	zeroOnFree bool
+	// what Reset does with the memory, see WithResetPolicy
+	resetPolicy ResetPolicy
+	// bytes are wrapped as is instead of taking a slice from the pool
+	bytes []byte
+}
+
+// GrowthStrategy returns the capacity a buffer of capacity cap grows to
+// when it must hold need bytes. Results below need are raised to need.
+type GrowthStrategy func(cap, need int) int
+
+// GrowExact grows buffers to exactly the size needed, trading more
+// frequent copies for less memory.
+func GrowExact(cap, need int) int {
+	return need
+}
+
+// GrowLinear grows buffers by multiples of step bytes.
+func GrowLinear(step int) GrowthStrategy {
+	if step <= 0 {
+		step = DefaultSize
+	}
+	return func(cap, need int) int {
+		return (need + step - 1) / step * step
+	}
+}
+
+// WithCapacity sets the initial capacity, DefaultSize by default.
+func WithCapacity(n int) Option {
+	return func(o *ioBufferOptions) {
+		o.capacity = n
+	}
+}
+
+// WithMaxSize caps the number of unread bytes the buffer holds. Writes
+// that don't fit fail with ErrTooLarge without writing anything, reads
+// stop at the cap and fail with ErrTooLarge when more data is available.
+// n <= 0 means no cap, the default.
+func WithMaxSize(n int) Option {
+	return func(o *ioBufferOptions) {
+		o.maxSize = n
+	}
+}
+
+// WithPool makes the buffer take its memory from the NamedPool registered
+// under name. New panics if no such pool is registered.
+func WithPool(name string) Option {
+	return func(o *ioBufferOptions) {
+		o.pool = name
+	}
+}
+
+// WithBytePool makes the buffer take its memory from p instead of the
+// package level pool, e.g. an arena or a cgo allocator, and hand it back to
+// p when growing, on Free and on Close. It overrides WithPool.
+func WithBytePool(p BytePool) Option {
+	return func(o *ioBufferOptions) {
+		o.bytePool = p
+	}
+}
+
+// WithBudget charges the memory the buffer takes from its pool against g,
+// see Budget.
+func WithBudget(g *Budget) Option {
+	return func(o *ioBufferOptions) {
+		o.budget = g
+	}
+}
+
+// WithGrowthStrategy sets how the buffer grows, by default it doubles its
+// capacity plus the bytes needed.
+func WithGrowthStrategy(s GrowthStrategy) Option {
+	return func(o *ioBufferOptions) {
+		o.growth = s
+	}
+}
+
+// WithZeroOnFree makes the buffer clear its memory before handing it back
+// to the pool, on Free as well as when growing, e.g. for buffers holding
+// secrets.
+func WithZeroOnFree() Option {
+	return func(o *ioBufferOptions) {
+		o.zeroOnFree = true
+	}
+}
+
+// withBytes makes the buffer wrap p instead of taking a slice from the pool
+func withBytes(p []byte) Option {
+	return func(o *ioBufferOptions) {
+		o.bytes = p
+	}
+}
+
+// New returns an IoBuffer configured by opts.
+//
+// The options apply until the buffer is recycled by the pool, buffers taken
+// from the pool are plain buffers again.
+func New(opts ...Option) IoBuffer {
+	var o ioBufferOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var bp *byteBufferPool
+	if o.pool != "" {
+		p := LookupNamedPool(o.pool)
+		if p == nil {
+			panic(fmt.Sprintf("buffer: pool %q not registered", o.pool))
+		}
+		if p.bp != bbPool {
+			bp = p.bp
+		}
+	}
+
+	b := &ioBuffer{
+		offMark:     ResetOffMark,
+		count:       atomic.NewInt32(1),
+		bp:          bp,
+		pool:        o.bytePool,
+		budget:      o.budget,
+		maxSize:     o.maxSize,
+		growth:      o.growth,
+		zeroOnFree:  o.zeroOnFree,
+		resetPolicy: o.resetPolicy,
+	}
+	if o.bytes != nil {
+		b.buf = o.bytes
+		return b
+	}
+	capacity := o.capacity
+	if capacity <= 0 {
+		capacity = DefaultSize
+	}
+	if o.budget != nil {
+		if a := o.budget.Available(); capacity > a {
+			capacity = a
+		}
+	}
+	b.b = b.makeSlice(capacity)
+	b.buf = (*b.b)[:0]
+	b.charge(capacity)
+	return b
+}
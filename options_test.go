@@ -0,0 +1,132 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewOptions(t *testing.T) {
+	b := New(WithCapacity(1000))
+	if b.Cap() < 1000 {
+		t.Errorf("Expect capacity of at least 1000, but got %d", b.Cap())
+	}
+
+	p := NewNamedPool("test-options", WithSizeClasses(64, 4096))
+	b = New(WithPool("test-options"), WithCapacity(100))
+	if b.(*ioBuffer).bp != p.bp {
+		t.Error("Expect the memory to come from the named pool")
+	}
+	b.Free()
+	if s := p.Stats(); s.InUse != 0 || s.Gets != 1 {
+		t.Errorf("unexpected named pool stats: %+v", s)
+	}
+
+	expectPanic(t, "New with an unknown pool", func() { New(WithPool("test-options-missing")) })
+}
+
+func TestNewMaxSize(t *testing.T) {
+	b := New(WithMaxSize(10))
+	if n, err := b.Write([]byte("0123456789")); n != 10 || err != nil {
+		t.Fatalf("Expect 10 bytes written, but got %d, %v", n, err)
+	}
+	if n, err := b.WriteString("x"); n != 0 || !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expect ErrTooLarge, but got %d, %v", n, err)
+	}
+	if err := b.(*ioBuffer).Append([]byte("x")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expect ErrTooLarge, but got %v", err)
+	}
+
+	b.Drain(4)
+	n, err := b.ReadFrom(strings.NewReader("abcdefgh"))
+	if n != 4 || !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expect 4 bytes read and ErrTooLarge, but got %d, %v", n, err)
+	}
+	if b.String() != "456789abcd" {
+		t.Errorf("Expect 456789abcd, but got %q", b.String())
+	}
+
+	// reading exactly up to the cap is fine
+	b.Drain(2)
+	if n, err := b.ReadFrom(strings.NewReader("ef")); n != 2 || err != nil {
+		t.Errorf("Expect 2 bytes read, but got %d, %v", n, err)
+	}
+	if n, err := b.ReadOnce(strings.NewReader("g"), 0); n != 0 || !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expect ErrTooLarge, but got %d, %v", n, err)
+	}
+}
+
+func TestNewGrowthStrategy(t *testing.T) {
+	b := New(WithCapacity(64), WithGrowthStrategy(GrowLinear(1000)))
+	b.Write(make([]byte, 100))
+	if c := b.Cap(); c < 1000 || c >= 2000 {
+		t.Errorf("Expect capacity of 1000 rounded to a size class, but got %d", c)
+	}
+
+	b = New(WithCapacity(64), WithGrowthStrategy(GrowExact))
+	b.Write(make([]byte, 64))
+	b.Write(make([]byte, 1))
+	if c := b.Cap(); c != 128 {
+		t.Errorf("Expect capacity of 65 rounded to 128, but got %d", c)
+	}
+}
+
+func TestNewZeroOnFree(t *testing.T) {
+	b := New(WithZeroOnFree())
+	b.Write([]byte("secret"))
+	p := b.(*ioBuffer).b
+	b.Free()
+	if !bytes.Equal((*p)[:cap(*p)][:6], make([]byte, 6)) {
+		t.Error("Expect the memory to be cleared")
+	}
+
+	b.Alloc(0)
+	if ib := b.(*ioBuffer); ib.zeroOnFree || ib.maxSize != 0 || ib.growth != nil {
+		t.Error("Expect Alloc to reset the options")
+	}
+}
+
+var _ BytePool = (*NamedPool)(nil)
+
+// countingBytePool is a BytePool tracking the slices it hands out
+type countingBytePool struct {
+	out map[*[]byte]bool
+}
+
+func (p *countingBytePool) Get(n int) *[]byte {
+	b := make([]byte, n)
+	p.out[&b] = true
+	return &b
+}
+
+func (p *countingBytePool) Put(b *[]byte) {
+	if !p.out[b] {
+		panic("buffer: slice not from this pool")
+	}
+	delete(p.out, b)
+}
+
+func TestNewBytePool(t *testing.T) {
+	p := &countingBytePool{out: make(map[*[]byte]bool)}
+	b := New(WithBytePool(p), WithCapacity(16))
+	if len(p.out) != 1 {
+		t.Fatalf("%d slices out, want 1", len(p.out))
+	}
+	b.Write(make([]byte, 100))
+	if len(p.out) != 1 || b.Len() != 100 {
+		t.Fatalf("%d slices out, len %d", len(p.out), b.Len())
+	}
+	b.Close()
+	if len(p.out) != 0 {
+		t.Fatalf("%d slices out after Close, want 0", len(p.out))
+	}
+
+	// buffers recycled by the pool are plain buffers again
+	b = New(WithBytePool(p))
+	b.Alloc(32)
+	if len(p.out) != 0 {
+		t.Fatalf("%d slices out after Alloc, want 0", len(p.out))
+	}
+	b.Free()
+}
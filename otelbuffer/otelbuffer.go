@@ -0,0 +1,76 @@
+// Package otelbuffer exports the buffer package's pool counters as
+// OpenTelemetry metrics.
+package otelbuffer
+
+import (
+	"context"
+
+	"github.com/gottingen/buffer"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ScopeName is the instrumentation scope of the registered instruments.
+const ScopeName = "github.com/gottingen/buffer/otelbuffer"
+
+// Register registers observable instruments reporting buffer.GetPoolStats
+// with a meter obtained from mp. Unregister the returned registration to
+// stop reporting.
+func Register(mp metric.MeterProvider) (metric.Registration, error) {
+	meter := mp.Meter(ScopeName)
+
+	gets, err := meter.Int64ObservableCounter("buffer.pool.gets",
+		metric.WithDescription("Number of byte slices taken from the pool."))
+	if err != nil {
+		return nil, err
+	}
+	puts, err := meter.Int64ObservableCounter("buffer.pool.puts",
+		metric.WithDescription("Number of byte slices returned to the pool."))
+	if err != nil {
+		return nil, err
+	}
+	misses, err := meter.Int64ObservableCounter("buffer.pool.misses",
+		metric.WithDescription("Number of byte slice gets that allocated fresh memory."))
+	if err != nil {
+		return nil, err
+	}
+	inUse, err := meter.Int64ObservableGauge("buffer.pool.in_use",
+		metric.WithDescription("Number of byte slices handed out and not yet returned."))
+	if err != nil {
+		return nil, err
+	}
+	inUseBytes, err := meter.Int64ObservableGauge("buffer.pool.in_use_bytes",
+		metric.WithDescription("Capacity of the byte slices in use."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	pooledBytes, err := meter.Int64ObservableGauge("buffer.pool.pooled_bytes",
+		metric.WithDescription("Capacity of the byte slices cached by the pool, ready to be handed out."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	ioGets, err := meter.Int64ObservableCounter("buffer.iobuffer.gets",
+		metric.WithDescription("Number of IoBuffers taken from the pool."))
+	if err != nil {
+		return nil, err
+	}
+	ioPuts, err := meter.Int64ObservableCounter("buffer.iobuffer.puts",
+		metric.WithDescription("Number of IoBuffers returned to the pool."))
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := buffer.GetPoolStats()
+		o.ObserveInt64(gets, int64(s.Gets))
+		o.ObserveInt64(puts, int64(s.Puts))
+		o.ObserveInt64(misses, int64(s.Misses))
+		o.ObserveInt64(inUse, s.InUse)
+		o.ObserveInt64(inUseBytes, s.InUseBytes)
+		o.ObserveInt64(pooledBytes, s.PooledBytes)
+		o.ObserveInt64(ioGets, int64(s.IoBufferGets))
+		o.ObserveInt64(ioPuts, int64(s.IoBufferPuts))
+		return nil
+	}, gets, puts, misses, inUse, inUseBytes, pooledBytes, ioGets, ioPuts)
+}
@@ -0,0 +1,47 @@
+package otelbuffer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gottingen/buffer"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegister(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	reg, err := Register(mp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer reg.Unregister()
+
+	buffer.PutBytes(buffer.GetBytes(128))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rm.ScopeMetrics) != 1 {
+		t.Fatalf("unexpected scope count: %d", len(rm.ScopeMetrics))
+	}
+	found := map[string]bool{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		found[m.Name] = true
+		if m.Name == "buffer.pool.gets" {
+			sum := m.Data.(metricdata.Sum[int64])
+			if sum.DataPoints[0].Value < 1 {
+				t.Fatalf("unexpected gets: %d", sum.DataPoints[0].Value)
+			}
+		}
+	}
+	for _, name := range []string{"buffer.pool.gets", "buffer.pool.puts", "buffer.pool.misses",
+		"buffer.pool.in_use", "buffer.pool.in_use_bytes", "buffer.pool.pooled_bytes", "buffer.iobuffer.gets", "buffer.iobuffer.puts"} {
+		if !found[name] {
+			t.Errorf("metric %s not reported", name)
+		}
+	}
+}
@@ -48,10 +48,13 @@ func Get() *Buffer { return defaultPool.Get() }
 func (p *Pool) Get() *Buffer {
 	v := p.pool.Get()
 	if v != nil {
-		return v.(*Buffer)
+		b := v.(*Buffer)
+		b.pool = p
+		return b
 	}
 	return &Buffer{
-		B: make([]byte, 0, atomic.LoadUint64(&p.defaultSize)),
+		B:    make([]byte, 0, atomic.LoadUint64(&p.defaultSize)),
+		pool: p,
 	}
 }
 
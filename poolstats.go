@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"sync/atomic"
+)
+
+// PoolStats are counters of the package level pools or of a NamedPool.
+type PoolStats struct {
+	// Gets is the number of GetBytes calls.
+	Gets uint64
+	// Puts is the number of PutBytes calls.
+	Puts uint64
+	// Misses is the number of GetBytes calls that allocated fresh memory.
+	Misses uint64
+	// InUse is the number of byte slices handed out and not yet put back.
+	InUse int64
+	// InUseBytes is the total capacity of the byte slices in use.
+	InUseBytes int64
+	// PooledBytes is the capacity of the byte slices cached per P and per
+	// NUMA node, ready to be handed out. Slices in the sync.Pools behind
+	// those caches aren't counted, the GC drops them at will.
+	PooledBytes int64
+
+	// IoBufferGets is the number of GetIoBuffer calls.
+	IoBufferGets uint64
+	// IoBufferPuts is the number of IoBuffers returned to the pool.
+	IoBufferPuts uint64
+}
+
+// GetPoolStats returns a snapshot of the package level pool counters.
+func GetPoolStats() PoolStats {
+	return poolStats(bbPool, &ibPool)
+}
+
+func poolStats(bp *byteBufferPool, ib *IoBufferPool) PoolStats {
+	gets, puts, misses, inUse, inUseBytes := bp.counters()
+	return PoolStats{
+		Gets:         gets,
+		Puts:         puts,
+		Misses:       misses,
+		InUse:        inUse,
+		InUseBytes:   inUseBytes,
+		PooledBytes:  bp.cachedBytes(),
+		IoBufferGets: atomic.LoadUint64(&ib.gets),
+		IoBufferPuts: atomic.LoadUint64(&ib.puts),
+	}
+}
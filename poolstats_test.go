@@ -0,0 +1,36 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestGetPoolStats(t *testing.T) {
+	before := GetPoolStats()
+
+	b := GetBytes(100)
+	s := GetPoolStats()
+	if s.Gets != before.Gets+1 {
+		t.Errorf("Expect %d gets, but got %d", before.Gets+1, s.Gets)
+	}
+	if s.InUse != before.InUse+1 || s.InUseBytes != before.InUseBytes+int64(cap(*b)) {
+		t.Errorf("unexpected in use counters: %d, %d", s.InUse, s.InUseBytes)
+	}
+	PutBytes(b)
+	s = GetPoolStats()
+	if s.Puts != before.Puts+1 || s.InUse != before.InUse {
+		t.Errorf("unexpected put counters: %d, %d", s.Puts, s.InUse)
+	}
+
+	big := GetBytes(100 << 10)
+	PutBytes(big)
+	if s = GetPoolStats(); !raceEnabled && s.PooledBytes < int64(cap(*big)) {
+		t.Errorf("Expect the slice to be counted as pooled, but got %d", s.PooledBytes)
+	}
+
+	buf := GetIoBuffer(10)
+	PutIoBuffer(buf)
+	s = GetPoolStats()
+	if s.IoBufferGets != before.IoBufferGets+1 || s.IoBufferPuts != before.IoBufferPuts+1 {
+		t.Errorf("unexpected io buffer counters: %d, %d", s.IoBufferGets, s.IoBufferPuts)
+	}
+}
@@ -0,0 +1,64 @@
+package buffer
+
+import "math"
+
+// Label is a name and value pair of a metric, see WriteMetricLine.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// WriteMetricLine writes one sample in the Prometheus text exposition
+// format, name{label="value",...} value [timestamp], ending in a newline.
+// Label values are escaped, the braces are left out without labels and the
+// timestamp, in milliseconds since the epoch, when ts is 0. Names are
+// written as given, they must be valid metric and label names.
+func (b *Buffer) WriteMetricLine(name string, labels []Label, value float64, ts int64) {
+	b.B = append(b.B, name...)
+	if len(labels) > 0 {
+		b.B = append(b.B, '{')
+		for i, l := range labels {
+			if i > 0 {
+				b.B = append(b.B, ',')
+			}
+			b.B = append(b.B, l.Name...)
+			b.B = append(b.B, '=', '"')
+			b.writeLabelValue(l.Value)
+			b.B = append(b.B, '"')
+		}
+		b.B = append(b.B, '}')
+	}
+	b.B = append(b.B, ' ')
+	switch {
+	case math.IsInf(value, 1):
+		b.B = append(b.B, "+Inf"...)
+	case math.IsInf(value, -1):
+		b.B = append(b.B, "-Inf"...)
+	case math.IsNaN(value):
+		b.B = append(b.B, "NaN"...)
+	default:
+		b.WriteFloatFmt(value, 'g', -1, 64)
+	}
+	if ts != 0 {
+		b.B = append(b.B, ' ')
+		b.WriteInt(ts)
+	}
+	b.B = append(b.B, '\n')
+}
+
+// writeLabelValue writes s with backslashes, double quotes and newlines
+// escaped
+func (b *Buffer) writeLabelValue(s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b.B = append(b.B, '\\', '\\')
+		case '"':
+			b.B = append(b.B, '\\', '"')
+		case '\n':
+			b.B = append(b.B, '\\', 'n')
+		default:
+			b.B = append(b.B, c)
+		}
+	}
+}
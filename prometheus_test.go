@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBufferWriteMetricLine(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		labels []Label
+		value  float64
+		ts     int64
+		want   string
+	}{
+		{"up", nil, 1, 0, "up 1\n"},
+		{"http_requests_total", []Label{{"method", "post"}, {"code", "200"}}, 1027, 1395066363000,
+			"http_requests_total{method=\"post\",code=\"200\"} 1027 1395066363000\n"},
+		{"msdos_file_access_time_seconds", []Label{{"path", `C:\DIR\FILE.TXT`}, {"error", "Cannot find file:\n\"FILE.TXT\""}}, 1.458255915e9, 0,
+			`msdos_file_access_time_seconds{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.458255915e+09` + "\n"},
+		{"rpc_duration_seconds", []Label{{"quantile", "0.5"}}, 4773, 0, "rpc_duration_seconds{quantile=\"0.5\"} 4773\n"},
+		{"x", nil, 0.001, 0, "x 0.001\n"},
+		{"x", nil, math.Inf(1), 0, "x +Inf\n"},
+		{"x", nil, math.Inf(-1), 0, "x -Inf\n"},
+		{"x", nil, math.NaN(), -1, "x NaN -1\n"},
+	} {
+		var bb Buffer
+		bb.WriteMetricLine(c.name, c.labels, c.value, c.ts)
+		if bb.String() != c.want {
+			t.Errorf("got %q, want %q", bb.String(), c.want)
+		}
+	}
+
+	labels := []Label{{"method", "get"}, {"path", `/a"b`}}
+	var bb Buffer
+	bb.Grow(256)
+	if n := testing.AllocsPerRun(100, func() {
+		bb.Reset()
+		bb.WriteMetricLine("requests", labels, 12.5, 1395066363000)
+	}); n != 0 {
+		t.Fatalf("WriteMetricLine allocates %v times", n)
+	}
+}
@@ -0,0 +1,249 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+)
+
+// ErrInvalidProxyHeader is returned by ReadProxyHeader for bytes that don't
+// start with a PROXY protocol header, and by WriteProxyHeader for headers
+// that can't be encoded.
+var ErrInvalidProxyHeader = errors.New("io buffer: invalid proxy protocol header")
+
+const (
+	proxyV1Prefix = "PROXY "
+	// proxyV1MaxLen is the longest v1 header including CRLF
+	proxyV1MaxLen = 107
+	proxyV2Sig    = "\r\n\r\n\x00\r\nQUIT\n"
+	// proxyV2HeaderLen is the size of the fixed part of a v2 header
+	proxyV2HeaderLen = 16
+)
+
+// proxyV2Networks maps the address family and transport byte of a v2
+// header to networks
+var proxyV2Networks = map[byte]string{
+	0x11: "tcp4", 0x12: "udp4", 0x21: "tcp6", 0x22: "udp6", 0x31: "unix", 0x32: "unixgram",
+}
+
+// proxyV2AddrLen is the size of the addresses of each v2 address family
+var proxyV2AddrLen = [...]int{1: 12, 2: 36, 3: 216}
+
+// ProxyHeader is a HAProxy PROXY protocol header, which load balancers
+// send ahead of the proxied connection's data to pass on its addresses.
+type ProxyHeader struct {
+	// Version is 1 for the text format and 2 for the binary one.
+	Version int
+	// Local marks a v2 LOCAL command or a v1 UNKNOWN connection, e.g. a
+	// health check of the proxy itself, whose addresses are to be ignored.
+	Local bool
+	// Network is "tcp4" or "tcp6", or with v2 also "udp4", "udp6", "unix"
+	// or "unixgram". Unix addresses aren't decoded, leaving the IPs nil.
+	Network string
+	SrcIP   net.IP
+	DstIP   net.IP
+	SrcPort uint16
+	DstPort uint16
+	// TLVs holds the raw type-length-value extensions of a v2 header.
+	TLVs []byte
+}
+
+// WriteProxyHeader writes h in the format of its version.
+func WriteProxyHeader(b IoBuffer, h *ProxyHeader) error {
+	var p []byte
+	switch h.Version {
+	case 1:
+		p = appendProxyV1(make([]byte, 0, proxyV1MaxLen), h)
+	case 2:
+		p = appendProxyV2(make([]byte, 0, proxyV2HeaderLen+36+len(h.TLVs)), h)
+	}
+	if p == nil {
+		return opError("write proxy header", 0, b, ErrInvalidProxyHeader)
+	}
+	_, err := b.Write(p)
+	return err
+}
+
+func appendProxyV1(p []byte, h *ProxyHeader) []byte {
+	p = append(p, proxyV1Prefix...)
+	if h.Local {
+		return append(p, "UNKNOWN\r\n"...)
+	}
+	src, dst := h.SrcIP.To4(), h.DstIP.To4()
+	switch {
+	case h.Network == "tcp4" && src != nil && dst != nil:
+		p = append(p, "TCP4 "...)
+	case h.Network == "tcp6" && len(h.SrcIP) == net.IPv6len && len(h.DstIP) == net.IPv6len:
+		p = append(p, "TCP6 "...)
+		src, dst = h.SrcIP, h.DstIP
+	default:
+		return nil
+	}
+	p = append(p, src.String()...)
+	p = append(p, ' ')
+	p = append(p, dst.String()...)
+	p = append(p, ' ')
+	p = strconv.AppendUint(p, uint64(h.SrcPort), 10)
+	p = append(p, ' ')
+	p = strconv.AppendUint(p, uint64(h.DstPort), 10)
+	return append(p, "\r\n"...)
+}
+
+func appendProxyV2(p []byte, h *ProxyHeader) []byte {
+	p = append(p, proxyV2Sig...)
+	var addrs []byte
+	if h.Local {
+		p = append(p, 0x20, 0)
+	} else {
+		var fam byte
+		for f, network := range proxyV2Networks {
+			if network == h.Network {
+				fam = f
+			}
+		}
+		if fam == 0 || fam>>4 == 3 {
+			return nil
+		}
+		src, dst := h.SrcIP.To4(), h.DstIP.To4()
+		if fam>>4 == 2 {
+			src, dst = h.SrcIP.To16(), h.DstIP.To16()
+		}
+		if src == nil || dst == nil {
+			return nil
+		}
+		p = append(p, 0x21, fam)
+		var ports [4]byte
+		binary.BigEndian.PutUint16(ports[:], h.SrcPort)
+		binary.BigEndian.PutUint16(ports[2:], h.DstPort)
+		addrs = append(append(append(make([]byte, 0, 36), src...), dst...), ports[:]...)
+	}
+	n := len(addrs) + len(h.TLVs)
+	if n > 1<<16-1 {
+		return nil
+	}
+	p = append(p, byte(n>>8), byte(n))
+	p = append(p, addrs...)
+	return append(p, h.TLVs...)
+}
+
+// ReadProxyHeader reads the PROXY protocol header, of either version, at
+// the start of the unread bytes of b. ErrNeedMore is returned, and nothing
+// consumed, while the header is incomplete, ErrInvalidProxyHeader if the
+// bytes aren't a header, so connections without one can be told apart as
+// soon as their first bytes arrive.
+func ReadProxyHeader(b IoBuffer) (ProxyHeader, error) {
+	n := b.Len()
+	if n > proxyV2HeaderLen {
+		n = proxyV2HeaderLen
+	}
+	p := b.Peek(n)
+	switch {
+	case bytes.HasPrefix(p, []byte(proxyV2Sig)):
+		return readProxyV2(b)
+	case bytes.HasPrefix(p, []byte(proxyV1Prefix)):
+		return readProxyV1(b)
+	case bytes.HasPrefix([]byte(proxyV2Sig), p) || bytes.HasPrefix([]byte(proxyV1Prefix), p):
+		return ProxyHeader{}, ErrNeedMore
+	}
+	return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+}
+
+func readProxyV1(b IoBuffer) (ProxyHeader, error) {
+	n := b.Len()
+	if n > proxyV1MaxLen {
+		n = proxyV1MaxLen
+	}
+	p := b.Peek(n)
+	end := bytes.Index(p, []byte("\r\n"))
+	if end < 0 {
+		if n == proxyV1MaxLen {
+			return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+		}
+		return ProxyHeader{}, ErrNeedMore
+	}
+	h := ProxyHeader{Version: 1}
+	fields := bytes.Split(p[len(proxyV1Prefix):end], []byte(" "))
+	switch string(fields[0]) {
+	case "UNKNOWN":
+		h.Local = true
+	case "TCP4", "TCP6":
+		if len(fields) != 5 {
+			return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+		}
+		h.Network = "tcp4"
+		if fields[0][3] == '6' {
+			h.Network = "tcp6"
+		}
+		var ok1, ok2, ok3, ok4 bool
+		h.SrcIP, ok1 = parseProxyIP(fields[1], h.Network)
+		h.DstIP, ok2 = parseProxyIP(fields[2], h.Network)
+		h.SrcPort, ok3 = parseProxyPort(fields[3])
+		h.DstPort, ok4 = parseProxyPort(fields[4])
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+		}
+	default:
+		return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+	}
+	b.Drain(end + 2)
+	return h, nil
+}
+
+func parseProxyIP(p []byte, network string) (net.IP, bool) {
+	ip := net.ParseIP(string(p))
+	if network == "tcp4" {
+		ip = ip.To4()
+	} else if bytes.IndexByte(p, ':') < 0 {
+		return nil, false
+	}
+	return ip, ip != nil
+}
+
+func parseProxyPort(p []byte) (uint16, bool) {
+	if len(p) > 1 && p[0] == '0' {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(p), 10, 16)
+	return uint16(v), err == nil
+}
+
+func readProxyV2(b IoBuffer) (ProxyHeader, error) {
+	p := b.Peek(proxyV2HeaderLen)
+	if p == nil {
+		return ProxyHeader{}, ErrNeedMore
+	}
+	verCmd, fam := p[12], p[13]
+	n := proxyV2HeaderLen + int(binary.BigEndian.Uint16(p[14:]))
+	if verCmd>>4 != 2 || verCmd&0xf > 1 {
+		return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+	}
+	if p = b.Peek(n); p == nil {
+		return ProxyHeader{}, ErrNeedMore
+	}
+	h := ProxyHeader{Version: 2, Local: verCmd&0xf == 0}
+	body := p[proxyV2HeaderLen:]
+	if fam != 0 {
+		h.Network = proxyV2Networks[fam]
+		if h.Network == "" || len(body) < proxyV2AddrLen[fam>>4] {
+			return ProxyHeader{}, opError("read proxy header", 0, b, ErrInvalidProxyHeader)
+		}
+		size := proxyV2AddrLen[fam>>4]
+		if ipLen := (size - 4) / 2; fam>>4 != 3 {
+			h.SrcIP = append(net.IP(nil), body[:ipLen]...)
+			h.DstIP = append(net.IP(nil), body[ipLen:2*ipLen]...)
+			h.SrcPort = binary.BigEndian.Uint16(body[2*ipLen:])
+			h.DstPort = binary.BigEndian.Uint16(body[2*ipLen+2:])
+		}
+		body = body[size:]
+	}
+	if h.Local {
+		h.Network, h.SrcIP, h.DstIP, h.SrcPort, h.DstPort = "", nil, nil, 0, 0
+	}
+	if len(body) > 0 {
+		h.TLVs = append([]byte(nil), body...)
+	}
+	b.Drain(n)
+	return h, nil
+}
@@ -0,0 +1,108 @@
+package buffer
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestProxyHeaderV1(t *testing.T) {
+	b := NewIoBuffer(0)
+	h := ProxyHeader{Version: 1, Network: "tcp4", SrcIP: net.ParseIP("192.168.0.1"), DstIP: net.ParseIP("192.168.0.11"),
+		SrcPort: 56324, DstPort: 443}
+	if err := WriteProxyHeader(b, &h); err != nil {
+		t.Fatal(err)
+	}
+	want := "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"
+	if b.String() != want {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+	b.WriteString("GET /")
+	got, err := ReadProxyHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Network != "tcp4" || !got.SrcIP.Equal(h.SrcIP) || !got.DstIP.Equal(h.DstIP) || got.SrcPort != 56324 || got.DstPort != 443 {
+		t.Errorf("got %+v", got)
+	}
+	if b.String() != "GET /" {
+		t.Errorf("left %q", b.String())
+	}
+
+	for raw, want := range map[string]ProxyHeader{
+		"PROXY UNKNOWN\r\n":             {Version: 1, Local: true},
+		"PROXY UNKNOWN ffff::1 ::1\r\n": {Version: 1, Local: true},
+		"PROXY TCP6 2001:db8::1 ::1 1 65535\r\n": {Version: 1, Network: "tcp6",
+			SrcIP: net.ParseIP("2001:db8::1"), DstIP: net.ParseIP("::1"), SrcPort: 1, DstPort: 65535},
+	} {
+		got, err := ReadProxyHeader(NewIoBufferString(raw))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %+v, %v", raw, got, err)
+		}
+	}
+}
+
+func TestProxyHeaderV2(t *testing.T) {
+	for _, h := range []ProxyHeader{
+		{Version: 2, Network: "tcp4", SrcIP: net.ParseIP("10.0.0.1").To4(), DstIP: net.ParseIP("10.0.0.2").To4(),
+			SrcPort: 1234, DstPort: 80},
+		{Version: 2, Network: "udp6", SrcIP: net.ParseIP("2001:db8::1"), DstIP: net.ParseIP("2001:db8::2"),
+			SrcPort: 53, DstPort: 5353, TLVs: []byte{0x04, 0x00, 0x01, 0x2a}},
+		{Version: 2, Local: true},
+	} {
+		b := NewIoBuffer(0)
+		if err := WriteProxyHeader(b, &h); err != nil {
+			t.Fatal(err)
+		}
+		raw := b.CopyBytes()
+		// every prefix asks for more
+		for i := 0; i < len(raw); i++ {
+			if _, err := ReadProxyHeader(NewIoBufferBytes(raw[:i])); err != ErrNeedMore {
+				t.Fatalf("%d bytes: got %v, want ErrNeedMore", i, err)
+			}
+		}
+		got, err := ReadProxyHeader(b)
+		if err != nil || !reflect.DeepEqual(got, h) {
+			t.Errorf("got %+v, %v, want %+v", got, err, h)
+		}
+		if b.Len() != 0 {
+			t.Errorf("%d bytes left", b.Len())
+		}
+	}
+}
+
+func TestProxyHeaderInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"GET / HTTP/1.1\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n",
+		"PROXY TCP4 ::1 ::1 1 2\r\n",
+		"PROXY TCP6 1.2.3.4 1.2.3.4 1 2\r\n",
+		"PROXY TCP4 1.2.3.4 1.2.3.4 1 65536\r\n",
+		"PROXY TCP4 1.2.3.4 1.2.3.4 01 2\r\n",
+		"PROXY SCTP 1.2.3.4 1.2.3.4 1 2\r\n",
+		"PROXY TCP4 " + string(make([]byte, 100)),
+		"\r\n\r\n\x00\r\nQUIT\n\x31\x11\x00\x00",
+		"\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x04\x01\x02\x03\x04",
+		"\r\n\r\n\x00\r\nQUIT\n\x21\x41\x00\x00",
+	} {
+		if _, err := ReadProxyHeader(NewIoBufferString(raw)); !errors.Is(err, ErrInvalidProxyHeader) {
+			t.Errorf("%q: got %v, want ErrInvalidProxyHeader", raw, err)
+		}
+	}
+	b := NewIoBuffer(0)
+	for _, h := range []ProxyHeader{
+		{Version: 3, Local: true},
+		{Version: 1, Network: "udp4", SrcIP: net.IPv4zero, DstIP: net.IPv4zero},
+		{Version: 1, Network: "tcp4", SrcIP: net.IPv6loopback, DstIP: net.IPv4zero},
+		{Version: 2, Network: "unix"},
+		{Version: 2, Network: "tcp4"},
+	} {
+		if err := WriteProxyHeader(b, &h); !errors.Is(err, ErrInvalidProxyHeader) {
+			t.Errorf("%+v: got %v, want ErrInvalidProxyHeader", h, err)
+		}
+	}
+	if b.Len() != 0 {
+		t.Errorf("invalid headers wrote %q", b.String())
+	}
+}
@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package buffer
+
+// raceEnabled disables the per-P shard caches, whose P-pinned handoff is
+// invisible to the race detector
+const raceEnabled = true
@@ -0,0 +1,99 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrHighWatermark is returned by ReadLoop when more unread bytes than the
+// high watermark are left in the buffer after onData.
+var ErrHighWatermark = errors.New("io buffer: high watermark exceeded")
+
+// defaultReadLoopTimeout is how long a single ReadOnce of ReadLoop waits for
+// data by default
+const defaultReadLoopTimeout = 15 * time.Second
+
+type readLoopOptions struct {
+	readTimeout   time.Duration
+	idleTimeout   time.Duration
+	highWatermark int
+}
+
+// ReadLoopOption configures ReadLoop.
+type ReadLoopOption func(*readLoopOptions)
+
+// WithReadTimeout sets how long a single read waits for data, it is passed
+// to ReadOnce. The default is 15 seconds.
+func WithReadTimeout(d time.Duration) ReadLoopOption {
+	return func(o *readLoopOptions) {
+		o.readTimeout = d
+	}
+}
+
+// WithIdleTimeout makes ReadLoop give up with the timeout error once no
+// data arrived for d. By default read timeouts are retried forever.
+func WithIdleTimeout(d time.Duration) ReadLoopOption {
+	return func(o *readLoopOptions) {
+		o.idleTimeout = d
+	}
+}
+
+// WithHighWatermark makes ReadLoop fail with ErrHighWatermark when onData
+// leaves more than n unread bytes in the buffer, bounding the memory a peer
+// sending an incomplete message can pin. n <= 0 means no limit.
+func WithHighWatermark(n int) ReadLoopOption {
+	return func(o *readLoopOptions) {
+		o.highWatermark = n
+	}
+}
+
+// ReadLoop reads conn into b with ReadOnce until the connection ends,
+// calling onData whenever b holds unread bytes. onData consumes what it can
+// handle, e.g. by Drain, and leaves partial messages in the buffer.
+//
+// Read timeouts are retried, refreshing the deadline each time, unless an
+// idle timeout is configured. ReadLoop returns nil when the peer closed the
+// connection or conn was closed locally, the error returned by onData, or
+// the read error otherwise.
+func ReadLoop(conn net.Conn, b IoBuffer, onData func(IoBuffer) error, opts ...ReadLoopOption) error {
+	o := readLoopOptions{readTimeout: defaultReadLoopTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	lastData := time.Now()
+	for {
+		n, err := b.ReadOnce(conn, o.readTimeout)
+		if n > 0 {
+			lastData = time.Now()
+			if e := onData(b); e != nil {
+				return e
+			}
+			if o.highWatermark > 0 && b.Len() > o.highWatermark {
+				return opError("read loop", o.highWatermark, b, ErrHighWatermark)
+			}
+		}
+
+		if err == nil {
+			continue
+		}
+		if err == io.EOF || isClosedConnError(err) {
+			return nil
+		}
+		if te, ok := err.(net.Error); ok && te.Timeout() {
+			if o.idleTimeout <= 0 || time.Since(lastData) < o.idleTimeout {
+				continue
+			}
+		}
+		return err
+	}
+}
+
+// isClosedConnError reports whether err comes from using a connection
+// closed locally
+func isClosedConnError(err error) bool {
+	return errors.Is(err, io.ErrClosedPipe) || strings.Contains(err.Error(), "use of closed network connection")
+}
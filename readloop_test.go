@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReadLoop(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		for i := 0; i < 10; i++ {
+			client.Write([]byte("line\n"))
+		}
+		client.Close()
+	}()
+
+	var lines int
+	err := ReadLoop(server, NewIoBuffer(0), func(b IoBuffer) error {
+		for {
+			i := bytes.IndexByte(b.Bytes(), '\n')
+			if i < 0 {
+				return nil
+			}
+			lines++
+			b.Drain(i + 1)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != 10 {
+		t.Errorf("Expect 10 lines, but got %d", lines)
+	}
+}
+
+func TestReadLoopCallbackError(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go client.Write([]byte("data"))
+
+	errStop := errors.New("stop")
+	err := ReadLoop(server, NewIoBuffer(0), func(IoBuffer) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("Expect errStop, but got %v", err)
+	}
+}
+
+func TestReadLoopHighWatermark(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go client.Write(make([]byte, 100))
+
+	err := ReadLoop(server, NewIoBuffer(0), func(IoBuffer) error {
+		return nil
+	}, WithHighWatermark(10))
+	if !errors.Is(err, ErrHighWatermark) {
+		t.Errorf("Expect ErrHighWatermark, but got %v", err)
+	}
+}
+
+func TestReadLoopIdleTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	start := time.Now()
+	err := ReadLoop(server, NewIoBuffer(0), func(IoBuffer) error {
+		return nil
+	}, WithReadTimeout(10*time.Millisecond), WithIdleTimeout(50*time.Millisecond))
+	if te, ok := err.(net.Error); !ok || !te.Timeout() {
+		t.Fatalf("Expect timeout error, but got %v", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("ReadLoop gave up before the idle timeout")
+	}
+}
+
+func TestReadLoopLocalClose(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	time.AfterFunc(20*time.Millisecond, func() { server.Close() })
+
+	err := ReadLoop(server, NewIoBuffer(0), func(IoBuffer) error {
+		return nil
+	}, WithReadTimeout(5*time.Millisecond))
+	if err != nil {
+		t.Errorf("Expect nil on local close, but got %v", err)
+	}
+}
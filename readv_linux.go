@@ -0,0 +1,119 @@
+//go:build linux
+// +build linux
+
+package buffer
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// readvSpareSize is the size of the pooled segment read into after the
+// free space at the end of the buffer, it is kept by the buffer until it is
+// emptied by Reset, Free or reading all of it
+const readvSpareSize = 16 << 10
+
+var readvDisabled int32
+
+// SetReadv turns the readv fast path of ReadFrom and ReadOnce on or off.
+// It is on by default and only used on Linux for *net.TCPConn and
+// *net.UnixConn readers, wrappers of them are read with Read.
+func SetReadv(on bool) {
+	var v int32
+	if !on {
+		v = 1
+	}
+	atomic.StoreInt32(&readvDisabled, v)
+}
+
+// readInto reads at most max bytes from r into the free space at the end of
+// the buffer, max <= 0 means no limit.
+//
+// For TCP and Unix connections it issues a single readv into the free space
+// plus a spare pooled segment, appending whatever landed in the segment,
+// so that one syscall suffices when the kernel has more data buffered than
+// the free space can hold. Other syscall.Conn implementations, e.g. types
+// embedding a connection, may rely on their Read being called and are
+// left alone.
+func (b *ioBuffer) readInto(r io.Reader, max int) (int, error) {
+	tail := b.buf[len(b.buf):cap(b.buf)]
+	var sc syscall.Conn
+	switch c := r.(type) {
+	case *net.TCPConn:
+		sc = c
+	case *net.UnixConn:
+		sc = c
+	}
+	if sc == nil || atomic.LoadInt32(&readvDisabled) != 0 || (max > 0 && max <= len(tail)) {
+		return b.readPlain(r, max)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return b.readPlain(r, max)
+	}
+
+	spareLen := readvSpareSize
+	if max > 0 && max-len(tail) < spareLen {
+		spareLen = max - len(tail)
+	}
+	if b.spare == nil {
+		b.spare = GetBytes(readvSpareSize)
+	}
+	spare := b.spare
+
+	var n int
+	var rerr error
+	err = rc.Read(func(fd uintptr) bool {
+		n, rerr = readv(fd, tail, (*spare)[:spareLen])
+		return rerr != syscall.EAGAIN
+	})
+	if err == nil && rerr != nil {
+		err = os.NewSyscallError("readv", rerr)
+	}
+	if n <= 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+
+	if n <= len(tail) {
+		b.buf = b.buf[:len(b.buf)+n]
+	} else {
+		b.buf = b.buf[:cap(b.buf)]
+		extra := n - len(tail)
+		m := b.grow(extra)
+		copy(b.buf[m:], (*spare)[:extra])
+	}
+	return n, err
+}
+
+func readv(fd uintptr, bufs ...[]byte) (int, error) {
+	var iovecs [2]syscall.Iovec
+	iov := iovecs[:0]
+	for _, p := range bufs {
+		if len(p) == 0 {
+			continue
+		}
+		v := syscall.Iovec{Base: &p[0]}
+		v.SetLen(len(p))
+		iov = append(iov, v)
+	}
+	if len(iov) == 0 {
+		return 0, nil
+	}
+	for {
+		n, _, errno := syscall.Syscall(syscall.SYS_READV, fd, uintptr(unsafe.Pointer(&iov[0])), uintptr(len(iov)))
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return 0, errno
+		}
+		return int(n), nil
+	}
+}
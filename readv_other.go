@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package buffer
+
+import "io"
+
+// SetReadv is a no-op on platforms without the readv fast path.
+func SetReadv(on bool) {}
+
+func (b *ioBuffer) readInto(r io.Reader, max int) (int, error) {
+	return b.readPlain(r, max)
+}
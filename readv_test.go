@@ -0,0 +1,200 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestReadOnceTCPLargePayload(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp loopback unavailable: %s", err)
+	}
+	defer ln.Close()
+
+	payload := make([]byte, 256<<10)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Write(payload)
+		c.Close()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := NewIoBuffer(64)
+	for b.Len() < len(payload) {
+		_, err := b.ReadOnce(conn, time.Second)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(b.Bytes(), payload) {
+		t.Fatalf("payload corrupted: got %d bytes", b.Len())
+	}
+
+	_, err = b.ReadOnce(conn, time.Second)
+	if err != io.EOF {
+		t.Errorf("Expect io.EOF, but got %v", err)
+	}
+}
+
+func TestReadFromTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp loopback unavailable: %s", err)
+	}
+	defer ln.Close()
+
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Write(payload)
+		c.Close()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := NewIoBuffer(16)
+	n, err := b.ReadFrom(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(payload)) || !bytes.Equal(b.Bytes(), payload) {
+		t.Fatalf("payload corrupted: got %d bytes", n)
+	}
+}
+
+func TestReadFromLimitTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp loopback unavailable: %s", err)
+	}
+	defer ln.Close()
+
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Write(payload)
+		c.Close()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := NewIoBuffer(16)
+	n, err := b.ReadFromLimit(conn, 50000)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expect ErrLimitExceeded, but got %v", err)
+	}
+	if n != 50000 || !bytes.Equal(b.Bytes(), payload[:50000]) {
+		t.Fatalf("payload corrupted: got %d bytes", n)
+	}
+}
+
+type countingConn struct {
+	*net.TCPConn
+	reads int
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	c.reads++
+	return c.TCPConn.Read(p)
+}
+
+func TestReadvConnTypes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp loopback unavailable: %s", err)
+	}
+	defer ln.Close()
+
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	go func() {
+		for i := 0; i < 2; i++ {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Write(payload)
+			c.Close()
+		}
+	}()
+
+	// a wrapper of a connection is read through its Read
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := &countingConn{TCPConn: conn.(*net.TCPConn)}
+	b := NewIoBuffer(16)
+	if _, err := b.ReadFrom(cc); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if cc.reads == 0 || !bytes.Equal(b.Bytes(), payload) {
+		t.Fatalf("Expect the wrapper to be read, got %d reads of %d bytes", cc.reads, b.Len())
+	}
+
+	// the spare segment of readv is kept across reads until the buffer is
+	// emptied
+	conn, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ib := NewIoBuffer(16).(*ioBuffer)
+	var spare *[]byte
+	for ib.Len() < len(payload) {
+		if _, err := ib.ReadOnce(conn, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if runtime.GOOS != "linux" {
+			continue
+		}
+		if spare == nil {
+			spare = ib.spare
+		} else if ib.spare != spare {
+			t.Fatal("Expect the spare segment to be kept")
+		}
+	}
+	ib.Reset()
+	if ib.spare != nil {
+		t.Error("Expect Reset to release the spare segment")
+	}
+	ib.spare = GetBytes(64)
+	ib.Free()
+	if ib.spare != nil {
+		t.Error("Expect Free to release the spare segment")
+	}
+}
@@ -0,0 +1,461 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// OpKind is the kind of an operation journaled by a RecordingBuffer.
+type OpKind uint8
+
+const (
+	// OpWrite records bytes appended by Write, WriteString, WriteRune,
+	// ReadFrom, ReadFromLimit or ReadOnce.
+	OpWrite OpKind = iota + 1
+	// OpRead records bytes consumed by Read, WriteTo, DrainTo or a Window
+	// commit.
+	OpRead
+	// OpDrain records bytes skipped by Drain or DiscardAll.
+	OpDrain
+	// OpReset records a Reset, Free or Alloc, which drop the contents.
+	OpReset
+	// OpReadRune records the bytes of a rune consumed by ReadRune.
+	OpReadRune
+	// OpUnreadRune records an UnreadRune.
+	OpUnreadRune
+	// OpRewrite records an in-place rewrite of the unread bytes by
+	// SanitizeUTF8, ToUpperASCII, ToLowerASCII, ReplaceByte or ReplaceRange,
+	// Data holds the unread bytes afterwards.
+	OpRewrite
+	// OpClose records a Close.
+	OpClose
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpWrite:
+		return "write"
+	case OpRead:
+		return "read"
+	case OpDrain:
+		return "drain"
+	case OpReset:
+		return "reset"
+	case OpReadRune:
+		return "read rune"
+	case OpUnreadRune:
+		return "unread rune"
+	case OpRewrite:
+		return "rewrite"
+	case OpClose:
+		return "close"
+	}
+	return fmt.Sprintf("OpKind(%d)", uint8(k))
+}
+
+// RecordedOp is one operation journaled by a RecordingBuffer.
+type RecordedOp struct {
+	Kind OpKind
+	Time time.Time
+	// N is the number of bytes affected by the operation.
+	N int
+	// Data holds the bytes written or read, or the rewritten unread bytes;
+	// it is nil for drains, resets, unread runes and closes.
+	Data []byte
+}
+
+// ErrReplayMismatch is returned by Replay when the buffer diverges from the journal.
+var ErrReplayMismatch = errors.New("io buffer: replay mismatch")
+
+// RecordingBuffer wraps an IoBuffer and journals every call changing its
+// contents: writes, reads, drains, resets, frees, in-place rewrites and
+// Close, so that a byte stream observed in production can be replayed
+// deterministically in tests. Views returned by Limit read through the
+// RecordingBuffer and are journaled too. SpliceTo journals the buffered
+// bytes it writes out, the bytes it passes from src to dst never stay in
+// the buffer and aren't journaled.
+type RecordingBuffer struct {
+	IoBuffer
+
+	mu  sync.Mutex
+	ops []RecordedOp
+}
+
+// NewRecordingBuffer returns a RecordingBuffer journaling operations on b.
+func NewRecordingBuffer(b IoBuffer) *RecordingBuffer {
+	return &RecordingBuffer{IoBuffer: b}
+}
+
+func (rb *RecordingBuffer) record(kind OpKind, n int, data []byte) {
+	var cp []byte
+	if data != nil {
+		cp = make([]byte, len(data))
+		copy(cp, data)
+	}
+	rb.mu.Lock()
+	rb.ops = append(rb.ops, RecordedOp{Kind: kind, Time: time.Now(), N: n, Data: cp})
+	rb.mu.Unlock()
+}
+
+// Ops returns a copy of the journal.
+func (rb *RecordingBuffer) Ops() []RecordedOp {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	ops := make([]RecordedOp, len(rb.ops))
+	copy(ops, rb.ops)
+	return ops
+}
+
+func (rb *RecordingBuffer) Read(p []byte) (int, error) {
+	n, err := rb.IoBuffer.Read(p)
+	if n > 0 {
+		rb.record(OpRead, n, p[:n])
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) ReadFrom(r io.Reader) (int64, error) {
+	before := rb.IoBuffer.Len()
+	n, err := rb.IoBuffer.ReadFrom(r)
+	if n > 0 {
+		rb.record(OpWrite, int(n), rb.IoBuffer.Bytes()[before:])
+	}
+	return n, err
+}
+
+// ReadFromAsync records the bytes read once r is drained, progress isn't
+// reported.
+func (rb *RecordingBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, _ func(int64)) (int64, error) {
+		return rb.ReadFrom(r)
+	}, r)
+}
+
+func (rb *RecordingBuffer) ReadOnce(r io.Reader, duration time.Duration) (int64, error) {
+	before := rb.IoBuffer.Len()
+	n, err := rb.IoBuffer.ReadOnce(r, duration)
+	if n > 0 {
+		rb.record(OpWrite, int(n), rb.IoBuffer.Bytes()[before:])
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	before := rb.IoBuffer.Len()
+	n, err := rb.IoBuffer.ReadFromLimit(r, max)
+	if n > 0 {
+		rb.record(OpWrite, int(n), rb.IoBuffer.Bytes()[before:])
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) Write(p []byte) (int, error) {
+	n, err := rb.IoBuffer.Write(p)
+	if n > 0 {
+		rb.record(OpWrite, n, p[:n])
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) WriteString(s string) (int, error) {
+	n, err := rb.IoBuffer.WriteString(s)
+	if n > 0 {
+		rb.record(OpWrite, n, []byte(s[:n]))
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) WriteRune(r rune) (int, error) {
+	n, err := rb.IoBuffer.WriteRune(r)
+	if n > 0 {
+		var p [utf8.UTFMax]byte
+		rb.record(OpWrite, n, p[:utf8.EncodeRune(p[:], r)])
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) ReadRune() (rune, int, error) {
+	n := rb.IoBuffer.Len()
+	if n > utf8.UTFMax {
+		n = utf8.UTFMax
+	}
+	data := rb.IoBuffer.Peek(n)
+	r, size, err := rb.IoBuffer.ReadRune()
+	if size > 0 {
+		rb.record(OpReadRune, size, data[:size])
+	}
+	return r, size, err
+}
+
+func (rb *RecordingBuffer) UnreadRune() error {
+	err := rb.IoBuffer.UnreadRune()
+	if err == nil {
+		rb.record(OpUnreadRune, 0, nil)
+	}
+	return err
+}
+
+func (rb *RecordingBuffer) WriteTo(w io.Writer) (int64, error) {
+	data := rb.IoBuffer.Bytes()
+	n, err := rb.IoBuffer.WriteTo(w)
+	if n > 0 {
+		rb.record(OpRead, int(n), data[:n])
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) Drain(offset int) {
+	before := rb.IoBuffer.Len()
+	rb.IoBuffer.Drain(offset)
+	if n := before - rb.IoBuffer.Len(); n > 0 {
+		rb.record(OpDrain, n, nil)
+	}
+}
+
+func (rb *RecordingBuffer) DiscardAll() {
+	if n := rb.IoBuffer.Len(); n > 0 {
+		rb.IoBuffer.DiscardAll()
+		rb.record(OpDrain, n, nil)
+	}
+}
+
+func (rb *RecordingBuffer) DrainTo(w io.Writer, n int) (int, error) {
+	data := rb.IoBuffer.Bytes()
+	m, err := rb.IoBuffer.DrainTo(w, n)
+	if m > 0 {
+		rb.record(OpRead, m, data[:m])
+	}
+	return m, err
+}
+
+// Window journals the bytes consumed by the commit function as read.
+func (rb *RecordingBuffer) Window() ([]byte, func(consumed int)) {
+	p, commit := rb.IoBuffer.Window()
+	return p, func(consumed int) {
+		var data []byte
+		if consumed > 0 && consumed <= len(p) {
+			data = make([]byte, consumed)
+			copy(data, p)
+		}
+		commit(consumed)
+		if data != nil {
+			rb.record(OpRead, consumed, data)
+		}
+	}
+}
+
+func (rb *RecordingBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	n, err := rb.IoBuffer.SanitizeUTF8(replacement)
+	if n > 0 {
+		rb.recordRewrite()
+	}
+	return n, err
+}
+
+func (rb *RecordingBuffer) ToUpperASCII() {
+	rb.IoBuffer.ToUpperASCII()
+	rb.recordRewrite()
+}
+
+func (rb *RecordingBuffer) ToLowerASCII() {
+	rb.IoBuffer.ToLowerASCII()
+	rb.recordRewrite()
+}
+
+func (rb *RecordingBuffer) ReplaceByte(old, new byte) {
+	rb.IoBuffer.ReplaceByte(old, new)
+	rb.recordRewrite()
+}
+
+func (rb *RecordingBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	err := rb.IoBuffer.ReplaceRange(from, to, replacement)
+	if err == nil {
+		rb.recordRewrite()
+	}
+	return err
+}
+
+func (rb *RecordingBuffer) recordRewrite() {
+	data := rb.IoBuffer.Bytes()
+	rb.record(OpRewrite, len(data), data)
+}
+
+func (rb *RecordingBuffer) Reset() {
+	rb.IoBuffer.Reset()
+	rb.record(OpReset, 0, nil)
+}
+
+func (rb *RecordingBuffer) Free() {
+	rb.IoBuffer.Free()
+	rb.record(OpReset, 0, nil)
+}
+
+func (rb *RecordingBuffer) Alloc(size int) {
+	rb.IoBuffer.Alloc(size)
+	rb.record(OpReset, 0, nil)
+}
+
+func (rb *RecordingBuffer) Close() error {
+	err := rb.IoBuffer.Close()
+	if err == nil {
+		rb.record(OpClose, 0, nil)
+	}
+	return err
+}
+
+// Limit returns a view reading through rb, so that its reads are journaled.
+func (rb *RecordingBuffer) Limit(n int) IoBuffer {
+	return newLimitedIoBuffer(rb, n)
+}
+
+// SpliceTo journals the buffered bytes written to dst as read, and the
+// bytes a failed write left behind as a rewrite.
+func (rb *RecordingBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	before := rb.IoBuffer.CopyBytes()
+	n, err := rb.IoBuffer.SpliceTo(dst, src, max)
+	consumed := len(before)
+	if int64(consumed) > n {
+		consumed = int(n)
+	}
+	if consumed > 0 {
+		rb.record(OpRead, consumed, before[:consumed])
+	}
+	if !bytes.Equal(rb.IoBuffer.Bytes(), before[consumed:]) {
+		rb.recordRewrite()
+	}
+	return n, err
+}
+
+// UnmarshalJSON journals the replaced contents as a reset and a write.
+func (rb *RecordingBuffer) UnmarshalJSON(data []byte) error {
+	if err := rb.IoBuffer.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	rb.record(OpReset, 0, nil)
+	if p := rb.IoBuffer.Bytes(); len(p) > 0 {
+		rb.record(OpWrite, len(p), p)
+	}
+	return nil
+}
+
+func (rb *RecordingBuffer) ReadCloser() io.ReadCloser {
+	return &ioBufferReadCloser{b: rb}
+}
+
+// WriteLog writes the journal to w in a binary format readable by ReadOpLog.
+func (rb *RecordingBuffer) WriteLog(w io.Writer) error {
+	return WriteOpLog(w, rb.Ops())
+}
+
+// WriteOpLog writes ops to w in a binary format readable by ReadOpLog.
+//
+// Each record is the kind byte followed by the uvarint encoded timestamp
+// in unix nanoseconds, N and the data length, followed by the data.
+func WriteOpLog(w io.Writer, ops []RecordedOp) error {
+	var hdr [1 + 3*binary.MaxVarintLen64]byte
+	for _, op := range ops {
+		hdr[0] = byte(op.Kind)
+		n := 1
+		n += binary.PutVarint(hdr[n:], op.Time.UnixNano())
+		n += binary.PutUvarint(hdr[n:], uint64(op.N))
+		n += binary.PutUvarint(hdr[n:], uint64(len(op.Data)))
+		if _, err := w.Write(hdr[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(op.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadOpLog reads a journal written by WriteLog or WriteOpLog.
+func ReadOpLog(r io.Reader) ([]RecordedOp, error) {
+	br := bufio.NewReader(r)
+	var ops []RecordedOp
+	for {
+		kind, err := br.ReadByte()
+		if err == io.EOF {
+			return ops, nil
+		}
+		if err != nil {
+			return ops, err
+		}
+		ts, err := binary.ReadVarint(br)
+		if err != nil {
+			return ops, io.ErrUnexpectedEOF
+		}
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return ops, io.ErrUnexpectedEOF
+		}
+		dataLen, err := binary.ReadUvarint(br)
+		if err != nil {
+			return ops, io.ErrUnexpectedEOF
+		}
+		op := RecordedOp{Kind: OpKind(kind), Time: time.Unix(0, ts), N: int(n)}
+		if dataLen > 0 {
+			op.Data = make([]byte, dataLen)
+			if _, err := io.ReadFull(br, op.Data); err != nil {
+				return ops, io.ErrUnexpectedEOF
+			}
+		}
+		ops = append(ops, op)
+	}
+}
+
+// Replay applies ops to b in order. Bytes read during replay are compared
+// with the recorded ones and ErrReplayMismatch is returned on divergence.
+func Replay(b IoBuffer, ops []RecordedOp) error {
+	for i, op := range ops {
+		switch op.Kind {
+		case OpWrite:
+			if _, err := b.Write(op.Data); err != nil {
+				return err
+			}
+		case OpRead:
+			p := make([]byte, op.N)
+			n, _ := b.Read(p)
+			if !bytes.Equal(p[:n], op.Data) {
+				return fmt.Errorf("%w: op %d read %q, recorded %q", ErrReplayMismatch, i, p[:n], op.Data)
+			}
+		case OpDrain:
+			if b.Len() < op.N {
+				return fmt.Errorf("%w: op %d drains %d bytes, only %d buffered", ErrReplayMismatch, i, op.N, b.Len())
+			}
+			b.Drain(op.N)
+		case OpReset:
+			b.Reset()
+		case OpReadRune:
+			if p := b.Peek(op.N); !bytes.Equal(p, op.Data) {
+				return fmt.Errorf("%w: op %d reads rune %q, recorded %q", ErrReplayMismatch, i, p, op.Data)
+			}
+			if _, size, err := b.ReadRune(); size != op.N {
+				return fmt.Errorf("%w: op %d reads a %d byte rune, recorded %d: %v", ErrReplayMismatch, i, size, op.N, err)
+			}
+		case OpUnreadRune:
+			if err := b.UnreadRune(); err != nil {
+				return fmt.Errorf("%w: op %d: %v", ErrReplayMismatch, i, err)
+			}
+		case OpRewrite:
+			if err := b.ReplaceRange(0, b.Len(), op.Data); err != nil {
+				return err
+			}
+		case OpClose:
+			if err := b.Close(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: op %d has unknown kind %v", ErrReplayMismatch, i, op.Kind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRecordingBufferReplay(t *testing.T) {
+	rb := NewRecordingBuffer(NewIoBuffer(0))
+	rb.Write([]byte("GET / HTTP/1.1\r\n"))
+	rb.ReadFrom(strings.NewReader("Host: x\r\n\r\n"))
+	p := make([]byte, 4)
+	rb.Read(p)
+	rb.Drain(2)
+	var w bytes.Buffer
+	rb.WriteTo(&w)
+	rb.Reset()
+
+	ops := rb.Ops()
+	if len(ops) != 6 {
+		t.Fatalf("Expect 6 ops, but got %d", len(ops))
+	}
+	if ops[2].Kind != OpRead || string(ops[2].Data) != "GET " {
+		t.Errorf("unexpected read op: %v %q", ops[2].Kind, ops[2].Data)
+	}
+
+	var log bytes.Buffer
+	if err := rb.WriteLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ReadOpLog(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != len(ops) {
+		t.Fatalf("Expect %d ops, but got %d", len(ops), len(replayed))
+	}
+	for i := range ops {
+		if ops[i].Kind != replayed[i].Kind || ops[i].N != replayed[i].N ||
+			!bytes.Equal(ops[i].Data, replayed[i].Data) || !ops[i].Time.Equal(replayed[i].Time) {
+			t.Fatalf("op %d differs after log round trip", i)
+		}
+	}
+
+	if err := Replay(NewIoBuffer(0), replayed); err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+
+	replayed[0].Data = []byte("PUT / HTTP/1.1\r\n")
+	if err := Replay(NewIoBuffer(0), replayed); !errors.Is(err, ErrReplayMismatch) {
+		t.Fatalf("Expect ErrReplayMismatch, but got %v", err)
+	}
+}
+
+func TestRecordingBufferReplayAllOps(t *testing.T) {
+	rb := NewRecordingBuffer(NewIoBuffer(0))
+	rb.ReadFromLimit(strings.NewReader("héllo, \xffworld"), 64)
+	rb.WriteRune('!')
+	if _, size, _ := rb.ReadRune(); size != 1 {
+		t.Fatalf("Expect a 1 byte rune, but got %d", size)
+	}
+	rb.ReadRune()
+	rb.UnreadRune()
+	rb.ReadRune()
+	rb.SanitizeUTF8('?')
+	rb.ToUpperASCII()
+	rb.ReplaceByte(',', ';')
+	rb.ReplaceRange(0, 2, []byte("LL"))
+	rb.ToLowerASCII()
+	p, commit := rb.Window()
+	if len(p) < 2 {
+		t.Fatalf("unexpected window %q", p)
+	}
+	commit(2)
+	var w bytes.Buffer
+	rb.DrainTo(&w, 3)
+	rb.DiscardAll()
+	rb.WriteString("tail")
+	if p, _ := ioutil.ReadAll(rb.Limit(2)); string(p) != "ta" {
+		t.Fatalf("unexpected view contents %q", p)
+	}
+
+	// the buffered bytes go out first, then the bytes passed through
+	src, srcPeer := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	go func() {
+		srcPeer.Write([]byte("xyz"))
+		srcPeer.Close()
+	}()
+	spliced := make(chan []byte)
+	go func() {
+		p, _ := ioutil.ReadAll(dstPeer)
+		spliced <- p
+	}()
+	if _, err := rb.SpliceTo(dst, src, 0); err != nil {
+		t.Fatal(err)
+	}
+	dst.Close()
+	if p := <-spliced; string(p) != "ilxyz" {
+		t.Fatalf("unexpected spliced bytes %q", p)
+	}
+	rb.WriteString("freed")
+	rb.Free()
+	rb.WriteString("alloc")
+	rb.Alloc(0)
+	rb.WriteString("end")
+	rb.Close()
+
+	kinds := map[OpKind]bool{}
+	for _, op := range rb.Ops() {
+		kinds[op.Kind] = true
+	}
+	for _, k := range []OpKind{OpWrite, OpRead, OpDrain, OpReset, OpReadRune, OpUnreadRune, OpRewrite, OpClose} {
+		if !kinds[k] {
+			t.Errorf("no %v op journaled", k)
+		}
+	}
+
+	var log bytes.Buffer
+	if err := rb.WriteLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := ReadOpLog(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewIoBuffer(0)
+	if err := Replay(b, ops[:len(ops)-1]); err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if b.String() != "end" {
+		t.Fatalf("replayed buffer holds %q, recorded %q", b.String(), "end")
+	}
+	if err := Replay(b, ops[len(ops)-1:]); err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if _, err := b.Write([]byte("x")); !errors.Is(err, ErrClosedBuffer) {
+		t.Errorf("Expect the replayed buffer closed, but got %v", err)
+	}
+}
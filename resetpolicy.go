@@ -0,0 +1,50 @@
+package buffer
+
+// ResetPolicy decides what IoBuffer.Reset does with the memory of the
+// buffer.
+type ResetPolicy int32
+
+const (
+	// ResetKeep keeps the full capacity, the default.
+	ResetKeep ResetPolicy = iota
+	// ResetShrink halves the capacity when less than a quarter of it was
+	// used since the buffer last moved its contents, so a buffer sized by
+	// one burst shrinks back over a few quiet cycles.
+	ResetShrink
+	// ResetRelease returns the memory to the pool, the buffer takes new
+	// memory on the next write.
+	ResetRelease
+)
+
+// WithResetPolicy sets what Reset does with the memory of the buffer,
+// ResetKeep by default. Long lived buffers, e.g. per connection, otherwise
+// retain the capacity of the largest message they ever held.
+func WithResetPolicy(p ResetPolicy) Option {
+	return func(o *ioBufferOptions) {
+		o.resetPolicy = p
+	}
+}
+
+// applyResetPolicy shrinks or releases the memory of the emptied buffer,
+// used is the length of its slice before the reset. Buffers wrapping memory
+// they don't own, such as the ones returned by NewIoBufferBytes, always
+// keep it.
+func (b *ioBuffer) applyResetPolicy(used int) {
+	if b.b == nil || b.fixed {
+		return
+	}
+	switch b.resetPolicy {
+	case ResetShrink:
+		if c := cap(b.buf); c > DefaultSize && used < c/4 {
+			p := b.makeSlice(c / 2)
+			b.onFree(cap(*b.b))
+			b.putSlice(b.b)
+			b.b = p
+			b.buf = (*p)[:0]
+			b.charge(c / 2)
+		}
+	case ResetRelease:
+		b.onFree(cap(*b.b))
+		b.giveSlice()
+	}
+}
@@ -0,0 +1,77 @@
+package buffer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestResetPolicy(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 64<<10)
+
+	keep := NewIoBuffer(16)
+	keep.Write(big)
+	c := keep.Cap()
+	keep.Reset()
+	if keep.Cap() != c {
+		t.Errorf("Expect ResetKeep to keep capacity %d, but got %d", c, keep.Cap())
+	}
+
+	b := New(WithCapacity(16), WithResetPolicy(ResetShrink))
+	b.Write(big)
+	b.Reset()
+	if b.Cap() != c {
+		t.Errorf("Expect a well used buffer to keep capacity %d, but got %d", c, b.Cap())
+	}
+	var freed int
+	b.SetHooks(&Hooks{OnFree: func(_ IoBuffer, capacity int) { freed += capacity }})
+	for i := 0; i < 3; i++ {
+		b.WriteString("small")
+		b.Reset()
+	}
+	if b.Cap() != c/8 || freed != c+c/2+c/4 {
+		t.Errorf("Expect capacity halved on each quiet reset to %d, but got %d, freed %d", c/8, b.Cap(), freed)
+	}
+
+	// internal resets, e.g. reading the buffer empty, keep the memory
+	b.Write(big[:b.Cap()])
+	b.Read(make([]byte, b.Len()+1))
+	b.Read(nil)
+	if b.Cap() != c/8 {
+		t.Errorf("Expect an internal reset to keep capacity %d, but got %d", c/8, b.Cap())
+	}
+
+	// the policy is per buffer, others keep their memory
+	keep.WriteString("small")
+	keep.Reset()
+	if keep.Cap() != c {
+		t.Errorf("Expect ResetKeep to keep capacity %d, but got %d", c, keep.Cap())
+	}
+
+	r := New(WithResetPolicy(ResetRelease))
+	r.Write(big)
+	r.Reset()
+	if r.Cap() != 0 {
+		t.Errorf("Expect ResetRelease to drop the memory, but got capacity %d", r.Cap())
+	}
+	r.WriteString("again")
+	if r.String() != "again" {
+		t.Errorf("Expect the buffer usable after release, but got %q", r.String())
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Alloc, e.g. by the pool, restores the default
+	r = New(WithResetPolicy(ResetRelease))
+	r.Alloc(100)
+	r.Reset()
+	if r.Cap() == 0 {
+		t.Error("Expect Alloc to restore ResetKeep")
+	}
+
+	fixed := NewIoBufferBytes(make([]byte, 100))
+	fixed.Reset()
+	if fixed.Cap() != 100 {
+		t.Errorf("Expect wrapped memory kept, but got capacity %d", fixed.Cap())
+	}
+}
@@ -0,0 +1,234 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrRingFull is returned by writes to a RingBuffer with the RingReject
+// policy when not all bytes fit.
+var ErrRingFull = errors.New("io buffer: ring full")
+
+// RingPolicy decides what writes to a full RingBuffer do.
+type RingPolicy int
+
+const (
+	// RingReject writes what fits and fails with ErrRingFull.
+	RingReject RingPolicy = iota
+	// RingDropOldest overwrites the oldest unread bytes, counting them as
+	// dropped, so the ring always holds the most recent bytes, e.g. as a
+	// flight recorder of the last traffic.
+	RingDropOldest
+)
+
+// RingBuffer is a fixed size circular byte buffer backed by a pooled
+// slice. It is safe for concurrent use.
+type RingBuffer struct {
+	dropped uint64 // accessed atomically, kept first for 64-bit alignment
+
+	mu     sync.Mutex
+	b      *[]byte
+	buf    []byte
+	r      int // read position
+	n      int // unread bytes
+	policy RingPolicy
+}
+
+// NewRingBuffer returns a RingBuffer holding up to size bytes.
+func NewRingBuffer(size int, policy RingPolicy) *RingBuffer {
+	if size <= 0 {
+		panic(&Error{Op: "ring", Size: size, Err: ErrNegativeCount})
+	}
+	b := GetBytes(size)
+	return &RingBuffer{b: b, buf: (*b)[:size], policy: policy}
+}
+
+// write copies p to the free space, which must be large enough
+func (rb *RingBuffer) write(p []byte) {
+	w := (rb.r + rb.n) % len(rb.buf)
+	m := copy(rb.buf[w:], p)
+	copy(rb.buf, p[m:])
+	rb.n += len(p)
+}
+
+// Write appends p. With RingDropOldest it always succeeds, overwriting the
+// oldest unread bytes as needed, and only the last Cap bytes of p are kept
+// when p is larger than the ring.
+func (rb *RingBuffer) Write(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	size := len(rb.buf)
+	if rb.policy == RingReject {
+		free := size - rb.n
+		if len(p) <= free {
+			rb.write(p)
+			return len(p), nil
+		}
+		rb.write(p[:free])
+		return free, &Error{Op: "write", Size: len(p), Len: rb.n, Cap: size, Err: ErrRingFull}
+	}
+
+	if len(p) >= size {
+		atomic.AddUint64(&rb.dropped, uint64(rb.n+len(p)-size))
+		rb.r, rb.n = 0, 0
+		rb.write(p[len(p)-size:])
+		return len(p), nil
+	}
+	if over := rb.n + len(p) - size; over > 0 {
+		atomic.AddUint64(&rb.dropped, uint64(over))
+		rb.r = (rb.r + over) % size
+		rb.n -= over
+	}
+	rb.write(p)
+	return len(p), nil
+}
+
+// WriteString is Write for strings.
+func (rb *RingBuffer) WriteString(s string) (int, error) {
+	return rb.Write([]byte(s))
+}
+
+// TryWrite appends p if it fits in the free space without overwriting
+// anything, regardless of the policy. It writes all of p or nothing and
+// reports whether p was written.
+func (rb *RingBuffer) TryWrite(p []byte) (int, bool) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.n+len(p) > len(rb.buf) {
+		return 0, false
+	}
+	rb.write(p)
+	return len(p), true
+}
+
+// read copies unread bytes to p without consuming them
+func (rb *RingBuffer) read(p []byte) int {
+	if len(p) > rb.n {
+		p = p[:rb.n]
+	}
+	m := copy(p, rb.buf[rb.r:])
+	copy(p[m:], rb.buf)
+	return len(p)
+}
+
+// Read reads the oldest unread bytes.
+func (rb *RingBuffer) Read(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.n == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := rb.read(p)
+	rb.r = (rb.r + n) % len(rb.buf)
+	rb.n -= n
+	return n, nil
+}
+
+// WriteTo writes all unread bytes to w, oldest first, and consumes what
+// was written.
+func (rb *RingBuffer) WriteTo(w io.Writer) (int64, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	var written int64
+	for rb.n > 0 {
+		end := rb.r + rb.n
+		if end > len(rb.buf) {
+			end = len(rb.buf)
+		}
+		chunk := rb.buf[rb.r:end]
+		m, err := w.Write(chunk)
+		written += int64(m)
+		rb.r = (rb.r + m) % len(rb.buf)
+		rb.n -= m
+		if err != nil {
+			return written, err
+		}
+		if m < len(chunk) {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
+
+// Bytes returns a copy of the unread bytes, oldest first, without
+// consuming them.
+func (rb *RingBuffer) Bytes() []byte {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	p := make([]byte, rb.n)
+	rb.read(p)
+	return p
+}
+
+// Peek returns a copy of the next n unread bytes without consuming them,
+// or nil if fewer are buffered.
+func (rb *RingBuffer) Peek(n int) []byte {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if n > rb.n {
+		return nil
+	}
+	p := make([]byte, n)
+	rb.read(p)
+	return p
+}
+
+// Discard drops the next n unread bytes, they don't count as dropped. If
+// fewer are buffered it drops them all and returns their number with
+// io.EOF.
+func (rb *RingBuffer) Discard(n int) (int, error) {
+	if n < 0 {
+		return 0, &Error{Op: "discard", Size: n, Err: ErrNegativeCount}
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	var err error
+	if n > rb.n {
+		n, err = rb.n, io.EOF
+	}
+	if n > 0 {
+		rb.r = (rb.r + n) % len(rb.buf)
+		rb.n -= n
+	}
+	return n, err
+}
+
+// Len returns the number of unread bytes.
+func (rb *RingBuffer) Len() int {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.n
+}
+
+// Cap returns the size of the ring.
+func (rb *RingBuffer) Cap() int {
+	return len(rb.buf)
+}
+
+// Dropped returns the number of unread bytes overwritten or skipped by the
+// RingDropOldest policy.
+func (rb *RingBuffer) Dropped() uint64 {
+	return atomic.LoadUint64(&rb.dropped)
+}
+
+// Reset drops all unread bytes, they don't count as dropped.
+func (rb *RingBuffer) Reset() {
+	rb.mu.Lock()
+	rb.r, rb.n = 0, 0
+	rb.mu.Unlock()
+}
+
+// Release returns the backing slice to the pool. The ring mustn't be used
+// afterwards.
+func (rb *RingBuffer) Release() {
+	rb.mu.Lock()
+	PutBytes(rb.b)
+	rb.b, rb.buf = nil, nil
+	rb.r, rb.n = 0, 0
+	rb.mu.Unlock()
+}
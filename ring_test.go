@@ -0,0 +1,71 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRingBufferDropOldest(t *testing.T) {
+	rb := NewRingBuffer(8, RingDropOldest)
+	defer rb.Release()
+
+	rb.Write([]byte("abcde"))
+	rb.Write([]byte("fghij"))
+	if got := string(rb.Bytes()); got != "cdefghij" {
+		t.Errorf("Expect cdefghij, but got %q", got)
+	}
+	if d := rb.Dropped(); d != 2 {
+		t.Errorf("Expect 2 dropped bytes, but got %d", d)
+	}
+
+	p := make([]byte, 3)
+	if n, _ := rb.Read(p); string(p[:n]) != "cde" {
+		t.Errorf("Expect cde, but got %q", p[:n])
+	}
+
+	// larger than the ring, only the tail is kept
+	n, err := rb.Write([]byte("0123456789"))
+	if n != 10 || err != nil {
+		t.Fatalf("Expect 10 bytes written, but got %d, %v", n, err)
+	}
+	if got := string(rb.Bytes()); got != "23456789" {
+		t.Errorf("Expect 23456789, but got %q", got)
+	}
+	if d := rb.Dropped(); d != 2+5+2 {
+		t.Errorf("Expect 9 dropped bytes, but got %d", d)
+	}
+
+	var out bytes.Buffer
+	if n, err := rb.WriteTo(&out); n != 8 || err != nil || out.String() != "23456789" {
+		t.Errorf("Expect 23456789 written, but got %q, %d, %v", out.String(), n, err)
+	}
+	if rb.Len() != 0 {
+		t.Errorf("Expect empty ring, but got %d bytes", rb.Len())
+	}
+}
+
+func TestRingBufferReject(t *testing.T) {
+	rb := NewRingBuffer(4, RingReject)
+	defer rb.Release()
+
+	n, err := rb.Write([]byte("abcdef"))
+	if n != 4 || !errors.Is(err, ErrRingFull) {
+		t.Errorf("Expect 4 bytes written and ErrRingFull, but got %d, %v", n, err)
+	}
+	if rb.Dropped() != 0 {
+		t.Errorf("Expect no dropped bytes, but got %d", rb.Dropped())
+	}
+
+	p := make([]byte, 2)
+	rb.Read(p)
+	if _, ok := rb.TryWrite([]byte("xyz")); ok {
+		t.Error("Expect TryWrite to fail")
+	}
+	if n, ok := rb.TryWrite([]byte("xy")); n != 2 || !ok {
+		t.Errorf("Expect TryWrite to write 2 bytes, but got %d, %v", n, ok)
+	}
+	if got := string(rb.Bytes()); got != "cdxy" {
+		t.Errorf("Expect cdxy, but got %q", got)
+	}
+}
@@ -0,0 +1,292 @@
+package buffer
+
+import (
+	"io"
+)
+
+// ropeChunkSize is the capacity of the pieces Write appends to, so that
+// small writes share pieces
+const ropeChunkSize = 4 << 10
+
+// Rope is a byte sequence supporting InsertAt and DeleteRange in O(log n)
+// without moving the bytes after the edit, e.g. to splice headers into
+// large captured payloads. It is a treap of pieces ordered by position,
+// edits cut pieces by reslicing and never copy existing bytes.
+//
+// Read and WriteTo consume from the front like IoBuffer. The zero value is
+// an empty rope ready to use. A Rope is not safe for concurrent use.
+type Rope struct {
+	root *ropeNode
+	seed uint32
+}
+
+type ropeNode struct {
+	left, right *ropeNode
+	piece       []byte
+	size        int // bytes in the subtree
+	prio        uint32
+}
+
+func (n *ropeNode) sizeOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *ropeNode) update() {
+	n.size = n.left.sizeOf() + len(n.piece) + n.right.sizeOf()
+}
+
+// NewRope returns a rope holding a copy of p.
+func NewRope(p []byte) *Rope {
+	r := &Rope{}
+	r.Write(p)
+	return r
+}
+
+// rand returns the priority of a new node
+func (r *Rope) rand() uint32 {
+	if r.seed == 0 {
+		r.seed = 2463534242
+	}
+	// xorshift32
+	r.seed ^= r.seed << 13
+	r.seed ^= r.seed >> 17
+	r.seed ^= r.seed << 5
+	return r.seed
+}
+
+func (r *Rope) newNode(piece []byte) *ropeNode {
+	return &ropeNode{piece: piece, size: len(piece), prio: r.rand()}
+}
+
+// ropeSplit returns the first off bytes of n and the rest
+func ropeSplit(n *ropeNode, off int) (*ropeNode, *ropeNode) {
+	if n == nil {
+		return nil, nil
+	}
+	ls := n.left.sizeOf()
+	switch {
+	case off <= ls:
+		a, b := ropeSplit(n.left, off)
+		n.left = b
+		n.update()
+		return a, n
+	case off >= ls+len(n.piece):
+		a, b := ropeSplit(n.right, off-ls-len(n.piece))
+		n.right = a
+		n.update()
+		return n, b
+	default:
+		// cut the piece, the left part loses its spare capacity so that
+		// appending to it can't overwrite the right part
+		k := off - ls
+		right := &ropeNode{piece: n.piece[k:], right: n.right, prio: n.prio}
+		right.update()
+		n.piece = n.piece[:k:k]
+		n.right = nil
+		n.update()
+		return n, right
+	}
+}
+
+func ropeMerge(a, b *ropeNode) *ropeNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.prio >= b.prio {
+		a.right = ropeMerge(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = ropeMerge(a, b.left)
+	b.update()
+	return b
+}
+
+func (r *Rope) checkRange(op string, from, to int) {
+	if from < 0 || from > to || to > r.Len() {
+		panic(&Error{Op: op, Size: to - from, Len: r.Len(), Err: ErrOutOfRange})
+	}
+}
+
+// Len returns the number of bytes in the rope.
+func (r *Rope) Len() int {
+	return r.root.sizeOf()
+}
+
+// InsertAt inserts a copy of p before the byte at off, off == Len appends.
+// It panics if off is out of range.
+func (r *Rope) InsertAt(off int, p []byte) {
+	r.checkRange("insert", off, off)
+	if len(p) == 0 {
+		return
+	}
+	l, rest := ropeSplit(r.root, off)
+	r.root = ropeMerge(ropeMerge(l, r.newNode(append([]byte(nil), p...))), rest)
+}
+
+// DeleteRange deletes the bytes from offset from up to but not including
+// offset to. It panics if the range is out of bounds.
+func (r *Rope) DeleteRange(from, to int) {
+	r.checkRange("delete", from, to)
+	if from == to {
+		return
+	}
+	l, rest := ropeSplit(r.root, from)
+	_, rest = ropeSplit(rest, to-from)
+	r.root = ropeMerge(l, rest)
+}
+
+// Write appends a copy of p, filling the spare capacity of the last piece
+// first.
+func (r *Rope) Write(p []byte) (int, error) {
+	n := len(p)
+	if n == 0 {
+		return 0, nil
+	}
+	if r.root != nil {
+		p = ropeAppendLast(r.root, p)
+	}
+	if len(p) > 0 {
+		size := ropeChunkSize
+		if len(p) > size {
+			size = len(p)
+		}
+		piece := append(make([]byte, 0, size), p...)
+		r.root = ropeMerge(r.root, r.newNode(piece))
+	}
+	return n, nil
+}
+
+// WriteString appends s.
+func (r *Rope) WriteString(s string) (int, error) {
+	return r.Write(UnsafeBytes(s))
+}
+
+// ropeAppendLast appends as much of p as fits in the spare capacity of the last
+// piece, returning the rest
+func ropeAppendLast(n *ropeNode, p []byte) []byte {
+	if n.right != nil {
+		p = ropeAppendLast(n.right, p)
+	} else {
+		k := cap(n.piece) - len(n.piece)
+		if k > len(p) {
+			k = len(p)
+		}
+		n.piece = append(n.piece, p[:k]...)
+		p = p[k:]
+	}
+	n.update()
+	return p
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *Rope) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &Error{Op: "read", Size: int(off), Len: r.Len(), Err: ErrOutOfRange}
+	}
+	if off >= int64(r.Len()) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := ropeReadAt(r.root, p, int(off))
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ropeReadAt copies the bytes of n from off to p
+func ropeReadAt(n *ropeNode, p []byte, off int) int {
+	if n == nil || len(p) == 0 {
+		return 0
+	}
+	copied := 0
+	ls := n.left.sizeOf()
+	if off < ls {
+		copied = ropeReadAt(n.left, p, off)
+		off = ls
+	}
+	if k := off - ls; k < len(n.piece) {
+		copied += copy(p[copied:], n.piece[k:])
+	}
+	if copied < len(p) {
+		k := off - ls - len(n.piece)
+		if k < 0 {
+			k = 0
+		}
+		copied += ropeReadAt(n.right, p[copied:], k)
+	}
+	return copied
+}
+
+// Read reads and consumes the first len(p) bytes.
+func (r *Rope) Read(p []byte) (int, error) {
+	if r.Len() == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n, _ := r.ReadAt(p, 0)
+	r.DeleteRange(0, n)
+	return n, nil
+}
+
+// WriteTo writes the pieces in order to w, consuming what was written.
+func (r *Rope) WriteTo(w io.Writer) (int64, error) {
+	var written int
+	err := ropeWalk(r.root, func(piece []byte) error {
+		m, err := w.Write(piece)
+		written += m
+		if err == nil && m < len(piece) {
+			err = io.ErrShortWrite
+		}
+		return err
+	})
+	r.DeleteRange(0, written)
+	return int64(written), err
+}
+
+// ropeWalk calls fn with the pieces in order until fn fails
+func ropeWalk(n *ropeNode, fn func([]byte) error) error {
+	if n == nil {
+		return nil
+	}
+	if err := ropeWalk(n.left, fn); err != nil {
+		return err
+	}
+	if len(n.piece) > 0 {
+		if err := fn(n.piece); err != nil {
+			return err
+		}
+	}
+	return ropeWalk(n.right, fn)
+}
+
+// Bytes returns a copy of the contents.
+func (r *Rope) Bytes() []byte {
+	p := make([]byte, 0, r.Len())
+	ropeWalk(r.root, func(piece []byte) error {
+		p = append(p, piece...)
+		return nil
+	})
+	return p
+}
+
+// String returns the contents as a string.
+func (r *Rope) String() string {
+	return string(r.Bytes())
+}
+
+// Reset empties the rope.
+func (r *Rope) Reset() {
+	r.root = nil
+}
@@ -0,0 +1,111 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestRopeEdits(t *testing.T) {
+	r := NewRope([]byte("GET / HTTP/1.1\r\n\r\nbody"))
+	r.InsertAt(16, []byte("Host: example.com\r\n"))
+	r.InsertAt(5, []byte("index.html"))
+	r.DeleteRange(0, 4)
+	r.InsertAt(0, []byte("POST "))
+	want := "POST /index.html HTTP/1.1\r\nHost: example.com\r\n\r\nbody"
+	if r.String() != want || r.Len() != len(want) {
+		t.Fatalf("Expect %q, but got %q", want, r.String())
+	}
+
+	p := make([]byte, 4)
+	if n, err := r.ReadAt(p, int64(r.Len()-4)); n != 4 || err != nil || string(p) != "body" {
+		t.Errorf("unexpected ReadAt: %q, %d, %v", p, n, err)
+	}
+	if n, err := r.ReadAt(p, int64(r.Len()-2)); n != 2 || err != io.EOF {
+		t.Errorf("Expect a short ReadAt with io.EOF, but got %d, %v", n, err)
+	}
+
+	if n, _ := r.Read(p); string(p[:n]) != "POST" {
+		t.Errorf("unexpected Read: %q", p[:n])
+	}
+	var out bytes.Buffer
+	if n, err := r.WriteTo(&out); int(n) != len(want)-4 || err != nil || out.String() != want[4:] {
+		t.Errorf("unexpected WriteTo: %q, %d, %v", out.String(), n, err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("Expect WriteTo to consume the rope, but got %d bytes", r.Len())
+	}
+	if _, err := r.Read(p); err != io.EOF {
+		t.Errorf("Expect io.EOF, but got %v", err)
+	}
+
+	defer func() {
+		var e *Error
+		if err, _ := recover().(error); !errors.As(err, &e) || !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Expect a panic with ErrOutOfRange, but got %v", err)
+		}
+	}()
+	r.DeleteRange(0, 1)
+}
+
+func TestRopeRandom(t *testing.T) {
+	var r Rope
+	var model []byte
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		switch op := rnd.Intn(4); {
+		case op == 0:
+			p := []byte(randString(rnd.Intn(100)))
+			r.Write(p)
+			model = append(model, p...)
+		case op == 1:
+			off := rnd.Intn(len(model) + 1)
+			p := []byte(randString(rnd.Intn(20)))
+			r.InsertAt(off, p)
+			model = append(model[:off], append(p, model[off:]...)...)
+		case op == 2:
+			from := rnd.Intn(len(model) + 1)
+			to := from + rnd.Intn(len(model)-from+1)
+			r.DeleteRange(from, to)
+			model = append(model[:from], model[to:]...)
+		default:
+			p := make([]byte, rnd.Intn(10))
+			n, _ := r.Read(p)
+			if !bytes.Equal(p[:n], model[:n]) {
+				t.Fatalf("step %d: unexpected Read %q", i, p[:n])
+			}
+			model = model[n:]
+		}
+		if r.Len() != len(model) {
+			t.Fatalf("step %d: Expect len %d, but got %d", i, len(model), r.Len())
+		}
+	}
+	if !bytes.Equal(r.Bytes(), model) {
+		t.Fatal("contents differ from the model")
+	}
+}
+
+func BenchmarkRopeInsert(b *testing.B) {
+	payload := bytes.Repeat([]byte("x"), 16<<20)
+	header := []byte("X-Forwarded-For: 10.0.0.1\r\n")
+	b.Run("Rope", func(b *testing.B) {
+		r := NewRope(payload)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r.InsertAt(64, header)
+			r.DeleteRange(64, 64+len(header))
+		}
+	})
+	b.Run("Slice", func(b *testing.B) {
+		p := append([]byte(nil), payload...)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p = append(p[:64], append(header, p[64:]...)...)
+			p = append(p[:64], p[64+len(header):]...)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package buffer
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// WriteRune appends the UTF-8 encoding of r to Buffer.B.
+func (b *Buffer) WriteRune(r rune) (int, error) {
+	if r < utf8.RuneSelf {
+		b.B = append(b.B, byte(r))
+		return 1, nil
+	}
+	var p [utf8.UTFMax]byte
+	n := utf8.EncodeRune(p[:], r)
+	b.B = append(b.B, p[:n]...)
+	return n, nil
+}
+
+func (b *ioBuffer) WriteRune(r rune) (int, error) {
+	var p [utf8.UTFMax]byte
+	n := utf8.EncodeRune(p[:], r)
+	return b.Write(p[:n])
+}
+
+func (b *ioBuffer) ReadRune() (r rune, size int, err error) {
+	if b.closed {
+		return 0, 0, opError("read", 0, b, ErrClosedBuffer)
+	}
+	if b.off >= len(b.buf) {
+		b.reset()
+		return 0, 0, io.EOF
+	}
+	if c := b.buf[b.off]; c < utf8.RuneSelf {
+		r, size = rune(c), 1
+	} else {
+		r, size = utf8.DecodeRune(b.buf[b.off:])
+	}
+	b.teeBytes(b.buf[b.off : b.off+size])
+	b.off += size
+	b.stats.BytesRead += int64(size)
+	b.lastRune, b.runeOff = size, b.off
+	return r, size, nil
+}
+
+func (b *ioBuffer) UnreadRune() error {
+	if b.lastRune == 0 || b.off != b.runeOff {
+		return opError("unread rune", 0, b, ErrUnreadRune)
+	}
+	b.off -= b.lastRune
+	b.stats.BytesRead -= int64(b.lastRune)
+	b.lastRune = 0
+	return nil
+}
+
+func (m *multiIoBuffer) WriteRune(r rune) (int, error) {
+	var p [utf8.UTFMax]byte
+	n := utf8.EncodeRune(p[:], r)
+	return m.Write(p[:n])
+}
+
+// ReadRune decodes runes spanning buffers by coalescing them
+func (m *multiIoBuffer) ReadRune() (r rune, size int, err error) {
+	if m.closed {
+		return 0, 0, opError("read", 0, m, ErrClosedBuffer)
+	}
+	m.releaseExhausted()
+	if len(m.bufs) == 0 {
+		m.lastRuneLen = 0
+		return 0, 0, io.EOF
+	}
+	p := m.bufs[0].Bytes()
+	if !utf8.FullRune(p) && len(m.bufs) > 1 {
+		n := utf8.UTFMax
+		if l := m.Len(); l < n {
+			n = l
+		}
+		p = m.Peek(n)
+	}
+	r, size = utf8.DecodeRune(p)
+	var last [utf8.UTFMax]byte
+	copy(last[:], p[:size])
+	m.Drain(size)
+	m.lastRune, m.lastRuneLen = last, size
+	return r, size, nil
+}
+
+// UnreadRune puts the rune back in front as a buffer of its own, the buffer
+// it was read from may have been released already
+func (m *multiIoBuffer) UnreadRune() error {
+	if m.lastRuneLen == 0 {
+		return opError("unread rune", 0, m, ErrUnreadRune)
+	}
+	b := GetIoBuffer(m.lastRuneLen)
+	b.SetHooks(m.hooks)
+	b.SetAutoEOF(m.autoEOF)
+	b.Tee(m.tee)
+	b.Write(m.lastRune[:m.lastRuneLen])
+	m.bufs = append([]IoBuffer{b}, m.bufs...)
+	m.stats.BytesRead -= int64(m.lastRuneLen)
+	m.lastRuneLen = 0
+	return nil
+}
+
+func (v *limitedIoBuffer) WriteRune(r rune) (int, error) {
+	return 0, v.readOnly("write", utf8.RuneLen(r))
+}
+
+func (v *limitedIoBuffer) ReadRune() (r rune, size int, err error) {
+	if v.closed {
+		return 0, 0, opError("read", 0, v, ErrClosedBuffer)
+	}
+	v.lastRune = 0
+	p := v.Bytes()
+	if len(p) == 0 {
+		return 0, 0, io.EOF
+	}
+	if !utf8.FullRune(p) {
+		// the rune is cut off by the limit
+		v.Drain(1)
+		return utf8.RuneError, 1, nil
+	}
+	r, size, err = v.b.ReadRune()
+	if err != nil {
+		return r, size, err
+	}
+	v.consumed(p[:size])
+	v.lastRune = size
+	return r, size, nil
+}
+
+func (v *limitedIoBuffer) UnreadRune() error {
+	if v.lastRune == 0 {
+		return opError("unread rune", 0, v, ErrUnreadRune)
+	}
+	if err := v.b.UnreadRune(); err != nil {
+		return err
+	}
+	v.n += v.lastRune
+	v.stats.BytesRead -= int64(v.lastRune)
+	v.lastRune = 0
+	return nil
+}
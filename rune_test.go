@@ -0,0 +1,106 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func readRunes(t *testing.T, b IoBuffer) string {
+	t.Helper()
+	var out []rune
+	for {
+		r, _, err := b.ReadRune()
+		if err == io.EOF {
+			return string(out)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+}
+
+func TestIoBufferReadRune(t *testing.T) {
+	b := NewIoBuffer(0)
+	for _, r := range "héllo, 世界" {
+		b.WriteRune(r)
+	}
+	r, size, err := b.ReadRune()
+	if r != 'h' || size != 1 || err != nil {
+		t.Fatalf("unexpected rune: %q, %d, %v", r, size, err)
+	}
+	r, size, _ = b.ReadRune()
+	if r != 'é' || size != 2 {
+		t.Fatalf("unexpected rune: %q, %d", r, size)
+	}
+	if err := b.UnreadRune(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UnreadRune(); !errors.Is(err, ErrUnreadRune) {
+		t.Errorf("Expect ErrUnreadRune, but got %v", err)
+	}
+	if s := readRunes(t, b); s != "éllo, 世界" {
+		t.Errorf("unexpected runes: %q", s)
+	}
+
+	b.WriteString("\xffa")
+	if r, size, _ := b.ReadRune(); r != '�' || size != 1 {
+		t.Errorf("Expect U+FFFD, 1 for invalid UTF-8, but got %q, %d", r, size)
+	}
+	b.ReadRune()
+	b.Write(make([]byte, 100))
+	if err := b.UnreadRune(); !errors.Is(err, ErrUnreadRune) {
+		t.Errorf("Expect ErrUnreadRune after the buffer moved, but got %v", err)
+	}
+}
+
+func TestMultiIoBufferReadRune(t *testing.T) {
+	// 世 is split across the buffers
+	m := MultiIoBuffer(NewIoBufferString("a\xe4"), NewIoBufferString("\xb8\x96b"))
+	if r, _, _ := m.ReadRune(); r != 'a' {
+		t.Fatalf("unexpected rune: %q", r)
+	}
+	r, size, err := m.ReadRune()
+	if r != '世' || size != 3 || err != nil {
+		t.Fatalf("unexpected rune: %q, %d, %v", r, size, err)
+	}
+	if err := m.UnreadRune(); err != nil {
+		t.Fatal(err)
+	}
+	if s := readRunes(t, m); s != "世b" {
+		t.Errorf("unexpected runes: %q", s)
+	}
+	if err := m.UnreadRune(); !errors.Is(err, ErrUnreadRune) {
+		t.Errorf("Expect ErrUnreadRune after io.EOF, but got %v", err)
+	}
+}
+
+func TestLimitedIoBufferReadRune(t *testing.T) {
+	b := NewIoBufferString("é世x")
+	v := b.Limit(4)
+	if r, _, _ := v.ReadRune(); r != 'é' {
+		t.Fatalf("unexpected rune: %q", r)
+	}
+	if err := v.UnreadRune(); err != nil || v.Len() != 4 || b.Len() != 6 {
+		t.Fatalf("unexpected unread: %v, %d, %d", err, v.Len(), b.Len())
+	}
+	v.ReadRune()
+	// the limit cuts 世 after its first two bytes
+	if r, size, _ := v.ReadRune(); r != '�' || size != 1 {
+		t.Errorf("Expect U+FFFD, 1, but got %q, %d", r, size)
+	}
+	if _, err := v.WriteRune('x'); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly, but got %v", err)
+	}
+}
+
+func TestBufferWriteRune(t *testing.T) {
+	var bb Buffer
+	for _, r := range "a世" {
+		bb.WriteRune(r)
+	}
+	if bb.String() != "a世" {
+		t.Errorf("unexpected contents: %q", bb.String())
+	}
+}
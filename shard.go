@@ -0,0 +1,205 @@
+package buffer
+
+import (
+	"runtime"
+	"sync/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+//go:linkname runtime_procPin runtime.procPin
+func runtime_procPin() int
+
+//go:linkname runtime_procUnpin runtime.procUnpin
+func runtime_procUnpin()
+
+// shardCacheSize is the number of slices each shard caches per size class
+// in front of the shared sync.Pool
+const shardCacheSize = 4
+
+// cacheLineSize is used to pad shards against false sharing
+const cacheLineSize = 128
+
+type shardSlot struct {
+	n     int
+	items [shardCacheSize]*[]byte
+}
+
+// poolShard holds the counters and a small per size class cache of one P.
+//
+// The cache is only accessed while pinned to the owning P and by the sweep
+// after each GC, which skips the shard while busy is set. When it runs
+// empty or full, the shard falls back to the slot's sync.Pool, which
+// steals from other Ps.
+type poolShard struct {
+	// counters are accessed atomically and kept first for 64-bit alignment
+	gets       uint64
+	puts       uint64
+	misses     uint64
+	inUse      int64
+	inUseBytes int64
+	// capacity of the slices in the per size class cache
+	cachedBytes int64
+	// set while the cache is accessed
+	busy int32
+	// histogram of the requested sizes, see SizePercentile
+	sizes [sizeBuckets]uint64
+
+	slots []shardSlot
+
+	_ [cacheLineSize]byte
+}
+
+func newPoolShards(slots int) []poolShard {
+	shards := make([]poolShard, runtime.GOMAXPROCS(0))
+	for i := range shards {
+		shards[i].slots = make([]shardSlot, slots)
+	}
+	return shards
+}
+
+// shard returns the shard of the current P, used for counters only
+func (p *byteBufferPool) shard() *poolShard {
+	pid := runtime_procPin()
+	runtime_procUnpin()
+	return &p.shards[pid%len(p.shards)]
+}
+
+func (sh *poolShard) countPut(size int) {
+	atomic.AddUint64(&sh.puts, 1)
+	atomic.AddInt64(&sh.inUse, -1)
+	atomic.AddInt64(&sh.inUseBytes, -int64(size))
+}
+
+// getSlot returns a pooled slice of slot, or nil, and the shard of the
+// current P
+func (p *byteBufferPool) getSlot(slot int) (*[]byte, *poolShard) {
+	var b *[]byte
+	pid := runtime_procPin()
+	sh := &p.shards[pid%len(p.shards)]
+	if pid < len(p.shards) && !raceEnabled && atomic.CompareAndSwapInt32(&sh.busy, 0, 1) {
+		s := &sh.slots[slot]
+		if s.n > 0 {
+			s.n--
+			b = s.items[s.n]
+			s.items[s.n] = nil
+			atomic.AddInt64(&sh.cachedBytes, -int64(cap(*b)))
+		}
+		atomic.StoreInt32(&sh.busy, 0)
+	}
+	runtime_procUnpin()
+	if b != nil {
+		return b, sh
+	}
+	if numa := p.numaCache(); numa != nil {
+		if b = numa.get(pid, slot); b != nil {
+			return b, sh
+		}
+	}
+	if v := p.pool[slot].pool.Get(); v != nil {
+		b = v.(*[]byte)
+	}
+	return b, sh
+}
+
+// putSlot pools a slice of slot and accounts the put of a slice of capacity
+// size, which may have been trimmed to the slot since it was taken
+func (p *byteBufferPool) putSlot(slot int, b *[]byte, size int) {
+	pid := runtime_procPin()
+	sh := &p.shards[pid%len(p.shards)]
+	if pid < len(p.shards) && !raceEnabled && atomic.CompareAndSwapInt32(&sh.busy, 0, 1) {
+		s := &sh.slots[slot]
+		cached := s.n < shardCacheSize
+		if cached {
+			s.items[s.n] = b
+			s.n++
+			atomic.AddInt64(&sh.cachedBytes, int64(cap(*b)))
+		}
+		atomic.StoreInt32(&sh.busy, 0)
+		if cached {
+			runtime_procUnpin()
+			sh.countPut(size)
+			return
+		}
+	}
+	runtime_procUnpin()
+	sh.countPut(size)
+	if numa := p.numaCache(); numa != nil && numa.put(pid, slot, b) {
+		return
+	}
+	p.pool[slot].pool.Put(b)
+}
+
+// sweepCaches moves the slices cached by the shards and the node caches to
+// the sync.Pools, so that the GC frees them unless they're taken again
+// before the next cycle, like any sync.Pool item. Shards in use are left
+// for the next sweep.
+func (p *byteBufferPool) sweepCaches() {
+	for i := range p.shards {
+		sh := &p.shards[i]
+		if !atomic.CompareAndSwapInt32(&sh.busy, 0, 1) {
+			continue
+		}
+		for slot := range sh.slots {
+			s := &sh.slots[slot]
+			for s.n > 0 {
+				s.n--
+				b := s.items[s.n]
+				s.items[s.n] = nil
+				atomic.AddInt64(&sh.cachedBytes, -int64(cap(*b)))
+				p.pool[slot].pool.Put(b)
+			}
+		}
+		atomic.StoreInt32(&sh.busy, 0)
+	}
+	if numa := p.numaCache(); numa != nil {
+		numa.sweep(func(slot int, b *[]byte) {
+			p.pool[slot].pool.Put(b)
+		})
+	}
+}
+
+// gcSentinel is a heap object whose finalizer runs once per GC cycle,
+// it holds a pointer so that it isn't batched by the tiny allocator
+type gcSentinel struct {
+	_ *int
+}
+
+func init() {
+	armGCSweep()
+}
+
+// armGCSweep makes the next GC sweep the caches of all registered pools
+func armGCSweep() {
+	runtime.SetFinalizer(&gcSentinel{}, func(*gcSentinel) {
+		for _, np := range NamedPools() {
+			np.bp.sweepCaches()
+		}
+		armGCSweep()
+	})
+}
+
+// cachedBytes sums the capacity of the slices cached by the shards and the
+// node caches, the sync.Pools behind them are opaque
+func (p *byteBufferPool) cachedBytes() int64 {
+	var n int64
+	for i := range p.shards {
+		n += atomic.LoadInt64(&p.shards[i].cachedBytes)
+	}
+	if numa := p.numaCache(); numa != nil {
+		n += numa.cachedBytes()
+	}
+	return n
+}
+
+// counters sums the counters of all shards
+func (p *byteBufferPool) counters() (gets, puts, misses uint64, inUse, inUseBytes int64) {
+	for i := range p.shards {
+		sh := &p.shards[i]
+		gets += atomic.LoadUint64(&sh.gets)
+		puts += atomic.LoadUint64(&sh.puts)
+		misses += atomic.LoadUint64(&sh.misses)
+		inUse += atomic.LoadInt64(&sh.inUse)
+		inUseBytes += atomic.LoadInt64(&sh.inUseBytes)
+	}
+	return
+}
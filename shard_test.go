@@ -0,0 +1,59 @@
+package buffer
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedPoolConcurrent(t *testing.T) {
+	p := NewNamedPool("test-sharded")
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				b := p.Get(64 + i%512)
+				(*b)[0] = byte(g)
+				p.Put(b)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	s := p.Stats()
+	if s.Gets != 8000 || s.Puts != 8000 || s.InUse != 0 || s.InUseBytes != 0 {
+		t.Errorf("unexpected stats after concurrent use: %+v", s)
+	}
+	if s.Misses >= s.Gets {
+		t.Errorf("Expect the pool to recycle slices, but got %d misses", s.Misses)
+	}
+}
+
+func BenchmarkGetPutBytes(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			PutBytes(GetBytes(1024))
+		}
+	})
+}
+
+func TestShardCachesSweptOnGC(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the shard caches are disabled under the race detector")
+	}
+	p := NewNamedPool("shard-gc-sweep")
+	p.Put(p.Get(4096))
+	if n := p.Stats().PooledBytes; n == 0 {
+		t.Fatal("Expect the slice to be cached")
+	}
+	for i := 0; i < 100 && p.Stats().PooledBytes != 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if n := p.Stats().PooledBytes; n != 0 {
+		t.Errorf("Expect the caches to be swept on GC, but %d bytes are cached", n)
+	}
+}
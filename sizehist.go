@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// The sizes passed to GetBytes are counted in a log-linear histogram like
+// HDR histograms do: each power of two is split into sizeSubBuckets buckets,
+// so a bucket covers at most 1/sizeSubBuckets of the sizes it holds.
+const (
+	sizeSubBits    = 3
+	sizeSubBuckets = 1 << sizeSubBits
+	sizeBuckets    = (bits.UintSize - sizeSubBits + 1) * sizeSubBuckets
+)
+
+// sizeBucket returns the histogram bucket of size
+func sizeBucket(size int) int {
+	if size < sizeSubBuckets {
+		if size < 0 {
+			return 0
+		}
+		return size
+	}
+	e := bits.Len(uint(size)) - 1
+	sub := (size >> uint(e-sizeSubBits)) & (sizeSubBuckets - 1)
+	return (e-sizeSubBits+1)*sizeSubBuckets + sub
+}
+
+// sizeBucketMax returns the largest size of bucket i
+func sizeBucketMax(i int) int {
+	if i < sizeSubBuckets {
+		return i
+	}
+	e := uint(i/sizeSubBuckets + sizeSubBits - 1)
+	sub := i % sizeSubBuckets
+	return (sizeSubBuckets+sub+1)<<(e-sizeSubBits) - 1
+}
+
+// countSize adds size to the histogram of the shard
+func (sh *poolShard) countSize(size int) {
+	atomic.AddUint64(&sh.sizes[sizeBucket(size)], 1)
+}
+
+// sizePercentile returns the size at or below which a fraction q of the
+// requested sizes fall
+func (p *byteBufferPool) sizePercentile(q float64) int {
+	var counts [sizeBuckets]uint64
+	var total uint64
+	for i := range p.shards {
+		sh := &p.shards[i]
+		for j := range counts {
+			c := atomic.LoadUint64(&sh.sizes[j])
+			counts[j] += c
+			total += c
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+	rank := uint64(q * float64(total))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return sizeBucketMax(i)
+		}
+	}
+	return sizeBucketMax(sizeBuckets - 1)
+}
+
+// SizePercentile returns the size at or below which a fraction p, e.g.
+// 0.99, of the sizes requested via GetBytes and by IoBuffers of the package
+// level pool fall, to pick MinRead or DefaultSize from the production
+// distribution. Sizes are counted in buckets of 1/8 of a power of two and
+// the largest size of the bucket is returned, so the result overestimates by
+// at most 12.5%. It returns 0 if nothing was requested yet.
+func SizePercentile(p float64) int {
+	return bbPool.sizePercentile(p)
+}
+
+// SizePercentile is SizePercentile for the pool.
+func (p *NamedPool) SizePercentile(q float64) int {
+	return p.bp.sizePercentile(q)
+}
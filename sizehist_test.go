@@ -0,0 +1,52 @@
+package buffer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizeBucket(t *testing.T) {
+	prev := -1
+	for _, size := range []int{0, 1, 7, 8, 9, 15, 16, 17, 18, 100, 1000, 4096, 1 << 20, 1<<20 + 1, math.MaxInt32, maxInt} {
+		i := sizeBucket(size)
+		if i < prev || i >= sizeBuckets {
+			t.Fatalf("size %d in bucket %d after %d", size, i, prev)
+		}
+		prev = i
+		max := sizeBucketMax(i)
+		if max < size || float64(max-size) > float64(size)/sizeSubBuckets {
+			t.Fatalf("size %d in bucket %d up to %d", size, i, max)
+		}
+		if i > 0 && sizeBucketMax(i-1) >= size {
+			t.Fatalf("size %d fits bucket %d up to %d", size, i-1, sizeBucketMax(i-1))
+		}
+	}
+}
+
+func TestSizePercentile(t *testing.T) {
+	p := NewNamedPool("size-percentile")
+	if got := p.SizePercentile(0.5); got != 0 {
+		t.Fatalf("got %d before any Get, want 0", got)
+	}
+	for i := 0; i < 90; i++ {
+		p.Put(p.Get(100))
+	}
+	for i := 0; i < 10; i++ {
+		p.Put(p.Get(5000))
+	}
+	for _, c := range []struct {
+		q        float64
+		min, max int
+	}{
+		{0, 100, 103},
+		{0.5, 100, 103},
+		{0.9, 100, 103},
+		{0.91, 5000, 5119},
+		{1, 5000, 5119},
+		{2, 5000, 5119},
+	} {
+		if got := p.SizePercentile(c.q); got < c.min || got > c.max {
+			t.Errorf("SizePercentile(%v) = %d, want %d..%d", c.q, got, c.min, c.max)
+		}
+	}
+}
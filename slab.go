@@ -0,0 +1,245 @@
+package buffer
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// DefaultSlabSize is the size of the slabs of a SlabAllocator created with
+// a non-positive slab size.
+const DefaultSlabSize = 1 << 20
+
+// SlabClassStats is the occupancy of one size class of a SlabAllocator.
+type SlabClassStats struct {
+	// ChunkSize is the size of the chunks of the class.
+	ChunkSize int
+	// Slabs is the number of slabs allocated for the class.
+	Slabs int
+	// Chunks is the total number of chunks in those slabs.
+	Chunks int
+	// InUse is the number of chunks handed out.
+	InUse int
+}
+
+// Occupancy returns the fraction of chunks in use.
+func (s SlabClassStats) Occupancy() float64 {
+	if s.Chunks == 0 {
+		return 0
+	}
+	return float64(s.InUse) / float64(s.Chunks)
+}
+
+// SlabAllocator hands out fixed size chunks carved from large slabs, with a
+// free list per slab. Unlike sync.Pool based pooling its memory is never
+// dropped by the GC, which gives long running processes predictable
+// fragmentation behavior.
+//
+// It can back GetBytes and PutBytes, see SetSlabBackend.
+type SlabAllocator struct {
+	classes []*slabClass
+}
+
+type slabClass struct {
+	mu        sync.Mutex
+	chunkSize int
+	perSlab   int
+	slabs     []*slab // sorted by base address
+	inUse     int
+}
+
+type slab struct {
+	mem  []byte
+	base uintptr
+	free []int32 // stack of free chunk indexes
+	used []bool
+}
+
+// NewSlabAllocator returns a SlabAllocator with the given chunk sizes,
+// carving chunks from slabs of slabSize bytes. Requests larger than the
+// largest chunk size are allocated directly.
+func NewSlabAllocator(chunkSizes []int, slabSize int) *SlabAllocator {
+	if slabSize <= 0 {
+		slabSize = DefaultSlabSize
+	}
+	sizes := append([]int(nil), chunkSizes...)
+	sort.Ints(sizes)
+	s := &SlabAllocator{}
+	for _, size := range sizes {
+		if size <= 0 || (len(s.classes) > 0 && s.classes[len(s.classes)-1].chunkSize == size) {
+			continue
+		}
+		perSlab := slabSize / size
+		if perSlab < 1 {
+			perSlab = 1
+		}
+		s.classes = append(s.classes, &slabClass{chunkSize: size, perSlab: perSlab})
+	}
+	return s
+}
+
+func (s *SlabAllocator) class(n int) *slabClass {
+	i := sort.Search(len(s.classes), func(i int) bool {
+		return s.classes[i].chunkSize >= n
+	})
+	if i == len(s.classes) {
+		return nil
+	}
+	return s.classes[i]
+}
+
+// Get returns a slice of length n. Its capacity is the chunk size of the
+// class serving it.
+func (s *SlabAllocator) Get(n int) *[]byte {
+	c := s.class(n)
+	if c == nil {
+		b := make([]byte, n)
+		return &b
+	}
+	b := c.get()[:n]
+	return &b
+}
+
+// Put returns a slice obtained via Get. Slices that were not carved from
+// the allocator's slabs are ignored.
+func (s *SlabAllocator) Put(buf *[]byte) {
+	if buf == nil || cap(*buf) == 0 {
+		return
+	}
+	c := s.class(cap(*buf))
+	if c == nil || c.chunkSize != cap(*buf) {
+		return
+	}
+	c.put(uintptr(unsafe.Pointer(&(*buf)[:1][0])))
+}
+
+// owns reports whether p is a chunk carved from the allocator's slabs
+func (s *SlabAllocator) owns(p []byte) bool {
+	if cap(p) == 0 {
+		return false
+	}
+	c := s.class(cap(p))
+	if c == nil || c.chunkSize != cap(p) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sl, _ := c.chunk(uintptr(unsafe.Pointer(&p[:1][0])))
+	return sl != nil
+}
+
+// Stats returns the occupancy of every size class.
+func (s *SlabAllocator) Stats() []SlabClassStats {
+	stats := make([]SlabClassStats, len(s.classes))
+	for i, c := range s.classes {
+		c.mu.Lock()
+		stats[i] = SlabClassStats{
+			ChunkSize: c.chunkSize,
+			Slabs:     len(c.slabs),
+			Chunks:    len(c.slabs) * c.perSlab,
+			InUse:     c.inUse,
+		}
+		c.mu.Unlock()
+	}
+	return stats
+}
+
+func (c *slabClass) get() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var sl *slab
+	for _, candidate := range c.slabs {
+		if len(candidate.free) > 0 {
+			sl = candidate
+			break
+		}
+	}
+	if sl == nil {
+		sl = c.grow()
+	}
+	idx := sl.free[len(sl.free)-1]
+	sl.free = sl.free[:len(sl.free)-1]
+	sl.used[idx] = true
+	c.inUse++
+	off := int(idx) * c.chunkSize
+	return sl.mem[off : off+c.chunkSize : off+c.chunkSize]
+}
+
+// grow adds a slab, c.mu must be held
+func (c *slabClass) grow() *slab {
+	mem := make([]byte, c.perSlab*c.chunkSize)
+	sl := &slab{
+		mem:  mem,
+		base: uintptr(unsafe.Pointer(&mem[0])),
+		free: make([]int32, c.perSlab),
+		used: make([]bool, c.perSlab),
+	}
+	for i := range sl.free {
+		sl.free[i] = int32(c.perSlab - 1 - i)
+	}
+	i := sort.Search(len(c.slabs), func(i int) bool {
+		return c.slabs[i].base > sl.base
+	})
+	c.slabs = append(c.slabs, nil)
+	copy(c.slabs[i+1:], c.slabs[i:])
+	c.slabs[i] = sl
+	return sl
+}
+
+// chunk returns the slab holding the chunk at addr and its index in
+// c.slabs, nil if addr isn't the start of a chunk of c, c.mu must be held
+func (c *slabClass) chunk(addr uintptr) (*slab, int) {
+	i := sort.Search(len(c.slabs), func(i int) bool {
+		return c.slabs[i].base > addr
+	}) - 1
+	if i < 0 {
+		return nil, i
+	}
+	sl := c.slabs[i]
+	off := addr - sl.base
+	if off >= uintptr(len(sl.mem)) || off%uintptr(c.chunkSize) != 0 {
+		return nil, i
+	}
+	return sl, i
+}
+
+func (c *slabClass) put(addr uintptr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sl, i := c.chunk(addr)
+	if sl == nil {
+		return
+	}
+	idx := int32((addr - sl.base) / uintptr(c.chunkSize))
+	if !sl.used[idx] {
+		return
+	}
+	sl.used[idx] = false
+	sl.free = append(sl.free, idx)
+	c.inUse--
+
+	// drop fully free slabs, keeping one around for the next get
+	if len(sl.free) == c.perSlab && len(c.slabs) > 1 {
+		c.slabs = append(c.slabs[:i], c.slabs[i+1:]...)
+	}
+}
+
+// slabBackend holds the *SlabAllocator backing the package level pool
+var slabBackend atomic.Value
+
+// SetSlabBackend makes GetBytes and PutBytes allocate from s instead of the
+// size class pools. Counters and debug checks keep working. DisablePooling,
+// SetMaxPooledSize and the thresholds for huge pages and pprof labels still
+// apply, only the sizes GetBytes would pool and s has a class for are
+// carved from the slabs. Passing nil
+// restores the size class pools; slices must be put back to the backend
+// they were taken from.
+func SetSlabBackend(s *SlabAllocator) {
+	slabBackend.Store(s)
+}
+
+func getSlabBackend() *SlabAllocator {
+	s, _ := slabBackend.Load().(*SlabAllocator)
+	return s
+}
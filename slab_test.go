@@ -0,0 +1,90 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestSlabAllocator(t *testing.T) {
+	s := NewSlabAllocator([]int{128, 64, 1024}, 1024)
+
+	var bufs []*[]byte
+	for i := 0; i < 20; i++ {
+		b := s.Get(100)
+		if len(*b) != 100 || cap(*b) != 128 {
+			t.Fatalf("unexpected chunk: len %d, cap %d", len(*b), cap(*b))
+		}
+		(*b)[0] = byte(i)
+		bufs = append(bufs, b)
+	}
+	for i, b := range bufs {
+		if (*b)[0] != byte(i) {
+			t.Fatalf("chunk %d overlaps another chunk", i)
+		}
+	}
+
+	stats := s.Stats()
+	if len(stats) != 3 || stats[1].ChunkSize != 128 {
+		t.Fatalf("unexpected classes: %+v", stats)
+	}
+	if stats[1].InUse != 20 || stats[1].Slabs != 3 || stats[1].Chunks != 24 {
+		t.Errorf("unexpected occupancy: %+v", stats[1])
+	}
+
+	for _, b := range bufs {
+		s.Put(b)
+	}
+	s.Put(bufs[0])
+	foreign := make([]byte, 128)
+	s.Put(&foreign)
+
+	stats = s.Stats()
+	if stats[1].InUse != 0 || stats[1].Slabs != 1 || stats[1].Occupancy() != 0 {
+		t.Errorf("unexpected occupancy after put: %+v", stats[1])
+	}
+
+	if b := s.Get(4096); len(*b) != 4096 {
+		t.Errorf("unexpected oversized allocation: %d", len(*b))
+	}
+}
+
+func TestSetSlabBackend(t *testing.T) {
+	s := NewSlabAllocator([]int{256}, 4096)
+	SetSlabBackend(s)
+	defer SetSlabBackend(nil)
+
+	b := GetBytes(200)
+	if cap(*b) != 256 {
+		t.Errorf("Expect slab chunk, but got cap %d", cap(*b))
+	}
+	if st := s.Stats()[0]; st.InUse != 1 {
+		t.Errorf("unexpected in use chunks: %d", st.InUse)
+	}
+	PutBytes(b)
+	if st := s.Stats()[0]; st.InUse != 0 {
+		t.Errorf("unexpected in use chunks after put: %d", st.InUse)
+	}
+	// the kill switch and the max pooled size come first, only pooled
+	// sizes within the slab classes are carved from the slabs
+	DisablePooling()
+	b = GetBytes(200)
+	EnablePooling()
+	SetMaxPooledSize(128)
+	c := GetBytes(200)
+	SetMaxPooledSize(0)
+	d := GetBytes(1000)
+	if st := s.Stats()[0]; st.InUse != 0 || cap(*b) != 200 || cap(*c) != 200 {
+		t.Errorf("Expect direct allocations, but got %d chunks in use, caps %d, %d", st.InUse, cap(*b), cap(*c))
+	}
+	PutBytes(b)
+	PutBytes(c)
+	PutBytes(d)
+
+	// a chunk goes back to the slab even when it is no longer pooled
+	b = GetBytes(200)
+	DisablePooling()
+	PutBytes(b)
+	EnablePooling()
+	if st := s.Stats()[0]; st.InUse != 0 {
+		t.Errorf("Expect the chunk back in the slab, but got %d in use", st.InUse)
+	}
+}
@@ -0,0 +1,258 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// ErrInvalidSocks5 is returned for malformed SOCKS5 handshake messages.
+var ErrInvalidSocks5 = errors.New("io buffer: invalid socks5 message")
+
+// SOCKS5 authentication methods (RFC 1928).
+const (
+	Socks5NoAuth       byte = 0x00
+	Socks5UserPass     byte = 0x02
+	Socks5NoAcceptable byte = 0xff
+)
+
+// SOCKS5 request commands.
+const (
+	Socks5Connect      byte = 0x01
+	Socks5Bind         byte = 0x02
+	Socks5UDPAssociate byte = 0x03
+)
+
+// SOCKS5 reply codes, besides the rejections 0x02 to 0x08.
+const (
+	Socks5Succeeded      byte = 0x00
+	Socks5GeneralFailure byte = 0x01
+)
+
+const (
+	socks5Version     = 5
+	socks5AuthVersion = 1
+
+	socks5IPv4   = 1
+	socks5Domain = 3
+	socks5IPv6   = 4
+)
+
+// Socks5Addr is the address of a SOCKS5 request or reply, either an IP or,
+// when IP is nil, a domain name.
+type Socks5Addr struct {
+	IP   net.IP
+	Name string
+	Port uint16
+}
+
+// Socks5Request is the request of a SOCKS5 client after authentication.
+type Socks5Request struct {
+	Command byte
+	Addr    Socks5Addr
+}
+
+// Socks5Reply is the reply of a SOCKS5 server to a request, Addr is the
+// address the server bound.
+type Socks5Reply struct {
+	Reply byte
+	Addr  Socks5Addr
+}
+
+// The SOCKS5 readers parse the handshake message at the start of the
+// unread bytes of b and consume it. They return ErrNeedMore, consuming
+// nothing, while the message is incomplete and ErrInvalidSocks5 for
+// malformed ones. The writers validate before writing.
+
+// WriteSocks5Greeting writes the client greeting offering methods.
+func WriteSocks5Greeting(b IoBuffer, methods []byte) error {
+	if len(methods) == 0 || len(methods) > 255 {
+		return opError("write socks5 greeting", len(methods), b, ErrInvalidSocks5)
+	}
+	if _, err := b.Write([]byte{socks5Version, byte(len(methods))}); err != nil {
+		return err
+	}
+	_, err := b.Write(methods)
+	return err
+}
+
+// ReadSocks5Greeting reads a client greeting and returns a copy of the
+// offered methods.
+func ReadSocks5Greeting(b IoBuffer) ([]byte, error) {
+	p := b.Peek(2)
+	if p == nil {
+		return nil, ErrNeedMore
+	}
+	if p[0] != socks5Version || p[1] == 0 {
+		return nil, opError("read socks5 greeting", 0, b, ErrInvalidSocks5)
+	}
+	n := 2 + int(p[1])
+	if p = b.Peek(n); p == nil {
+		return nil, ErrNeedMore
+	}
+	methods := append([]byte(nil), p[2:]...)
+	b.Drain(n)
+	return methods, nil
+}
+
+// WriteSocks5Method writes the method chosen by the server.
+func WriteSocks5Method(b IoBuffer, method byte) error {
+	_, err := b.Write([]byte{socks5Version, method})
+	return err
+}
+
+// ReadSocks5Method reads the method chosen by the server.
+func ReadSocks5Method(b IoBuffer) (byte, error) {
+	p := b.Peek(2)
+	if p == nil {
+		return 0, ErrNeedMore
+	}
+	if p[0] != socks5Version {
+		return 0, opError("read socks5 method", 0, b, ErrInvalidSocks5)
+	}
+	method := p[1]
+	b.Drain(2)
+	return method, nil
+}
+
+// WriteSocks5UserPass writes the username and password authentication
+// request of RFC 1929, both are at most 255 bytes.
+func WriteSocks5UserPass(b IoBuffer, user, password string) error {
+	if len(user) == 0 || len(user) > 255 || len(password) == 0 || len(password) > 255 {
+		return opError("write socks5 auth", 0, b, ErrInvalidSocks5)
+	}
+	p := make([]byte, 0, 3+len(user)+len(password))
+	p = append(p, socks5AuthVersion, byte(len(user)))
+	p = append(p, user...)
+	p = append(p, byte(len(password)))
+	p = append(p, password...)
+	_, err := b.Write(p)
+	return err
+}
+
+// ReadSocks5UserPass reads a username and password authentication
+// request.
+func ReadSocks5UserPass(b IoBuffer) (user, password string, err error) {
+	p := b.Peek(2)
+	if p == nil {
+		return "", "", ErrNeedMore
+	}
+	if p[0] != socks5AuthVersion {
+		return "", "", opError("read socks5 auth", 0, b, ErrInvalidSocks5)
+	}
+	ulen := int(p[1])
+	if p = b.Peek(3 + ulen); p == nil {
+		return "", "", ErrNeedMore
+	}
+	n := 3 + ulen + int(p[2+ulen])
+	if p = b.Peek(n); p == nil {
+		return "", "", ErrNeedMore
+	}
+	user, password = string(p[2:2+ulen]), string(p[3+ulen:n])
+	b.Drain(n)
+	return user, password, nil
+}
+
+// WriteSocks5AuthStatus writes the status of a username and password
+// authentication, 0 for success.
+func WriteSocks5AuthStatus(b IoBuffer, status byte) error {
+	_, err := b.Write([]byte{socks5AuthVersion, status})
+	return err
+}
+
+// ReadSocks5AuthStatus reads the status of a username and password
+// authentication.
+func ReadSocks5AuthStatus(b IoBuffer) (byte, error) {
+	p := b.Peek(2)
+	if p == nil {
+		return 0, ErrNeedMore
+	}
+	if p[0] != socks5AuthVersion {
+		return 0, opError("read socks5 auth status", 0, b, ErrInvalidSocks5)
+	}
+	status := p[1]
+	b.Drain(2)
+	return status, nil
+}
+
+// WriteSocks5Request writes a request.
+func WriteSocks5Request(b IoBuffer, r *Socks5Request) error {
+	return writeSocks5Message(b, "write socks5 request", r.Command, &r.Addr)
+}
+
+// ReadSocks5Request reads a request.
+func ReadSocks5Request(b IoBuffer) (Socks5Request, error) {
+	cmd, addr, err := readSocks5Message(b, "read socks5 request")
+	return Socks5Request{Command: cmd, Addr: addr}, err
+}
+
+// WriteSocks5Reply writes a reply.
+func WriteSocks5Reply(b IoBuffer, r *Socks5Reply) error {
+	return writeSocks5Message(b, "write socks5 reply", r.Reply, &r.Addr)
+}
+
+// ReadSocks5Reply reads a reply.
+func ReadSocks5Reply(b IoBuffer) (Socks5Reply, error) {
+	rep, addr, err := readSocks5Message(b, "read socks5 reply")
+	return Socks5Reply{Reply: rep, Addr: addr}, err
+}
+
+// writeSocks5Message writes a request or reply, which only differ in the
+// meaning of their code
+func writeSocks5Message(b IoBuffer, op string, code byte, a *Socks5Addr) error {
+	p := make([]byte, 0, 6+net.IPv6len+len(a.Name))
+	p = append(p, socks5Version, code, 0)
+	switch ip4 := a.IP.To4(); {
+	case ip4 != nil:
+		p = append(p, socks5IPv4)
+		p = append(p, ip4...)
+	case len(a.IP) == net.IPv6len:
+		p = append(p, socks5IPv6)
+		p = append(p, a.IP...)
+	case a.IP == nil && len(a.Name) > 0 && len(a.Name) <= 255:
+		p = append(p, socks5Domain, byte(len(a.Name)))
+		p = append(p, a.Name...)
+	default:
+		return opError(op, 0, b, ErrInvalidSocks5)
+	}
+	p = append(p, byte(a.Port>>8), byte(a.Port))
+	_, err := b.Write(p)
+	return err
+}
+
+func readSocks5Message(b IoBuffer, op string) (byte, Socks5Addr, error) {
+	var a Socks5Addr
+	p := b.Peek(5)
+	if p == nil {
+		return 0, a, ErrNeedMore
+	}
+	if p[0] != socks5Version || p[2] != 0 {
+		return 0, a, opError(op, 0, b, ErrInvalidSocks5)
+	}
+	var n int
+	switch p[3] {
+	case socks5IPv4:
+		n = 4 + net.IPv4len + 2
+	case socks5IPv6:
+		n = 4 + net.IPv6len + 2
+	case socks5Domain:
+		if p[4] == 0 {
+			return 0, a, opError(op, 0, b, ErrInvalidSocks5)
+		}
+		n = 5 + int(p[4]) + 2
+	default:
+		return 0, a, opError(op, 0, b, ErrInvalidSocks5)
+	}
+	if p = b.Peek(n); p == nil {
+		return 0, a, ErrNeedMore
+	}
+	if p[3] == socks5Domain {
+		a.Name = string(p[5 : n-2])
+	} else {
+		a.IP = append(net.IP(nil), p[4:n-2]...)
+	}
+	a.Port = binary.BigEndian.Uint16(p[n-2:])
+	code := p[1]
+	b.Drain(n)
+	return code, a, nil
+}
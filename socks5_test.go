@@ -0,0 +1,100 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestSocks5Handshake(t *testing.T) {
+	c2s, s2c := NewIoBuffer(0), NewIoBuffer(0)
+
+	WriteSocks5Greeting(c2s, []byte{Socks5NoAuth, Socks5UserPass})
+	if methods, err := ReadSocks5Greeting(c2s); err != nil || !bytes.Equal(methods, []byte{0, 2}) {
+		t.Fatalf("got %v, %v", methods, err)
+	}
+	WriteSocks5Method(s2c, Socks5UserPass)
+	if m, err := ReadSocks5Method(s2c); err != nil || m != Socks5UserPass {
+		t.Fatalf("got %d, %v", m, err)
+	}
+	WriteSocks5UserPass(c2s, "user", "secret")
+	if u, p, err := ReadSocks5UserPass(c2s); err != nil || u != "user" || p != "secret" {
+		t.Fatalf("got %q %q, %v", u, p, err)
+	}
+	WriteSocks5AuthStatus(s2c, 0)
+	if st, err := ReadSocks5AuthStatus(s2c); err != nil || st != 0 {
+		t.Fatalf("got %d, %v", st, err)
+	}
+
+	for _, req := range []Socks5Request{
+		{Command: Socks5Connect, Addr: Socks5Addr{Name: "example.com", Port: 443}},
+		{Command: Socks5Connect, Addr: Socks5Addr{IP: net.ParseIP("10.1.2.3").To4(), Port: 80}},
+		{Command: Socks5UDPAssociate, Addr: Socks5Addr{IP: net.ParseIP("2001:db8::1"), Port: 53}},
+	} {
+		if err := WriteSocks5Request(c2s, &req); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadSocks5Request(c2s)
+		if err != nil || !reflect.DeepEqual(got, req) {
+			t.Errorf("got %+v, %v, want %+v", got, err, req)
+		}
+	}
+	rep := Socks5Reply{Reply: Socks5Succeeded, Addr: Socks5Addr{IP: net.ParseIP("192.0.2.1").To4(), Port: 40000}}
+	WriteSocks5Reply(s2c, &rep)
+	want := []byte{5, 0, 0, 1, 192, 0, 2, 1, 0x9c, 0x40}
+	if !bytes.Equal(s2c.Bytes(), want) {
+		t.Errorf("got % x, want % x", s2c.Bytes(), want)
+	}
+	if got, err := ReadSocks5Reply(s2c); err != nil || !reflect.DeepEqual(got, rep) {
+		t.Errorf("got %+v, %v", got, err)
+	}
+	if c2s.Len() != 0 || s2c.Len() != 0 {
+		t.Errorf("%d and %d bytes left", c2s.Len(), s2c.Len())
+	}
+}
+
+func TestSocks5Partial(t *testing.T) {
+	b := NewIoBuffer(0)
+	WriteSocks5Request(b, &Socks5Request{Command: Socks5Connect, Addr: Socks5Addr{Name: "example.com", Port: 443}})
+	raw := b.CopyBytes()
+	for i := 0; i < len(raw); i++ {
+		if _, err := ReadSocks5Request(NewIoBufferBytes(raw[:i])); err != ErrNeedMore {
+			t.Fatalf("%d bytes: got %v, want ErrNeedMore", i, err)
+		}
+	}
+	b.Reset()
+	WriteSocks5UserPass(b, "user", "secret")
+	raw = b.CopyBytes()
+	for i := 0; i < len(raw); i++ {
+		if _, _, err := ReadSocks5UserPass(NewIoBufferBytes(raw[:i])); err != ErrNeedMore {
+			t.Fatalf("%d bytes: got %v, want ErrNeedMore", i, err)
+		}
+	}
+}
+
+func TestSocks5Invalid(t *testing.T) {
+	for name, err := range map[string]error{
+		"greeting version": func() error { _, err := ReadSocks5Greeting(NewIoBufferBytes([]byte{4, 1, 0})); return err }(),
+		"no methods":       func() error { _, err := ReadSocks5Greeting(NewIoBufferBytes([]byte{5, 0})); return err }(),
+		"method version":   func() error { _, err := ReadSocks5Method(NewIoBufferBytes([]byte{4, 0})); return err }(),
+		"auth version":     func() error { _, _, err := ReadSocks5UserPass(NewIoBufferBytes([]byte{5, 1, 'u', 1, 'p'})); return err }(),
+		"reserved": func() error {
+			_, err := ReadSocks5Request(NewIoBufferBytes([]byte{5, 1, 1, 1, 0, 0, 0, 0, 0, 0}))
+			return err
+		}(),
+		"address type": func() error {
+			_, err := ReadSocks5Request(NewIoBufferBytes([]byte{5, 1, 0, 2, 0, 0, 0, 0, 0, 0}))
+			return err
+		}(),
+		"empty domain":     func() error { _, err := ReadSocks5Request(NewIoBufferBytes([]byte{5, 1, 0, 3, 0, 0, 0})); return err }(),
+		"write no methods": WriteSocks5Greeting(NewIoBuffer(0), nil),
+		"write no address": WriteSocks5Request(NewIoBuffer(0), &Socks5Request{Command: Socks5Connect}),
+		"write user":       WriteSocks5UserPass(NewIoBuffer(0), "", "p"),
+	} {
+		if !errors.Is(err, ErrInvalidSocks5) {
+			t.Errorf("%s: got %v, want ErrInvalidSocks5", name, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package buffer
+
+import (
+	"io"
+)
+
+// copyThrough writes the buffered bytes to dst, then copies at most max
+// bytes from src to dst through the buffer, max <= 0 means until io.EOF
+func (b *ioBuffer) copyThrough(dst io.Writer, src io.Reader, max int) (int64, error) {
+	if b.closed {
+		return 0, opError("splice", max, b, ErrClosedBuffer)
+	}
+	var written int64
+	if b.Len() > 0 {
+		n, err := b.WriteTo(dst)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	r := src
+	if max > 0 {
+		r = io.LimitReader(src, int64(max))
+	}
+	for {
+		b.reset()
+		if cap(b.buf) < MinRead {
+			b.copy(MinRead)
+		}
+
+		m, e := b.readInto(r, 0)
+		b.wrote(m)
+		if m > 0 {
+			n, err := b.WriteTo(dst)
+			written += n
+			if err != nil {
+				return written, err
+			}
+		}
+
+		if e == io.EOF || (e == nil && m == 0) {
+			return written, nil
+		}
+		if e != nil {
+			return written, opError("read", 0, b, e)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+//go:build linux
+// +build linux
+
+package buffer
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+const (
+	spliceMove     = 0x1 // SPLICE_F_MOVE
+	spliceNonblock = 0x2 // SPLICE_F_NONBLOCK
+
+	// maxSpliceSize is the largest chunk moved by a single splice call
+	maxSpliceSize = 1 << 20
+)
+
+// SpliceTo moves at most max bytes from src to dst, max <= 0 means until
+// io.EOF.
+//
+// When the buffer is empty and both ends are *net.TCPConn, the bytes are
+// moved by splice(2) through a pipe without being copied to userspace.
+// Otherwise the buffered bytes are written to dst first and the rest is
+// copied through the buffer.
+func (b *ioBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	dc, ok := dst.(*net.TCPConn)
+	sc, ok2 := src.(*net.TCPConn)
+	if !ok || !ok2 || b.Len() > 0 || b.closed {
+		return b.copyThrough(dst, src, max)
+	}
+	return splice(dc, sc, max)
+}
+
+func splice(dst, src *net.TCPConn, max int) (int64, error) {
+	rsrc, err := src.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	rdst, err := dst.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		return 0, os.NewSyscallError("pipe2", err)
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+
+	var written int64
+	for max <= 0 || written < int64(max) {
+		chunk := maxSpliceSize
+		if max > 0 && int64(chunk) > int64(max)-written {
+			chunk = int(int64(max) - written)
+		}
+
+		// the pipe is empty here, so EAGAIN means src has nothing to read
+		var n int
+		var serr error
+		err := rsrc.Read(func(fd uintptr) bool {
+			n, serr = spliceOnce(int(fd), p[1], chunk)
+			return serr != syscall.EAGAIN
+		})
+		if err == nil && serr != nil {
+			err = os.NewSyscallError("splice", serr)
+		}
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, nil
+		}
+
+		for n > 0 {
+			var m int
+			err := rdst.Write(func(fd uintptr) bool {
+				m, serr = spliceOnce(p[0], int(fd), n)
+				return serr != syscall.EAGAIN
+			})
+			if err == nil && serr != nil {
+				err = os.NewSyscallError("splice", serr)
+			}
+			if err != nil {
+				return written, err
+			}
+			n -= m
+			written += int64(m)
+		}
+	}
+	return written, nil
+}
+
+func spliceOnce(rfd, wfd, n int) (int, error) {
+	for {
+		m, err := syscall.Splice(rfd, nil, wfd, nil, n, spliceMove|spliceNonblock)
+		if err == syscall.EINTR {
+			continue
+		}
+		return int(m), err
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package buffer
+
+import "net"
+
+// SpliceTo moves at most max bytes from src to dst through the buffer,
+// max <= 0 means until io.EOF.
+func (b *ioBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	return b.copyThrough(dst, src, max)
+}
@@ -0,0 +1,105 @@
+package buffer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// tcpPair returns both ends of a loopback TCP connection
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp loopback unavailable: %s", err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := <-accepted
+	if s == nil {
+		t.Fatal("accept failed")
+	}
+	return c, s
+}
+
+func testSpliceTo(t *testing.T, b IoBuffer, max int, expect []byte) {
+	client, src := tcpPair(t)
+	dst, server := tcpPair(t)
+	defer src.Close()
+	defer server.Close()
+
+	payload := bytes.Repeat([]byte("splice"), 50000)
+	go func() {
+		client.Write(payload)
+		client.Close()
+	}()
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := ioutil.ReadAll(server)
+		received <- data
+	}()
+
+	n, err := b.SpliceTo(dst, src, max)
+	dst.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := <-received
+	if n != int64(len(got)) {
+		t.Errorf("SpliceTo returned %d, but %d bytes arrived", n, len(got))
+	}
+	if max > 0 {
+		payload = payload[:max]
+	}
+	if !bytes.Equal(got, append(expect, payload...)) {
+		t.Errorf("payload corrupted: got %d bytes", len(got))
+	}
+}
+
+func TestSpliceTo(t *testing.T) {
+	testSpliceTo(t, NewIoBuffer(0), 0, nil)
+}
+
+func TestSpliceToMax(t *testing.T) {
+	testSpliceTo(t, NewIoBuffer(0), 100000, nil)
+}
+
+func TestSpliceToBuffered(t *testing.T) {
+	testSpliceTo(t, NewIoBufferString("buffered"), 0, []byte("buffered"))
+	testSpliceTo(t, NewIoBufferString("buffered"), 1000, []byte("buffered"))
+}
+
+func TestSpliceToPipe(t *testing.T) {
+	client, src := net.Pipe()
+	dst, server := net.Pipe()
+
+	payload := bytes.Repeat([]byte("pipe"), 1000)
+	go func() {
+		client.Write(payload)
+		client.Close()
+	}()
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := ioutil.ReadAll(server)
+		received <- data
+	}()
+
+	b := NewIoBuffer(0)
+	n, err := b.SpliceTo(dst, src, 0)
+	dst.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; n != int64(len(payload)) || !bytes.Equal(got, payload) {
+		t.Errorf("payload corrupted: got %d bytes", len(got))
+	}
+}
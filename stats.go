@@ -0,0 +1,30 @@
+package buffer
+
+// BufferStats are usage statistics of an IoBuffer, collected since the
+// buffer was created or last taken from the pool.
+type BufferStats struct {
+	// Grows is the number of times the backing slice was reallocated.
+	Grows int64
+	// CopiedBytes is the number of unread bytes slid to the front of the
+	// buffer by compaction.
+	CopiedBytes int64
+	// PeakLen is the largest number of unread bytes held at once.
+	PeakLen int
+	// BytesRead is the number of bytes consumed by Read, WriteTo, Drain and Cut.
+	BytesRead int64
+	// BytesWritten is the number of bytes appended by Write, WriteString,
+	// Append, ReadFrom and ReadOnce.
+	BytesWritten int64
+}
+
+func (b *ioBuffer) Stats() BufferStats {
+	return b.stats
+}
+
+// wrote accounts n bytes appended to the buffer
+func (b *ioBuffer) wrote(n int) {
+	b.stats.BytesWritten += int64(n)
+	if l := b.Len(); l > b.stats.PeakLen {
+		b.stats.PeakLen = l
+	}
+}
@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIoBufferStats(t *testing.T) {
+	b := NewIoBuffer(16)
+	b.Write(make([]byte, 100))
+	b.WriteString("hello")
+	b.ReadFrom(strings.NewReader("world"))
+
+	s := b.Stats()
+	if s.BytesWritten != 110 {
+		t.Errorf("Expect 110 bytes written, but got %d", s.BytesWritten)
+	}
+	if s.PeakLen != 110 {
+		t.Errorf("Expect peak 110, but got %d", s.PeakLen)
+	}
+	if s.Grows == 0 {
+		t.Errorf("Expect at least one grow")
+	}
+
+	b.Read(make([]byte, 10))
+	b.Drain(50)
+	b.WriteTo(&bytes.Buffer{})
+	s = b.Stats()
+	if s.BytesRead != 110 {
+		t.Errorf("Expect 110 bytes read, but got %d", s.BytesRead)
+	}
+
+	b.Write(make([]byte, 8))
+	b.Drain(4)
+	b.(*ioBuffer).copy(0)
+	if s = b.Stats(); s.CopiedBytes != 4 {
+		t.Errorf("Expect 4 copied bytes, but got %d", s.CopiedBytes)
+	}
+	if s.PeakLen != 110 {
+		t.Errorf("Expect peak 110, but got %d", s.PeakLen)
+	}
+}
@@ -0,0 +1,130 @@
+package buffer
+
+import (
+	"sync"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// StringBuilder is used to efficiently build a string using Write methods,
+// like strings.Builder, while the builders themselves are pooled.
+//
+// String hands the accumulated bytes to the returned string without copying
+// and finalizes the builder: further writes panic until Reset is called.
+//
+// Use GetStringBuilder for obtaining an empty builder.
+type StringBuilder struct {
+	buf       []byte
+	finalized bool
+}
+
+var sbPool sync.Pool
+
+// GetStringBuilder returns an empty StringBuilder from the pool.
+//
+// The builder may be returned to the pool via PutStringBuilder. Strings
+// produced by the builder stay valid after that.
+func GetStringBuilder() *StringBuilder {
+	v := sbPool.Get()
+	if v == nil {
+		return &StringBuilder{}
+	}
+	return v.(*StringBuilder)
+}
+
+// PutStringBuilder returns sb to the pool.
+//
+// The builder mustn't be accessed after returning it to the pool.
+func PutStringBuilder(sb *StringBuilder) {
+	if sb.finalized {
+		// the bytes are owned by the produced string now.
+		sb.buf = nil
+		sb.finalized = false
+	}
+	sb.buf = sb.buf[:0]
+	sbPool.Put(sb)
+}
+
+func (sb *StringBuilder) checkWritable() {
+	if sb.finalized {
+		panic("buffer: write to finalized StringBuilder")
+	}
+}
+
+// String returns the accumulated string and finalizes the builder.
+func (sb *StringBuilder) String() string {
+	sb.finalized = true
+	return *(*string)(unsafe.Pointer(&sb.buf))
+}
+
+// Len returns the number of accumulated bytes.
+func (sb *StringBuilder) Len() int {
+	return len(sb.buf)
+}
+
+// Cap returns the capacity of the builder's underlying byte slice.
+func (sb *StringBuilder) Cap() int {
+	return cap(sb.buf)
+}
+
+// Reset makes the builder empty and writable again. The bytes of a
+// previously produced string are never reused.
+func (sb *StringBuilder) Reset() {
+	if sb.finalized {
+		sb.buf = nil
+		sb.finalized = false
+		return
+	}
+	sb.buf = sb.buf[:0]
+}
+
+// Grow grows the builder's capacity, if necessary, to guarantee space for
+// another n bytes. It panics if n is negative.
+func (sb *StringBuilder) Grow(n int) {
+	sb.checkWritable()
+	if n < 0 {
+		panic("buffer: negative count")
+	}
+	if cap(sb.buf)-len(sb.buf) < n {
+		buf := make([]byte, len(sb.buf), 2*cap(sb.buf)+n)
+		copy(buf, sb.buf)
+		sb.buf = buf
+	}
+}
+
+// Write appends the contents of p to the builder. It always returns
+// len(p), nil.
+func (sb *StringBuilder) Write(p []byte) (int, error) {
+	sb.checkWritable()
+	sb.buf = append(sb.buf, p...)
+	return len(p), nil
+}
+
+// WriteByte appends the byte c to the builder. It always returns nil.
+func (sb *StringBuilder) WriteByte(c byte) error {
+	sb.checkWritable()
+	sb.buf = append(sb.buf, c)
+	return nil
+}
+
+// WriteRune appends the UTF-8 encoding of r to the builder.
+// It returns the length of r and a nil error.
+func (sb *StringBuilder) WriteRune(r rune) (int, error) {
+	sb.checkWritable()
+	if r < utf8.RuneSelf {
+		sb.buf = append(sb.buf, byte(r))
+		return 1, nil
+	}
+	var enc [utf8.UTFMax]byte
+	n := utf8.EncodeRune(enc[:], r)
+	sb.buf = append(sb.buf, enc[:n]...)
+	return n, nil
+}
+
+// WriteString appends the contents of s to the builder. It always returns
+// len(s), nil.
+func (sb *StringBuilder) WriteString(s string) (int, error) {
+	sb.checkWritable()
+	sb.buf = append(sb.buf, s...)
+	return len(s), nil
+}
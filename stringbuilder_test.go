@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestStringBuilder(t *testing.T) {
+	sb := GetStringBuilder()
+	sb.WriteString("foo")
+	sb.WriteByte('-')
+	sb.Write([]byte("bar"))
+	sb.WriteRune('世')
+
+	expectedS := "foo-bar世"
+	s := sb.String()
+	if s != expectedS {
+		t.Fatalf("unexpected result: %q. Expecting %q", s, expectedS)
+	}
+	if sb.Len() != len(expectedS) {
+		t.Fatalf("unexpected length: %d. Expecting %d", sb.Len(), len(expectedS))
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected panic on write to finalized builder")
+			}
+		}()
+		sb.WriteString("baz")
+	}()
+
+	sb.Reset()
+	sb.WriteString("other")
+	if s != expectedS {
+		t.Fatalf("finalized string was modified: %q", s)
+	}
+	if sb.String() != "other" {
+		t.Fatalf("unexpected result after reset: %q", sb.String())
+	}
+	PutStringBuilder(sb)
+
+	sb = GetStringBuilder()
+	if sb.Len() != 0 {
+		t.Fatalf("non-empty builder returned from pool")
+	}
+	sb.Grow(64)
+	if sb.Cap() < 64 {
+		t.Fatalf("unexpected capacity after Grow: %d", sb.Cap())
+	}
+	PutStringBuilder(sb)
+}
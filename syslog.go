@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+const syslogTimeFormat = "2006-01-02T15:04:05.999999Z07:00"
+
+// SyslogHeader is the header of an RFC 5424 syslog message. Empty fields
+// and a zero Timestamp are written as the nil value "-".
+type SyslogHeader struct {
+	// Facility is 0 to 23, Severity 0 (emergency) to 7 (debug).
+	Facility int
+	Severity int
+
+	// Timestamp is written with microsecond precision.
+	Timestamp time.Time
+
+	Hostname string
+	AppName  string
+	ProcID   string
+	MsgID    string
+}
+
+// SyslogMessage assembles an RFC 5424 syslog message in a Buffer taken from
+// the pool: NewSyslogMessage writes the header, Element adds structured
+// data and Finish adds the message text. Header fields and names are cut
+// to their maximum length and bytes the RFC doesn't allow in them are
+// replaced with '_', parameter values are escaped.
+type SyslogMessage struct {
+	b  *Buffer
+	sd bool
+}
+
+// NewSyslogMessage starts a message with header h. It panics if the
+// facility or severity is out of range.
+func NewSyslogMessage(h SyslogHeader) SyslogMessage {
+	if h.Facility < 0 || h.Facility > 23 || h.Severity < 0 || h.Severity > 7 {
+		panic("buffer: syslog facility or severity out of range")
+	}
+	b := Get()
+	b.B = append(b.B, '<')
+	b.WriteInt(int64(h.Facility<<3 | h.Severity))
+	b.B = append(b.B, ">1 "...)
+	if h.Timestamp.IsZero() {
+		b.B = append(b.B, '-')
+	} else {
+		b.B = h.Timestamp.AppendFormat(b.B, syslogTimeFormat)
+	}
+	b.B = append(b.B, ' ')
+	b.writeSyslogField(h.Hostname, 255, false)
+	b.B = append(b.B, ' ')
+	b.writeSyslogField(h.AppName, 48, false)
+	b.B = append(b.B, ' ')
+	b.writeSyslogField(h.ProcID, 128, false)
+	b.B = append(b.B, ' ')
+	b.writeSyslogField(h.MsgID, 32, false)
+	b.B = append(b.B, ' ')
+	return SyslogMessage{b: b}
+}
+
+// Element adds the structured data element [id name="value" ...].
+func (m *SyslogMessage) Element(id string, params ...Label) {
+	m.sd = true
+	m.b.B = append(m.b.B, '[')
+	m.b.writeSyslogField(id, 32, true)
+	for _, p := range params {
+		m.b.B = append(m.b.B, ' ')
+		m.b.writeSyslogField(p.Name, 32, true)
+		m.b.B = append(m.b.B, '=', '"')
+		for i := 0; i < len(p.Value); i++ {
+			switch c := p.Value[i]; c {
+			case '"', '\\', ']':
+				m.b.B = append(m.b.B, '\\', c)
+			default:
+				m.b.B = append(m.b.B, c)
+			}
+		}
+		m.b.B = append(m.b.B, '"')
+	}
+	m.b.B = append(m.b.B, ']')
+}
+
+// Finish adds msg, prefixed with a byte order mark if it is non-ASCII
+// UTF-8 as the RFC requires, and returns the message. The Buffer should be
+// returned to the pool with Put once sent, m mustn't be used anymore.
+func (m *SyslogMessage) Finish(msg string) *Buffer {
+	b := m.b
+	m.b = nil
+	if !m.sd {
+		b.B = append(b.B, '-')
+	}
+	if msg != "" {
+		b.B = append(b.B, ' ')
+		if !isASCII(msg) && utf8.ValidString(msg) {
+			b.B = append(b.B, "\xef\xbb\xbf"...)
+		}
+		b.B = append(b.B, msg...)
+	}
+	return b
+}
+
+// writeSyslogField writes s cut to max bytes with the bytes outside of
+// printable ASCII replaced, and for structured data names also '=', ']'
+// and '"'. An empty s is written as "-".
+func (b *Buffer) writeSyslogField(s string, max int, sdName bool) {
+	if s == "" {
+		b.B = append(b.B, '-')
+		return
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 || sdName && (c == '=' || c == ']' || c == '"') {
+			c = '_'
+		}
+		b.B = append(b.B, c)
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
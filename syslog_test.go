@@ -0,0 +1,62 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogMessage(t *testing.T) {
+	ts := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+
+	m := NewSyslogMessage(SyslogHeader{Facility: 4, Severity: 2, Timestamp: ts,
+		Hostname: "mymachine.example.com", AppName: "su", MsgID: "ID47"})
+	b := m.Finish("'su root' failed for lonvick on /dev/pts/8")
+	want := "<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	Put(b)
+
+	m = NewSyslogMessage(SyslogHeader{Facility: 20, Severity: 5, Timestamp: ts,
+		Hostname: "mymachine.example.com", AppName: "evntslog", MsgID: "ID47"})
+	m.Element("exampleSDID@32473", Label{"iut", "3"}, Label{"eventSource", "Application"}, Label{"eventID", "1011"})
+	m.Element("examplePriority@32473", Label{"class", "high"})
+	b = m.Finish("An application event log entry…")
+	want = `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 ` +
+		`[exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] ` +
+		"\xef\xbb\xbfAn application event log entry…"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	Put(b)
+
+	m = NewSyslogMessage(SyslogHeader{Facility: 23, Severity: 7,
+		Hostname: "my host", AppName: strings.Repeat("a", 60), ProcID: "1234"})
+	m.Element("a=b c]", Label{"k\"ey", `v"a\l]ue`})
+	b = m.Finish("")
+	want = `<191>1 - my_host ` + strings.Repeat("a", 48) + ` 1234 - [a_b_c_ k_ey="v\"a\\l\]ue"]`
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	Put(b)
+
+	expectPanic(t, "facility", func() { NewSyslogMessage(SyslogHeader{Facility: 24}) })
+	expectPanic(t, "severity", func() { NewSyslogMessage(SyslogHeader{Severity: -1}) })
+}
+
+func TestSyslogMessageAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector drops pooled items at random")
+	}
+	h := SyslogHeader{Facility: 1, Severity: 6, Timestamp: time.Now(),
+		Hostname: "host", AppName: "app", ProcID: "42", MsgID: "msg"}
+	Put(Get())
+	if n := testing.AllocsPerRun(100, func() {
+		m := NewSyslogMessage(h)
+		m.Element("meta", Label{"request", "abc"})
+		Put(m.Finish("hello"))
+	}); n != 0 {
+		t.Errorf("%v allocations per message", n)
+	}
+}
@@ -0,0 +1,50 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestIoBufferTee(t *testing.T) {
+	b := NewIoBufferString("0123456789abcdef")
+	mirror := NewIoBuffer(0)
+	b.Tee(mirror)
+
+	p := make([]byte, 4)
+	b.Read(p)
+	b.Drain(2)
+	b.(*ioBuffer).Cut(2)
+	b.WriteTo(ioutil.Discard)
+	if mirror.String() != "0123456789abcdef" {
+		t.Errorf("Expect all consumed bytes mirrored, but got %q", mirror.String())
+	}
+
+	b.Tee(nil)
+	b.WriteString("more")
+	b.Drain(4)
+	if mirror.Len() != 16 {
+		t.Errorf("Expect teeing to stop, but got %q", mirror.String())
+	}
+}
+
+func TestIoBufferTeePeek(t *testing.T) {
+	b := NewIoBufferString("data")
+	mirror := NewIoBuffer(0)
+	b.Tee(mirror)
+	b.Peek(2)
+	b.Bytes()
+	if mirror.Len() != 0 {
+		t.Errorf("Expect Peek and Bytes not to consume, but got %q", mirror.String())
+	}
+}
+
+func TestMultiIoBufferTee(t *testing.T) {
+	m := newMulti()
+	mirror := NewIoBuffer(0)
+	m.Tee(mirror)
+	m.Drain(len("header|body|"))
+	m.WriteTo(ioutil.Discard)
+	if mirror.String() != "header|body|trailer" {
+		t.Errorf("Expect all consumed bytes mirrored, but got %q", mirror.String())
+	}
+}
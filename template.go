@@ -0,0 +1,38 @@
+package buffer
+
+import "io"
+
+// Template renders to an io.Writer, as *html/template.Template and
+// *text/template.Template do.
+type Template interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// templatePool holds the buffers of renders, calibrated to the size of
+// rendered output apart from other uses of the pools
+var templatePool Pool
+
+// RenderTemplate executes t with data into a pooled Buffer, which should
+// be released with Free once used. The buffer goes back to the pool when
+// rendering fails.
+func RenderTemplate(t Template, data interface{}) (*Buffer, error) {
+	b := templatePool.Get()
+	if err := t.Execute(b, data); err != nil {
+		b.Free()
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExecutePooled executes t with data into a pooled Buffer and writes the
+// output to w in one Write. Unlike t.Execute(w, data) it writes nothing
+// when rendering fails, so an HTTP handler can still reply with an error.
+func ExecutePooled(w io.Writer, t Template, data interface{}) error {
+	b, err := RenderTemplate(t, data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b.Bytes())
+	b.Free()
+	return err
+}
@@ -0,0 +1,61 @@
+package buffer
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := htmltemplate.Must(htmltemplate.New("page").Parse(`<p>{{.}}</p>`))
+	b, err := RenderTemplate(tmpl, "<b>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "<p>&lt;b&gt;</p>" {
+		t.Errorf("got %q", b.String())
+	}
+	b.Free()
+
+	text := texttemplate.Must(texttemplate.New("line").Parse(`{{.Name}}={{.Value}}`))
+	if b, err = RenderTemplate(text, struct{ Name, Value string }{"a", "<b>"}); err != nil || b.String() != "a=<b>" {
+		t.Errorf("got %q, %v", b, err)
+	}
+
+	failing := texttemplate.Must(texttemplate.New("fail").Parse(`partial {{.Missing}}`))
+	if b, err := RenderTemplate(failing, struct{}{}); err == nil || b != nil {
+		t.Errorf("got %v, %v, want an error", b, err)
+	}
+}
+
+func TestExecutePooled(t *testing.T) {
+	tmpl := htmltemplate.Must(htmltemplate.New("page").Parse(`{{range .}}<li>{{.}}</li>{{end}}`))
+	var out bytes.Buffer
+	if err := ExecutePooled(&out, tmpl, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "<li>a</li><li>b</li>" {
+		t.Errorf("got %q", out.String())
+	}
+
+	out.Reset()
+	failing := texttemplate.Must(texttemplate.New("fail").Parse(`partial {{.Missing}}`))
+	if err := ExecutePooled(&out, failing, struct{}{}); err == nil || out.Len() != 0 {
+		t.Errorf("got %v with %q written, want an error and no output", err, out.String())
+	}
+
+	// rendering into a warm buffer doesn't allocate for the output, the
+	// race detector drops pooled items at random
+	if raceEnabled {
+		return
+	}
+	static := texttemplate.Must(texttemplate.New("static").Parse(string(bytes.Repeat([]byte("x"), 4096))))
+	ExecutePooled(ioutil.Discard, static, nil)
+	if n := testing.AllocsPerRun(100, func() {
+		ExecutePooled(ioutil.Discard, static, nil)
+	}); n > 2 {
+		t.Errorf("%v allocations per render", n)
+	}
+}
@@ -0,0 +1,115 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// FrameHeaderSize is the size of the frame header of framed transports,
+// such as Thrift's TFramedTransport: a big endian int32 payload length.
+const FrameHeaderSize = 4
+
+// maxFrameSize is the largest payload a frame header can announce
+const maxFrameSize = 1<<31 - 1
+
+// ErrInvalidThrift is returned by PeekThriftHeader for bytes that don't
+// start with a strict binary protocol message header.
+var ErrInvalidThrift = errors.New("io buffer: invalid thrift message")
+
+// WriteFrame writes p to b as one frame. Payloads larger than max, or the
+// 2 GiB the header can hold, fail with ErrFrameTooLarge, max <= 0 means no
+// limit.
+func WriteFrame(b IoBuffer, p []byte, max int) error {
+	if max > 0 && len(p) > max || int64(len(p)) > maxFrameSize {
+		return opError("write frame", len(p), b, ErrFrameTooLarge)
+	}
+	var hdr [FrameHeaderSize]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(p)))
+	return writeFrame(b, hdr[:], p)
+}
+
+// PeekFrame returns the payload of the frame at the start of the unread
+// bytes of b without consuming it, Drain(FrameHeaderSize+len(payload))
+// does. The payload aliases b and is valid until the next Drain or write.
+// ErrNeedMore is returned until the frame is complete, frames larger than
+// max fail with ErrFrameTooLarge once their header is buffered, max <= 0
+// means no limit.
+func PeekFrame(b IoBuffer, max int) ([]byte, error) {
+	hdr := b.Peek(FrameHeaderSize)
+	if hdr == nil {
+		return nil, ErrNeedMore
+	}
+	n := int64(int32(binary.BigEndian.Uint32(hdr)))
+	if n < 0 || max > 0 && n > int64(max) || n > int64(maxInt-FrameHeaderSize) {
+		return nil, opError("peek frame", int(uint32(n)), b, ErrFrameTooLarge)
+	}
+	p := b.Peek(FrameHeaderSize + int(n))
+	if p == nil {
+		return nil, ErrNeedMore
+	}
+	return p[FrameHeaderSize:], nil
+}
+
+// ThriftMessageType is the type of a Thrift message.
+type ThriftMessageType byte
+
+// The Thrift message types.
+const (
+	ThriftCall      ThriftMessageType = 1
+	ThriftReply     ThriftMessageType = 2
+	ThriftException ThriftMessageType = 3
+	ThriftOneway    ThriftMessageType = 4
+)
+
+// thriftVersion1 is the version marker of the strict binary protocol
+const thriftVersion1 = 0x80010000
+
+// ThriftHeader is the header of a Thrift message in the strict binary
+// protocol, enough to route a call without decoding its arguments.
+type ThriftHeader struct {
+	// Name is the method name, it aliases the peeked buffer and is valid
+	// until the next Drain or write.
+	Name  []byte
+	Type  ThriftMessageType
+	SeqID int32
+	// Len is the size of the header, excluding the frame header of a
+	// framed message.
+	Len int
+}
+
+// PeekThriftHeader returns the header of the Thrift message at the start of
+// the unread bytes of b without consuming them. A framed message starts
+// with a frame header, whose size must cover the message header.
+// ErrNeedMore is returned until the header is buffered, ErrInvalidThrift
+// for messages that aren't in the strict binary protocol.
+func PeekThriftHeader(b IoBuffer, framed bool) (ThriftHeader, error) {
+	var h ThriftHeader
+	skip := 0
+	if framed {
+		skip = FrameHeaderSize
+	}
+	p := b.Peek(skip + 8)
+	if p == nil {
+		return h, ErrNeedMore
+	}
+	hdr, p := p[:skip], p[skip:]
+	version := binary.BigEndian.Uint32(p)
+	h.Type = ThriftMessageType(version)
+	if version&0xffff0000 != thriftVersion1 || version&0xff00 != 0 || h.Type < ThriftCall || h.Type > ThriftOneway {
+		return ThriftHeader{}, opError("peek thrift header", 0, b, ErrInvalidThrift)
+	}
+	n := int64(int32(binary.BigEndian.Uint32(p[4:])))
+	if n < 0 || n > int64(maxInt-skip-12) ||
+		framed && 12+n > int64(binary.BigEndian.Uint32(hdr)) {
+		return ThriftHeader{}, opError("peek thrift header", 0, b, ErrInvalidThrift)
+	}
+	h.Len = 12 + int(n)
+	p = b.Peek(skip + h.Len)
+	if p == nil {
+		return ThriftHeader{}, ErrNeedMore
+	}
+	p = p[skip:]
+	h.Name = p[8 : 8+n]
+	h.SeqID = int32(binary.BigEndian.Uint32(p[8+n:]))
+	return h, nil
+}
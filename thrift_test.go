@@ -0,0 +1,111 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// thriftMessage returns the strict binary header of a message followed by
+// an empty struct
+func thriftMessage(typ ThriftMessageType, name string, seq int32) []byte {
+	p := make([]byte, 12+len(name)+1)
+	binary.BigEndian.PutUint32(p, thriftVersion1|uint32(typ))
+	binary.BigEndian.PutUint32(p[4:], uint32(len(name)))
+	copy(p[8:], name)
+	binary.BigEndian.PutUint32(p[8+len(name):], uint32(seq))
+	return p
+}
+
+func TestFrame(t *testing.T) {
+	b := NewIoBuffer(0)
+	if err := WriteFrame(b, []byte("first"), 16); err != nil {
+		t.Fatal(err)
+	}
+	WriteFrame(b, nil, 16)
+	if err := WriteFrame(b, make([]byte, 17), 16); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("got %v, want ErrFrameTooLarge", err)
+	}
+	if b.Len() != 2*FrameHeaderSize+5 {
+		t.Fatalf("unexpected framed length %d", b.Len())
+	}
+
+	for _, want := range []string{"first", ""} {
+		p, err := PeekFrame(b, 16)
+		if err != nil || string(p) != want {
+			t.Fatalf("got %q, %v, want %q", p, err, want)
+		}
+		b.Drain(FrameHeaderSize + len(p))
+	}
+	if _, err := PeekFrame(b, 16); err != ErrNeedMore {
+		t.Errorf("got %v, want ErrNeedMore", err)
+	}
+
+	b.Write([]byte{0, 0, 0, 5, 'a', 'b'})
+	if _, err := PeekFrame(b, 16); err != ErrNeedMore {
+		t.Errorf("partial frame: got %v, want ErrNeedMore", err)
+	}
+	if _, err := PeekFrame(b, 4); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("got %v, want ErrFrameTooLarge", err)
+	}
+	b.Reset()
+	b.Write([]byte{0x80, 0, 0, 0})
+	if _, err := PeekFrame(b, 0); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("negative size: got %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestPeekThriftHeader(t *testing.T) {
+	msg := thriftMessage(ThriftCall, "getUser", 42)
+	b := NewIoBuffer(0)
+	for i := 0; i < len(msg)-1; i++ {
+		if _, err := PeekThriftHeader(b, false); err != ErrNeedMore {
+			t.Fatalf("%d bytes: got %v, want ErrNeedMore", i, err)
+		}
+		b.Write(msg[i : i+1])
+	}
+	b.Write(msg[len(msg)-1:])
+	h, err := PeekThriftHeader(b, false)
+	if err != nil || string(h.Name) != "getUser" || h.Type != ThriftCall || h.SeqID != 42 || h.Len != 19 {
+		t.Fatalf("got %+v, %v", h, err)
+	}
+	if b.Len() != len(msg) {
+		t.Errorf("PeekThriftHeader consumed bytes")
+	}
+
+	b.Reset()
+	WriteFrame(b, thriftMessage(ThriftOneway, "log", -1), 0)
+	h, err = PeekThriftHeader(b, true)
+	if err != nil || string(h.Name) != "log" || h.Type != ThriftOneway || h.SeqID != -1 {
+		t.Fatalf("framed: got %+v, %v", h, err)
+	}
+
+	for name, p := range map[string][]byte{
+		"non-strict": {0, 0, 0, 3, 'a', 'b', 'c', 1, 0, 0, 0, 1},
+		"version":    {0x80, 0x02, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0},
+		"type":       thriftMessage(5, "x", 1),
+		"name":       {0x80, 0x01, 0, 1, 0xff, 0xff, 0xff, 0xff},
+	} {
+		b.Reset()
+		b.Write(p)
+		if _, err := PeekThriftHeader(b, false); !errors.Is(err, ErrInvalidThrift) {
+			t.Errorf("%s: got %v, want ErrInvalidThrift", name, err)
+		}
+	}
+	b.Reset()
+	b.Write([]byte{0, 0, 0, 8})
+	b.Write(thriftMessage(ThriftCall, "method", 1))
+	if _, err := PeekThriftHeader(b, true); !errors.Is(err, ErrInvalidThrift) {
+		t.Errorf("short frame: got %v, want ErrInvalidThrift", err)
+	}
+}
+
+func TestWriteFrameAtomic(t *testing.T) {
+	b := New(WithMaxSize(FrameHeaderSize + 4))
+	if err := WriteFrame(b, []byte("12345"), 0); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Expect ErrTooLarge, but got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("Expect no partial frame, but got %d bytes", b.Len())
+	}
+}
@@ -0,0 +1,74 @@
+package buffer
+
+import (
+	"crypto/rand"
+	"sync"
+)
+
+// entropySize is the number of random bytes fetched from the OS at once
+const entropySize = 4096
+
+// entropy holds random bytes fetched ahead, consumed from off on
+type entropy struct {
+	buf [entropySize]byte
+	off int
+}
+
+var entropyPool = sync.Pool{
+	New: func() interface{} {
+		return &entropy{off: entropySize}
+	},
+}
+
+// WriteUUID writes u in the canonical hyphenated form,
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, with lower-case hex digits.
+func (b *Buffer) WriteUUID(u [16]byte) {
+	n := len(b.B)
+	b.B = append(b.B, "00000000-0000-0000-0000-000000000000"...)
+	p := b.B[n:]
+	j := 0
+	for i, c := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			j++
+		}
+		p[j] = hexDigits[c>>4]
+		p[j+1] = hexDigits[c&0x0f]
+		j += 2
+	}
+}
+
+// WriteRandomHex writes n random bytes from crypto/rand as 2*n hex digits,
+// e.g. a request ID. The random bytes are fetched from the OS in blocks kept
+// in a pool, so that stamping IDs on hot paths needs no system call per ID.
+// An error of crypto/rand is returned with nothing written.
+func (b *Buffer) WriteRandomHex(n int) error {
+	if n < 0 {
+		panic("buffer: negative count")
+	}
+	e := entropyPool.Get().(*entropy)
+	defer entropyPool.Put(e)
+	start := len(b.B)
+	for n > 0 {
+		if e.off == entropySize {
+			if _, err := rand.Read(e.buf[:]); err != nil {
+				b.B = b.B[:start]
+				return err
+			}
+			e.off = 0
+		}
+		m := entropySize - e.off
+		if m > n {
+			m = n
+		}
+		for _, c := range e.buf[e.off : e.off+m] {
+			b.B = append(b.B, hexDigits[c>>4], hexDigits[c&0x0f])
+		}
+		// never hand out the same bytes twice
+		for i := e.off; i < e.off+m; i++ {
+			e.buf[i] = 0
+		}
+		e.off += m
+		n -= m
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package buffer
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBufferWriteUUID(t *testing.T) {
+	u := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	var bb Buffer
+	bb.WriteString("id=")
+	bb.WriteUUID(u)
+	if want := "id=123e4567-e89b-12d3-a456-426614174000"; bb.String() != want {
+		t.Fatalf("got %q, want %q", bb.String(), want)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		bb.Reset()
+		bb.WriteUUID(u)
+	}); n != 0 {
+		t.Fatalf("WriteUUID allocates %v times", n)
+	}
+}
+
+func TestBufferWriteRandomHex(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		var bb Buffer
+		if err := bb.WriteRandomHex(16); err != nil {
+			t.Fatal(err)
+		}
+		s := bb.String()
+		if len(s) != 32 {
+			t.Fatalf("got %d hex digits, want 32", len(s))
+		}
+		if _, err := hex.DecodeString(s); err != nil || strings.ToLower(s) != s {
+			t.Fatalf("%q is not lower-case hex", s)
+		}
+		if seen[s] {
+			t.Fatalf("%q repeated", s)
+		}
+		seen[s] = true
+	}
+
+	// more than one block of entropy
+	var bb Buffer
+	if err := bb.WriteRandomHex(3 * entropySize); err != nil {
+		t.Fatal(err)
+	}
+	if bb.Len() != 6*entropySize || strings.Contains(bb.String(), strings.Repeat("0", 64)) {
+		t.Fatalf("len %d", bb.Len())
+	}
+	bb.Reset()
+	bb.WriteRandomHex(0)
+	if bb.Len() != 0 {
+		t.Fatalf("len %d, want 0", bb.Len())
+	}
+	expectPanic(t, "negative", func() { bb.WriteRandomHex(-1) })
+}
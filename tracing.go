@@ -0,0 +1,50 @@
+package buffer
+
+import (
+	"context"
+	"runtime/trace"
+	"strconv"
+	"sync/atomic"
+)
+
+var lifecycleTracing int32
+
+// SetLifecycleTracing makes IoBuffers taken from a pool emit a runtime/trace
+// task named "buffer" spanning from GetIoBuffer to PutIoBuffer, logging the
+// requested size and every grow, so that go tool trace shows buffer
+// lifetimes alongside goroutine scheduling. Tasks rather than regions are
+// used since buffers are often released on another goroutine than the one
+// that took them. Nothing is emitted unless the execution tracer runs.
+func SetLifecycleTracing(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&lifecycleTracing, v)
+}
+
+func lifecycleTracingEnabled() bool {
+	return atomic.LoadInt32(&lifecycleTracing) != 0 && trace.IsEnabled()
+}
+
+// startTask starts the lifecycle task of the buffer taken with size
+func (b *ioBuffer) startTask(size int) {
+	b.endTask()
+	ctx, task := trace.NewTask(context.Background(), "buffer")
+	trace.Log(ctx, "size", strconv.Itoa(size))
+	b.task, b.taskCtx = task, ctx
+}
+
+// endTask ends the lifecycle task of the released buffer
+func (b *ioBuffer) endTask() {
+	if b.task != nil {
+		b.task.End()
+		b.task, b.taskCtx = nil, nil
+	}
+}
+
+func (b *ioBuffer) traceGrow(oldCap, newCap int) {
+	if b.task != nil {
+		trace.Log(b.taskCtx, "grow", strconv.Itoa(oldCap)+"->"+strconv.Itoa(newCap))
+	}
+}
@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+)
+
+func TestLifecycleTracing(t *testing.T) {
+	SetLifecycleTracing(true)
+	defer SetLifecycleTracing(false)
+
+	var out bytes.Buffer
+	if err := trace.Start(&out); err != nil {
+		t.Skipf("tracer already running: %v", err)
+	}
+	b := GetIoBuffer(100)
+	ib := b.(*ioBuffer)
+	if ib.task == nil {
+		trace.Stop()
+		t.Fatal("no task started")
+	}
+	b.Write(make([]byte, 1000))
+	PutIoBuffer(b)
+	if ib.task != nil {
+		trace.Stop()
+		t.Fatal("task not ended")
+	}
+	trace.Stop()
+
+	for _, s := range []string{"buffer", "size", "grow"} {
+		if !bytes.Contains(out.Bytes(), []byte(s)) {
+			t.Errorf("trace lacks %q", s)
+		}
+	}
+
+	// no tasks while the tracer is off
+	b = GetIoBuffer(100)
+	if b.(*ioBuffer).task != nil {
+		t.Error("task started without tracer")
+	}
+	PutIoBuffer(b)
+}
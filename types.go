@@ -1,7 +1,9 @@
 package buffer
 
 import (
+	"fmt"
 	"io"
+	"net"
 	"time"
 )
 
@@ -11,18 +13,76 @@ type IoBuffer interface {
 
 	ReadFrom(r io.Reader) (int64, error)
 
+	// ReadFromLimit is ReadFrom buffering at most max bytes. It fails with
+	// ErrLimitExceeded when r holds more, in which case one byte past max
+	// has been consumed from r and dropped.
+	ReadFromLimit(r io.Reader, max int64) (int64, error)
+
 	ReadOnce(r io.Reader, duration time.Duration) (int64, error)
 
 	Write(b []byte) (int, error)
 
 	WriteString(s string) (n int, err error)
 
+	// WriteRune appends the UTF-8 encoding of r.
+	WriteRune(r rune) (int, error)
+
+	// ReadRune reads the next UTF-8 encoded rune, an invalid encoding
+	// consumes one byte and returns U+FFFD, 1.
+	ReadRune() (r rune, size int, err error)
+
+	// UnreadRune unreads the rune returned by the last ReadRune and fails
+	// with ErrUnreadRune if the buffer was read otherwise since.
+	UnreadRune() error
+
 	WriteTo(w io.Writer) (n int64, err error)
 
 	Peek(n int) []byte
 
+	// ValidUTF8 reports whether the unread bytes are valid UTF-8.
+	ValidUTF8() bool
+
+	// SanitizeUTF8 replaces each run of invalid UTF-8 in the unread bytes
+	// with replacement, typically utf8.RuneError, a negative replacement
+	// drops the invalid bytes. It returns the number of runs replaced.
+	SanitizeUTF8(replacement rune) (int, error)
+
+	// ToUpperASCII and ToLowerASCII change the case of the ASCII letters of
+	// the unread bytes in place, e.g. to normalize header names.
+	ToUpperASCII()
+
+	ToLowerASCII()
+
+	// ReplaceByte replaces every old byte of the unread bytes with new in
+	// place.
+	ReplaceByte(old, new byte)
+
+	// ReplaceRange replaces the unread bytes from offset from up to but not
+	// including offset to with replacement, moving whichever side of the
+	// range is smaller. It panics if the range is out of bounds.
+	ReplaceRange(from, to int, replacement []byte) error
+
+	// Compact slides the unread bytes to the front of the buffer's memory
+	// and, if trim is set, shrinks the memory to the smallest pool size
+	// holding them. It is meant for natural quiesce points such as the end
+	// of a response, instead of waiting for writes to slide or grow.
+	Compact(trim bool)
+
+	// Window returns unread bytes to parse in place and a commit function
+	// dropping the number of bytes consumed, which must not exceed the
+	// window. The window may hold less than Len bytes, e.g. only the first
+	// segment of a MultiIoBuffer, parsers call Window again after committing.
+	// The window is invalid once the buffer is modified.
+	Window() ([]byte, func(consumed int))
+
+	// Bytes returns the unread bytes. The slice aliases the buffer and is
+	// only valid until the next modification of the buffer.
 	Bytes() []byte
 
+	// CopyBytes returns a freshly allocated copy of the unread bytes, owned
+	// by the caller.
+	CopyBytes() []byte
+
 	Drain(offset int)
 
 	Alloc(int)
@@ -39,11 +99,84 @@ type IoBuffer interface {
 
 	String() string
 
+	// UnsafeString returns the unread bytes as a string without copying.
+	// The string aliases the buffer and is invalid once the buffer is
+	// written, drained, reset or returned to the pool.
+	UnsafeString() string
+
 	Count(int32) int32
 
 	EOF() bool
 
 	SetEOF(eof bool)
 
+	// SetAutoEOF makes ReadFrom and ReadOnce set the EOF flag when the reader
+	// returns io.EOF. It is off by default and for buffers taken from the pool.
+	SetAutoEOF(on bool)
+
+	// Close releases the backing slice and marks the buffer unusable, later
+	// reads and writes fail with ErrClosedBuffer. Alloc, which is called when
+	// the buffer is taken from the pool, reopens it.
+	Close() error
+
+	// Tee makes every byte consumed by Read, WriteTo, Drain and Cut also be
+	// appended to dst, e.g. to mirror traffic. nil stops teeing, and so does
+	// taking the buffer from the pool.
+	Tee(dst IoBuffer)
+
+	// Limit returns a read-only view over the next n unread bytes, e.g. one
+	// message body out of several pipelined ones. Reading the view consumes
+	// the buffer, reads beyond n bytes return io.EOF. Bytes consumed from
+	// the buffer directly are gone from the view too, bytes written later
+	// never show up in it. Reset, Free and Close of the view skip its
+	// unread bytes.
+	Limit(n int) IoBuffer
+
+	// DiscardAll drops all unread bytes.
+	DiscardAll()
+
+	// DrainTo writes at most n unread bytes straight from the buffer to w
+	// and drops them, returning the number of bytes written.
+	DrainTo(w io.Writer, n int) (int, error)
+
+	// MarshalJSON encodes the unread bytes without consuming them, as a
+	// base64 string, see JSONStringIoBuffer for plain strings.
+	MarshalJSON() ([]byte, error)
+
+	// UnmarshalJSON replaces the contents with the decoded bytes.
+	UnmarshalJSON(data []byte) error
+
+	// ReadCloser returns an io.ReadCloser reading the buffer, whose Close
+	// returns the buffer to the pool via PutIoBuffer
+	ReadCloser() io.ReadCloser
+
+	// Dump returns an xxd-style hexdump of at most maxBytes unread bytes,
+	// maxBytes <= 0 dumps the whole unread region
+	Dump(maxBytes int) string
+
+	// Format implements fmt.Formatter so that logging a buffer stays
+	// short: %v prints the length and an escaped preview of the first 64
+	// bytes, %+v a hexdump of the first 512 bytes, a precision such as
+	// %.16v sets the number of bytes. %s, %q, %x and %X print the whole
+	// contents like for a []byte.
+	Format(f fmt.State, verb rune)
+
+	// SetHooks sets instrumentation hooks overriding the default hooks,
+	// nil restores the default hooks
+	SetHooks(h *Hooks)
+
+	// Stats returns usage statistics of the buffer
+	Stats() BufferStats
+
+	// SpliceTo moves at most max bytes from src to dst, max <= 0 means until
+	// io.EOF. Buffered bytes are written to dst first. On Linux an empty
+	// buffer between two TCP connections is bypassed with splice(2).
+	SpliceTo(dst, src net.Conn, max int) (int64, error)
+
+	// ReadFromAsync reads r until io.EOF in a new goroutine, reporting
+	// progress and completion on the returned channel. The buffer mustn't be
+	// touched until a ReadResult with Done set has been received.
+	ReadFromAsync(r io.Reader) <-chan ReadResult
+
 }
 
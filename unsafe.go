@@ -0,0 +1,40 @@
+package buffer
+
+import "unsafe"
+
+// UnsafeBytes returns a slice aliasing the bytes of s without copying,
+// e.g. to hand a string to a Write method on hot paths. The slice must
+// not be modified, strings are immutable.
+func UnsafeBytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		Cap int
+	}{s, len(s)}))
+}
+
+// unsafeString returns a string aliasing p without copying
+func unsafeString(p []byte) string {
+	return *(*string)(unsafe.Pointer(&p))
+}
+
+// UnsafeString returns the unread portion of Buffer.B as a string without
+// copying. The string aliases the buffer and is invalid once the buffer is
+// modified, reset or returned to the pool, use String for a safe copy.
+func (b *Buffer) UnsafeString() string {
+	return unsafeString(b.B[b.off:])
+}
+
+func (b *ioBuffer) UnsafeString() string {
+	return unsafeString(b.buf[b.off:])
+}
+
+func (m *multiIoBuffer) UnsafeString() string {
+	return unsafeString(m.Bytes())
+}
+
+func (v *limitedIoBuffer) UnsafeString() string {
+	return unsafeString(v.Bytes())
+}
@@ -0,0 +1,69 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestUnsafeString(t *testing.T) {
+	var bb Buffer
+	bb.WriteString("hello world")
+	bb.Next(6)
+	if s := bb.UnsafeString(); s != "world" {
+		t.Errorf("Expect world, but got %q", s)
+	}
+
+	b := NewIoBufferString("hello world")
+	b.Drain(6)
+	s := b.UnsafeString()
+	if s != "world" {
+		t.Errorf("Expect world, but got %q", s)
+	}
+	// the string aliases the buffer
+	b.Bytes()[0] = 'W'
+	if s != "World" {
+		t.Errorf("Expect the string to alias the buffer, but got %q", s)
+	}
+
+	m := MultiIoBuffer(NewIoBufferString("ab"), NewIoBufferString("cd"))
+	if s := m.UnsafeString(); s != "abcd" {
+		t.Errorf("Expect abcd, but got %q", s)
+	}
+	if s := m.Limit(3).UnsafeString(); s != "abc" {
+		t.Errorf("Expect abc, but got %q", s)
+	}
+	if s := NewIoBuffer(0).UnsafeString(); s != "" {
+		t.Errorf("Expect an empty string, but got %q", s)
+	}
+}
+
+func TestUnsafeBytes(t *testing.T) {
+	if p := UnsafeBytes(""); len(p) != 0 {
+		t.Errorf("Expect an empty slice, but got %q", p)
+	}
+	p := UnsafeBytes("hello")
+	if string(p) != "hello" || cap(p) != 5 {
+		t.Errorf("Expect hello with cap 5, but got %q with cap %d", p, cap(p))
+	}
+
+	b := NewIoBuffer(0)
+	b.Write(UnsafeBytes("hello"))
+	if b.String() != "hello" {
+		t.Errorf("Expect hello, but got %q", b.String())
+	}
+}
+
+func BenchmarkIoBufferString(b *testing.B) {
+	buf := NewIoBufferString("GET /index.html HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	b.Run("String", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = buf.String()
+		}
+	})
+	b.Run("UnsafeString", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = buf.UnsafeString()
+		}
+	})
+}
@@ -0,0 +1,11 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package uring
+
+// io_uring syscall numbers shared by the architectures with the generic
+// syscall table
+const (
+	sysIoUringSetup = 425
+	sysIoUringEnter = 426
+)
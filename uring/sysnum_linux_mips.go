@@ -0,0 +1,7 @@
+package uring
+
+// io_uring syscall numbers of the mips o32 ABI, offset by 4000
+const (
+	sysIoUringSetup = 4425
+	sysIoUringEnter = 4426
+)
@@ -0,0 +1,7 @@
+package uring
+
+// io_uring syscall numbers of the mips64 n64 ABI, offset by 5000
+const (
+	sysIoUringSetup = 5425
+	sysIoUringEnter = 5426
+)
@@ -0,0 +1,55 @@
+// Package uring fills pooled buffers from file descriptors asynchronously
+// with io_uring.
+//
+// The package is experimental and Linux only, on other platforms New
+// returns ErrNotSupported. It is meant for proxies serving many
+// connections, where one ring replaces a goroutine blocked in Read per
+// connection:
+//
+//	r, err := uring.New(256)
+//	...
+//	r.AsyncFill(fd, 16<<10)
+//	for c := range r.Completions() {
+//		// handle c.Buffer or c.Err, then
+//		c.Release()
+//	}
+package uring
+
+import (
+	"errors"
+
+	"github.com/gottingen/buffer"
+)
+
+var (
+	// ErrNotSupported is returned by New when io_uring isn't available.
+	ErrNotSupported = errors.New("uring: io_uring not supported")
+	// ErrClosed is returned by AsyncFill after the ring has been closed.
+	ErrClosed = errors.New("uring: ring closed")
+	// ErrBusy is returned by AsyncFill when as many reads as the ring has
+	// entries are in flight.
+	ErrBusy = errors.New("uring: too many reads in flight")
+)
+
+// Completion is a finished read submitted by AsyncFill.
+type Completion struct {
+	// Fd is the file descriptor read from.
+	Fd int
+	// Buffer holds the bytes read, it is nil when Err is set.
+	Buffer buffer.IoBuffer
+	// Err is io.EOF when the peer closed, or the read error.
+	Err error
+
+	b *[]byte
+}
+
+// Release returns the pooled slice backing Buffer.
+//
+// Buffer mustn't be touched after releasing it.
+func (c *Completion) Release() {
+	if c.b != nil {
+		buffer.PutBytes(c.b)
+		c.b = nil
+		c.Buffer = nil
+	}
+}
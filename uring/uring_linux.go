@@ -0,0 +1,336 @@
+//go:build linux
+// +build linux
+
+package uring
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/gottingen/buffer"
+)
+
+const (
+	opNop         = 0
+	opAsyncCancel = 14
+	opRead        = 22
+
+	enterGetEvents = 1 << 0
+	featSingleMmap = 1 << 0
+
+	offSQRing = 0
+	offCQRing = 0x8000000
+	offSQEs   = 0x10000000
+
+	sqeSize = 64
+	cqeSize = 16
+
+	// wakeTag tags submissions whose completions are only meant to wake
+	// the reaper
+	wakeTag = 0
+)
+
+type sqringOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	flags       uint32
+	dropped     uint32
+	array       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type cqringOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	overflow    uint32
+	cqes        uint32
+	flags       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type params struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFd         uint32
+	resv         [3]uint32
+	sqOff        sqringOffsets
+	cqOff        cqringOffsets
+}
+
+type sqe struct {
+	opcode   uint8
+	flags    uint8
+	ioprio   uint16
+	fd       int32
+	off      uint64
+	addr     uint64
+	len      uint32
+	rwFlags  uint32
+	userData uint64
+	pad      [3]uint64
+}
+
+type cqe struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// request is a read in flight, it keeps the target slice reachable while
+// the kernel may write to it
+type request struct {
+	fd int
+	b  *[]byte
+}
+
+// Ring is an io_uring instance filling pooled buffers.
+type Ring struct {
+	fd int
+
+	sqMem, cqMem, sqeMem []byte
+
+	sqHead, sqTail, sqMask *uint32
+	sqArray                unsafe.Pointer
+	cqHead, cqTail, cqMask *uint32
+	cqes                   unsafe.Pointer
+
+	entries     int
+	completions chan Completion
+	done        chan struct{}
+
+	mu      sync.Mutex
+	nextTag uint64
+	pending map[uint64]request
+	closed  bool
+}
+
+// New returns a Ring with room for entries reads in flight.
+//
+// It returns ErrNotSupported when the kernel lacks io_uring or it's
+// disabled by policy.
+func New(entries int) (*Ring, error) {
+	var p params
+	fd, _, errno := syscall.Syscall(sysIoUringSetup, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		if errno == syscall.ENOSYS || errno == syscall.EPERM {
+			return nil, ErrNotSupported
+		}
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	r := &Ring{
+		fd:          int(fd),
+		entries:     int(p.sqEntries),
+		completions: make(chan Completion, p.cqEntries),
+		done:        make(chan struct{}),
+		nextTag:     wakeTag + 1,
+		pending:     make(map[uint64]request),
+	}
+	if err := r.mmap(&p); err != nil {
+		r.unmap()
+		syscall.Close(r.fd)
+		return nil, err
+	}
+	go r.reap()
+	return r, nil
+}
+
+func (r *Ring) mmap(p *params) error {
+	sqSize := int(p.sqOff.array + p.sqEntries*4)
+	cqSize := int(p.cqOff.cqes + p.cqEntries*cqeSize)
+	single := p.features&featSingleMmap != 0
+	if single && cqSize > sqSize {
+		sqSize = cqSize
+	}
+
+	var err error
+	prot := syscall.PROT_READ | syscall.PROT_WRITE
+	flags := syscall.MAP_SHARED | syscall.MAP_POPULATE
+	if r.sqMem, err = syscall.Mmap(r.fd, offSQRing, sqSize, prot, flags); err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	if single {
+		r.cqMem = r.sqMem
+	} else if r.cqMem, err = syscall.Mmap(r.fd, offCQRing, cqSize, prot, flags); err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	if r.sqeMem, err = syscall.Mmap(r.fd, offSQEs, int(p.sqEntries)*sqeSize, prot, flags); err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sqMem[p.sqOff.head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sqMem[p.sqOff.tail]))
+	r.sqMask = (*uint32)(unsafe.Pointer(&r.sqMem[p.sqOff.ringMask]))
+	r.sqArray = unsafe.Pointer(&r.sqMem[p.sqOff.array])
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cqMem[p.cqOff.head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cqMem[p.cqOff.tail]))
+	r.cqMask = (*uint32)(unsafe.Pointer(&r.cqMem[p.cqOff.ringMask]))
+	r.cqes = unsafe.Pointer(&r.cqMem[p.cqOff.cqes])
+	return nil
+}
+
+func (r *Ring) unmap() {
+	if r.sqeMem != nil {
+		syscall.Munmap(r.sqeMem)
+	}
+	if r.cqMem != nil && &r.cqMem[0] != &r.sqMem[0] {
+		syscall.Munmap(r.cqMem)
+	}
+	if r.sqMem != nil {
+		syscall.Munmap(r.sqMem)
+	}
+}
+
+// AsyncFill submits a read of at most n bytes from fd into a pooled buffer,
+// n <= 0 reads at most buffer.MinRead bytes. The result is delivered on
+// Completions.
+//
+// fd should be in blocking mode or be pollable, the kernel then waits for
+// data without occupying a thread.
+func (r *Ring) AsyncFill(fd int, n int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if len(r.pending) >= r.entries {
+		return ErrBusy
+	}
+	if n <= 0 {
+		n = buffer.MinRead
+	}
+
+	b := buffer.GetBytes(n)
+	tag := r.nextTag
+	r.nextTag++
+	err := r.submit(sqe{
+		opcode:   opRead,
+		fd:       int32(fd),
+		off:      ^uint64(0), // read from the current position
+		addr:     uint64(uintptr(unsafe.Pointer(&(*b)[0]))),
+		len:      uint32(n),
+		userData: tag,
+	})
+	if err != nil {
+		buffer.PutBytes(b)
+		return err
+	}
+	r.pending[tag] = request{fd: fd, b: b}
+	return nil
+}
+
+// Completions returns the channel finished reads are delivered on. It is
+// closed once the ring is closed and all reads in flight have finished.
+//
+// The channel must be drained, the ring stops reaping while it is full.
+func (r *Ring) Completions() <-chan Completion {
+	return r.completions
+}
+
+// Close cancels the reads in flight, waits for their completions to be
+// delivered and releases the ring.
+func (r *Ring) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+	r.closed = true
+	for tag := range r.pending {
+		r.submit(sqe{opcode: opAsyncCancel, fd: -1, addr: tag, userData: wakeTag})
+	}
+	// wakes the reaper even when nothing is in flight
+	r.submit(sqe{opcode: opNop, fd: -1, userData: wakeTag})
+	r.mu.Unlock()
+
+	<-r.done
+	r.unmap()
+	return syscall.Close(r.fd)
+}
+
+// submit queues e and enters the ring, so the submission queue is empty
+// whenever r.mu is released. It must be called with r.mu held.
+func (r *Ring) submit(e sqe) error {
+	tail := *r.sqTail
+	idx := tail & *r.sqMask
+	*(*sqe)(unsafe.Pointer(&r.sqeMem[uintptr(idx)*sqeSize])) = e
+	*(*uint32)(unsafe.Pointer(uintptr(r.sqArray) + uintptr(idx)*4)) = idx
+	atomic.StoreUint32(r.sqTail, tail+1)
+	for {
+		_, _, errno := syscall.Syscall6(sysIoUringEnter, uintptr(r.fd), 1, 0, 0, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			// take the entry back, the kernel didn't consume it
+			atomic.StoreUint32(r.sqTail, tail)
+			return os.NewSyscallError("io_uring_enter", errno)
+		}
+		return nil
+	}
+}
+
+// reap delivers completions until the ring is closed and drained
+func (r *Ring) reap() {
+	defer close(r.done)
+	defer close(r.completions)
+	for {
+		_, _, errno := syscall.Syscall6(sysIoUringEnter, uintptr(r.fd), 0, 1, enterGetEvents, 0, 0)
+		if errno != 0 && errno != syscall.EINTR {
+			return
+		}
+
+		head := *r.cqHead
+		tail := atomic.LoadUint32(r.cqTail)
+		for ; head != tail; head++ {
+			c := *(*cqe)(unsafe.Pointer(uintptr(r.cqes) + uintptr(head&*r.cqMask)*cqeSize))
+			atomic.StoreUint32(r.cqHead, head+1)
+			if c.userData == wakeTag {
+				continue
+			}
+			r.mu.Lock()
+			req, ok := r.pending[c.userData]
+			delete(r.pending, c.userData)
+			r.mu.Unlock()
+			if ok {
+				r.completions <- complete(req, c.res)
+			}
+		}
+
+		r.mu.Lock()
+		drained := r.closed && len(r.pending) == 0
+		r.mu.Unlock()
+		if drained {
+			return
+		}
+	}
+}
+
+func complete(req request, res int32) Completion {
+	c := Completion{Fd: req.fd}
+	switch {
+	case res > 0:
+		c.b = req.b
+		c.Buffer = buffer.NewIoBufferBytes((*req.b)[:res])
+	case res == 0:
+		buffer.PutBytes(req.b)
+		c.Err = io.EOF
+	default:
+		buffer.PutBytes(req.b)
+		c.Err = os.NewSyscallError("read", syscall.Errno(-res))
+	}
+	return c
+}
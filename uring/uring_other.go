@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package uring
+
+// Ring is an io_uring instance, it is unavailable on this platform.
+type Ring struct{}
+
+// New returns ErrNotSupported on platforms without io_uring.
+func New(entries int) (*Ring, error) {
+	return nil, ErrNotSupported
+}
+
+// AsyncFill returns ErrNotSupported on platforms without io_uring.
+func (r *Ring) AsyncFill(fd int, n int) error {
+	return ErrNotSupported
+}
+
+// Completions returns nil on platforms without io_uring.
+func (r *Ring) Completions() <-chan Completion {
+	return nil
+}
+
+// Close is a no-op on platforms without io_uring.
+func (r *Ring) Close() error {
+	return nil
+}
@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+package uring
+
+import (
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newRing(t *testing.T) *Ring {
+	r, err := New(8)
+	if err == ErrNotSupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func pipe(t *testing.T) (int, int) {
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		t.Fatal(err)
+	}
+	return p[0], p[1]
+}
+
+func next(t *testing.T, r *Ring) Completion {
+	select {
+	case c, ok := <-r.Completions():
+		if !ok {
+			t.Fatal("completions closed")
+		}
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for completion")
+	}
+	return Completion{}
+}
+
+func TestAsyncFill(t *testing.T) {
+	r := newRing(t)
+	defer r.Close()
+	rfd, wfd := pipe(t)
+	defer syscall.Close(rfd)
+
+	if err := r.AsyncFill(rfd, 1024); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Write(wfd, []byte("hello uring"))
+	c := next(t, r)
+	if c.Err != nil {
+		t.Fatal(c.Err)
+	}
+	if c.Fd != rfd || c.Buffer.String() != "hello uring" {
+		t.Errorf("unexpected completion fd %d, data %q", c.Fd, c.Buffer.String())
+	}
+	c.Release()
+	if c.Buffer != nil {
+		t.Error("Buffer should be nil after Release")
+	}
+
+	syscall.Close(wfd)
+	if err := r.AsyncFill(rfd, 1024); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(t, r); c.Err != io.EOF {
+		t.Errorf("Expect io.EOF, but got %v", c.Err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	r := newRing(t)
+	rfd, wfd := pipe(t)
+	defer syscall.Close(rfd)
+	defer syscall.Close(wfd)
+
+	for i := 0; i < 8; i++ {
+		if err := r.AsyncFill(rfd, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.AsyncFill(rfd, 0); err != ErrBusy {
+		t.Errorf("Expect ErrBusy, but got %v", err)
+	}
+
+	done := make(chan int)
+	go func() {
+		n := 0
+		for c := range r.Completions() {
+			if c.Err == nil {
+				t.Error("read should have been canceled")
+			}
+			n++
+		}
+		done <- n
+	}()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := <-done; n != 8 {
+		t.Errorf("Expect 8 canceled completions, but got %d", n)
+	}
+	if err := r.AsyncFill(rfd, 0); err != ErrClosed {
+		t.Errorf("Expect ErrClosed, but got %v", err)
+	}
+}
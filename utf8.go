@@ -0,0 +1,133 @@
+package buffer
+
+import "unicode/utf8"
+
+// appendValidUTF8 appends p to dst with each run of invalid UTF-8 replaced
+// by repl, returning the number of runs replaced
+func appendValidUTF8(dst, p, repl []byte) ([]byte, int) {
+	runs := 0
+	invalid := false
+	for i := 0; i < len(p); {
+		c := p[i]
+		if c < utf8.RuneSelf {
+			dst = append(dst, c)
+			i++
+			invalid = false
+			continue
+		}
+		_, size := utf8.DecodeRune(p[i:])
+		if size == 1 {
+			if !invalid {
+				dst = append(dst, repl...)
+				runs++
+				invalid = true
+			}
+			i++
+			continue
+		}
+		dst = append(dst, p[i:i+size]...)
+		i += size
+		invalid = false
+	}
+	return dst, runs
+}
+
+// replacementBytes returns the encoding of r, nothing for r < 0
+func replacementBytes(r rune, p *[utf8.UTFMax]byte) []byte {
+	if r < 0 {
+		return nil
+	}
+	return p[:utf8.EncodeRune(p[:], r)]
+}
+
+// sanitizeUTF8 returns p with invalid UTF-8 replaced in a pooled slice to be
+// put back by the caller, nil if p is valid
+func sanitizeUTF8(p []byte, replacement rune) (*[]byte, int) {
+	if utf8.Valid(p) {
+		return nil, 0
+	}
+	var buf [utf8.UTFMax]byte
+	repl := replacementBytes(replacement, &buf)
+	// every byte may be an invalid run of its own, append must not outgrow
+	// the pooled slice or the caller would put back a foreign one
+	size := len(p)
+	if len(repl) > 1 {
+		size *= len(repl)
+	}
+	out := GetBytes(size)
+	var runs int
+	*out, runs = appendValidUTF8((*out)[:0], p, repl)
+	return out, runs
+}
+
+// ValidUTF8 reports whether the unread bytes are valid UTF-8.
+func (b *Buffer) ValidUTF8() bool {
+	return utf8.Valid(b.B[b.off:])
+}
+
+// SanitizeUTF8 replaces each run of invalid UTF-8 in the unread bytes with
+// replacement, typically utf8.RuneError, a negative replacement drops the
+// invalid bytes. It returns the number of runs replaced.
+func (b *Buffer) SanitizeUTF8(replacement rune) int {
+	out, runs := sanitizeUTF8(b.B[b.off:], replacement)
+	if out == nil {
+		return 0
+	}
+	b.B = append(b.B[:b.off], *out...)
+	b.lastRead = opInvalid
+	PutBytes(out)
+	return runs
+}
+
+func (b *ioBuffer) ValidUTF8() bool {
+	return utf8.Valid(b.buf[b.off:])
+}
+
+func (b *ioBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	if b.closed {
+		return 0, opError("sanitize", 0, b, ErrClosedBuffer)
+	}
+	out, runs := sanitizeUTF8(b.buf[b.off:], replacement)
+	if out == nil {
+		return 0, nil
+	}
+	if d := len(*out) - b.Len(); d > 0 {
+		if err := b.checkGrow("sanitize", d); err != nil {
+			PutBytes(out)
+			return 0, err
+		}
+	}
+	b.buf = b.buf[:b.off]
+	m, ok := b.tryGrowByReslice(len(*out))
+	if !ok {
+		m = b.grow(len(*out))
+	}
+	copy(b.buf[m:], *out)
+	b.lastRune = 0
+	PutBytes(out)
+	return runs, nil
+}
+
+func (m *multiIoBuffer) ValidUTF8() bool {
+	return utf8.Valid(m.Bytes())
+}
+
+func (m *multiIoBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	if m.closed {
+		return 0, opError("sanitize", 0, m, ErrClosedBuffer)
+	}
+	m.coalesce()
+	if len(m.bufs) == 0 {
+		return 0, nil
+	}
+	m.lastRuneLen = 0
+	return m.bufs[0].SanitizeUTF8(replacement)
+}
+
+func (v *limitedIoBuffer) ValidUTF8() bool {
+	return utf8.Valid(v.Bytes())
+}
+
+func (v *limitedIoBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	return 0, v.readOnly("sanitize", 0)
+}
@@ -0,0 +1,82 @@
+package buffer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeUTF8(t *testing.T) {
+	cases := []struct {
+		in, out, drop string
+		runs          int
+	}{
+		{"hello, 世界", "hello, 世界", "hello, 世界", 0},
+		{"a\xffb", "a�b", "ab", 1},
+		{"\xff\xfe\xfdab\xe4\xb8", "�ab�", "ab", 2},
+		{"\xed\xa0\x80x", "�x", "x", 1}, // surrogate
+	}
+	for _, c := range cases {
+		var bb Buffer
+		bb.WriteString("skip" + c.in)
+		bb.Next(4)
+		if bb.ValidUTF8() != (c.runs == 0) {
+			t.Errorf("%q: unexpected ValidUTF8 %v", c.in, bb.ValidUTF8())
+		}
+		if runs := bb.SanitizeUTF8(utf8.RuneError); runs != c.runs || bb.String() != c.out {
+			t.Errorf("%q: Expect %q with %d runs, but got %q with %d", c.in, c.out, c.runs, bb.String(), runs)
+		}
+		if !bb.ValidUTF8() {
+			t.Errorf("%q: Expect valid UTF-8 after sanitizing", c.in)
+		}
+
+		b := NewIoBufferString("skip" + c.in)
+		b.Drain(4)
+		if runs, err := b.SanitizeUTF8(-1); err != nil || runs != c.runs || b.String() != c.drop {
+			t.Errorf("%q: Expect %q with %d runs, but got %q with %d, %v", c.in, c.drop, c.runs, b.String(), runs, err)
+		}
+	}
+}
+
+func TestSanitizeUTF8Views(t *testing.T) {
+	// a rune split across buffers is valid
+	m := MultiIoBuffer(NewIoBufferString("a\xe4"), NewIoBufferString("\xb8\x96\xff"))
+	if m.ValidUTF8() {
+		t.Error("Expect invalid UTF-8")
+	}
+	if runs, err := m.SanitizeUTF8('?'); runs != 1 || err != nil || m.String() != "a世?" {
+		t.Errorf("unexpected sanitizing: %q, %d, %v", m.String(), runs, err)
+	}
+
+	v := NewIoBufferString("ok\xff").Limit(2)
+	if !v.ValidUTF8() {
+		t.Error("Expect the view to be valid UTF-8")
+	}
+	if _, err := v.SanitizeUTF8('?'); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expect ErrReadOnly, but got %v", err)
+	}
+}
+
+func TestSanitizeUTF8Growth(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	// U+FFFD takes three bytes per invalid one, the pooled scratch slice must not be
+	// outgrown and put back as a foreign slice
+	in := strings.Repeat("\xffa", 40)
+	var bb Buffer
+	bb.WriteString(in)
+	if runs := bb.SanitizeUTF8(utf8.RuneError); runs != 40 || bb.String() != strings.Repeat("\uFFFDa", 40) {
+		t.Errorf("unexpected sanitizing: %q, %d", bb.String(), runs)
+	}
+
+	b := New(WithMaxSize(len(in) + 8))
+	b.WriteString(in)
+	if _, err := b.SanitizeUTF8(utf8.RuneError); !errors.Is(err, ErrTooLarge) || b.String() != in {
+		t.Errorf("Expect SanitizeUTF8 past the max size to fail, but got %q, %v", b.String(), err)
+	}
+	if runs, err := b.SanitizeUTF8('?'); err != nil || runs != 40 || b.String() != strings.Repeat("?a", 40) {
+		t.Errorf("unexpected sanitizing: %q, %d, %v", b.String(), runs, err)
+	}
+}
@@ -0,0 +1,566 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gottingen/atomic"
+)
+
+// The v2 interfaces split IoBuffer into its reading, writing and lifecycle
+// concerns, so that implementations such as RingBuffer only provide the
+// methods that make sense for them and lifecycle failures are returned
+// instead of being dropped. ToV2 and FromV2 convert between both worlds.
+
+// Reader is the read side of a buffer.
+type Reader interface {
+	io.Reader
+	io.WriterTo
+
+	// Peek returns the next n unread bytes without consuming them, or nil if
+	// fewer are buffered. The slice may alias the buffer and is only valid
+	// until the next modification of the buffer.
+	Peek(n int) []byte
+
+	// Discard drops the next n unread bytes. If fewer are buffered it drops
+	// them all and returns their number with io.EOF.
+	Discard(n int) (int, error)
+
+	// Len returns the number of unread bytes.
+	Len() int
+}
+
+// Writer is the write side of a buffer.
+type Writer interface {
+	io.Writer
+	io.StringWriter
+}
+
+// Lifecycle controls the memory of a buffer.
+type Lifecycle interface {
+	// Reset drops the contents and keeps the memory for reuse.
+	Reset() error
+
+	// Close releases the memory, the buffer mustn't be used afterwards.
+	Close() error
+}
+
+// IoBufferV2 is a buffer with all three concerns.
+type IoBufferV2 interface {
+	Reader
+	Writer
+	Lifecycle
+}
+
+// ToV2 returns b as an IoBufferV2. Buffers returned by FromV2 are unwrapped.
+func ToV2(b IoBuffer) IoBufferV2 {
+	if a, ok := b.(*v2IoBuffer); ok {
+		return a.v
+	}
+	return &ioBufferV2{b: b}
+}
+
+// FromV2 returns an IoBuffer backed by b. Buffers returned by ToV2 are
+// unwrapped.
+//
+// The IoBuffer reads and writes b through the v2 methods only: the in-place
+// transforms such as ReplaceRange or SanitizeUTF8 take the unread bytes out
+// of b and write the result back, UnreadRune always fails and SetHooks and
+// Compact have no effect. Bytes and Peek return what b.Peek returns, which
+// may be a copy. Views returned by Limit can't tell bytes consumed from b
+// behind their back from bytes written later, they only shrink to Len.
+func FromV2(b IoBufferV2) IoBuffer {
+	if a, ok := b.(*ioBufferV2); ok {
+		return a.b
+	}
+	return &v2IoBuffer{v: b, count: atomic.NewInt32(1)}
+}
+
+// ioBufferV2 adapts an IoBuffer to IoBufferV2
+type ioBufferV2 struct {
+	b IoBuffer
+}
+
+func (a *ioBufferV2) Read(p []byte) (int, error) {
+	return a.b.Read(p)
+}
+
+func (a *ioBufferV2) WriteTo(w io.Writer) (int64, error) {
+	return a.b.WriteTo(w)
+}
+
+func (a *ioBufferV2) Peek(n int) []byte {
+	return a.b.Peek(n)
+}
+
+func (a *ioBufferV2) Discard(n int) (int, error) {
+	if n < 0 {
+		return 0, opError("discard", n, a.b, ErrNegativeCount)
+	}
+	if l := a.b.Len(); n > l {
+		a.b.Drain(l)
+		return l, io.EOF
+	}
+	a.b.Drain(n)
+	return n, nil
+}
+
+func (a *ioBufferV2) Len() int {
+	return a.b.Len()
+}
+
+func (a *ioBufferV2) Write(p []byte) (int, error) {
+	return a.b.Write(p)
+}
+
+func (a *ioBufferV2) WriteString(s string) (int, error) {
+	return a.b.WriteString(s)
+}
+
+func (a *ioBufferV2) Reset() error {
+	a.b.Reset()
+	return nil
+}
+
+func (a *ioBufferV2) Close() error {
+	return a.b.Close()
+}
+
+// v2IoBuffer adapts an IoBufferV2 to IoBuffer
+type v2IoBuffer struct {
+	v       IoBufferV2
+	count   *atomic.Int32
+	eof     bool
+	autoEOF bool
+	closed  bool
+	tee     IoBuffer
+	stats   BufferStats
+}
+
+// consumed accounts p read from the buffer
+func (a *v2IoBuffer) consumed(p []byte) {
+	a.stats.BytesRead += int64(len(p))
+	if a.tee != nil && len(p) > 0 {
+		a.tee.Write(p)
+	}
+}
+
+func (a *v2IoBuffer) wrote(n int64) {
+	a.stats.BytesWritten += n
+	if l := a.v.Len(); l > a.stats.PeakLen {
+		a.stats.PeakLen = l
+	}
+}
+
+// rewrite takes the unread bytes out of the buffer and writes back what f
+// makes of them
+func (a *v2IoBuffer) rewrite(op string, f func(p []byte) []byte) error {
+	if a.closed {
+		return opError(op, 0, a, ErrClosedBuffer)
+	}
+	p := a.CopyBytes()
+	if _, err := a.v.Discard(len(p)); err != nil {
+		return opError(op, len(p), a, err)
+	}
+	if _, err := a.v.Write(f(p)); err != nil {
+		return opError(op, len(p), a, err)
+	}
+	return nil
+}
+
+func (a *v2IoBuffer) Read(p []byte) (int, error) {
+	if a.closed {
+		return 0, opError("read", len(p), a, ErrClosedBuffer)
+	}
+	n, err := a.v.Read(p)
+	a.consumed(p[:n])
+	return n, err
+}
+
+// readFrom reads r until io.EOF
+func (a *v2IoBuffer) readFrom(r io.Reader) (int64, error) {
+	if a.closed {
+		return 0, opError("read", 0, a, ErrClosedBuffer)
+	}
+	// hide the other methods of the buffer from io.Copy
+	n, err := io.Copy(struct{ io.Writer }{a.v}, r)
+	a.wrote(n)
+	if err != nil {
+		return n, opError("read", 0, a, err)
+	}
+	return n, nil
+}
+
+func (a *v2IoBuffer) ReadFrom(r io.Reader) (int64, error) {
+	n, err := a.readFrom(r)
+	if err == nil && a.autoEOF {
+		a.eof = true
+	}
+	return n, err
+}
+
+func (a *v2IoBuffer) ReadFromLimit(r io.Reader, max int64) (int64, error) {
+	if max < 0 {
+		panic(opError("read", int(max), a, ErrNegativeCount))
+	}
+	n, err := a.readFrom(io.LimitReader(r, max))
+	if err != nil {
+		return n, err
+	}
+	if n == max {
+		// one byte past max tells whether r has more
+		var p [1]byte
+		if m, _ := io.ReadFull(r, p[:]); m > 0 {
+			return n, opError("read", int(max), a, ErrLimitExceeded)
+		}
+	}
+	if a.autoEOF {
+		a.eof = true
+	}
+	return n, nil
+}
+
+func (a *v2IoBuffer) ReadOnce(r io.Reader, duration time.Duration) (int64, error) {
+	if a.closed {
+		return 0, opError("read", 0, a, ErrClosedBuffer)
+	}
+	if conn, ok := r.(net.Conn); ok {
+		conn.SetReadDeadline(time.Now().Add(duration))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+	p := GetBytes(MinRead)
+	defer PutBytes(p)
+	m, e := r.Read(*p)
+	if m > 0 {
+		if _, err := a.v.Write((*p)[:m]); err != nil {
+			return 0, opError("read", m, a, err)
+		}
+		a.wrote(int64(m))
+	}
+	if e != nil {
+		if e == io.EOF && a.autoEOF {
+			a.eof = true
+		}
+		return int64(m), opError("read", 0, a, e)
+	}
+	return int64(m), nil
+}
+
+func (a *v2IoBuffer) Write(p []byte) (int, error) {
+	if a.closed {
+		return 0, opError("write", len(p), a, ErrClosedBuffer)
+	}
+	n, err := a.v.Write(p)
+	a.wrote(int64(n))
+	return n, err
+}
+
+func (a *v2IoBuffer) WriteString(s string) (int, error) {
+	if a.closed {
+		return 0, opError("write", len(s), a, ErrClosedBuffer)
+	}
+	n, err := a.v.WriteString(s)
+	a.wrote(int64(n))
+	return n, err
+}
+
+func (a *v2IoBuffer) WriteRune(r rune) (int, error) {
+	var p [utf8.UTFMax]byte
+	return a.Write(p[:utf8.EncodeRune(p[:], r)])
+}
+
+func (a *v2IoBuffer) ReadRune() (r rune, size int, err error) {
+	if a.closed {
+		return 0, 0, opError("read", 0, a, ErrClosedBuffer)
+	}
+	n := a.v.Len()
+	if n == 0 {
+		return 0, 0, io.EOF
+	}
+	if n > utf8.UTFMax {
+		n = utf8.UTFMax
+	}
+	p := a.v.Peek(n)
+	r, size = utf8.DecodeRune(p)
+	a.consumed(p[:size])
+	a.v.Discard(size)
+	return r, size, nil
+}
+
+// UnreadRune fails, the rune has been discarded from the v2 buffer
+func (a *v2IoBuffer) UnreadRune() error {
+	return opError("unread rune", 0, a, ErrUnreadRune)
+}
+
+func (a *v2IoBuffer) WriteTo(w io.Writer) (int64, error) {
+	if a.closed {
+		return 0, opError("write to", 0, a, ErrClosedBuffer)
+	}
+	n, err := a.v.WriteTo(&v2TeeWriter{w: w, a: a})
+	if err != nil {
+		return n, opError("write to", 0, a, err)
+	}
+	return n, nil
+}
+
+// v2TeeWriter accounts the bytes the v2 buffer writes to w
+type v2TeeWriter struct {
+	w io.Writer
+	a *v2IoBuffer
+}
+
+func (t *v2TeeWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if n > len(p) {
+		panic(opError("write to", n, t.a, ErrInvalidWriteCount))
+	}
+	t.a.consumed(p[:n])
+	return n, err
+}
+
+func (a *v2IoBuffer) Peek(n int) []byte {
+	return a.v.Peek(n)
+}
+
+func (a *v2IoBuffer) ValidUTF8() bool {
+	return utf8.Valid(a.Bytes())
+}
+
+func (a *v2IoBuffer) SanitizeUTF8(replacement rune) (int, error) {
+	out, runs := sanitizeUTF8(a.Bytes(), replacement)
+	if out == nil {
+		return 0, nil
+	}
+	defer PutBytes(out)
+	return runs, a.rewrite("sanitize", func([]byte) []byte {
+		return *out
+	})
+}
+
+func (a *v2IoBuffer) ToUpperASCII() {
+	a.rewrite("upper", func(p []byte) []byte {
+		toUpperASCII(p)
+		return p
+	})
+}
+
+func (a *v2IoBuffer) ToLowerASCII() {
+	a.rewrite("lower", func(p []byte) []byte {
+		toLowerASCII(p)
+		return p
+	})
+}
+
+func (a *v2IoBuffer) ReplaceByte(old, new byte) {
+	a.rewrite("replace", func(p []byte) []byte {
+		replaceByte(p, old, new)
+		return p
+	})
+}
+
+func (a *v2IoBuffer) ReplaceRange(from, to int, replacement []byte) error {
+	if a.closed {
+		return opError("replace", len(replacement), a, ErrClosedBuffer)
+	}
+	if from < 0 || from > to || to > a.v.Len() {
+		panic(opError("replace", to-from, a, ErrOutOfRange))
+	}
+	return a.rewrite("replace", func(p []byte) []byte {
+		out := make([]byte, 0, len(p)-(to-from)+len(replacement))
+		out = append(out, p[:from]...)
+		out = append(out, replacement...)
+		return append(out, p[to:]...)
+	})
+}
+
+func (a *v2IoBuffer) Compact(bool) {}
+
+func (a *v2IoBuffer) Window() ([]byte, func(consumed int)) {
+	return window(a, a.Bytes(), a.Drain)
+}
+
+func (a *v2IoBuffer) Bytes() []byte {
+	return a.v.Peek(a.v.Len())
+}
+
+func (a *v2IoBuffer) CopyBytes() []byte {
+	return append([]byte(nil), a.Bytes()...)
+}
+
+func (a *v2IoBuffer) Drain(offset int) {
+	if offset < 0 {
+		panic(opError("drain", offset, a, ErrNegativeCount))
+	}
+	if offset > a.v.Len() {
+		return
+	}
+	if a.tee != nil {
+		a.consumed(a.v.Peek(offset))
+	} else {
+		a.stats.BytesRead += int64(offset)
+	}
+	a.v.Discard(offset)
+}
+
+func (a *v2IoBuffer) DiscardAll() {
+	a.Drain(a.v.Len())
+}
+
+func (a *v2IoBuffer) DrainTo(w io.Writer, n int) (int, error) {
+	if n < 0 {
+		panic(opError("drain to", n, a, ErrNegativeCount))
+	}
+	if a.closed {
+		return 0, opError("drain to", n, a, ErrClosedBuffer)
+	}
+	if l := a.v.Len(); n > l {
+		n = l
+	}
+	p := a.v.Peek(n)
+	m, err := w.Write(p)
+	if m > n {
+		panic(opError("drain to", m, a, ErrInvalidWriteCount))
+	}
+	a.Drain(m)
+	if err != nil {
+		return m, opError("drain to", n, a, err)
+	}
+	if m < n {
+		return m, io.ErrShortWrite
+	}
+	return m, nil
+}
+
+// Alloc drops the contents, the memory belongs to the v2 buffer
+func (a *v2IoBuffer) Alloc(int) {
+	a.v.Reset()
+	a.stats = BufferStats{}
+	a.eof = false
+	a.autoEOF = false
+	a.tee = nil
+}
+
+func (a *v2IoBuffer) Free() {
+	a.v.Reset()
+}
+
+func (a *v2IoBuffer) Len() int {
+	return a.v.Len()
+}
+
+// Cap returns the capacity of the v2 buffer if it tells, Len otherwise
+func (a *v2IoBuffer) Cap() int {
+	if c, ok := a.v.(interface{ Cap() int }); ok {
+		return c.Cap()
+	}
+	return a.v.Len()
+}
+
+func (a *v2IoBuffer) Reset() {
+	a.v.Reset()
+	a.eof = false
+}
+
+func (a *v2IoBuffer) Clone() IoBuffer {
+	buf := GetIoBuffer(a.v.Len())
+	buf.Write(a.Bytes())
+	buf.SetEOF(a.eof)
+	return buf
+}
+
+func (a *v2IoBuffer) String() string {
+	return string(a.Bytes())
+}
+
+func (a *v2IoBuffer) UnsafeString() string {
+	return unsafeString(a.Bytes())
+}
+
+func (a *v2IoBuffer) Count(count int32) int32 {
+	return a.count.Add(count)
+}
+
+func (a *v2IoBuffer) EOF() bool {
+	return a.eof
+}
+
+func (a *v2IoBuffer) SetEOF(eof bool) {
+	a.eof = eof
+}
+
+func (a *v2IoBuffer) SetAutoEOF(on bool) {
+	a.autoEOF = on
+}
+
+func (a *v2IoBuffer) Close() error {
+	if a.closed {
+		return opError("close", 0, a, ErrClosedBuffer)
+	}
+	a.closed = true
+	return a.v.Close()
+}
+
+func (a *v2IoBuffer) Tee(dst IoBuffer) {
+	a.tee = dst
+}
+
+func (a *v2IoBuffer) Limit(n int) IoBuffer {
+	return newLimitedIoBuffer(a, n)
+}
+
+func (a *v2IoBuffer) MarshalJSON() ([]byte, error) {
+	return marshalJSON(a.Bytes(), false)
+}
+
+func (a *v2IoBuffer) UnmarshalJSON(data []byte) error {
+	p, err := unmarshalJSON(data, false)
+	if err != nil {
+		return err
+	}
+	a.Reset()
+	_, err = a.Write(p)
+	return err
+}
+
+func (a *v2IoBuffer) ReadCloser() io.ReadCloser {
+	return &ioBufferReadCloser{b: a}
+}
+
+func (a *v2IoBuffer) Dump(maxBytes int) string {
+	return dump(a.Bytes(), maxBytes)
+}
+
+func (a *v2IoBuffer) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, a.Bytes())
+}
+
+func (a *v2IoBuffer) SetHooks(*Hooks) {}
+
+func (a *v2IoBuffer) Stats() BufferStats {
+	return a.stats
+}
+
+func (a *v2IoBuffer) SpliceTo(dst, src net.Conn, max int) (int64, error) {
+	if a.closed {
+		return 0, opError("splice", max, a, ErrClosedBuffer)
+	}
+	written, err := a.WriteTo(dst)
+	if err != nil {
+		return written, err
+	}
+	var r io.Reader = src
+	if max > 0 {
+		r = io.LimitReader(src, int64(max))
+	}
+	n, err := io.Copy(dst, r)
+	return written + n, err
+}
+
+func (a *v2IoBuffer) ReadFromAsync(r io.Reader) <-chan ReadResult {
+	return readFromAsync(func(r io.Reader, _ func(int64)) (int64, error) {
+		return a.ReadFrom(r)
+	}, r)
+}
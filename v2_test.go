@@ -0,0 +1,122 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var (
+	_ Reader = (*RingBuffer)(nil)
+	_ Writer = (*RingBuffer)(nil)
+)
+
+// ringV2 is a RingBuffer with the v2 lifecycle
+type ringV2 struct {
+	*RingBuffer
+}
+
+func (r ringV2) Reset() error {
+	r.RingBuffer.Reset()
+	return nil
+}
+
+func (r ringV2) Close() error {
+	r.Release()
+	return nil
+}
+
+func TestToV2(t *testing.T) {
+	buf := NewIoBufferString("hello world")
+	v := ToV2(buf)
+	if p := v.Peek(5); string(p) != "hello" {
+		t.Fatalf("got %q", p)
+	}
+	if n, err := v.Discard(6); n != 6 || err != nil {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	v.WriteString("!")
+	if n, err := v.Discard(10); n != 6 || err != io.EOF {
+		t.Fatalf("got %d, %v, want 6, EOF", n, err)
+	}
+	if _, err := v.Discard(-1); !errors.Is(err, ErrNegativeCount) {
+		t.Fatalf("got %v, want ErrNegativeCount", err)
+	}
+	if err := v.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Close(); !errors.Is(err, ErrClosedBuffer) {
+		t.Fatalf("got %v, want ErrClosedBuffer", err)
+	}
+	if FromV2(ToV2(buf)) != buf {
+		t.Fatal("FromV2 didn't unwrap")
+	}
+}
+
+func TestFromV2(t *testing.T) {
+	rb := ringV2{NewRingBuffer(64, RingReject)}
+	buf := FromV2(rb)
+	if ToV2(buf) != IoBufferV2(rb) {
+		t.Fatal("ToV2 didn't unwrap")
+	}
+
+	buf.WriteString("hello ")
+	buf.Write([]byte("wörld"))
+	if buf.Len() != 12 || buf.Cap() != 64 {
+		t.Fatalf("len %d cap %d", buf.Len(), buf.Cap())
+	}
+	tee := NewIoBuffer(0)
+	buf.Tee(tee)
+	p := make([]byte, 6)
+	if n, _ := buf.Read(p); string(p[:n]) != "hello " {
+		t.Fatalf("got %q", p[:n])
+	}
+	buf.ToUpperASCII()
+	if buf.String() != "WöRLD" {
+		t.Fatalf("got %q", buf.String())
+	}
+	if err := buf.ReplaceRange(1, 3, []byte("o")); err != nil {
+		t.Fatal(err)
+	}
+	if r, size, _ := buf.ReadRune(); r != 'W' || size != 1 {
+		t.Fatalf("got %q, %d", r, size)
+	}
+	if err := buf.UnreadRune(); !errors.Is(err, ErrUnreadRune) {
+		t.Fatalf("got %v, want ErrUnreadRune", err)
+	}
+	var out bytes.Buffer
+	if n, err := buf.WriteTo(&out); n != 4 || err != nil || out.String() != "oRLD" {
+		t.Fatalf("got %d, %v, %q", n, err, out.String())
+	}
+	if tee.String() != "hello WoRLD" {
+		t.Fatalf("tee got %q", tee.String())
+	}
+	if s := buf.Stats(); s.BytesRead != 11 || s.BytesWritten != 12 {
+		t.Fatalf("stats %+v", s)
+	}
+
+	buf.Tee(nil)
+	if _, err := buf.ReadFromLimit(strings.NewReader("0123456789"), 4); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got %v, want ErrLimitExceeded", err)
+	}
+	buf.SetAutoEOF(true)
+	if n, err := buf.ReadFrom(strings.NewReader("abc")); n != 3 || err != nil || !buf.EOF() {
+		t.Fatalf("got %d, %v, eof %v", n, err, buf.EOF())
+	}
+	if buf.String() != "0123abc" {
+		t.Fatalf("got %q", buf.String())
+	}
+	// the ring rejects what doesn't fit
+	if _, err := buf.Write(make([]byte, 64)); !errors.Is(err, ErrRingFull) {
+		t.Fatalf("got %v, want ErrRingFull", err)
+	}
+
+	if err := buf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.Write(p); !errors.Is(err, ErrClosedBuffer) {
+		t.Fatalf("got %v, want ErrClosedBuffer", err)
+	}
+}
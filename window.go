@@ -0,0 +1,30 @@
+package buffer
+
+// window returns p and a commit function draining at most len(p) bytes
+// through drain
+func window(b IoBuffer, p []byte, drain func(int)) ([]byte, func(consumed int)) {
+	return p, func(consumed int) {
+		if consumed < 0 || consumed > len(p) {
+			panic(opError("commit", consumed, b, ErrOutOfRange))
+		}
+		drain(consumed)
+	}
+}
+
+func (b *ioBuffer) Window() ([]byte, func(consumed int)) {
+	return window(b, b.buf[b.off:], b.Drain)
+}
+
+// Window hands out the first buffer only, the segments aren't coalesced
+func (m *multiIoBuffer) Window() ([]byte, func(consumed int)) {
+	m.releaseExhausted()
+	var p []byte
+	if len(m.bufs) > 0 {
+		p = m.bufs[0].Bytes()
+	}
+	return window(m, p, m.Drain)
+}
+
+func (v *limitedIoBuffer) Window() ([]byte, func(consumed int)) {
+	return window(v, v.Bytes(), v.Drain)
+}
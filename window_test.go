@@ -0,0 +1,56 @@
+package buffer
+
+import (
+	"bytes"
+	"testing"
+)
+
+// parseLines reads the complete lines of b through Window
+func parseLines(b IoBuffer) []string {
+	var lines []string
+	var partial []byte
+	for {
+		p, commit := b.Window()
+		if len(p) == 0 {
+			break
+		}
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			// the line continues in the next segment
+			partial = append(partial, p...)
+			commit(len(p))
+			continue
+		}
+		lines = append(lines, string(append(partial, p[:i]...)))
+		partial = partial[:0]
+		commit(i + 1)
+	}
+	return lines
+}
+
+func TestWindow(t *testing.T) {
+	b := NewIoBufferString("one\ntwo\nthree")
+	p, commit := b.Window()
+	if string(p) != "one\ntwo\nthree" {
+		t.Fatalf("unexpected window: %q", p)
+	}
+	commit(4)
+	if b.String() != "two\nthree" {
+		t.Errorf("Expect the commit to drain, but got %q", b.String())
+	}
+	expectPanic(t, "commit past the window", func() { commit(len(p) + 1) })
+
+	m := MultiIoBuffer(NewIoBufferString("on"), NewIoBufferString("e\ntw"), NewIoBufferString("o\n"))
+	if p, _ := m.Window(); string(p) != "on" {
+		t.Errorf("Expect the first segment, but got %q", p)
+	}
+	if lines := parseLines(m); len(lines) != 2 || lines[0] != "one" || lines[1] != "two" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+
+	src := NewIoBufferString("a\nb\nc\n")
+	v := src.Limit(4)
+	if lines := parseLines(v); len(lines) != 2 || src.String() != "c\n" {
+		t.Errorf("unexpected lines through the view: %q, rest %q", lines, src.String())
+	}
+}
@@ -0,0 +1,60 @@
+package buffer
+
+import (
+	"io"
+	"net"
+)
+
+// WriteAllTo writes the unread bytes of bufs to w in order, with one
+// vectored write, a writev on connections of package net, and sequential
+// writes otherwise. The segments of MultiIoBuffers are written without
+// coalescing them.
+//
+// The bytes written are drained from the buffers, written[i] is the number
+// of bytes written from bufs[i]. After a failure the buffers hold what is
+// left to write, so the call can simply be retried.
+func WriteAllTo(w io.Writer, bufs ...IoBuffer) (written []int64, err error) {
+	written = make([]int64, len(bufs))
+	_, _, err = writeAllTo(w, bufs, written, nil)
+	return written, err
+}
+
+// writeAllTo implements WriteAllTo returning the total written, written may
+// be nil. vec is scratch space returned emptied for reuse.
+func writeAllTo(w io.Writer, bufs []IoBuffer, written []int64, vec net.Buffers) (int64, net.Buffers, error) {
+	vec = vec[:0]
+	for _, b := range bufs {
+		if m, ok := b.(*multiIoBuffer); ok {
+			for _, seg := range m.bufs {
+				if seg.Len() > 0 {
+					vec = append(vec, seg.Bytes())
+				}
+			}
+		} else if b.Len() > 0 {
+			vec = append(vec, b.Bytes())
+		}
+	}
+	// WriteTo consumes the slice it is called on
+	v := vec
+	total, err := v.WriteTo(w)
+	for i := range vec {
+		vec[i] = nil
+	}
+
+	n := total
+	for i, b := range bufs {
+		if n == 0 {
+			break
+		}
+		k := int64(b.Len())
+		if n < k {
+			k = n
+		}
+		b.Drain(int(k))
+		n -= k
+		if written != nil {
+			written[i] = k
+		}
+	}
+	return total, vec[:0], err
+}
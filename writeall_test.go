@@ -0,0 +1,62 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestWriteAllTo(t *testing.T) {
+	var out bytes.Buffer
+	bufs := []IoBuffer{NewIoBufferString("head|"), NewIoBufferEOF(), newMulti(), NewIoBufferString("|tail")}
+	written, err := WriteAllTo(&out, bufs...)
+	if err != nil || out.String() != "head|header|body|trailer|tail" {
+		t.Fatalf("unexpected output %q, %v", out.String(), err)
+	}
+	for i, want := range []int64{5, 0, 19, 5} {
+		if written[i] != want || bufs[i].Len() != 0 {
+			t.Errorf("Expect %d bytes written from buffer %d, but got %d", want, i, written[i])
+		}
+	}
+
+	// a failed write is retried with the buffers left
+	w := &failingWriter{limit: 7}
+	bufs = []IoBuffer{NewIoBufferString("12345"), NewIoBufferString("67890")}
+	written, err = WriteAllTo(w, bufs...)
+	if err != errWriterFull || written[0] != 5 || written[1] != 2 || bufs[1].String() != "890" {
+		t.Fatalf("unexpected partial write %v, %v", written, err)
+	}
+	w.limit = 100
+	if written, err = WriteAllTo(w, bufs...); err != nil || written[0] != 0 || written[1] != 3 {
+		t.Errorf("unexpected retry %v, %v", written, err)
+	}
+	if w.String() != "1234567890" {
+		t.Errorf("Expect the bytes in order, but got %q", w.String())
+	}
+}
+
+func TestWriteAllToConn(t *testing.T) {
+	c1, c2 := tcpPair(t)
+	defer c1.Close()
+	defer c2.Close()
+
+	var bufs []IoBuffer
+	var want bytes.Buffer
+	for i := 0; i < 100; i++ {
+		s := randString(randN(4096) + 1)
+		want.WriteString(s)
+		bufs = append(bufs, NewIoBufferString(s))
+	}
+	done := make(chan []byte)
+	go func() {
+		got, _ := ioutil.ReadAll(io.LimitReader(c2, int64(want.Len())))
+		done <- got
+	}()
+	if _, err := WriteAllTo(c1, bufs...); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-done; !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("Expect %d bytes over the connection, but got %d", want.Len(), len(got))
+	}
+}
@@ -0,0 +1,67 @@
+package buffer
+
+import (
+	"strconv"
+	"time"
+)
+
+// The methods below mirror go.uber.org/zap/buffer.Buffer, so encoders
+// written against zap's buffer can use Buffer and its pools unchanged.
+
+// NewPool returns a new byte buffer pool.
+//
+// The purpose of this function is zap buffer.NewPool compatibility.
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// AppendByte writes a single byte to the Buffer.
+func (b *Buffer) AppendByte(v byte) {
+	b.B = append(b.B, v)
+}
+
+// AppendBytes writes a byte slice to the Buffer.
+func (b *Buffer) AppendBytes(v []byte) {
+	b.B = append(b.B, v...)
+}
+
+// AppendString writes a string to the Buffer.
+func (b *Buffer) AppendString(s string) {
+	b.B = append(b.B, s...)
+}
+
+// AppendInt appends an integer to the Buffer.
+func (b *Buffer) AppendInt(i int64) {
+	b.B = strconv.AppendInt(b.B, i, 10)
+}
+
+// AppendUint appends an unsigned integer to the Buffer.
+func (b *Buffer) AppendUint(i uint64) {
+	b.B = strconv.AppendUint(b.B, i, 10)
+}
+
+// AppendBool appends a bool to the Buffer.
+func (b *Buffer) AppendBool(v bool) {
+	b.B = strconv.AppendBool(b.B, v)
+}
+
+// AppendFloat appends a float to the Buffer. bitSize is 32 or 64.
+func (b *Buffer) AppendFloat(f float64, bitSize int) {
+	b.B = strconv.AppendFloat(b.B, f, 'f', -1, bitSize)
+}
+
+// AppendTime appends the time formatted using the specified layout.
+func (b *Buffer) AppendTime(t time.Time, layout string) {
+	b.B = t.AppendFormat(b.B, layout)
+}
+
+// Free returns the Buffer to the pool it was obtained from. Buffers not
+// obtained from a pool are left alone, they aren't adopted by the default
+// pool.
+//
+// The buffer mustn't be accessed after calling Free.
+func (b *Buffer) Free() {
+	if b.pool != nil {
+		b.pool.Put(b)
+	}
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferAppend(t *testing.T) {
+	p := NewPool()
+	b := p.Get()
+	b.AppendByte('v')
+	b.AppendBytes([]byte("=1"))
+	b.AppendString(" i=")
+	b.AppendInt(-42)
+	b.AppendString(" u=")
+	b.AppendUint(42)
+	b.AppendString(" b=")
+	b.AppendBool(true)
+	b.AppendString(" f=")
+	b.AppendFloat(3.25, 64)
+	b.AppendString(" t=")
+	b.AppendTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), time.RFC3339)
+
+	expectedS := "v=1 i=-42 u=42 b=true f=3.25 t=2020-01-02T03:04:05Z"
+	if b.String() != expectedS {
+		t.Fatalf("unexpected result: %q. Expecting %q", b.B, expectedS)
+	}
+	b.Free()
+
+	b = p.Get()
+	if b.Len() != 0 {
+		t.Fatalf("non-empty byte buffer returned from pool")
+	}
+	b.Free()
+}
+
+func TestBufferFreeWithoutPool(t *testing.T) {
+	var b Buffer
+	b.AppendString("foo")
+	b.Free()
+	// a pooled buffer would have been reset
+	if b.String() != "foo" {
+		t.Fatalf("unexpected result: %q. Expecting the buffer to stay untouched", b.B)
+	}
+}